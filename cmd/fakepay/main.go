@@ -0,0 +1,194 @@
+//go:build dev
+
+package main
+
+import (
+	"bytes"
+	"computer-club/internal/config"
+	"computer-club/internal/payments"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Локальный тестовый платежный провайдер. Принимает создание платежей от сервиса,
+// показывает страницу оплаты с кнопками "Оплатить" и "Отклонить" и отправляет
+// подписанное уведомление на callback_url
+type intent struct {
+	payments.FakeIntentRequest
+	ID     string
+	Status string
+}
+
+type server struct {
+	secret  string
+	baseURL string
+	client  *http.Client
+	mu      sync.Mutex
+	intents map[string]*intent
+}
+
+var payPage = template.Must(template.New("pay").Parse(`<!DOCTYPE html>
+<html><head><meta charset="utf-8"><title>Fakepay</title></head>
+<body>
+<h1>Оплата {{.Amount}}</h1>
+<p>{{.Description}}</p>
+<p>Статус: {{.Status}}</p>
+{{if eq .Status "pending"}}
+<form method="post" action="/pay/{{.ID}}?status=succeeded"><button>Оплатить</button></form>
+<form method="post" action="/pay/{{.ID}}?status=failed"><button>Отклонить</button></form>
+{{end}}
+</body></html>`))
+
+func main() {
+	cfg := config.LoadConfig()
+	if cfg.Payments.WebhookSecret == "" {
+		log.Fatal("PAYMENTS_WEBHOOK_SECRET не задан")
+	}
+	s := &server{
+		secret:  cfg.Payments.WebhookSecret,
+		baseURL: cfg.Payments.FakePayURL,
+		client:  &http.Client{Timeout: 10 * time.Second},
+		intents: make(map[string]*intent),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/intents", s.createIntent)
+	mux.HandleFunc("/intents/", s.refund)
+	mux.HandleFunc("/pay/", s.pay)
+
+	fmt.Println("Fakepay запущен на порту:", cfg.Payments.FakePayPort)
+	log.Fatal(http.ListenAndServe(":"+cfg.Payments.FakePayPort, mux))
+}
+
+func (s *server) createIntent(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	body, ok := s.readSigned(w, r)
+	if !ok {
+		return
+	}
+	var req payments.FakeIntentRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+
+	buf := make([]byte, 8)
+	rand.Read(buf)
+	it := &intent{FakeIntentRequest: req, ID: "pi_" + hex.EncodeToString(buf), Status: "pending"}
+
+	s.mu.Lock()
+	s.intents[it.ID] = it
+	s.mu.Unlock()
+	log.Printf("Создан платеж %s на %s (reference %d)", it.ID, req.Amount, req.Reference)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(payments.FakeIntentResponse{
+		ID:          it.ID,
+		RedirectURL: s.baseURL + "/pay/" + it.ID,
+	})
+}
+
+// pay показывает страницу оплаты (GET) или завершает платеж и отправляет уведомление (POST)
+func (s *server) pay(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimPrefix(r.URL.Path, "/pay/")
+	s.mu.Lock()
+	it, ok := s.intents[id]
+	s.mu.Unlock()
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	if r.Method == http.MethodPost {
+		status := payments.EventStatus(r.URL.Query().Get("status"))
+		if status != payments.EventSucceeded && status != payments.EventFailed {
+			http.Error(w, "unknown status", http.StatusBadRequest)
+			return
+		}
+		s.mu.Lock()
+		if it.Status == "pending" {
+			it.Status = string(status)
+		}
+		s.mu.Unlock()
+		if err := s.notify(it, status); err != nil {
+			log.Printf("Не удалось отправить уведомление по платежу %s: %v", it.ID, err)
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	payPage.Execute(w, it)
+}
+
+func (s *server) refund(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/intents/"), "/refund")
+	if r.Method != http.MethodPost || !strings.HasSuffix(r.URL.Path, "/refund") {
+		http.NotFound(w, r)
+		return
+	}
+	if _, ok := s.readSigned(w, r); !ok {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	it, ok := s.intents[id]
+	if !ok || it.Status != string(payments.EventSucceeded) {
+		http.Error(w, "intent is not refundable", http.StatusConflict)
+		return
+	}
+	it.Status = "refunded"
+	log.Printf("Платеж %s возвращен", it.ID)
+	w.WriteHeader(http.StatusOK)
+}
+
+// notify отправляет подписанное уведомление. Повторное нажатие на странице повторяет уведомление,
+// что позволяет проверить идемпотентность зачисления
+func (s *server) notify(it *intent, status payments.EventStatus) error {
+	body, err := json.Marshal(payments.FakeWebhook{
+		IntentID:  it.ID,
+		Reference: it.Reference,
+		Status:    status,
+		Amount:    it.Amount,
+	})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, it.CallbackURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(payments.SignatureHeader, payments.Sign(s.secret, body))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	log.Printf("Уведомление по платежу %s: ответ %d", it.ID, resp.StatusCode)
+	return nil
+}
+
+func (s *server) readSigned(w http.ResponseWriter, r *http.Request) ([]byte, bool) {
+	var buf bytes.Buffer
+	if _, err := buf.ReadFrom(r.Body); err != nil {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return nil, false
+	}
+	if !payments.Verify(s.secret, buf.Bytes(), r.Header.Get(payments.SignatureHeader)) {
+		http.Error(w, "invalid signature", http.StatusUnauthorized)
+		return nil, false
+	}
+	return buf.Bytes(), true
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"computer-club/internal/config"
+	"computer-club/internal/repository"
+	"computer-club/internal/usecase"
+	"context"
+	"fmt"
+	"log"
+	"os"
+)
+
+// Сверка балансов кошельков игроков и организаций с журналом двойной записи.
+// Завершается с кодом 1, если найдены расхождения
+func main() {
+	cfg := config.LoadConfig()
+	db := repository.NewPostgresDB(cfg)
+
+	ledgerUsecase := usecase.NewLedgerUsecase(repository.NewPostgresLedgerRepo(db))
+	report, err := ledgerUsecase.Reconcile(context.Background())
+	if err != nil {
+		log.Fatalf("Ошибка сверки: %v", err)
+	}
+
+	for _, mismatch := range report.WalletMismatches {
+		fmt.Printf("Кошелек пользователя %d (%s): баланс %s, по журналу %s\n",
+			mismatch.UserID, mismatch.Balance, mismatch.WalletBalance, mismatch.LedgerBalance)
+	}
+	for _, mismatch := range report.OrganizationMismatches {
+		fmt.Printf("Кошелек организации %d: баланс %s, по журналу %s\n",
+			mismatch.OrganizationID, mismatch.Balance, mismatch.LedgerBalance)
+	}
+	for _, entry := range report.UnbalancedEntries {
+		fmt.Printf("Проводка %d не сбалансирована: сумма строк %s\n", entry.EntryID, entry.Sum)
+	}
+
+	if !report.OK() {
+		fmt.Printf("Найдено расхождений: %d кошельков, %d организаций, %d проводок\n",
+			len(report.WalletMismatches), len(report.OrganizationMismatches), len(report.UnbalancedEntries))
+		os.Exit(1)
+	}
+	fmt.Println("Расхождений не найдено")
+}
@@ -4,14 +4,28 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"strconv"
+	"time"
 
 	"github.com/joho/godotenv"
 )
 
 type Config struct {
-	Server   ServerConfig
-	Database DatabaseConfig
-	Redis    RedisConfig
+	Server      ServerConfig
+	Database    DatabaseConfig
+	Redis       RedisConfig
+	Pricing     PricingConfig
+	Idempotency IdempotencyConfig
+	Transfer    TransferConfig
+	Bonus       BonusConfig
+	Loyalty     LoyaltyConfig
+	Payments    PaymentsConfig
+	Shift       ShiftConfig
+	Receipt     ReceiptConfig
+	Voucher     VoucherConfig
+	Auth        AuthConfig
+	Mail        MailConfig
+	Login       LoginConfig
 }
 
 type ServerConfig struct {
@@ -31,6 +45,123 @@ type RedisConfig struct {
 	Port string
 }
 
+// PricingConfig - параметры динамического ценообразования
+type PricingConfig struct {
+	OccupancyLow            float64
+	OccupancyLowMultiplier  float64
+	OccupancyHigh           float64
+	OccupancyHighMultiplier float64
+	OffPeakFromHour         int
+	OffPeakToHour           int
+	OffPeakMultiplier       float64
+	PeakFromHour            int
+	PeakToHour              int
+	PeakMultiplier          float64
+	VIPZoneMultiplier       float64
+	QuoteTTL                time.Duration
+}
+
+// IdempotencyConfig - сколько хранятся ответы на запросы с ключом идемпотентности
+type IdempotencyConfig struct {
+	TTL time.Duration
+}
+
+// TransferConfig - ограничения на переводы между игроками, суммы в рублях
+type TransferConfig struct {
+	MinAmount  int
+	DailyLimit int
+}
+
+// BonusConfig - порядок списания бонусов (bonus_first или real_first) и срок их действия
+type BonusConfig struct {
+	SpendPriority string
+	TTL           time.Duration
+}
+
+// LoyaltyConfig - начисление баллов, пороги уровней (траты в рублях за окно Window) и обмен баллов на минуты
+type LoyaltyConfig struct {
+	PointsPerRouble       float64
+	PointsPerMinute       float64
+	Window                time.Duration
+	SilverSpend           int
+	GoldSpend             int
+	SilverDiscount        float64
+	GoldDiscount          float64
+	RedeemPointsPerMinute int
+	RedeemValidityDays    int
+}
+
+// PaymentsConfig - подключение к платежному провайдеру. FakePayURL - адрес тестового сервера cmd/fakepay,
+// CallbackURL - адрес, на который провайдер отправляет уведомления
+type PaymentsConfig struct {
+	FakePayURL    string
+	FakePayPort   string
+	WebhookSecret string
+	CallbackURL   string
+}
+
+// ShiftConfig - допустимое расхождение наличности при закрытии смены, в копейках
+type ShiftConfig struct {
+	DiscrepancyTolerance int
+}
+
+// ReceiptConfig - название клуба в шапке чека
+type ReceiptConfig struct {
+	ClubName string
+}
+
+// VoucherConfig - подарочные карты: максимальный размер партии, срок действия карт и пакетов
+// из карт на минуты в днях, число неудачных попыток погашения за окно AttemptWindow
+type VoucherConfig struct {
+	MaxBatchSize        int
+	ValidityDays        int
+	PackageValidityDays int
+	MaxAttempts         int
+	AttemptWindow       time.Duration
+}
+
+// AuthConfig - время жизни access-токена и refresh-токена, ссылок подтверждения почты и сброса пароля.
+// С RequireVerifiedEmail вход без подтвержденной почты запрещен. TOTP*: название сервиса в приложении-
+// аутентификаторе, сколько вход ждет код, число резервных кодов и неверных кодов за окно AttemptWindow
+type AuthConfig struct {
+	AccessTTL            time.Duration
+	RefreshTTL           time.Duration
+	VerificationTTL      time.Duration
+	ResetTTL             time.Duration
+	RequireVerifiedEmail bool
+	TOTPIssuer           string
+	TOTPChallengeTTL     time.Duration
+	TOTPBackupCodes      int
+	TOTPMaxAttempts      int
+	TOTPAttemptWindow    time.Duration
+}
+
+// MailConfig - отправка писем. Driver: smtp, file (письма .eml в каталог Dir) или memory.
+// LinkBaseURL - адрес клиентского приложения, к которому добавляются ссылки из писем
+type MailConfig struct {
+	Driver       string
+	From         string
+	SMTPHost     string
+	SMTPPort     string
+	SMTPUsername string
+	SMTPPassword string
+	Dir          string
+	LinkBaseURL  string
+}
+
+// LoginConfig - защита входа от перебора: неудачи считаются за окно Window, начиная с BackoffAfter
+// неудачи аккаунт ждет BaseDelay, удваивая паузу до MaxDelay; после MaxAccountFailures неудач аккаунт,
+// а после MaxIPFailures IP блокируются на LockoutDuration
+type LoginConfig struct {
+	Window             time.Duration
+	BackoffAfter       int
+	BaseDelay          time.Duration
+	MaxDelay           time.Duration
+	MaxAccountFailures int
+	MaxIPFailures      int
+	LockoutDuration    time.Duration
+}
+
 func LoadConfig() *Config {
 	if err := godotenv.Load(); err != nil {
 		log.Println("No .env file found, using default environment variables")
@@ -51,6 +182,92 @@ func LoadConfig() *Config {
 			Host: getEnv("REDIS_HOST", "localhost"),
 			Port: getEnv("REDIS_PORT", "6379"),
 		},
+		Pricing: PricingConfig{
+			OccupancyLow:            getEnvFloat("PRICING_OCCUPANCY_LOW", 0.3),
+			OccupancyLowMultiplier:  getEnvFloat("PRICING_OCCUPANCY_LOW_MULTIPLIER", 0.9),
+			OccupancyHigh:           getEnvFloat("PRICING_OCCUPANCY_HIGH", 0.8),
+			OccupancyHighMultiplier: getEnvFloat("PRICING_OCCUPANCY_HIGH_MULTIPLIER", 1.2),
+			OffPeakFromHour:         getEnvInt("PRICING_OFFPEAK_FROM_HOUR", 8),
+			OffPeakToHour:           getEnvInt("PRICING_OFFPEAK_TO_HOUR", 14),
+			OffPeakMultiplier:       getEnvFloat("PRICING_OFFPEAK_MULTIPLIER", 0.8),
+			PeakFromHour:            getEnvInt("PRICING_PEAK_FROM_HOUR", 18),
+			PeakToHour:              getEnvInt("PRICING_PEAK_TO_HOUR", 24),
+			PeakMultiplier:          getEnvFloat("PRICING_PEAK_MULTIPLIER", 1.1),
+			VIPZoneMultiplier:       getEnvFloat("PRICING_VIP_MULTIPLIER", 1.5),
+			QuoteTTL:                getEnvDuration("PRICING_QUOTE_TTL", 5*time.Minute),
+		},
+		Idempotency: IdempotencyConfig{
+			TTL: getEnvDuration("IDEMPOTENCY_TTL", 24*time.Hour),
+		},
+		Transfer: TransferConfig{
+			MinAmount:  getEnvInt("TRANSFER_MIN_AMOUNT", 10),
+			DailyLimit: getEnvInt("TRANSFER_DAILY_LIMIT", 5000),
+		},
+		Bonus: BonusConfig{
+			SpendPriority: getEnv("BONUS_SPEND_PRIORITY", "bonus_first"),
+			TTL:           getEnvDuration("BONUS_TTL", 30*24*time.Hour),
+		},
+		Loyalty: LoyaltyConfig{
+			PointsPerRouble:       getEnvFloat("LOYALTY_POINTS_PER_ROUBLE", 0.1),
+			PointsPerMinute:       getEnvFloat("LOYALTY_POINTS_PER_MINUTE", 0),
+			Window:                getEnvDuration("LOYALTY_WINDOW", 90*24*time.Hour),
+			SilverSpend:           getEnvInt("LOYALTY_SILVER_SPEND", 3000),
+			GoldSpend:             getEnvInt("LOYALTY_GOLD_SPEND", 10000),
+			SilverDiscount:        getEnvFloat("LOYALTY_SILVER_DISCOUNT", 0.05),
+			GoldDiscount:          getEnvFloat("LOYALTY_GOLD_DISCOUNT", 0.1),
+			RedeemPointsPerMinute: getEnvInt("LOYALTY_REDEEM_POINTS_PER_MINUTE", 10),
+			RedeemValidityDays:    getEnvInt("LOYALTY_REDEEM_VALIDITY_DAYS", 30),
+		},
+		Payments: PaymentsConfig{
+			FakePayURL:    getEnv("FAKEPAY_URL", "http://localhost:8090"),
+			FakePayPort:   getEnv("FAKEPAY_PORT", "8090"),
+			WebhookSecret: getEnv("PAYMENTS_WEBHOOK_SECRET", ""),
+			CallbackURL:   getEnv("PAYMENTS_CALLBACK_URL", "http://localhost:8080/payments/webhook"),
+		},
+		Shift: ShiftConfig{
+			DiscrepancyTolerance: getEnvInt("SHIFT_DISCREPANCY_TOLERANCE", 0),
+		},
+		Receipt: ReceiptConfig{
+			ClubName: getEnv("RECEIPT_CLUB_NAME", "Компьютерный клуб"),
+		},
+		Voucher: VoucherConfig{
+			MaxBatchSize:        getEnvInt("VOUCHER_MAX_BATCH_SIZE", 1000),
+			ValidityDays:        getEnvInt("VOUCHER_VALIDITY_DAYS", 365),
+			PackageValidityDays: getEnvInt("VOUCHER_PACKAGE_VALIDITY_DAYS", 30),
+			MaxAttempts:         getEnvInt("VOUCHER_MAX_ATTEMPTS", 5),
+			AttemptWindow:       getEnvDuration("VOUCHER_ATTEMPT_WINDOW", 15*time.Minute),
+		},
+		Auth: AuthConfig{
+			AccessTTL:            getEnvDuration("AUTH_ACCESS_TTL", 15*time.Minute),
+			RefreshTTL:           getEnvDuration("AUTH_REFRESH_TTL", 30*24*time.Hour),
+			VerificationTTL:      getEnvDuration("AUTH_VERIFICATION_TTL", 48*time.Hour),
+			ResetTTL:             getEnvDuration("AUTH_RESET_TTL", time.Hour),
+			RequireVerifiedEmail: getEnvBool("AUTH_REQUIRE_VERIFIED_EMAIL", false),
+			TOTPIssuer:           getEnv("AUTH_TOTP_ISSUER", "Компьютерный клуб"),
+			TOTPChallengeTTL:     getEnvDuration("AUTH_TOTP_CHALLENGE_TTL", 5*time.Minute),
+			TOTPBackupCodes:      getEnvInt("AUTH_TOTP_BACKUP_CODES", 10),
+			TOTPMaxAttempts:      getEnvInt("AUTH_TOTP_MAX_ATTEMPTS", 5),
+			TOTPAttemptWindow:    getEnvDuration("AUTH_TOTP_ATTEMPT_WINDOW", 15*time.Minute),
+		},
+		Mail: MailConfig{
+			Driver:       getEnv("MAIL_DRIVER", "file"),
+			From:         getEnv("MAIL_FROM", "Компьютерный клуб <noreply@club.local>"),
+			SMTPHost:     getEnv("MAIL_SMTP_HOST", "localhost"),
+			SMTPPort:     getEnv("MAIL_SMTP_PORT", "587"),
+			SMTPUsername: getEnv("MAIL_SMTP_USERNAME", ""),
+			SMTPPassword: getEnv("MAIL_SMTP_PASSWORD", ""),
+			Dir:          getEnv("MAIL_DIR", "mail"),
+			LinkBaseURL:  getEnv("MAIL_LINK_BASE_URL", "http://localhost:3000"),
+		},
+		Login: LoginConfig{
+			Window:             getEnvDuration("LOGIN_ATTEMPT_WINDOW", 15*time.Minute),
+			BackoffAfter:       getEnvInt("LOGIN_BACKOFF_AFTER", 3),
+			BaseDelay:          getEnvDuration("LOGIN_BACKOFF_BASE_DELAY", time.Second),
+			MaxDelay:           getEnvDuration("LOGIN_BACKOFF_MAX_DELAY", 5*time.Minute),
+			MaxAccountFailures: getEnvInt("LOGIN_MAX_ACCOUNT_FAILURES", 10),
+			MaxIPFailures:      getEnvInt("LOGIN_MAX_IP_FAILURES", 50),
+			LockoutDuration:    getEnvDuration("LOGIN_LOCKOUT_DURATION", 30*time.Minute),
+		},
 	}
 }
 
@@ -61,6 +278,46 @@ func getEnv(key, fallback string) string {
 	return fallback
 }
 
+func getEnvFloat(key string, fallback float64) float64 {
+	if value, exists := os.LookupEnv(key); exists {
+		if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+			return parsed
+		}
+		log.Printf("Invalid value for %s, using default %v", key, fallback)
+	}
+	return fallback
+}
+
+func getEnvInt(key string, fallback int) int {
+	if value, exists := os.LookupEnv(key); exists {
+		if parsed, err := strconv.Atoi(value); err == nil {
+			return parsed
+		}
+		log.Printf("Invalid value for %s, using default %v", key, fallback)
+	}
+	return fallback
+}
+
+func getEnvDuration(key string, fallback time.Duration) time.Duration {
+	if value, exists := os.LookupEnv(key); exists {
+		if parsed, err := time.ParseDuration(value); err == nil {
+			return parsed
+		}
+		log.Printf("Invalid value for %s, using default %v", key, fallback)
+	}
+	return fallback
+}
+
+func getEnvBool(key string, fallback bool) bool {
+	if value, exists := os.LookupEnv(key); exists {
+		if parsed, err := strconv.ParseBool(value); err == nil {
+			return parsed
+		}
+		log.Printf("Invalid value for %s, using default %v", key, fallback)
+	}
+	return fallback
+}
+
 func (c *Config) DSN() string {
 	return fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
 		c.Database.Host, c.Database.Port, c.Database.User, c.Database.Password, c.Database.Name)
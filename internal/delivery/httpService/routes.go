@@ -3,7 +3,9 @@ package httpService
 import (
 	"computer-club/internal/handlers"
 	"computer-club/internal/middleware"
+	"computer-club/internal/repository/models"
 	"github.com/go-chi/chi/v5"
+	"net/http"
 )
 
 // RegisterRoutes регистрирует эндпоинты
@@ -14,21 +16,107 @@ func RegisterRoutes(
 	sessionHandler handlers.SessionHandler,
 	walletHandler handlers.WalletHandler,
 	computerHandler handlers.ComputerHandler,
+	promotionHandler handlers.PromotionHandler,
+	packageHandler handlers.PackageHandler,
+	transferHandler handlers.TransferHandler,
+	loyaltyHandler handlers.LoyaltyHandler,
+	paymentHandler handlers.PaymentHandler,
+	shiftHandler handlers.ShiftHandler,
+	receiptHandler handlers.ReceiptHandler,
+	limitsHandler handlers.LimitsHandler,
+	voucherHandler handlers.VoucherHandler,
+	organizationHandler handlers.OrganizationHandler,
+	twoFactorHandler handlers.TwoFactorHandler,
+	loginGuardHandler handlers.LoginGuardHandler,
+	auth func(http.Handler) http.Handler,
+	idempotency func(http.Handler) http.Handler,
 ) {
 	r.Post("/register", userHandler.RegisterUser)
 	r.Post("/login", userHandler.LoginUser)
+	r.Post("/login/2fa", userHandler.LoginTwoFactor)
+	r.Post("/token/refresh", userHandler.RefreshToken)
+	r.Post("/email/verify", userHandler.VerifyEmail)
+	r.Post("/password/forgot", userHandler.ForgotPassword)
+	r.Post("/password/reset", userHandler.ResetPassword)
 
 	r.Get("/tariff", tariffHandler.GetTariff)
 	r.Get("/tariff/{id}", tariffHandler.GetTariffByID)
+	r.Get("/packages", packageHandler.GetPackages)
+	r.Post("/payments/webhook", paymentHandler.Webhook)
+
+	// Права сотрудников проверяются на маршруте, роли и их права описаны в models.RolePermissions
+	can := middleware.RequirePermission
 
 	r.Group(func(protected chi.Router) {
-		protected.Use(middleware.AuthMiddleware)
+		protected.Use(auth)
 
 		protected.Get("/info", userHandler.InfoUser)
-		protected.Post("/session/start", sessionHandler.StartSession)
+		protected.Post("/logout", userHandler.Logout)
+		protected.Post("/email/verify/resend", userHandler.ResendVerification)
+
+		// Двухфакторная аутентификация
+		protected.Get("/2fa", twoFactorHandler.GetStatus)
+		protected.Post("/2fa/enroll", twoFactorHandler.Enroll)
+		protected.Post("/2fa/confirm", twoFactorHandler.Confirm)
+		protected.Post("/2fa/backup-codes", twoFactorHandler.RegenerateBackupCodes)
+		protected.Delete("/2fa", twoFactorHandler.Disable)
+		protected.With(can(models.PermUserRoleAssign)).Put("/users/{id}/role", userHandler.SetRole)
+		protected.With(can(models.PermUserTokensRevoke)).Post("/users/{id}/revoke-tokens", userHandler.RevokeUserTokens)
+
+		// Управление пользователями
+		protected.With(can(models.PermUserView)).Get("/admin/users", userHandler.ListUsers)
+		protected.With(can(models.PermUserView)).Get("/admin/users/{id}", userHandler.GetUser)
+		protected.With(can(models.PermUserManage)).Patch("/admin/users/{id}", userHandler.UpdateUser)
+		protected.With(can(models.PermUserManage)).Delete("/admin/users/{id}", userHandler.DeleteUser)
+		protected.With(can(models.PermUserManage)).Post("/admin/users/{id}/block", userHandler.BlockUser)
+		protected.With(can(models.PermUserManage)).Post("/admin/users/{id}/unblock", userHandler.UnblockUser)
+		protected.With(can(models.PermUserManage)).Post("/admin/users/{id}/2fa/reset", userHandler.ResetTwoFactor)
+		protected.With(can(models.PermUserManage)).Post("/admin/users/{id}/unlock", loginGuardHandler.UnlockUser)
+		protected.With(can(models.PermUserManage)).Get("/admin/login-events", loginGuardHandler.ListLoginEvents)
+
+		protected.Post("/session/quote", sessionHandler.QuoteSession)
+		protected.With(idempotency).Post("/session/start", sessionHandler.StartSession)
 		protected.Post("/session/end", sessionHandler.EndSession)
-		protected.Put("/pay", walletHandler.PutMoneyOnWallet)
-		protected.Get("/sessions/active", sessionHandler.GetActiveSessions)
-		protected.Get("/computers/status", computerHandler.GetComputersStatus)
+		protected.With(can(models.PermWalletDeposit), idempotency).Put("/pay", walletHandler.PutMoneyOnWallet)
+		protected.With(can(models.PermWalletBonus)).Post("/wallet/bonus", walletHandler.GrantBonus)
+		protected.Get("/wallet/transactions", walletHandler.GetTransactions)
+		protected.With(can(models.PermTransactionViewAny)).Get("/admin/transactions", walletHandler.AdminTransactions)
+		protected.With(can(models.PermTransactionReverse)).Post("/wallet/transactions/{id}/reverse", walletHandler.ReverseTransaction)
+		protected.With(can(models.PermSessionViewAny)).Get("/sessions/active", sessionHandler.GetActiveSessions)
+		protected.With(can(models.PermComputerView)).Get("/computers/status", computerHandler.GetComputersStatus)
+		protected.With(can(models.PermTariffEdit)).Post("/tariff", tariffHandler.CreateTariff)
+		protected.With(can(models.PermTariffEdit)).Put("/tariff/{id}", tariffHandler.UpdateTariff)
+		protected.With(can(models.PermPromotionManage)).Post("/promotions", promotionHandler.CreatePromotion)
+		protected.With(can(models.PermPromotionManage)).Get("/promotions", promotionHandler.GetPromotions)
+		protected.With(can(models.PermPackageEdit)).Post("/packages", packageHandler.CreatePackage)
+		protected.Post("/packages/{id}/buy", packageHandler.BuyPackage)
+		protected.With(idempotency).Post("/wallet/transfer", transferHandler.Transfer)
+		protected.With(can(models.PermTransactionReverse)).Post("/wallet/transfers/{id}/reverse", transferHandler.ReverseTransfer)
+		protected.Get("/loyalty", loyaltyHandler.GetStatus)
+		protected.Post("/loyalty/redeem", loyaltyHandler.RedeemPoints)
+		protected.With(idempotency).Post("/payments", paymentHandler.CreatePayment)
+		protected.Get("/payments/{id}", paymentHandler.GetPayment)
+		protected.With(can(models.PermPaymentRefund)).Post("/payments/{id}/refund", paymentHandler.RefundPayment)
+		protected.With(can(models.PermShiftManage)).Post("/shifts/open", shiftHandler.OpenShift)
+		protected.With(can(models.PermShiftManage)).Post("/shifts/close", shiftHandler.CloseShift)
+		protected.With(can(models.PermShiftManage)).Get("/shifts/current", shiftHandler.CurrentReport)
+		protected.With(can(models.PermShiftManage)).Get("/shifts/{id}/report", shiftHandler.GetReport)
+		protected.Get("/receipts/{id}", receiptHandler.GetReceipt)
+		protected.Get("/users/{id}/limits", limitsHandler.GetLimits)
+		protected.Put("/users/{id}/limits", limitsHandler.SetLimits)
+		protected.With(can(models.PermLimitsManage)).Put("/users/{id}/guardian", limitsHandler.SetGuardian)
+		protected.Get("/guardian/wards", limitsHandler.GetWards)
+		protected.With(can(models.PermVoucherIssue)).Post("/vouchers/batches", voucherHandler.CreateBatch)
+		protected.With(can(models.PermVoucherIssue)).Get("/vouchers/batches/{id}", voucherHandler.GetBatch)
+		protected.With(can(models.PermVoucherSell)).Post("/vouchers/{code}/sell", voucherHandler.SellVoucher)
+		protected.Post("/wallet/redeem", voucherHandler.RedeemVoucher)
+		protected.With(can(models.PermOrganizationManage)).Post("/organizations", organizationHandler.CreateOrganization)
+		protected.Get("/organizations/{id}", organizationHandler.GetOrganization)
+		protected.Post("/organizations/{id}/members", organizationHandler.AddMember)
+		protected.Put("/organizations/{id}/members/{user_id}", organizationHandler.UpdateMember)
+		protected.Delete("/organizations/{id}/members/{user_id}", organizationHandler.RemoveMember)
+		protected.With(can(models.PermOrganizationManage), idempotency).Post("/organizations/{id}/deposit", organizationHandler.Deposit)
+		protected.Get("/organizations/{id}/invoices", organizationHandler.GetInvoices)
+		protected.With(can(models.PermOrganizationManage)).Post("/organizations/{id}/invoices", organizationHandler.GenerateInvoice)
 	})
 }
@@ -4,10 +4,16 @@ import (
 	"computer-club/internal/config"
 	"computer-club/internal/delivery/httpService"
 	"computer-club/internal/handlers"
+	"computer-club/internal/mailer"
 	"computer-club/internal/middleware"
+	"computer-club/internal/payments"
+	"computer-club/internal/pricing"
+	"computer-club/internal/receipts"
 	"computer-club/internal/repository"
+	"computer-club/internal/repository/models"
 	"computer-club/internal/usecase"
 	"computer-club/pkg/logger"
+	"computer-club/pkg/money"
 	"github.com/go-chi/chi/v5"
 	"github.com/redis/go-redis/v9"
 	"github.com/sirupsen/logrus"
@@ -16,26 +22,74 @@ import (
 
 // Container отвечает за хранение и инициализацию зависимостей
 type Container struct {
-	Cfg             *config.Config
-	Log             *logrus.Logger
-	DB              *gorm.DB
-	RedisClient     *redis.Client
-	UserHandler     handlers.UserHandler
-	SessionHandler  handlers.SessionHandler
-	ComputerHandler handlers.ComputerHandler
-	TariffHandler   handlers.TariffHandler
-	WalletHandler   handlers.WalletHandler
-	UserRepo        repository.UserRepository
-	SessionRepo     repository.SessionRepository
-	ComputerRepo    repository.ComputerRepository
-	TariffRepo      repository.TariffRepository
-	WalletRepo      repository.WalletRepository
-	UserUsecase     *usecase.UserService
-	SessionUsecase  *usecase.SessionService
-	ComputerUsecase *usecase.ComputerService
-	TariffUsecase   *usecase.TariffService
-	WalletUsecase   *usecase.WalletService
-	Router          *chi.Mux
+	Cfg                 *config.Config
+	Log                 *logrus.Logger
+	DB                  *gorm.DB
+	RedisClient         *redis.Client
+	UserHandler         handlers.UserHandler
+	SessionHandler      handlers.SessionHandler
+	ComputerHandler     handlers.ComputerHandler
+	TariffHandler       handlers.TariffHandler
+	WalletHandler       handlers.WalletHandler
+	PromotionHandler    handlers.PromotionHandler
+	PackageHandler      handlers.PackageHandler
+	LoyaltyHandler      handlers.LoyaltyHandler
+	PaymentHandler      handlers.PaymentHandler
+	ShiftHandler        handlers.ShiftHandler
+	ReceiptHandler      handlers.ReceiptHandler
+	LimitsHandler       handlers.LimitsHandler
+	VoucherHandler      handlers.VoucherHandler
+	OrganizationHandler handlers.OrganizationHandler
+	TransferHandler     handlers.TransferHandler
+	UserRepo            repository.UserRepository
+	SessionRepo         repository.SessionRepository
+	ComputerRepo        repository.ComputerRepository
+	TariffRepo          repository.TariffRepository
+	WalletRepo          repository.WalletRepository
+	PromotionRepo       repository.PromotionRepository
+	PackageRepo         repository.PackageRepository
+	QuoteRepo           repository.QuoteRepository
+	LedgerRepo          repository.LedgerRepository
+	TransferRepo        repository.TransferRepository
+	BonusRepo           repository.BonusRepository
+	LoyaltyRepo         repository.LoyaltyRepository
+	PaymentRepo         repository.PaymentRepository
+	ShiftRepo           repository.ShiftRepository
+	ReceiptRepo         repository.ReceiptRepository
+	LimitsRepo          repository.LimitsRepository
+	VoucherRepo         repository.VoucherRepository
+	AttemptRepo         repository.AttemptRepository
+	OrganizationRepo    repository.OrganizationRepository
+	RefreshTokenRepo    repository.RefreshTokenRepository
+	RevocationRepo      repository.RevocationRepository
+	UserTokenRepo       repository.UserTokenRepository
+	TwoFactorRepo       repository.TwoFactorRepository
+	LoginEventRepo      repository.LoginEventRepository
+	Mailer              mailer.Mailer
+	PaymentProvider     payments.Provider
+	FiscalRegistrar     receipts.FiscalRegistrar
+	TxManager           repository.TxManager
+	IdempotencyRepo     repository.IdempotencyRepository
+	PricingEngine       pricing.Engine
+	UserUsecase         *usecase.UserService
+	SessionUsecase      *usecase.SessionService
+	ComputerUsecase     *usecase.ComputerService
+	TariffUsecase       *usecase.TariffService
+	WalletUsecase       *usecase.WalletService
+	PromotionUsecase    *usecase.PromotionService
+	PackageUsecase      *usecase.PackageService
+	LedgerUsecase       *usecase.LedgerService
+	TransferUsecase     *usecase.TransferService
+	LoyaltyUsecase      *usecase.LoyaltyService
+	PaymentUsecase      *usecase.PaymentService
+	ShiftUsecase        *usecase.ShiftService
+	ReceiptUsecase      *usecase.ReceiptService
+	LimitsUsecase       *usecase.LimitsService
+	VoucherUsecase      *usecase.VoucherService
+	OrganizationUsecase *usecase.OrganizationService
+	TwoFactorUsecase    *usecase.TwoFactorService
+	LoginGuardUsecase   *usecase.LoginGuardService
+	Router              *chi.Mux
 }
 
 // NewContainer создает новый контейнер зависимостей
@@ -54,43 +108,223 @@ func NewContainer() *Container {
 	computerRepo := repository.NewComputerRepository(db)
 	tariffRepo := repository.NewTariffRepositoryPostgres(db)
 	walletRepo := repository.NewPostgresWalletRepo(db)
+	promotionRepo := repository.NewPostgresPromotionRepo(db)
+	packageRepo := repository.NewPostgresPackageRepo(db)
+	quoteRepo := repository.NewRedisQuoteRepo(redisClient)
+	ledgerRepo := repository.NewPostgresLedgerRepo(db)
+	transferRepo := repository.NewPostgresTransferRepo(db)
+	bonusRepo := repository.NewPostgresBonusRepo(db)
+	loyaltyRepo := repository.NewPostgresLoyaltyRepo(db)
+	paymentRepo := repository.NewPostgresPaymentRepo(db)
+	shiftRepo := repository.NewPostgresShiftRepo(db)
+	receiptRepo := repository.NewPostgresReceiptRepo(db)
+	limitsRepo := repository.NewPostgresLimitsRepo(db)
+	voucherRepo := repository.NewPostgresVoucherRepo(db)
+	organizationRepo := repository.NewPostgresOrganizationRepo(db)
+	refreshTokenRepo := repository.NewPostgresRefreshTokenRepo(db)
+	revocationRepo := repository.NewRedisRevocationRepo(redisClient)
+	userTokenRepo := repository.NewPostgresUserTokenRepo(db)
+	twoFactorRepo := repository.NewPostgresTwoFactorRepo(db)
+	loginEventRepo := repository.NewPostgresLoginEventRepo(db)
+
+	// Платежный провайдер
+	paymentProvider := newPaymentProvider(cfg.Payments, log)
+	// Почта: SMTP в рабочем окружении, файлы .eml или память при локальном запуске
+	mailSender := newMailer(cfg.Mail, log)
+	// Фискальный регистратор: заглушка, пока касса не подключена
+	fiscalRegistrar := receipts.NewStubRegistrar()
+	txManager := repository.NewTxManager(db)
+	idempotencyRepo := repository.NewRedisIdempotencyRepo(redisClient)
+	attemptRepo := repository.NewRedisAttemptRepo(redisClient)
+
+	// Ограничения аккаунта проверяются при оплате сессий, покупках и переводах
+	limitsUsecase := usecase.NewLimitsUsecase(limitsRepo, userRepo)
+	// Программа лояльности нужна движку цен для скидки по уровню
+	loyaltyUsecase := usecase.NewLoyaltyUsecase(loyaltyRepo, packageRepo, limitsUsecase, txManager, usecase.LoyaltyPolicy{
+		PointsPerRouble:       cfg.Loyalty.PointsPerRouble,
+		PointsPerMinute:       cfg.Loyalty.PointsPerMinute,
+		Window:                cfg.Loyalty.Window,
+		SilverSpend:           money.FromMajor(int64(cfg.Loyalty.SilverSpend)),
+		GoldSpend:             money.FromMajor(int64(cfg.Loyalty.GoldSpend)),
+		SilverDiscount:        cfg.Loyalty.SilverDiscount,
+		GoldDiscount:          cfg.Loyalty.GoldDiscount,
+		RedeemPointsPerMinute: int64(cfg.Loyalty.RedeemPointsPerMinute),
+		RedeemValidityDays:    cfg.Loyalty.RedeemValidityDays,
+	})
+
+	// Движок динамического ценообразования
+	pricingEngine := pricing.NewRuleEngine(computerRepo, loyaltyUsecase,
+		pricing.OccupancyRule{
+			Low:            cfg.Pricing.OccupancyLow,
+			LowMultiplier:  cfg.Pricing.OccupancyLowMultiplier,
+			High:           cfg.Pricing.OccupancyHigh,
+			HighMultiplier: cfg.Pricing.OccupancyHighMultiplier,
+		},
+		pricing.TimeOfDayRule{
+			RuleName:     "off_peak",
+			FromHour:     cfg.Pricing.OffPeakFromHour,
+			ToHour:       cfg.Pricing.OffPeakToHour,
+			WeekdaysOnly: true,
+			Factor:       cfg.Pricing.OffPeakMultiplier,
+		},
+		pricing.TimeOfDayRule{
+			RuleName: "peak",
+			FromHour: cfg.Pricing.PeakFromHour,
+			ToHour:   cfg.Pricing.PeakToHour,
+			Factor:   cfg.Pricing.PeakMultiplier,
+		},
+		pricing.ZoneRule{Multipliers: map[string]float64{
+			models.ZoneVIP: cfg.Pricing.VIPZoneMultiplier,
+		}},
+		pricing.TierRule{Discounts: map[models.LoyaltyTier]float64{
+			models.TierSilver: cfg.Loyalty.SilverDiscount,
+			models.TierGold:   cfg.Loyalty.GoldDiscount,
+		}},
+	)
 
 	// Инициализация usecase'ов
 	tariffUsecase := usecase.NewTariffUsecase(tariffRepo)
-	walletUsecase := usecase.NewWalletUsecase(walletRepo, tariffUsecase, userRepo)
-	userUsecase := usecase.NewUserUsecase(userRepo, walletUsecase)
-	sessionUsecase := usecase.NewSessionUsecase(sessionRepo, userRepo, computerRepo, tariffRepo, walletRepo)
+	ledgerUsecase := usecase.NewLedgerUsecase(ledgerRepo)
+	receiptUsecase := usecase.NewReceiptUsecase(receiptRepo, fiscalRegistrar, cfg.Receipt.ClubName)
+	walletUsecase := usecase.NewWalletUsecase(walletRepo, tariffUsecase, userRepo, txManager, ledgerUsecase,
+		bonusRepo, shiftRepo, sessionRepo, packageRepo, promotionRepo, loyaltyRepo, receiptUsecase, cfg.Bonus.SpendPriority, cfg.Bonus.TTL)
+	twoFactorUsecase := usecase.NewTwoFactorUsecase(twoFactorRepo, userRepo, attemptRepo, txManager, usecase.TwoFactorPolicy{
+		Issuer:        cfg.Auth.TOTPIssuer,
+		BackupCodes:   cfg.Auth.TOTPBackupCodes,
+		MaxAttempts:   int64(cfg.Auth.TOTPMaxAttempts),
+		AttemptWindow: cfg.Auth.TOTPAttemptWindow,
+	})
+	loginGuardUsecase := usecase.NewLoginGuardUsecase(attemptRepo, loginEventRepo, userRepo, usecase.LoginPolicy{
+		Window:             cfg.Login.Window,
+		BackoffAfter:       int64(cfg.Login.BackoffAfter),
+		BaseDelay:          cfg.Login.BaseDelay,
+		MaxDelay:           cfg.Login.MaxDelay,
+		MaxAccountFailures: int64(cfg.Login.MaxAccountFailures),
+		MaxIPFailures:      int64(cfg.Login.MaxIPFailures),
+		LockoutDuration:    cfg.Login.LockoutDuration,
+	})
+	userUsecase := usecase.NewUserUsecase(userRepo, walletUsecase, refreshTokenRepo, revocationRepo, userTokenRepo, mailSender, twoFactorUsecase, loginGuardUsecase, txManager, usecase.TokenPolicy{
+		AccessTTL:    cfg.Auth.AccessTTL,
+		RefreshTTL:   cfg.Auth.RefreshTTL,
+		ChallengeTTL: cfg.Auth.TOTPChallengeTTL,
+	}, usecase.AccountPolicy{
+		VerificationTTL:      cfg.Auth.VerificationTTL,
+		ResetTTL:             cfg.Auth.ResetTTL,
+		LinkBaseURL:          cfg.Mail.LinkBaseURL,
+		RequireVerifiedEmail: cfg.Auth.RequireVerifiedEmail,
+	})
+	promotionUsecase := usecase.NewPromotionUsecase(promotionRepo, sessionRepo)
+	packageUsecase := usecase.NewPackageUsecase(packageRepo, walletUsecase, loyaltyUsecase, limitsUsecase, txManager)
+	organizationUsecase := usecase.NewOrganizationUsecase(organizationRepo, userRepo, shiftRepo, walletRepo, ledgerUsecase, receiptUsecase, txManager)
+	sessionUsecase := usecase.NewSessionUsecase(sessionRepo, userRepo, computerRepo, tariffRepo, walletUsecase, promotionUsecase, packageUsecase, loyaltyUsecase, limitsUsecase, organizationUsecase, pricingEngine, quoteRepo, cfg.Pricing.QuoteTTL, txManager)
 	computerUsecase := usecase.NewComputerUsecase(computerRepo)
+	shiftUsecase := usecase.NewShiftUsecase(shiftRepo, txManager, money.New(int64(cfg.Shift.DiscrepancyTolerance)))
+	paymentUsecase := usecase.NewPaymentUsecase(paymentRepo, walletRepo, txManager, ledgerUsecase, receiptUsecase, paymentProvider)
+	voucherUsecase := usecase.NewVoucherUsecase(voucherRepo, walletRepo, packageRepo, attemptRepo, shiftRepo, ledgerUsecase, limitsUsecase, receiptUsecase, txManager, usecase.VoucherPolicy{
+		MaxBatchSize:        cfg.Voucher.MaxBatchSize,
+		DefaultValidityDays: cfg.Voucher.ValidityDays,
+		PackageValidityDays: cfg.Voucher.PackageValidityDays,
+		MaxAttempts:         int64(cfg.Voucher.MaxAttempts),
+		AttemptWindow:       cfg.Voucher.AttemptWindow,
+	})
+	transferUsecase := usecase.NewTransferUsecase(transferRepo, walletRepo, userRepo, txManager, ledgerUsecase, limitsUsecase,
+		money.FromMajor(int64(cfg.Transfer.MinAmount)), money.FromMajor(int64(cfg.Transfer.DailyLimit)))
 
 	// Инициализация хендлеров
-	userHandler := handlers.NewUserHandler(userUsecase, walletUsecase, log) // Добавили log
+	userHandler := handlers.NewUserHandler(userUsecase, walletUsecase, packageUsecase, loyaltyUsecase, log) // Добавили log
 	sessionHandler := handlers.NewSessionHandler(sessionUsecase, log)
 	tariffHandler := handlers.NewTariffHandler(tariffUsecase, log)
 	walletHandler := handlers.NewWalletHandler(walletUsecase, log)
 	computerHandler := handlers.NewComputerHandler(computerUsecase, log)
+	promotionHandler := handlers.NewPromotionHandler(promotionUsecase, log)
+	packageHandler := handlers.NewPackageHandler(packageUsecase, log)
+	transferHandler := handlers.NewTransferHandler(transferUsecase, log)
+	loyaltyHandler := handlers.NewLoyaltyHandler(loyaltyUsecase, log)
+	paymentHandler := handlers.NewPaymentHandler(paymentUsecase, log)
+	shiftHandler := handlers.NewShiftHandler(shiftUsecase, log)
+	receiptHandler := handlers.NewReceiptHandler(receiptUsecase, log)
+	limitsHandler := handlers.NewLimitsHandler(limitsUsecase, log)
+	voucherHandler := handlers.NewVoucherHandler(voucherUsecase, log)
+	organizationHandler := handlers.NewOrganizationHandler(organizationUsecase, log)
+	twoFactorHandler := handlers.NewTwoFactorHandler(twoFactorUsecase, log)
+	loginGuardHandler := handlers.NewLoginGuardHandler(loginGuardUsecase, log)
 
 	// Инициализация роутера
 	r := chi.NewRouter()
 	r.Use(middleware.LoggerMiddleware(log))
 
 	// Регистрация маршрутов
-	httpService.RegisterRoutes(r, userHandler, tariffHandler, sessionHandler, walletHandler, computerHandler)
+	httpService.RegisterRoutes(r, userHandler, tariffHandler, sessionHandler, walletHandler, computerHandler, promotionHandler, packageHandler, transferHandler, loyaltyHandler, paymentHandler, shiftHandler, receiptHandler, limitsHandler, voucherHandler, organizationHandler, twoFactorHandler, loginGuardHandler,
+		middleware.AuthMiddleware(revocationRepo),
+		middleware.IdempotencyMiddleware(idempotencyRepo, cfg.Idempotency.TTL))
 
 	return &Container{
-		Cfg:             cfg,
-		Log:             log,
-		DB:              db,
-		RedisClient:     redisClient,
-		UserRepo:        userRepo,
-		SessionRepo:     sessionRepo,
-		ComputerRepo:    computerRepo,
-		TariffRepo:      tariffRepo,
-		WalletRepo:      walletRepo,
-		UserUsecase:     &userUsecase,
-		SessionUsecase:  &sessionUsecase,
-		ComputerUsecase: &computerUsecase,
-		TariffUsecase:   &tariffUsecase,
-		WalletUsecase:   &walletUsecase,
-		Router:          r,
+		Cfg:                 cfg,
+		Log:                 log,
+		DB:                  db,
+		RedisClient:         redisClient,
+		UserRepo:            userRepo,
+		SessionRepo:         sessionRepo,
+		ComputerRepo:        computerRepo,
+		TariffRepo:          tariffRepo,
+		WalletRepo:          walletRepo,
+		PromotionRepo:       promotionRepo,
+		PackageRepo:         packageRepo,
+		QuoteRepo:           quoteRepo,
+		LedgerRepo:          ledgerRepo,
+		TransferRepo:        transferRepo,
+		BonusRepo:           bonusRepo,
+		LoyaltyRepo:         loyaltyRepo,
+		PaymentRepo:         paymentRepo,
+		ShiftRepo:           shiftRepo,
+		ReceiptRepo:         receiptRepo,
+		LimitsRepo:          limitsRepo,
+		VoucherRepo:         voucherRepo,
+		AttemptRepo:         attemptRepo,
+		OrganizationRepo:    organizationRepo,
+		RefreshTokenRepo:    refreshTokenRepo,
+		RevocationRepo:      revocationRepo,
+		UserTokenRepo:       userTokenRepo,
+		TwoFactorRepo:       twoFactorRepo,
+		LoginEventRepo:      loginEventRepo,
+		Mailer:              mailSender,
+		PaymentProvider:     paymentProvider,
+		FiscalRegistrar:     fiscalRegistrar,
+		TxManager:           txManager,
+		IdempotencyRepo:     idempotencyRepo,
+		PricingEngine:       pricingEngine,
+		UserUsecase:         &userUsecase,
+		SessionUsecase:      &sessionUsecase,
+		ComputerUsecase:     &computerUsecase,
+		TariffUsecase:       &tariffUsecase,
+		WalletUsecase:       &walletUsecase,
+		PromotionUsecase:    &promotionUsecase,
+		PackageUsecase:      &packageUsecase,
+		LedgerUsecase:       &ledgerUsecase,
+		TransferUsecase:     &transferUsecase,
+		LoyaltyUsecase:      &loyaltyUsecase,
+		PaymentUsecase:      &paymentUsecase,
+		ShiftUsecase:        &shiftUsecase,
+		ReceiptUsecase:      &receiptUsecase,
+		LimitsUsecase:       &limitsUsecase,
+		VoucherUsecase:      &voucherUsecase,
+		OrganizationUsecase: &organizationUsecase,
+		TwoFactorUsecase:    &twoFactorUsecase,
+		LoginGuardUsecase:   &loginGuardUsecase,
+		Router:              r,
+	}
+}
+
+func newMailer(cfg config.MailConfig, log *logrus.Logger) mailer.Mailer {
+	switch cfg.Driver {
+	case "smtp":
+		return mailer.NewSMTPMailer(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUsername, cfg.SMTPPassword, cfg.From)
+	case "memory":
+		return mailer.NewMemoryMailer()
+	case "file":
+		return mailer.NewFileMailer(cfg.Dir, cfg.From)
+	default:
+		log.Fatalf("Неизвестный MAIL_DRIVER: %s", cfg.Driver)
+		return nil
 	}
 }
@@ -0,0 +1,17 @@
+//go:build !dev
+
+package di
+
+import (
+	"computer-club/internal/config"
+	"computer-club/internal/payments"
+	"github.com/sirupsen/logrus"
+)
+
+// newPaymentProvider - в обычной сборке тестовый провайдер недоступен: подпись его уведомлений
+// проверяется общим секретом, и подделанное уведомление пополнило бы кошелек. Пока реальный
+// провайдер не подключен, онлайн-оплата отключена
+func newPaymentProvider(cfg config.PaymentsConfig, log *logrus.Logger) payments.Provider {
+	log.Warn("Платежный провайдер не подключен, онлайн-оплата отключена")
+	return payments.NewDisabledProvider()
+}
@@ -0,0 +1,19 @@
+//go:build dev
+
+package di
+
+import (
+	"computer-club/internal/config"
+	"computer-club/internal/payments"
+	"github.com/sirupsen/logrus"
+)
+
+// newPaymentProvider - в сборке с тегом dev подключается тестовый провайдер cmd/fakepay.
+// Без секрета подписи сервис не запускается: иначе уведомления мог бы подписать кто угодно
+func newPaymentProvider(cfg config.PaymentsConfig, log *logrus.Logger) payments.Provider {
+	if cfg.WebhookSecret == "" {
+		log.Fatal("PAYMENTS_WEBHOOK_SECRET не задан")
+	}
+	log.Warn("Подключен тестовый платежный провайдер fakepay, только для разработки")
+	return payments.NewFakeProvider(cfg.FakePayURL, cfg.WebhookSecret, cfg.CallbackURL)
+}
@@ -2,9 +2,7 @@ package handlers
 
 import (
 	"computer-club/internal/middleware"
-	"computer-club/internal/repository/models"
 	"computer-club/internal/usecase"
-	"computer-club/pkg/errors"
 	"encoding/json"
 	"github.com/sirupsen/logrus"
 	"net/http"
@@ -24,13 +22,6 @@ func (h computerHandler) GetComputersStatus(w http.ResponseWriter, r *http.Reque
 	ctx := r.Context()
 	h.log.Info("Запрос на получение статуса компьютеров")
 
-	role, ok := r.Context().Value("role").(string)
-	if !ok || role != string(models.Admin) {
-		h.log.WithError(errors.ErrForbidden).Error("Ошибка при получении списка компьютеров: недостаточно прав")
-		middleware.WriteError(w, http.StatusForbidden, errors.ErrForbidden.Error())
-		return
-	}
-
 	computers, err := h.computerService.GetComputersStatus(ctx)
 	if err != nil {
 		h.log.WithError(err).Error("Ошибка при получении списка компьютеров")
@@ -0,0 +1,207 @@
+package handlers
+
+import (
+	"computer-club/internal/middleware"
+	"computer-club/internal/repository/models"
+	"computer-club/internal/usecase"
+	"computer-club/pkg/errors"
+	"computer-club/pkg/money"
+	"encoding/json"
+	"github.com/go-chi/chi/v5"
+	"github.com/sirupsen/logrus"
+	"net/http"
+	"strconv"
+)
+
+type LimitsHandler interface {
+	GetLimits(http.ResponseWriter, *http.Request)
+	SetLimits(http.ResponseWriter, *http.Request)
+	SetGuardian(http.ResponseWriter, *http.Request)
+	GetWards(http.ResponseWriter, *http.Request)
+}
+
+func NewLimitsHandler(limitsService usecase.LimitsService, log *logrus.Logger) LimitsHandler {
+	return &limitsHandler{limitsService: limitsService, log: log}
+}
+
+type limitsHandler struct {
+	limitsService usecase.LimitsService
+	log           *logrus.Logger
+}
+
+// actor возвращает ID пользователя из токена и признак права limits.manage на любого игрока
+func (h limitsHandler) actor(w http.ResponseWriter, r *http.Request) (int64, bool, bool) {
+	userID, ok := r.Context().Value("user_id").(int64)
+	if !ok {
+		h.log.Error("Ошибка: user_id не найден в контексте")
+		middleware.WriteError(w, http.StatusUnauthorized, errors.ErrWrongIDFromJWT.Error())
+		return 0, false, false
+	}
+	return userID, middleware.HasPermission(r, models.PermLimitsManage), true
+}
+
+func (h limitsHandler) targetID(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		h.log.WithError(err).Error("Ошибка: некорректный ID пользователя")
+		middleware.WriteError(w, http.StatusBadRequest, errors.ErrInvalidUserID.Error())
+		return 0, false
+	}
+	return id, true
+}
+
+// GetLimits возвращает ограничения и текущий расход игрока. Доступно самому игроку, его опекуну и сотруднику с правом limits.manage
+func (h limitsHandler) GetLimits(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	h.log.Info("Запрос на получение ограничений игрока")
+
+	actorID, isAdmin, ok := h.actor(w, r)
+	if !ok {
+		return
+	}
+	userID, ok := h.targetID(w, r)
+	if !ok {
+		return
+	}
+
+	if actorID != userID {
+		allowed, err := h.limitsService.CanManage(ctx, actorID, isAdmin, userID)
+		if err != nil {
+			h.log.WithError(err).Error("Ошибка при проверке опекуна")
+			middleware.WriteError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		if !allowed {
+			middleware.WriteError(w, http.StatusForbidden, errors.ErrForbidden.Error())
+			return
+		}
+	}
+
+	status, err := h.limitsService.GetStatus(ctx, userID)
+	if err != nil {
+		h.log.WithError(err).Error("Ошибка при получении ограничений")
+		middleware.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(status)
+}
+
+// SetLimits заменяет ограничения игрока. Доступно опекуну игрока и сотруднику с правом limits.manage
+func (h limitsHandler) SetLimits(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	h.log.Info("Запрос на изменение ограничений игрока")
+
+	actorID, isAdmin, ok := h.actor(w, r)
+	if !ok {
+		return
+	}
+	userID, ok := h.targetID(w, r)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		DailySpend      money.Money `json:"daily_spend"`
+		WeeklySpend     money.Money `json:"weekly_spend"`
+		DailyMinutes    int64       `json:"daily_minutes"`
+		AllowedFromHour *int        `json:"allowed_from_hour"`
+		AllowedToHour   *int        `json:"allowed_to_hour"`
+		AllowedTariffs  []int64     `json:"allowed_tariffs"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.log.WithError(err).Error("Ошибка декодирования JSON")
+		middleware.WriteError(w, http.StatusBadRequest, errors.ErrJSONRequest.Error())
+		return
+	}
+	defer r.Body.Close()
+
+	limits, err := h.limitsService.SetLimits(ctx, actorID, isAdmin, &models.UserLimits{
+		UserID:          userID,
+		DailySpend:      req.DailySpend,
+		WeeklySpend:     req.WeeklySpend,
+		DailyMinutes:    req.DailyMinutes,
+		AllowedFromHour: req.AllowedFromHour,
+		AllowedToHour:   req.AllowedToHour,
+		AllowedTariffs:  req.AllowedTariffs,
+	})
+	if err != nil {
+		h.log.WithError(err).Error("Ошибка при изменении ограничений")
+		switch err {
+		case errors.ErrInvalidLimits:
+			middleware.WriteError(w, http.StatusBadRequest, err.Error())
+		case errors.ErrForbidden:
+			middleware.WriteError(w, http.StatusForbidden, err.Error())
+		case errors.ErrUserNotFound:
+			middleware.WriteError(w, http.StatusNotFound, err.Error())
+		default:
+			middleware.WriteError(w, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(limits)
+}
+
+// SetGuardian привязывает игрока к опекуну или снимает привязку при guardian_id: null (право limits.manage)
+func (h limitsHandler) SetGuardian(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	h.log.Info("Запрос на привязку опекуна")
+
+	userID, ok := h.targetID(w, r)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		GuardianID *int64 `json:"guardian_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.log.WithError(err).Error("Ошибка декодирования JSON")
+		middleware.WriteError(w, http.StatusBadRequest, errors.ErrJSONRequest.Error())
+		return
+	}
+	defer r.Body.Close()
+
+	if err := h.limitsService.SetGuardian(ctx, userID, req.GuardianID); err != nil {
+		h.log.WithError(err).Error("Ошибка при привязке опекуна")
+		switch err {
+		case errors.ErrInvalidGuardian:
+			middleware.WriteError(w, http.StatusBadRequest, err.Error())
+		case errors.ErrUserNotFound:
+			middleware.WriteError(w, http.StatusNotFound, err.Error())
+		default:
+			middleware.WriteError(w, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"user_id":     userID,
+		"guardian_id": req.GuardianID,
+	})
+}
+
+// GetWards возвращает подопечных текущего пользователя с их ограничениями и расходом
+func (h limitsHandler) GetWards(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	h.log.Info("Запрос на получение подопечных")
+
+	guardianID, _, ok := h.actor(w, r)
+	if !ok {
+		return
+	}
+
+	wards, err := h.limitsService.GetWards(ctx, guardianID)
+	if err != nil {
+		h.log.WithError(err).Error("Ошибка при получении подопечных")
+		middleware.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(wards)
+}
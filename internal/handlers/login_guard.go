@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"computer-club/internal/middleware"
+	"computer-club/internal/repository/models"
+	"computer-club/internal/usecase"
+	"computer-club/pkg/errors"
+	"encoding/json"
+	"github.com/go-chi/chi/v5"
+	"github.com/sirupsen/logrus"
+	"net/http"
+	"strconv"
+)
+
+type LoginGuardHandler interface {
+	ListLoginEvents(http.ResponseWriter, *http.Request)
+	UnlockUser(http.ResponseWriter, *http.Request)
+}
+
+func NewLoginGuardHandler(loginGuardService usecase.LoginGuardService, log *logrus.Logger) LoginGuardHandler {
+	return &loginGuardHandler{loginGuardService: loginGuardService, log: log}
+}
+
+type loginGuardHandler struct {
+	loginGuardService usecase.LoginGuardService
+	log               *logrus.Logger
+}
+
+// ListLoginEvents возвращает журнал подозрительной активности при входе (право user.manage)
+func (h loginGuardHandler) ListLoginEvents(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	h.log.Info("Запрос на получение журнала входа")
+
+	filter, err := parseLoginEventFilter(r)
+	if err != nil {
+		middleware.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	page, err := h.loginGuardService.ListEvents(ctx, filter)
+	if err != nil {
+		h.log.WithError(err).Error("Ошибка при получении журнала входа")
+		if err == errors.ErrInvalidLoginEventFilter {
+			middleware.WriteError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		middleware.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(page)
+}
+
+// UnlockUser снимает паузу и блокировку входа после неудачных попыток (право user.manage)
+func (h loginGuardHandler) UnlockUser(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	h.log.Info("Запрос на снятие блокировки входа")
+
+	actorID, ok := r.Context().Value("user_id").(int64)
+	if !ok {
+		h.log.Error("Ошибка: user_id не найден в контексте")
+		middleware.WriteError(w, http.StatusUnauthorized, errors.ErrWrongIDFromJWT.Error())
+		return
+	}
+	userID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		h.log.WithError(err).Error("Ошибка: некорректный ID пользователя")
+		middleware.WriteError(w, http.StatusBadRequest, errors.ErrInvalidUserID.Error())
+		return
+	}
+
+	if err := h.loginGuardService.Unlock(ctx, actorID, userID); err != nil {
+		h.log.WithError(err).Error("Ошибка при снятии блокировки входа")
+		if err == errors.ErrUserNotFound {
+			middleware.WriteError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		middleware.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	h.log.WithFields(logrus.Fields{
+		"user_id":     userID,
+		"unlocked_by": actorID,
+	}).Info("Блокировка входа снята")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func parseLoginEventFilter(r *http.Request) (models.LoginEventFilter, error) {
+	query := r.URL.Query()
+	filter := models.LoginEventFilter{
+		IP:   query.Get("ip"),
+		Type: models.LoginEventType(query.Get("type")),
+	}
+
+	if value := query.Get("user_id"); value != "" {
+		userID, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || userID <= 0 {
+			return filter, errors.ErrInvalidLoginEventFilter
+		}
+		filter.UserID = &userID
+	}
+	if value := query.Get("cursor"); value != "" {
+		cursor, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || cursor <= 0 {
+			return filter, errors.ErrInvalidLoginEventFilter
+		}
+		filter.Cursor = cursor
+	}
+	if value := query.Get("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit <= 0 {
+			return filter, errors.ErrInvalidLoginEventFilter
+		}
+		filter.Limit = limit
+	}
+	return filter, nil
+}
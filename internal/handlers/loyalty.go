@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"computer-club/internal/middleware"
+	"computer-club/internal/usecase"
+	"computer-club/pkg/errors"
+	"encoding/json"
+	"github.com/sirupsen/logrus"
+	"net/http"
+)
+
+type LoyaltyHandler interface {
+	GetStatus(http.ResponseWriter, *http.Request)
+	RedeemPoints(http.ResponseWriter, *http.Request)
+}
+
+func NewLoyaltyHandler(loyaltyService usecase.LoyaltyService, log *logrus.Logger) LoyaltyHandler {
+	return &loyaltyHandler{loyaltyService: loyaltyService, log: log}
+}
+
+type loyaltyHandler struct {
+	loyaltyService usecase.LoyaltyService
+	log            *logrus.Logger
+}
+
+// GetStatus возвращает баллы и уровень лояльности текущего пользователя
+func (h loyaltyHandler) GetStatus(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	h.log.Info("Запрос на получение статуса лояльности")
+
+	userID, ok := r.Context().Value("user_id").(int64)
+	if !ok {
+		h.log.Error("Ошибка: user_id не найден в контексте")
+		middleware.WriteError(w, http.StatusUnauthorized, errors.ErrWrongIDFromJWT.Error())
+		return
+	}
+
+	status, err := h.loyaltyService.GetStatus(ctx, userID)
+	if err != nil {
+		h.log.WithError(err).Error("Ошибка при получении статуса лояльности")
+		middleware.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(status)
+}
+
+// RedeemPoints обменивает баллы текущего пользователя на минуты игры
+func (h loyaltyHandler) RedeemPoints(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	h.log.Info("Запрос на обмен баллов лояльности")
+
+	userID, ok := r.Context().Value("user_id").(int64)
+	if !ok {
+		h.log.Error("Ошибка: user_id не найден в контексте")
+		middleware.WriteError(w, http.StatusUnauthorized, errors.ErrWrongIDFromJWT.Error())
+		return
+	}
+
+	var req struct {
+		Minutes int64 `json:"minutes"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.log.WithError(err).Error("Ошибка декодирования JSON")
+		middleware.WriteError(w, http.StatusBadRequest, errors.ErrJSONRequest.Error())
+		return
+	}
+	defer r.Body.Close()
+
+	userPackage, err := h.loyaltyService.RedeemPoints(ctx, userID, req.Minutes)
+	if err != nil {
+		h.log.WithError(err).Error("Ошибка при обмене баллов")
+		switch err {
+		case errors.ErrInvalidMinutes, errors.ErrNotEnoughPoints:
+			middleware.WriteError(w, http.StatusBadRequest, err.Error())
+		case errors.ErrOutsideAllowedHours:
+			middleware.WriteError(w, http.StatusForbidden, err.Error())
+		default:
+			middleware.WriteError(w, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	h.log.WithFields(logrus.Fields{
+		"user_id":         userID,
+		"user_package_id": userPackage.ID,
+		"minutes":         req.Minutes,
+	}).Info("Баллы обменяны на минуты")
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(userPackage)
+}
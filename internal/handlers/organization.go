@@ -0,0 +1,358 @@
+package handlers
+
+import (
+	"computer-club/internal/middleware"
+	"computer-club/internal/repository/models"
+	"computer-club/internal/usecase"
+	"computer-club/pkg/errors"
+	"computer-club/pkg/money"
+	"encoding/json"
+	"github.com/go-chi/chi/v5"
+	"github.com/sirupsen/logrus"
+	"net/http"
+	"strconv"
+)
+
+type OrganizationHandler interface {
+	CreateOrganization(http.ResponseWriter, *http.Request)
+	GetOrganization(http.ResponseWriter, *http.Request)
+	AddMember(http.ResponseWriter, *http.Request)
+	UpdateMember(http.ResponseWriter, *http.Request)
+	RemoveMember(http.ResponseWriter, *http.Request)
+	Deposit(http.ResponseWriter, *http.Request)
+	GetInvoices(http.ResponseWriter, *http.Request)
+	GenerateInvoice(http.ResponseWriter, *http.Request)
+}
+
+func NewOrganizationHandler(organizationService usecase.OrganizationService, log *logrus.Logger) OrganizationHandler {
+	return &organizationHandler{organizationService: organizationService, log: log}
+}
+
+type organizationHandler struct {
+	organizationService usecase.OrganizationService
+	log                 *logrus.Logger
+}
+
+// actor возвращает ID пользователя из токена и признак права organization.manage
+func (h organizationHandler) actor(w http.ResponseWriter, r *http.Request) (int64, bool, bool) {
+	userID, ok := r.Context().Value("user_id").(int64)
+	if !ok {
+		h.log.Error("Ошибка: user_id не найден в контексте")
+		middleware.WriteError(w, http.StatusUnauthorized, errors.ErrWrongIDFromJWT.Error())
+		return 0, false, false
+	}
+	return userID, middleware.HasPermission(r, models.PermOrganizationManage), true
+}
+
+// staffID возвращает ID сотрудника из токена. Права проверяет RequirePermission на маршруте
+func (h organizationHandler) staffID(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	staffID, ok := r.Context().Value("user_id").(int64)
+	if !ok {
+		h.log.Error("Ошибка: user_id не найден в контексте")
+		middleware.WriteError(w, http.StatusUnauthorized, errors.ErrWrongIDFromJWT.Error())
+		return 0, false
+	}
+	return staffID, true
+}
+
+func (h organizationHandler) organizationID(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		h.log.WithError(err).Error("Ошибка: некорректный ID организации")
+		middleware.WriteError(w, http.StatusBadRequest, errors.ErrInvalidOrganizationID.Error())
+		return 0, false
+	}
+	return id, true
+}
+
+func (h organizationHandler) memberID(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "user_id"), 10, 64)
+	if err != nil {
+		h.log.WithError(err).Error("Ошибка: некорректный ID участника")
+		middleware.WriteError(w, http.StatusBadRequest, errors.ErrInvalidUserID.Error())
+		return 0, false
+	}
+	return id, true
+}
+
+// writeError переводит ошибки организаций в HTTP-статусы
+func (h organizationHandler) writeError(w http.ResponseWriter, err error) {
+	switch err {
+	case errors.ErrInvalidOrganization, errors.ErrInvalidAmount, errors.ErrInvalidPaymentMethod,
+		errors.ErrCurrencyMismatch, errors.ErrInvalidPeriod, errors.ErrCannotRemoveOwner:
+		middleware.WriteError(w, http.StatusBadRequest, err.Error())
+	case errors.ErrForbidden, errors.ErrNotOrganizationMember:
+		middleware.WriteError(w, http.StatusForbidden, err.Error())
+	case errors.ErrOrganizationNotFound, errors.ErrUserNotFound:
+		middleware.WriteError(w, http.StatusNotFound, err.Error())
+	case errors.ErrAlreadyMember, errors.ErrNoOpenShift:
+		middleware.WriteError(w, http.StatusConflict, err.Error())
+	default:
+		middleware.WriteError(w, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// CreateOrganization заводит организацию с владельцем (право organization.manage)
+func (h organizationHandler) CreateOrganization(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	h.log.Info("Запрос на создание организации")
+
+	if _, ok := h.staffID(w, r); !ok {
+		return
+	}
+
+	var req struct {
+		Name    string `json:"name"`
+		OwnerID int64  `json:"owner_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.log.WithError(err).Error("Ошибка декодирования JSON")
+		middleware.WriteError(w, http.StatusBadRequest, errors.ErrJSONRequest.Error())
+		return
+	}
+	defer r.Body.Close()
+
+	details, err := h.organizationService.Create(ctx, req.Name, req.OwnerID)
+	if err != nil {
+		h.log.WithError(err).Error("Ошибка при создании организации")
+		h.writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(details)
+}
+
+// GetOrganization возвращает организацию с балансом и участниками. Доступно участникам и сотруднику с правом organization.manage
+func (h organizationHandler) GetOrganization(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	h.log.Info("Запрос на получение организации")
+
+	actorID, isAdmin, ok := h.actor(w, r)
+	if !ok {
+		return
+	}
+	organizationID, ok := h.organizationID(w, r)
+	if !ok {
+		return
+	}
+
+	details, err := h.organizationService.GetDetails(ctx, organizationID, actorID, isAdmin)
+	if err != nil {
+		h.log.WithError(err).Error("Ошибка при получении организации")
+		h.writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(details)
+}
+
+// AddMember добавляет участника с месячным лимитом расходов. Доступно владельцу и сотруднику с правом organization.manage
+func (h organizationHandler) AddMember(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	h.log.Info("Запрос на добавление участника организации")
+
+	actorID, isAdmin, ok := h.actor(w, r)
+	if !ok {
+		return
+	}
+	organizationID, ok := h.organizationID(w, r)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		UserID     int64       `json:"user_id"`
+		MonthlyCap money.Money `json:"monthly_cap"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.log.WithError(err).Error("Ошибка декодирования JSON")
+		middleware.WriteError(w, http.StatusBadRequest, errors.ErrJSONRequest.Error())
+		return
+	}
+	defer r.Body.Close()
+
+	member, err := h.organizationService.AddMember(ctx, organizationID, actorID, isAdmin, req.UserID, req.MonthlyCap)
+	if err != nil {
+		h.log.WithError(err).Error("Ошибка при добавлении участника организации")
+		h.writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(member)
+}
+
+// UpdateMember меняет месячный лимит участника. Доступно владельцу и сотруднику с правом organization.manage
+func (h organizationHandler) UpdateMember(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	h.log.Info("Запрос на изменение лимита участника организации")
+
+	actorID, isAdmin, ok := h.actor(w, r)
+	if !ok {
+		return
+	}
+	organizationID, ok := h.organizationID(w, r)
+	if !ok {
+		return
+	}
+	userID, ok := h.memberID(w, r)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		MonthlyCap money.Money `json:"monthly_cap"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.log.WithError(err).Error("Ошибка декодирования JSON")
+		middleware.WriteError(w, http.StatusBadRequest, errors.ErrJSONRequest.Error())
+		return
+	}
+	defer r.Body.Close()
+
+	if err := h.organizationService.UpdateMember(ctx, organizationID, actorID, isAdmin, userID, req.MonthlyCap); err != nil {
+		h.log.WithError(err).Error("Ошибка при изменении лимита участника организации")
+		if err == errors.ErrNotOrganizationMember {
+			middleware.WriteError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		h.writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RemoveMember исключает участника из организации. Доступно владельцу и сотруднику с правом organization.manage
+func (h organizationHandler) RemoveMember(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	h.log.Info("Запрос на исключение участника организации")
+
+	actorID, isAdmin, ok := h.actor(w, r)
+	if !ok {
+		return
+	}
+	organizationID, ok := h.organizationID(w, r)
+	if !ok {
+		return
+	}
+	userID, ok := h.memberID(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.organizationService.RemoveMember(ctx, organizationID, actorID, isAdmin, userID); err != nil {
+		h.log.WithError(err).Error("Ошибка при исключении участника организации")
+		if err == errors.ErrNotOrganizationMember {
+			middleware.WriteError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		h.writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Deposit пополняет кошелек организации (право organization.manage)
+func (h organizationHandler) Deposit(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	h.log.Info("Запрос на пополнение кошелька организации")
+
+	adminID, ok := h.staffID(w, r)
+	if !ok {
+		return
+	}
+	organizationID, ok := h.organizationID(w, r)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Amount        money.Money          `json:"amount"`
+		PaymentMethod models.PaymentMethod `json:"payment_method"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.log.WithError(err).Error("Ошибка декодирования JSON")
+		middleware.WriteError(w, http.StatusBadRequest, errors.ErrJSONRequest.Error())
+		return
+	}
+	defer r.Body.Close()
+
+	if req.PaymentMethod == "" {
+		req.PaymentMethod = models.PaymentCash
+	}
+
+	transaction, err := h.organizationService.Deposit(ctx, organizationID, adminID, req.Amount, req.PaymentMethod)
+	if err != nil {
+		h.log.WithError(err).Error("Ошибка при пополнении кошелька организации")
+		h.writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(transaction)
+}
+
+// GetInvoices возвращает ежемесячные счета организации. Доступно владельцу и сотруднику с правом organization.manage
+func (h organizationHandler) GetInvoices(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	h.log.Info("Запрос на получение счетов организации")
+
+	actorID, isAdmin, ok := h.actor(w, r)
+	if !ok {
+		return
+	}
+	organizationID, ok := h.organizationID(w, r)
+	if !ok {
+		return
+	}
+
+	invoices, err := h.organizationService.GetInvoices(ctx, organizationID, actorID, isAdmin)
+	if err != nil {
+		h.log.WithError(err).Error("Ошибка при получении счетов организации")
+		h.writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(invoices)
+}
+
+// GenerateInvoice формирует или пересчитывает счет организации за месяц (право organization.manage)
+func (h organizationHandler) GenerateInvoice(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	h.log.Info("Запрос на формирование счета организации")
+
+	if _, ok := h.staffID(w, r); !ok {
+		return
+	}
+	organizationID, ok := h.organizationID(w, r)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Period string `json:"period"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.log.WithError(err).Error("Ошибка декодирования JSON")
+		middleware.WriteError(w, http.StatusBadRequest, errors.ErrJSONRequest.Error())
+		return
+	}
+	defer r.Body.Close()
+
+	invoice, err := h.organizationService.GenerateInvoice(ctx, organizationID, req.Period)
+	if err != nil {
+		h.log.WithError(err).Error("Ошибка при формировании счета организации")
+		h.writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(invoice)
+}
@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"computer-club/internal/middleware"
+	"computer-club/internal/repository/models"
+	"computer-club/internal/usecase"
+	"computer-club/pkg/errors"
+	"encoding/json"
+	"github.com/go-chi/chi/v5"
+	"github.com/sirupsen/logrus"
+	"net/http"
+	"strconv"
+)
+
+type PackageHandler interface {
+	GetPackages(w http.ResponseWriter, r *http.Request)
+	CreatePackage(w http.ResponseWriter, r *http.Request)
+	BuyPackage(w http.ResponseWriter, r *http.Request)
+}
+
+type packageHandler struct {
+	packageService usecase.PackageService
+	log            *logrus.Logger
+}
+
+func NewPackageHandler(packageService usecase.PackageService, log *logrus.Logger) PackageHandler {
+	return &packageHandler{packageService: packageService, log: log}
+}
+
+// GetPackages возвращает список пакетов, доступных для покупки
+func (h packageHandler) GetPackages(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	h.log.Info("Запрос на получение списка пакетов")
+
+	packages, err := h.packageService.GetPackages(ctx)
+	if err != nil {
+		h.log.WithError(err).Error("Ошибка при получении списка пакетов")
+		middleware.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	h.log.WithField("count", len(packages)).Info("Список пакетов получен")
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(packages)
+}
+
+// CreatePackage создает новый пакет минут или абонемент (право package.edit)
+func (h packageHandler) CreatePackage(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	h.log.Info("Запрос на создание пакета")
+
+	var pack models.Package
+	if err := json.NewDecoder(r.Body).Decode(&pack); err != nil {
+		h.log.WithError(err).Error("Ошибка декодирования JSON")
+		middleware.WriteError(w, http.StatusBadRequest, errors.ErrJSONRequest.Error())
+		return
+	}
+	defer r.Body.Close()
+
+	if err := h.packageService.CreatePackage(ctx, &pack); err != nil {
+		h.log.WithError(err).Error("Ошибка при создании пакета")
+		switch err {
+		case errors.ErrInvalidPackage:
+			middleware.WriteError(w, http.StatusBadRequest, err.Error())
+		default:
+			middleware.WriteError(w, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	h.log.WithFields(logrus.Fields{
+		"package_id": pack.ID,
+		"type":       pack.Type,
+	}).Info("Пакет создан")
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(pack)
+}
+
+// BuyPackage покупает пакет с баланса кошелька текущего пользователя
+func (h packageHandler) BuyPackage(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	h.log.Info("Запрос на покупку пакета")
+
+	userID, ok := r.Context().Value("user_id").(int64)
+	if !ok {
+		h.log.Error("Ошибка: user_id не найден в контексте")
+		middleware.WriteError(w, http.StatusUnauthorized, errors.ErrWrongIDFromJWT.Error())
+		return
+	}
+
+	packageID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		h.log.WithError(err).Error("Некорректный ID пакета")
+		middleware.WriteError(w, http.StatusBadRequest, errors.ErrInvalidPackageID.Error())
+		return
+	}
+
+	userPackage, err := h.packageService.BuyPackage(ctx, userID, packageID)
+	if err != nil {
+		h.log.WithError(err).Error("Ошибка при покупке пакета")
+		switch err {
+		case errors.ErrPackageNotFound:
+			middleware.WriteError(w, http.StatusNotFound, err.Error())
+		case errors.ErrPackageInactive, errors.ErrInsufficientFunds:
+			middleware.WriteError(w, http.StatusBadRequest, err.Error())
+		case errors.ErrOutsideAllowedHours, errors.ErrDailySpendLimit, errors.ErrWeeklySpendLimit:
+			middleware.WriteError(w, http.StatusForbidden, err.Error())
+		default:
+			middleware.WriteError(w, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	h.log.WithFields(logrus.Fields{
+		"user_id":         userID,
+		"user_package_id": userPackage.ID,
+		"package_id":      packageID,
+	}).Info("Пакет куплен")
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(userPackage)
+}
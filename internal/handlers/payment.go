@@ -0,0 +1,184 @@
+package handlers
+
+import (
+	"computer-club/internal/middleware"
+	"computer-club/internal/usecase"
+	"computer-club/pkg/errors"
+	"computer-club/pkg/money"
+	"encoding/json"
+	"github.com/go-chi/chi/v5"
+	"github.com/sirupsen/logrus"
+	"io"
+	"net/http"
+	"strconv"
+)
+
+type PaymentHandler interface {
+	CreatePayment(http.ResponseWriter, *http.Request)
+	GetPayment(http.ResponseWriter, *http.Request)
+	Webhook(http.ResponseWriter, *http.Request)
+	RefundPayment(http.ResponseWriter, *http.Request)
+}
+
+func NewPaymentHandler(paymentService usecase.PaymentService, log *logrus.Logger) PaymentHandler {
+	return &paymentHandler{paymentService: paymentService, log: log}
+}
+
+type paymentHandler struct {
+	paymentService usecase.PaymentService
+	log            *logrus.Logger
+}
+
+// CreatePayment создает платеж для пополнения кошелька текущего пользователя
+func (h paymentHandler) CreatePayment(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	h.log.Info("Запрос на создание платежа")
+
+	userID, ok := r.Context().Value("user_id").(int64)
+	if !ok {
+		h.log.Error("Ошибка: user_id не найден в контексте")
+		middleware.WriteError(w, http.StatusUnauthorized, errors.ErrWrongIDFromJWT.Error())
+		return
+	}
+
+	var req struct {
+		Amount money.Money `json:"amount"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.log.WithError(err).Error("Ошибка декодирования JSON")
+		middleware.WriteError(w, http.StatusBadRequest, errors.ErrJSONRequest.Error())
+		return
+	}
+	defer r.Body.Close()
+
+	payment, err := h.paymentService.CreatePayment(ctx, userID, req.Amount)
+	if err != nil {
+		h.log.WithError(err).Error("Ошибка при создании платежа")
+		switch err {
+		case errors.ErrInvalidAmount, errors.ErrCurrencyMismatch:
+			middleware.WriteError(w, http.StatusBadRequest, err.Error())
+		case errors.ErrPaymentProvider:
+			middleware.WriteError(w, http.StatusBadGateway, err.Error())
+		case errors.ErrPaymentsDisabled:
+			middleware.WriteError(w, http.StatusServiceUnavailable, err.Error())
+		default:
+			middleware.WriteError(w, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(payment)
+}
+
+// GetPayment возвращает платеж текущего пользователя
+func (h paymentHandler) GetPayment(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	h.log.Info("Запрос на получение платежа")
+
+	userID, ok := r.Context().Value("user_id").(int64)
+	if !ok {
+		h.log.Error("Ошибка: user_id не найден в контексте")
+		middleware.WriteError(w, http.StatusUnauthorized, errors.ErrWrongIDFromJWT.Error())
+		return
+	}
+
+	paymentID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		h.log.WithError(err).Error("Некорректный ID платежа")
+		middleware.WriteError(w, http.StatusBadRequest, errors.ErrInvalidPaymentID.Error())
+		return
+	}
+
+	payment, err := h.paymentService.GetPayment(ctx, userID, paymentID)
+	if err != nil {
+		h.log.WithError(err).Error("Ошибка при получении платежа")
+		middleware.WriteError(w, http.StatusNotFound, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(payment)
+}
+
+// Webhook принимает уведомления платежного провайдера. Подпись проверяет провайдер
+func (h paymentHandler) Webhook(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	h.log.Info("Уведомление от платежного провайдера")
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		h.log.WithError(err).Error("Ошибка чтения тела уведомления")
+		middleware.WriteError(w, http.StatusBadRequest, errors.ErrJSONRequest.Error())
+		return
+	}
+	defer r.Body.Close()
+
+	if err := h.paymentService.HandleWebhook(ctx, body, r.Header); err != nil {
+		h.log.WithError(err).Error("Ошибка при обработке уведомления")
+		switch err {
+		case errors.ErrInvalidSignature:
+			middleware.WriteError(w, http.StatusUnauthorized, err.Error())
+		case errors.ErrJSONRequest, errors.ErrPaymentMismatch:
+			middleware.WriteError(w, http.StatusBadRequest, err.Error())
+		case errors.ErrPaymentNotFound:
+			middleware.WriteError(w, http.StatusNotFound, err.Error())
+		case errors.ErrPaymentsDisabled:
+			middleware.WriteError(w, http.StatusServiceUnavailable, err.Error())
+		default:
+			middleware.WriteError(w, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// RefundPayment возвращает онлайн-платеж (право payment.refund)
+func (h paymentHandler) RefundPayment(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	h.log.Info("Запрос на возврат платежа")
+
+	adminID, ok := r.Context().Value("user_id").(int64)
+	if !ok {
+		h.log.Error("Ошибка: user_id не найден в контексте")
+		middleware.WriteError(w, http.StatusUnauthorized, errors.ErrWrongIDFromJWT.Error())
+		return
+	}
+
+	paymentID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		h.log.WithError(err).Error("Некорректный ID платежа")
+		middleware.WriteError(w, http.StatusBadRequest, errors.ErrInvalidPaymentID.Error())
+		return
+	}
+
+	payment, err := h.paymentService.RefundPayment(ctx, paymentID, adminID)
+	if err != nil {
+		h.log.WithError(err).Error("Ошибка при возврате платежа")
+		switch err {
+		case errors.ErrPaymentNotFound:
+			middleware.WriteError(w, http.StatusNotFound, err.Error())
+		case errors.ErrPaymentNotRefundable:
+			middleware.WriteError(w, http.StatusConflict, err.Error())
+		case errors.ErrInsufficientFunds:
+			middleware.WriteError(w, http.StatusBadRequest, err.Error())
+		case errors.ErrPaymentProvider:
+			middleware.WriteError(w, http.StatusBadGateway, err.Error())
+		case errors.ErrPaymentsDisabled:
+			middleware.WriteError(w, http.StatusServiceUnavailable, err.Error())
+		default:
+			middleware.WriteError(w, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	h.log.WithFields(logrus.Fields{
+		"payment_id": payment.ID,
+		"admin_id":   adminID,
+	}).Info("Платеж возвращен")
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(payment)
+}
@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"computer-club/internal/middleware"
+	"computer-club/internal/repository/models"
+	"computer-club/internal/usecase"
+	"computer-club/pkg/errors"
+	"encoding/json"
+	"github.com/sirupsen/logrus"
+	"net/http"
+)
+
+type PromotionHandler interface {
+	CreatePromotion(w http.ResponseWriter, r *http.Request)
+	GetPromotions(w http.ResponseWriter, r *http.Request)
+}
+
+type promotionHandler struct {
+	promotionService usecase.PromotionService
+	log              *logrus.Logger
+}
+
+func NewPromotionHandler(promotionService usecase.PromotionService, log *logrus.Logger) PromotionHandler {
+	return &promotionHandler{promotionService: promotionService, log: log}
+}
+
+// CreatePromotion создает новый промокод (право promotion.manage)
+func (h promotionHandler) CreatePromotion(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	h.log.Info("Запрос на создание промокода")
+
+	var promotion models.Promotion
+	if err := json.NewDecoder(r.Body).Decode(&promotion); err != nil {
+		h.log.WithError(err).Error("Ошибка декодирования JSON")
+		middleware.WriteError(w, http.StatusBadRequest, errors.ErrJSONRequest.Error())
+		return
+	}
+	defer r.Body.Close()
+
+	if err := h.promotionService.CreatePromotion(ctx, &promotion); err != nil {
+		h.log.WithError(err).Error("Ошибка при создании промокода")
+		switch err {
+		case errors.ErrInvalidPromotion:
+			middleware.WriteError(w, http.StatusBadRequest, err.Error())
+		default:
+			middleware.WriteError(w, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	h.log.WithFields(logrus.Fields{
+		"promotion_id": promotion.ID,
+		"code":         promotion.Code,
+		"type":         promotion.Type,
+	}).Info("Промокод создан")
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(promotion)
+}
+
+// GetPromotions возвращает список промокодов (право promotion.manage)
+func (h promotionHandler) GetPromotions(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	h.log.Info("Запрос на получение списка промокодов")
+
+	promotions, err := h.promotionService.GetPromotions(ctx)
+	if err != nil {
+		h.log.WithError(err).Error("Ошибка при получении списка промокодов")
+		middleware.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	h.log.WithField("count", len(promotions)).Info("Список промокодов получен")
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(promotions)
+}
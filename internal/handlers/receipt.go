@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"computer-club/internal/middleware"
+	"computer-club/internal/repository/models"
+	"computer-club/internal/usecase"
+	"computer-club/pkg/errors"
+	"encoding/json"
+	"fmt"
+	"github.com/go-chi/chi/v5"
+	"github.com/sirupsen/logrus"
+	"net/http"
+	"strconv"
+)
+
+type ReceiptHandler interface {
+	GetReceipt(http.ResponseWriter, *http.Request)
+}
+
+func NewReceiptHandler(receiptService usecase.ReceiptService, log *logrus.Logger) ReceiptHandler {
+	return &receiptHandler{receiptService: receiptService, log: log}
+}
+
+type receiptHandler struct {
+	receiptService usecase.ReceiptService
+	log            *logrus.Logger
+}
+
+// GetReceipt возвращает чек в формате json, text или pdf (параметр format).
+// Клиент видит только свои чеки, сотрудник с правом receipt.view.any - любые
+func (h receiptHandler) GetReceipt(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	h.log.Info("Запрос на получение чека")
+
+	userID, ok := r.Context().Value("user_id").(int64)
+	if !ok {
+		h.log.Error("Ошибка: user_id не найден в контексте")
+		middleware.WriteError(w, http.StatusUnauthorized, errors.ErrWrongIDFromJWT.Error())
+		return
+	}
+
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		h.log.WithError(err).Error("Ошибка: некорректный ID чека")
+		middleware.WriteError(w, http.StatusBadRequest, errors.ErrInvalidReceiptID.Error())
+		return
+	}
+
+	receipt, err := h.receiptService.GetReceipt(ctx, id)
+	if err == nil && receipt.UserID != userID && !middleware.HasPermission(r, models.PermReceiptViewAny) {
+		err = errors.ErrReceiptNotFound
+	}
+	if err != nil {
+		h.log.WithError(err).Error("Ошибка при получении чека")
+		switch err {
+		case errors.ErrReceiptNotFound:
+			middleware.WriteError(w, http.StatusNotFound, err.Error())
+		default:
+			middleware.WriteError(w, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	switch r.URL.Query().Get("format") {
+	case "", "json":
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(receipt)
+	case "text":
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Write(h.receiptService.RenderText(receipt))
+	case "pdf":
+		w.Header().Set("Content-Type", "application/pdf")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("inline; filename=\"receipt-%s.pdf\"", receipt.Number))
+		w.Write(h.receiptService.RenderPDF(receipt))
+	default:
+		middleware.WriteError(w, http.StatusBadRequest, errors.ErrInvalidReceiptFormat.Error())
+	}
+}
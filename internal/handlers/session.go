@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"computer-club/internal/middleware"
+	"computer-club/internal/repository/models"
 	"computer-club/internal/usecase"
 	"computer-club/pkg/errors"
 	"encoding/json"
@@ -11,6 +12,7 @@ import (
 
 type SessionHandler interface {
 	StartSession(w http.ResponseWriter, r *http.Request)
+	QuoteSession(w http.ResponseWriter, r *http.Request)
 	EndSession(w http.ResponseWriter, r *http.Request)
 	GetActiveSessions(w http.ResponseWriter, r *http.Request)
 }
@@ -37,22 +39,46 @@ func (h sessionHandler) StartSession(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var req struct {
-		PCNumber int   `json:"pc_number"`
-		TariffID int64 `json:"tariff_id"`
+		PCNumber  int    `json:"pc_number"`
+		TariffID  int64  `json:"tariff_id"`
+		PromoCode string `json:"promo_code"`
+		QuoteID   string `json:"quote_id"`
+		// OrganizationID - оплатить сессию с кошелька организации, в которой состоит игрок
+		OrganizationID *int64 `json:"organization_id"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		h.log.WithError(err).Error("Ошибка декодирования JSON")
 		middleware.WriteError(w, http.StatusBadRequest, errors.ErrJSONRequest.Error())
 		return
 	}
-	session, err := h.sessionService.StartSession(ctx, userID, req.PCNumber, req.TariffID)
+
+	var session *models.Session
+	var err error
+	if req.OrganizationID != nil {
+		if req.PromoCode != "" || req.QuoteID != "" {
+			h.log.WithError(errors.ErrOrganizationSessionOptions).Error("Ошибка при запуске сессии")
+			middleware.WriteError(w, http.StatusBadRequest, errors.ErrOrganizationSessionOptions.Error())
+			return
+		}
+		session, err = h.sessionService.StartOrganizationSession(ctx, userID, *req.OrganizationID, req.PCNumber, req.TariffID)
+	} else {
+		session, err = h.sessionService.StartSession(ctx, userID, req.PCNumber, req.TariffID, req.PromoCode, req.QuoteID)
+	}
 	if err != nil {
 		// Проверяем тип ошибки
 		switch err {
-		case errors.ErrUserNotFound, errors.ErrComputerNotFound, errors.ErrTariffNotFound:
+		case errors.ErrUserNotFound, errors.ErrComputerNotFound, errors.ErrTariffNotFound, errors.ErrPromotionNotFound,
+			errors.ErrQuoteNotFound, errors.ErrOrganizationNotFound:
 			middleware.WriteError(w, http.StatusNotFound, err.Error())
-		case errors.ErrSessionActive, errors.ErrPCBusy:
+		case errors.ErrSessionActive, errors.ErrPCBusy, errors.ErrPromotionLimitReached, errors.ErrQuoteChanged:
 			middleware.WriteError(w, http.StatusConflict, err.Error())
+		case errors.ErrPromotionInactive, errors.ErrPromotionExpired, errors.ErrPromotionNotApplicable,
+			errors.ErrQuoteMismatch, errors.ErrCurrencyMismatch:
+			middleware.WriteError(w, http.StatusBadRequest, err.Error())
+		case errors.ErrInsufficientFunds, errors.ErrTariffNotAllowed, errors.ErrOutsideAllowedHours,
+			errors.ErrDailyMinutesLimit, errors.ErrDailySpendLimit, errors.ErrWeeklySpendLimit,
+			errors.ErrNotOrganizationMember, errors.ErrMemberCapExceeded, errors.ErrUserBlocked:
+			middleware.WriteError(w, http.StatusForbidden, err.Error())
 		case errors.ErrCreatedSession:
 			middleware.WriteError(w, http.StatusInternalServerError, err.Error())
 		default:
@@ -74,6 +100,61 @@ func (h sessionHandler) StartSession(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(session)
 }
 
+// QuoteSession возвращает итоговую цену сессии со скидками и котировку для ее фиксации
+func (h sessionHandler) QuoteSession(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	h.log.Info("Запрос на расчет цены сессии")
+
+	userID, ok := r.Context().Value("user_id").(int64)
+	if !ok {
+		h.log.Error("Ошибка: user_id не найден в контексте")
+		middleware.WriteError(w, http.StatusUnauthorized, errors.ErrWrongIDFromJWT.Error())
+		return
+	}
+
+	var req struct {
+		PCNumber  int    `json:"pc_number"`
+		TariffID  int64  `json:"tariff_id"`
+		PromoCode string `json:"promo_code"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.log.WithError(err).Error("Ошибка декодирования JSON")
+		middleware.WriteError(w, http.StatusBadRequest, errors.ErrJSONRequest.Error())
+		return
+	}
+
+	quote, err := h.sessionService.QuoteSession(ctx, userID, req.PCNumber, req.TariffID, req.PromoCode)
+	if err != nil {
+		switch err {
+		case errors.ErrUserNotFound, errors.ErrComputerNotFound, errors.ErrTariffNotFound, errors.ErrPromotionNotFound:
+			middleware.WriteError(w, http.StatusNotFound, err.Error())
+		case errors.ErrSessionActive, errors.ErrPCBusy, errors.ErrPromotionLimitReached:
+			middleware.WriteError(w, http.StatusConflict, err.Error())
+		case errors.ErrPromotionInactive, errors.ErrPromotionExpired, errors.ErrPromotionNotApplicable:
+			middleware.WriteError(w, http.StatusBadRequest, err.Error())
+		case errors.ErrUserBlocked:
+			middleware.WriteError(w, http.StatusForbidden, err.Error())
+		default:
+			middleware.WriteError(w, http.StatusInternalServerError, err.Error())
+		}
+		h.log.WithError(err).Error("Ошибка при расчете цены сессии")
+		return
+	}
+
+	h.log.WithFields(logrus.Fields{
+		"quote_id":      quote.ID,
+		"user_id":       userID,
+		"pc_number":     quote.PCNumber,
+		"tariff_id":     quote.TariffID,
+		"price":         quote.Price,
+		"wallet_covers": quote.WalletCovers,
+		"expires_at":    quote.ExpiresAt,
+	}).Info("Цена сессии рассчитана")
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(quote)
+}
+
 // EndSession завершает активную сессию
 func (h sessionHandler) EndSession(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
@@ -96,10 +177,24 @@ func (h sessionHandler) EndSession(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err := h.sessionService.EndSession(ctx, req.SessionID)
+	userID, ok := r.Context().Value("user_id").(int64)
+	if !ok {
+		h.log.Error("Ошибка: user_id не найден в контексте")
+		middleware.WriteError(w, http.StatusUnauthorized, errors.ErrWrongIDFromJWT.Error())
+		return
+	}
+
+	err := h.sessionService.EndUserSession(ctx, req.SessionID, userID, middleware.HasPermission(r, models.PermSessionEndAny))
 	if err != nil {
 		h.log.WithError(err).Error("Ошибка завершения сессии")
-		middleware.WriteError(w, http.StatusInternalServerError, err.Error())
+		switch err {
+		case errors.ErrSessionNotFound:
+			middleware.WriteError(w, http.StatusNotFound, err.Error())
+		case errors.ErrForbidden:
+			middleware.WriteError(w, http.StatusForbidden, err.Error())
+		default:
+			middleware.WriteError(w, http.StatusInternalServerError, err.Error())
+		}
 		return
 	}
 
@@ -114,13 +209,6 @@ func (h sessionHandler) GetActiveSessions(w http.ResponseWriter, r *http.Request
 	ctx := r.Context()
 	h.log.Info("Запрос на получение активных сессий")
 
-	role, ok := r.Context().Value("role").(string)
-	if !ok || role != "admin" {
-		h.log.WithError(errors.ErrForbidden).Error("Ошибка при получении списка компьютеров: недостаточно прав")
-		middleware.WriteError(w, http.StatusForbidden, errors.ErrForbidden.Error())
-		return
-	}
-
 	sessions := h.sessionService.GetActiveSessions(ctx)
 	h.log.WithField("count", len(sessions)).Info("Активные сессии получены")
 
@@ -0,0 +1,190 @@
+package handlers
+
+import (
+	"computer-club/internal/middleware"
+	"computer-club/internal/usecase"
+	"computer-club/pkg/errors"
+	"computer-club/pkg/money"
+	"encoding/json"
+	"github.com/go-chi/chi/v5"
+	"github.com/sirupsen/logrus"
+	"net/http"
+	"strconv"
+)
+
+type ShiftHandler interface {
+	OpenShift(http.ResponseWriter, *http.Request)
+	CloseShift(http.ResponseWriter, *http.Request)
+	CurrentReport(http.ResponseWriter, *http.Request)
+	GetReport(http.ResponseWriter, *http.Request)
+}
+
+func NewShiftHandler(shiftService usecase.ShiftService, log *logrus.Logger) ShiftHandler {
+	return &shiftHandler{shiftService: shiftService, log: log}
+}
+
+type shiftHandler struct {
+	shiftService usecase.ShiftService
+	log          *logrus.Logger
+}
+
+// staffID возвращает ID сотрудника из токена. Права проверяет RequirePermission на маршруте
+func (h shiftHandler) staffID(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	staffID, ok := r.Context().Value("user_id").(int64)
+	if !ok {
+		h.log.Error("Ошибка: user_id не найден в контексте")
+		middleware.WriteError(w, http.StatusUnauthorized, errors.ErrWrongIDFromJWT.Error())
+		return 0, false
+	}
+	return staffID, true
+}
+
+// OpenShift открывает кассовую смену с разменом на начало смены
+func (h shiftHandler) OpenShift(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	h.log.Info("Запрос на открытие смены")
+
+	adminID, ok := h.staffID(w, r)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		OpeningFloat money.Money `json:"opening_float"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.log.WithError(err).Error("Ошибка декодирования JSON")
+		middleware.WriteError(w, http.StatusBadRequest, errors.ErrJSONRequest.Error())
+		return
+	}
+	defer r.Body.Close()
+
+	shift, err := h.shiftService.OpenShift(ctx, adminID, req.OpeningFloat)
+	if err != nil {
+		h.log.WithError(err).Error("Ошибка при открытии смены")
+		switch err {
+		case errors.ErrInvalidAmount:
+			middleware.WriteError(w, http.StatusBadRequest, err.Error())
+		case errors.ErrShiftAlreadyOpen:
+			middleware.WriteError(w, http.StatusConflict, err.Error())
+		default:
+			middleware.WriteError(w, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	h.log.WithFields(logrus.Fields{
+		"shift_id": shift.ID,
+		"admin_id": adminID,
+	}).Info("Смена открыта")
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(shift)
+}
+
+// CloseShift закрывает смену с пересчитанной наличностью и возвращает Z-отчет
+func (h shiftHandler) CloseShift(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	h.log.Info("Запрос на закрытие смены")
+
+	adminID, ok := h.staffID(w, r)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		CountedCash money.Money `json:"counted_cash"`
+		Note        string      `json:"note"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.log.WithError(err).Error("Ошибка декодирования JSON")
+		middleware.WriteError(w, http.StatusBadRequest, errors.ErrJSONRequest.Error())
+		return
+	}
+	defer r.Body.Close()
+
+	report, err := h.shiftService.CloseShift(ctx, adminID, req.CountedCash, req.Note)
+	if err != nil {
+		h.log.WithError(err).Error("Ошибка при закрытии смены")
+		switch err {
+		case errors.ErrInvalidAmount:
+			middleware.WriteError(w, http.StatusBadRequest, err.Error())
+		case errors.ErrNoOpenShift:
+			middleware.WriteError(w, http.StatusConflict, err.Error())
+		default:
+			middleware.WriteError(w, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	entry := h.log.WithFields(logrus.Fields{
+		"shift_id":    report.Shift.ID,
+		"discrepancy": report.Shift.Discrepancy.String(),
+	})
+	if report.Flagged {
+		entry.Warn("Смена закрыта с расхождением по наличности")
+	} else {
+		entry.Info("Смена закрыта")
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+// CurrentReport возвращает X-отчет по открытой смене
+func (h shiftHandler) CurrentReport(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	h.log.Info("Запрос X-отчета по смене")
+
+	if _, ok := h.staffID(w, r); !ok {
+		return
+	}
+
+	report, err := h.shiftService.CurrentReport(ctx)
+	if err != nil {
+		h.log.WithError(err).Error("Ошибка при формировании X-отчета")
+		switch err {
+		case errors.ErrNoOpenShift:
+			middleware.WriteError(w, http.StatusNotFound, err.Error())
+		default:
+			middleware.WriteError(w, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+// GetReport возвращает отчет по смене: Z-отчет по закрытой, X-отчет по открытой
+func (h shiftHandler) GetReport(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	h.log.Info("Запрос отчета по смене")
+
+	if _, ok := h.staffID(w, r); !ok {
+		return
+	}
+
+	shiftID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		h.log.WithError(err).Error("Некорректный ID смены")
+		middleware.WriteError(w, http.StatusBadRequest, errors.ErrInvalidShiftID.Error())
+		return
+	}
+
+	report, err := h.shiftService.GetReport(ctx, shiftID)
+	if err != nil {
+		h.log.WithError(err).Error("Ошибка при формировании отчета по смене")
+		switch err {
+		case errors.ErrShiftNotFound:
+			middleware.WriteError(w, http.StatusNotFound, err.Error())
+		default:
+			middleware.WriteError(w, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"computer-club/internal/middleware"
+	"computer-club/internal/repository/models"
 	"computer-club/internal/usecase"
 	"computer-club/pkg/errors"
 	"encoding/json"
@@ -14,6 +15,8 @@ import (
 type TariffHandler interface {
 	GetTariff(w http.ResponseWriter, r *http.Request)
 	GetTariffByID(w http.ResponseWriter, r *http.Request)
+	CreateTariff(w http.ResponseWriter, r *http.Request)
+	UpdateTariff(w http.ResponseWriter, r *http.Request)
 }
 
 type tariffHandler struct {
@@ -60,6 +63,75 @@ func (h tariffHandler) GetTariffByID(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(tariff)
 }
 
+// CreateTariff создает новый тариф (право tariff.edit)
+func (h tariffHandler) CreateTariff(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	h.log.Info("Запрос на создание тарифа")
+
+	var tariff models.Tariff
+	if err := json.NewDecoder(r.Body).Decode(&tariff); err != nil {
+		h.log.WithError(err).Error("Ошибка декодирования JSON")
+		middleware.WriteError(w, http.StatusBadRequest, errors.ErrJSONRequest.Error())
+		return
+	}
+	defer r.Body.Close()
+
+	if err := h.tariffService.CreateTariff(ctx, &tariff); err != nil {
+		h.log.WithError(err).Error("Ошибка при создании тарифа")
+		switch err {
+		case errors.ErrInvalidTariff, errors.ErrCurrencyMismatch:
+			middleware.WriteError(w, http.StatusBadRequest, err.Error())
+		default:
+			middleware.WriteError(w, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	h.log.WithField("tariff_id", tariff.ID).Info("Тариф создан")
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(tariff)
+}
+
+// UpdateTariff меняет название, цену и длительность тарифа (право tariff.edit)
+func (h tariffHandler) UpdateTariff(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		h.log.WithError(err).Error("Некорректный ID тарифа")
+		middleware.WriteError(w, http.StatusBadRequest, errors.ErrInvalidTariffID.Error())
+		return
+	}
+
+	var tariff models.Tariff
+	if err := json.NewDecoder(r.Body).Decode(&tariff); err != nil {
+		h.log.WithError(err).Error("Ошибка декодирования JSON")
+		middleware.WriteError(w, http.StatusBadRequest, errors.ErrJSONRequest.Error())
+		return
+	}
+	defer r.Body.Close()
+	tariff.ID = id
+
+	if err := h.tariffService.UpdateTariff(ctx, &tariff); err != nil {
+		h.log.WithError(err).Error("Ошибка при обновлении тарифа")
+		switch err {
+		case errors.ErrInvalidTariff, errors.ErrCurrencyMismatch:
+			middleware.WriteError(w, http.StatusBadRequest, err.Error())
+		case errors.ErrTariffNotFound:
+			middleware.WriteError(w, http.StatusNotFound, err.Error())
+		default:
+			middleware.WriteError(w, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	h.log.WithField("tariff_id", tariff.ID).Info("Тариф обновлен")
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tariff)
+}
+
 func NewTariffHandler(tariffService usecase.TariffService, log *logrus.Logger) TariffHandler {
 	return &tariffHandler{tariffService: tariffService, log: log}
 }
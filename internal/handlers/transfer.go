@@ -0,0 +1,124 @@
+package handlers
+
+import (
+	"computer-club/internal/middleware"
+	"computer-club/internal/usecase"
+	"computer-club/pkg/errors"
+	"computer-club/pkg/money"
+	"encoding/json"
+	"github.com/go-chi/chi/v5"
+	"github.com/sirupsen/logrus"
+	"net/http"
+	"strconv"
+)
+
+type TransferHandler interface {
+	Transfer(http.ResponseWriter, *http.Request)
+	ReverseTransfer(http.ResponseWriter, *http.Request)
+}
+
+func NewTransferHandler(transferService usecase.TransferService, log *logrus.Logger) TransferHandler {
+	return &transferHandler{transferService: transferService, log: log}
+}
+
+type transferHandler struct {
+	transferService usecase.TransferService
+	log             *logrus.Logger
+}
+
+// Transfer переводит средства с кошелька текущего пользователя другому игроку
+func (h transferHandler) Transfer(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	h.log.Info("Запрос на перевод средств игроку")
+
+	userID, ok := r.Context().Value("user_id").(int64)
+	if !ok {
+		h.log.Error("Ошибка: user_id не найден в контексте")
+		middleware.WriteError(w, http.StatusUnauthorized, errors.ErrWrongIDFromJWT.Error())
+		return
+	}
+
+	var req struct {
+		ToUserID int64       `json:"to_user_id"`
+		Amount   money.Money `json:"amount"`
+		Comment  string      `json:"comment"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.log.WithError(err).Error("Ошибка декодирования JSON")
+		middleware.WriteError(w, http.StatusBadRequest, errors.ErrJSONRequest.Error())
+		return
+	}
+	defer r.Body.Close()
+
+	transfer, err := h.transferService.Transfer(ctx, userID, req.ToUserID, req.Amount, req.Comment)
+	if err != nil {
+		h.log.WithError(err).Error("Ошибка при переводе средств")
+		switch err {
+		case errors.ErrInvalidAmount, errors.ErrTransferToSelf, errors.ErrTransferTooSmall,
+			errors.ErrCurrencyMismatch, errors.ErrInsufficientFunds:
+			middleware.WriteError(w, http.StatusBadRequest, err.Error())
+		case errors.ErrTransferDailyLimit, errors.ErrOutsideAllowedHours, errors.ErrDailySpendLimit, errors.ErrWeeklySpendLimit:
+			middleware.WriteError(w, http.StatusForbidden, err.Error())
+		case errors.ErrUserNotFound:
+			middleware.WriteError(w, http.StatusNotFound, err.Error())
+		default:
+			middleware.WriteError(w, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	h.log.WithFields(logrus.Fields{
+		"transfer_id":  transfer.ID,
+		"from_user_id": transfer.FromUserID,
+		"to_user_id":   transfer.ToUserID,
+		"amount":       transfer.Amount.String(),
+	}).Info("Перевод выполнен")
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(transfer)
+}
+
+// ReverseTransfer отменяет перевод (право transaction.reverse)
+func (h transferHandler) ReverseTransfer(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	h.log.Info("Запрос на отмену перевода")
+
+	adminID, ok := r.Context().Value("user_id").(int64)
+	if !ok {
+		h.log.Error("Ошибка: user_id не найден в контексте")
+		middleware.WriteError(w, http.StatusUnauthorized, errors.ErrWrongIDFromJWT.Error())
+		return
+	}
+
+	transferID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		h.log.WithError(err).Error("Некорректный ID перевода")
+		middleware.WriteError(w, http.StatusBadRequest, errors.ErrInvalidTransferID.Error())
+		return
+	}
+
+	transfer, err := h.transferService.ReverseTransfer(ctx, transferID, adminID)
+	if err != nil {
+		h.log.WithError(err).Error("Ошибка при отмене перевода")
+		switch err {
+		case errors.ErrTransferNotFound:
+			middleware.WriteError(w, http.StatusNotFound, err.Error())
+		case errors.ErrTransferAlreadyReversed:
+			middleware.WriteError(w, http.StatusConflict, err.Error())
+		case errors.ErrInsufficientFunds:
+			middleware.WriteError(w, http.StatusBadRequest, err.Error())
+		default:
+			middleware.WriteError(w, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	h.log.WithFields(logrus.Fields{
+		"transfer_id": transfer.ID,
+		"admin_id":    adminID,
+	}).Info("Перевод отменен")
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(transfer)
+}
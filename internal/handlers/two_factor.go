@@ -0,0 +1,180 @@
+package handlers
+
+import (
+	"computer-club/internal/middleware"
+	"computer-club/internal/usecase"
+	"computer-club/pkg/errors"
+	"encoding/json"
+	"github.com/sirupsen/logrus"
+	"net/http"
+)
+
+type TwoFactorHandler interface {
+	GetStatus(http.ResponseWriter, *http.Request)
+	Enroll(http.ResponseWriter, *http.Request)
+	Confirm(http.ResponseWriter, *http.Request)
+	RegenerateBackupCodes(http.ResponseWriter, *http.Request)
+	Disable(http.ResponseWriter, *http.Request)
+}
+
+func NewTwoFactorHandler(twoFactorService usecase.TwoFactorService, log *logrus.Logger) TwoFactorHandler {
+	return &twoFactorHandler{twoFactorService: twoFactorService, log: log}
+}
+
+type twoFactorHandler struct {
+	twoFactorService usecase.TwoFactorService
+	log              *logrus.Logger
+}
+
+type twoFactorCodeRequest struct {
+	Code string `json:"code"`
+}
+
+// GetStatus возвращает, включена ли 2FA, обязательна ли она для роли и сколько осталось резервных кодов
+func (h twoFactorHandler) GetStatus(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	h.log.Info("Запрос на получение состояния 2FA")
+
+	userID, ok := r.Context().Value("user_id").(int64)
+	if !ok {
+		h.log.Error("Ошибка: user_id не найден в контексте")
+		middleware.WriteError(w, http.StatusUnauthorized, errors.ErrWrongIDFromJWT.Error())
+		return
+	}
+
+	status, err := h.twoFactorService.GetStatus(ctx, userID)
+	if err != nil {
+		h.log.WithError(err).Error("Ошибка при получении состояния 2FA")
+		h.writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(status)
+}
+
+// Enroll выдает секрет и ссылку otpauth:// для приложения-аутентификатора
+func (h twoFactorHandler) Enroll(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	h.log.Info("Запрос на подключение 2FA")
+
+	userID, ok := r.Context().Value("user_id").(int64)
+	if !ok {
+		h.log.Error("Ошибка: user_id не найден в контексте")
+		middleware.WriteError(w, http.StatusUnauthorized, errors.ErrWrongIDFromJWT.Error())
+		return
+	}
+
+	enrollment, err := h.twoFactorService.Enroll(ctx, userID)
+	if err != nil {
+		h.log.WithError(err).Error("Ошибка при подключении 2FA")
+		h.writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(enrollment)
+}
+
+// Confirm включает 2FA по первому коду и возвращает резервные коды
+func (h twoFactorHandler) Confirm(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	h.log.Info("Запрос на подтверждение 2FA")
+
+	userID, ok := r.Context().Value("user_id").(int64)
+	if !ok {
+		h.log.Error("Ошибка: user_id не найден в контексте")
+		middleware.WriteError(w, http.StatusUnauthorized, errors.ErrWrongIDFromJWT.Error())
+		return
+	}
+	var req twoFactorCodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		middleware.WriteError(w, http.StatusBadRequest, errors.ErrJSONRequest.Error())
+		return
+	}
+	defer r.Body.Close()
+
+	codes, err := h.twoFactorService.Confirm(ctx, userID, req.Code)
+	if err != nil {
+		h.log.WithError(err).Error("Ошибка при подтверждении 2FA")
+		h.writeError(w, err)
+		return
+	}
+
+	h.log.WithField("user_id", userID).Info("2FA включена")
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string][]string{"backup_codes": codes})
+}
+
+// RegenerateBackupCodes заменяет резервные коды новыми, нужен действующий код
+func (h twoFactorHandler) RegenerateBackupCodes(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	h.log.Info("Запрос на новые резервные коды 2FA")
+
+	userID, ok := r.Context().Value("user_id").(int64)
+	if !ok {
+		h.log.Error("Ошибка: user_id не найден в контексте")
+		middleware.WriteError(w, http.StatusUnauthorized, errors.ErrWrongIDFromJWT.Error())
+		return
+	}
+	var req twoFactorCodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		middleware.WriteError(w, http.StatusBadRequest, errors.ErrJSONRequest.Error())
+		return
+	}
+	defer r.Body.Close()
+
+	codes, err := h.twoFactorService.RegenerateBackupCodes(ctx, userID, req.Code)
+	if err != nil {
+		h.log.WithError(err).Error("Ошибка при выпуске резервных кодов 2FA")
+		h.writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string][]string{"backup_codes": codes})
+}
+
+// Disable отключает 2FA, нужен действующий код
+func (h twoFactorHandler) Disable(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	h.log.Info("Запрос на отключение 2FA")
+
+	userID, ok := r.Context().Value("user_id").(int64)
+	if !ok {
+		h.log.Error("Ошибка: user_id не найден в контексте")
+		middleware.WriteError(w, http.StatusUnauthorized, errors.ErrWrongIDFromJWT.Error())
+		return
+	}
+	var req twoFactorCodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		middleware.WriteError(w, http.StatusBadRequest, errors.ErrJSONRequest.Error())
+		return
+	}
+	defer r.Body.Close()
+
+	if err := h.twoFactorService.Disable(ctx, userID, req.Code); err != nil {
+		h.log.WithError(err).Error("Ошибка при отключении 2FA")
+		h.writeError(w, err)
+		return
+	}
+
+	h.log.WithField("user_id", userID).Info("2FA отключена")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h twoFactorHandler) writeError(w http.ResponseWriter, err error) {
+	switch err {
+	case errors.ErrInvalidTwoFactorCode, errors.ErrTwoFactorNotEnrolled:
+		middleware.WriteError(w, http.StatusBadRequest, err.Error())
+	case errors.ErrTwoFactorEnabled, errors.ErrTwoFactorNotEnabled:
+		middleware.WriteError(w, http.StatusConflict, err.Error())
+	case errors.ErrTooManyAttempts:
+		middleware.WriteError(w, http.StatusTooManyRequests, err.Error())
+	case errors.ErrUserNotFound:
+		middleware.WriteError(w, http.StatusNotFound, err.Error())
+	default:
+		middleware.WriteError(w, http.StatusInternalServerError, err.Error())
+	}
+}
@@ -5,25 +5,46 @@ import (
 	"computer-club/internal/repository/models"
 	"computer-club/internal/usecase"
 	"computer-club/pkg/errors"
+	"computer-club/pkg/money"
 	"encoding/json"
+	"github.com/go-chi/chi/v5"
 	"github.com/sirupsen/logrus"
 	"net/http"
+	"strconv"
 )
 
 type UserHandler interface {
 	RegisterUser(w http.ResponseWriter, r *http.Request)
 	LoginUser(w http.ResponseWriter, r *http.Request)
+	LoginTwoFactor(w http.ResponseWriter, r *http.Request)
+	ResetTwoFactor(w http.ResponseWriter, r *http.Request)
+	RefreshToken(w http.ResponseWriter, r *http.Request)
+	Logout(w http.ResponseWriter, r *http.Request)
+	RevokeUserTokens(w http.ResponseWriter, r *http.Request)
+	SetRole(w http.ResponseWriter, r *http.Request)
 	InfoUser(w http.ResponseWriter, r *http.Request)
+	ListUsers(w http.ResponseWriter, r *http.Request)
+	GetUser(w http.ResponseWriter, r *http.Request)
+	UpdateUser(w http.ResponseWriter, r *http.Request)
+	BlockUser(w http.ResponseWriter, r *http.Request)
+	UnblockUser(w http.ResponseWriter, r *http.Request)
+	DeleteUser(w http.ResponseWriter, r *http.Request)
+	VerifyEmail(w http.ResponseWriter, r *http.Request)
+	ResendVerification(w http.ResponseWriter, r *http.Request)
+	ForgotPassword(w http.ResponseWriter, r *http.Request)
+	ResetPassword(w http.ResponseWriter, r *http.Request)
 }
 
 type userHandler struct {
-	userService   usecase.UserService
-	walletService usecase.WalletService
-	log           *logrus.Logger
+	userService    usecase.UserService
+	walletService  usecase.WalletService
+	packageService usecase.PackageService
+	loyaltyService usecase.LoyaltyService
+	log            *logrus.Logger
 }
 
-func NewUserHandler(userService usecase.UserService, walletService usecase.WalletService, log *logrus.Logger) UserHandler {
-	return &userHandler{userService: userService, walletService: walletService, log: log}
+func NewUserHandler(userService usecase.UserService, walletService usecase.WalletService, packageService usecase.PackageService, loyaltyService usecase.LoyaltyService, log *logrus.Logger) UserHandler {
+	return &userHandler{userService: userService, walletService: walletService, packageService: packageService, loyaltyService: loyaltyService, log: log}
 }
 
 func (h userHandler) InfoUser(w http.ResponseWriter, r *http.Request) {
@@ -44,7 +65,7 @@ func (h userHandler) InfoUser(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Получение баланса кошелька
-	balance, err := h.walletService.GetBalance(ctx, userID)
+	wallet, err := h.walletService.GetWallet(ctx, userID)
 	if err != nil {
 		h.log.Error("Ошибка получения баланса кошелька")
 		middleware.WriteError(w, http.StatusInternalServerError, err.Error())
@@ -58,14 +79,50 @@ func (h userHandler) InfoUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	bonusGrants, err := h.walletService.GetBonusGrants(ctx, userID)
+	if err != nil {
+		h.log.Error("Ошибка получения бонусных начислений")
+		middleware.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	packages, err := h.packageService.GetUserPackages(ctx, userID)
+	if err != nil {
+		h.log.Error("Ошибка получения списка пакетов")
+		middleware.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	loyalty, err := h.loyaltyService.GetStatus(ctx, userID)
+	if err != nil {
+		h.log.Error("Ошибка получения баллов лояльности")
+		middleware.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	var packageMinutes int64
+	for _, pack := range packages {
+		packageMinutes += pack.MinutesLeft
+	}
+
 	response := struct {
-		User         *models.User         `json:"user"`
-		Balance      float64              `json:"balance"`
-		Transactions []models.Transaction `json:"transactions"`
+		User           *models.User          `json:"user"`
+		Balance        money.Money           `json:"balance"`
+		Bonus          money.Money           `json:"bonus"`
+		BonusGrants    []models.BonusGrant   `json:"bonus_grants"`
+		Loyalty        *models.LoyaltyStatus `json:"loyalty"`
+		PackageMinutes int64                 `json:"package_minutes"`
+		Packages       []models.UserPackage  `json:"packages"`
+		Transactions   []models.Transaction  `json:"transactions"`
 	}{
-		User:         user,
-		Balance:      balance,
-		Transactions: transactions,
+		User:           user,
+		Balance:        wallet.Balance,
+		Bonus:          wallet.Bonus,
+		BonusGrants:    bonusGrants,
+		Loyalty:        loyalty,
+		PackageMinutes: packageMinutes,
+		Packages:       packages,
+		Transactions:   transactions,
 	}
 
 	h.log.Info(w, http.StatusOK, "Получена информация о пользователе")
@@ -81,7 +138,6 @@ func (h userHandler) RegisterUser(w http.ResponseWriter, r *http.Request) {
 		Name     string `json:"name"`
 		Email    string `json:"email"`
 		Password string `json:"password"`
-		Role     string `json:"role"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		h.log.WithError(err).Error("Ошибка декодирования JSON")
@@ -90,14 +146,7 @@ func (h userHandler) RegisterUser(w http.ResponseWriter, r *http.Request) {
 	}
 	defer r.Body.Close()
 
-	role := models.UserRole(req.Role)
-	if role != models.Admin && role != models.Customer {
-		h.log.WithField("role", req.Role).Error("Неверная роль")
-		middleware.WriteError(w, http.StatusBadRequest, errors.ErrInvalidRole.Error())
-		return
-	}
-
-	user, err := h.userService.RegisterUser(ctx, req.Name, req.Email, req.Password, role)
+	user, err := h.userService.RegisterUser(ctx, req.Name, req.Email, req.Password)
 	if err != nil {
 		switch err {
 		case errors.ErrHashedPassword, errors.ErrRegistration:
@@ -136,13 +185,620 @@ func (h userHandler) LoginUser(w http.ResponseWriter, r *http.Request) {
 	defer r.Body.Close()
 
 	// Вызываем usecase для логина
-	token, err := h.userService.LoginUser(ctx, req.Email, req.Password)
+	tokens, err := h.userService.LoginUser(ctx, req.Email, req.Password, middleware.ClientIP(r))
 	if err != nil {
-		middleware.WriteError(w, http.StatusUnauthorized, err.Error())
+		switch err {
+		case errors.ErrInvalidCredentials:
+			middleware.WriteError(w, http.StatusUnauthorized, err.Error())
+		case errors.ErrUserBlocked, errors.ErrEmailNotVerified:
+			middleware.WriteError(w, http.StatusForbidden, err.Error())
+		case errors.ErrLoginThrottled:
+			middleware.WriteError(w, http.StatusTooManyRequests, err.Error())
+		case errors.ErrAccountLocked:
+			middleware.WriteError(w, http.StatusLocked, err.Error())
+		default:
+			h.log.WithError(err).Error("Ошибка при входе")
+			middleware.WriteError(w, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	// Отправляем токены или, при включенной 2FA, challenge-токен для второго шага
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tokens)
+}
+
+// LoginTwoFactor завершает вход кодом 2FA или резервным кодом
+func (h userHandler) LoginTwoFactor(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	h.log.Info("Запрос на вход с кодом 2FA")
+
+	var req struct {
+		ChallengeToken string `json:"challenge_token"`
+		Code           string `json:"code"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		middleware.WriteError(w, http.StatusBadRequest, errors.ErrJSONRequest.Error())
+		return
+	}
+	defer r.Body.Close()
+
+	tokens, err := h.userService.LoginTwoFactor(ctx, req.ChallengeToken, req.Code)
+	if err != nil {
+		h.log.WithError(err).Error("Ошибка при входе с кодом 2FA")
+		switch err {
+		case errors.ErrInvalidChallenge, errors.ErrInvalidTwoFactorCode:
+			middleware.WriteError(w, http.StatusUnauthorized, err.Error())
+		case errors.ErrUserBlocked:
+			middleware.WriteError(w, http.StatusForbidden, err.Error())
+		case errors.ErrTooManyAttempts:
+			middleware.WriteError(w, http.StatusTooManyRequests, err.Error())
+		default:
+			middleware.WriteError(w, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tokens)
+}
+
+// RefreshToken выдает новую пару токенов в обмен на refresh-токен
+func (h userHandler) RefreshToken(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	h.log.Info("Запрос на обновление токенов")
+
+	var req struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		middleware.WriteError(w, http.StatusBadRequest, errors.ErrJSONRequest.Error())
+		return
+	}
+	defer r.Body.Close()
+
+	tokens, err := h.userService.RefreshToken(ctx, req.RefreshToken)
+	if err != nil {
+		h.log.WithError(err).Error("Ошибка при обновлении токенов")
+		switch err {
+		case errors.ErrInvalidRefreshToken:
+			middleware.WriteError(w, http.StatusUnauthorized, err.Error())
+		case errors.ErrUserBlocked:
+			middleware.WriteError(w, http.StatusForbidden, err.Error())
+		default:
+			middleware.WriteError(w, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tokens)
+}
+
+// Logout отзывает текущий access-токен и refresh-токен, с all - все токены пользователя
+func (h userHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	h.log.Info("Запрос на выход")
+
+	claims, ok := r.Context().Value("claims").(*models.AccessClaims)
+	if !ok {
+		h.log.Error("Ошибка: данные токена не найдены в контексте")
+		middleware.WriteError(w, http.StatusUnauthorized, errors.ErrWrongToken.Error())
+		return
+	}
+
+	// Тело необязательное: без него отзывается только access-токен
+	var req struct {
+		RefreshToken string `json:"refresh_token"`
+		All          bool   `json:"all"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			middleware.WriteError(w, http.StatusBadRequest, errors.ErrJSONRequest.Error())
+			return
+		}
+		defer r.Body.Close()
+	}
+
+	if err := h.userService.Logout(ctx, claims, req.RefreshToken, req.All); err != nil {
+		h.log.WithError(err).Error("Ошибка при выходе")
+		if err == errors.ErrInvalidRefreshToken {
+			middleware.WriteError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		middleware.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RevokeUserTokens отзывает все токены пользователя (право user.tokens.revoke)
+func (h userHandler) RevokeUserTokens(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	h.log.Info("Запрос на отзыв токенов пользователя")
+
+	userID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		h.log.WithError(err).Error("Ошибка: некорректный ID пользователя")
+		middleware.WriteError(w, http.StatusBadRequest, errors.ErrInvalidUserID.Error())
+		return
+	}
+	if _, err := h.userService.GetUserByID(ctx, userID); err != nil {
+		middleware.WriteError(w, http.StatusNotFound, errors.ErrUserNotFound.Error())
 		return
 	}
 
-	// Отправляем токен
+	if err := h.userService.RevokeUserTokens(ctx, userID); err != nil {
+		h.log.WithError(err).Error("Ошибка при отзыве токенов пользователя")
+		middleware.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	h.log.WithField("user_id", userID).Info("Токены пользователя отозваны")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// SetRole назначает пользователю роль (право user.role.assign)
+func (h userHandler) SetRole(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	h.log.Info("Запрос на назначение роли")
+
+	actorID, ok := r.Context().Value("user_id").(int64)
+	if !ok {
+		h.log.Error("Ошибка: user_id не найден в контексте")
+		middleware.WriteError(w, http.StatusUnauthorized, errors.ErrWrongIDFromJWT.Error())
+		return
+	}
+	userID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		h.log.WithError(err).Error("Ошибка: некорректный ID пользователя")
+		middleware.WriteError(w, http.StatusBadRequest, errors.ErrInvalidUserID.Error())
+		return
+	}
+
+	var req struct {
+		Role models.UserRole `json:"role"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.log.WithError(err).Error("Ошибка декодирования JSON")
+		middleware.WriteError(w, http.StatusBadRequest, errors.ErrJSONRequest.Error())
+		return
+	}
+	defer r.Body.Close()
+
+	user, err := h.userService.SetRole(ctx, actorID, userID, req.Role)
+	if err != nil {
+		h.log.WithError(err).Error("Ошибка при назначении роли")
+		switch err {
+		case errors.ErrInvalidRole, errors.ErrCannotChangeOwnRole:
+			middleware.WriteError(w, http.StatusBadRequest, err.Error())
+		case errors.ErrRoleTooHigh:
+			middleware.WriteError(w, http.StatusForbidden, err.Error())
+		case errors.ErrUserNotFound:
+			middleware.WriteError(w, http.StatusNotFound, err.Error())
+		default:
+			middleware.WriteError(w, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	h.log.WithFields(logrus.Fields{
+		"user_id": user.ID,
+		"role":    user.Role,
+	}).Info("Роль пользователя изменена")
+
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]string{"token": token})
+	json.NewEncoder(w).Encode(user)
+}
+
+// ListUsers ищет пользователей по имени, почте или телефону (право user.view)
+func (h userHandler) ListUsers(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	h.log.Info("Запрос на поиск пользователей")
+
+	filter, err := parseUserFilter(r)
+	if err != nil {
+		middleware.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	page, err := h.userService.ListUsers(ctx, filter)
+	if err != nil {
+		h.log.WithError(err).Error("Ошибка при поиске пользователей")
+		if err == errors.ErrInvalidUserFilter {
+			middleware.WriteError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		middleware.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(page)
+}
+
+// GetUser возвращает карточку пользователя (право user.view)
+func (h userHandler) GetUser(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	h.log.Info("Запрос на получение пользователя")
+
+	userID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		h.log.WithError(err).Error("Ошибка: некорректный ID пользователя")
+		middleware.WriteError(w, http.StatusBadRequest, errors.ErrInvalidUserID.Error())
+		return
+	}
+
+	user, err := h.userService.GetUserByID(ctx, userID)
+	if err != nil {
+		middleware.WriteError(w, http.StatusNotFound, errors.ErrUserNotFound.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(user)
+}
+
+// UpdateUser меняет имя, почту или телефон пользователя (право user.manage)
+func (h userHandler) UpdateUser(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	h.log.Info("Запрос на изменение профиля пользователя")
+
+	actorID, ok := r.Context().Value("user_id").(int64)
+	if !ok {
+		h.log.Error("Ошибка: user_id не найден в контексте")
+		middleware.WriteError(w, http.StatusUnauthorized, errors.ErrWrongIDFromJWT.Error())
+		return
+	}
+	userID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		h.log.WithError(err).Error("Ошибка: некорректный ID пользователя")
+		middleware.WriteError(w, http.StatusBadRequest, errors.ErrInvalidUserID.Error())
+		return
+	}
+
+	var req models.UserProfile
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.log.WithError(err).Error("Ошибка декодирования JSON")
+		middleware.WriteError(w, http.StatusBadRequest, errors.ErrJSONRequest.Error())
+		return
+	}
+	defer r.Body.Close()
+
+	user, err := h.userService.UpdateProfile(ctx, actorID, userID, req)
+	if err != nil {
+		h.log.WithError(err).Error("Ошибка при изменении профиля пользователя")
+		switch err {
+		case errors.ErrNameEmpty, errors.ErrEmailEmpty:
+			middleware.WriteError(w, http.StatusBadRequest, err.Error())
+		case errors.ErrUserAlreadyExists, errors.ErrUsernameTaken, errors.ErrPhoneTaken:
+			middleware.WriteError(w, http.StatusConflict, err.Error())
+		case errors.ErrRoleTooHigh, errors.ErrStaffEmailChange:
+			middleware.WriteError(w, http.StatusForbidden, err.Error())
+		case errors.ErrUserNotFound:
+			middleware.WriteError(w, http.StatusNotFound, err.Error())
+		default:
+			middleware.WriteError(w, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	h.log.WithField("user_id", user.ID).Info("Профиль пользователя изменен")
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(user)
+}
+
+// BlockUser блокирует пользователя с указанием причины (право user.manage)
+func (h userHandler) BlockUser(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	h.log.Info("Запрос на блокировку пользователя")
+
+	actorID, ok := r.Context().Value("user_id").(int64)
+	if !ok {
+		h.log.Error("Ошибка: user_id не найден в контексте")
+		middleware.WriteError(w, http.StatusUnauthorized, errors.ErrWrongIDFromJWT.Error())
+		return
+	}
+	userID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		h.log.WithError(err).Error("Ошибка: некорректный ID пользователя")
+		middleware.WriteError(w, http.StatusBadRequest, errors.ErrInvalidUserID.Error())
+		return
+	}
+
+	var req struct {
+		Reason string `json:"reason"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.log.WithError(err).Error("Ошибка декодирования JSON")
+		middleware.WriteError(w, http.StatusBadRequest, errors.ErrJSONRequest.Error())
+		return
+	}
+	defer r.Body.Close()
+
+	user, err := h.userService.BlockUser(ctx, actorID, userID, req.Reason)
+	if err != nil {
+		h.log.WithError(err).Error("Ошибка при блокировке пользователя")
+		switch err {
+		case errors.ErrBlockReasonRequired, errors.ErrCannotModifySelf:
+			middleware.WriteError(w, http.StatusBadRequest, err.Error())
+		case errors.ErrRoleTooHigh:
+			middleware.WriteError(w, http.StatusForbidden, err.Error())
+		case errors.ErrUserNotFound:
+			middleware.WriteError(w, http.StatusNotFound, err.Error())
+		default:
+			middleware.WriteError(w, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	h.log.WithFields(logrus.Fields{
+		"user_id":    user.ID,
+		"blocked_by": actorID,
+		"reason":     user.BlockReason,
+	}).Info("Пользователь заблокирован")
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(user)
+}
+
+// UnblockUser снимает блокировку с пользователя (право user.manage)
+func (h userHandler) UnblockUser(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	h.log.Info("Запрос на разблокировку пользователя")
+
+	actorID, ok := r.Context().Value("user_id").(int64)
+	if !ok {
+		h.log.Error("Ошибка: user_id не найден в контексте")
+		middleware.WriteError(w, http.StatusUnauthorized, errors.ErrWrongIDFromJWT.Error())
+		return
+	}
+	userID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		h.log.WithError(err).Error("Ошибка: некорректный ID пользователя")
+		middleware.WriteError(w, http.StatusBadRequest, errors.ErrInvalidUserID.Error())
+		return
+	}
+
+	user, err := h.userService.UnblockUser(ctx, actorID, userID)
+	if err != nil {
+		h.log.WithError(err).Error("Ошибка при разблокировке пользователя")
+		switch err {
+		case errors.ErrUserNotBlocked:
+			middleware.WriteError(w, http.StatusConflict, err.Error())
+		case errors.ErrRoleTooHigh:
+			middleware.WriteError(w, http.StatusForbidden, err.Error())
+		case errors.ErrUserNotFound:
+			middleware.WriteError(w, http.StatusNotFound, err.Error())
+		default:
+			middleware.WriteError(w, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	h.log.WithField("user_id", user.ID).Info("Пользователь разблокирован")
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(user)
+}
+
+// DeleteUser мягко удаляет пользователя (право user.manage)
+func (h userHandler) DeleteUser(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	h.log.Info("Запрос на удаление пользователя")
+
+	actorID, ok := r.Context().Value("user_id").(int64)
+	if !ok {
+		h.log.Error("Ошибка: user_id не найден в контексте")
+		middleware.WriteError(w, http.StatusUnauthorized, errors.ErrWrongIDFromJWT.Error())
+		return
+	}
+	userID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		h.log.WithError(err).Error("Ошибка: некорректный ID пользователя")
+		middleware.WriteError(w, http.StatusBadRequest, errors.ErrInvalidUserID.Error())
+		return
+	}
+
+	if err := h.userService.DeleteUser(ctx, actorID, userID); err != nil {
+		h.log.WithError(err).Error("Ошибка при удалении пользователя")
+		switch err {
+		case errors.ErrCannotModifySelf:
+			middleware.WriteError(w, http.StatusBadRequest, err.Error())
+		case errors.ErrRoleTooHigh:
+			middleware.WriteError(w, http.StatusForbidden, err.Error())
+		case errors.ErrUserNotFound:
+			middleware.WriteError(w, http.StatusNotFound, err.Error())
+		default:
+			middleware.WriteError(w, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	h.log.WithFields(logrus.Fields{
+		"user_id":    userID,
+		"deleted_by": actorID,
+	}).Info("Пользователь удален")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ResetTwoFactor отключает 2FA сотрудника, потерявшего телефон (право user.manage)
+func (h userHandler) ResetTwoFactor(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	h.log.Info("Запрос на сброс 2FA пользователя")
+
+	actorID, ok := r.Context().Value("user_id").(int64)
+	if !ok {
+		h.log.Error("Ошибка: user_id не найден в контексте")
+		middleware.WriteError(w, http.StatusUnauthorized, errors.ErrWrongIDFromJWT.Error())
+		return
+	}
+	userID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		h.log.WithError(err).Error("Ошибка: некорректный ID пользователя")
+		middleware.WriteError(w, http.StatusBadRequest, errors.ErrInvalidUserID.Error())
+		return
+	}
+
+	if err := h.userService.ResetTwoFactor(ctx, actorID, userID); err != nil {
+		h.log.WithError(err).Error("Ошибка при сбросе 2FA пользователя")
+		switch err {
+		case errors.ErrCannotModifySelf:
+			middleware.WriteError(w, http.StatusBadRequest, err.Error())
+		case errors.ErrRoleTooHigh:
+			middleware.WriteError(w, http.StatusForbidden, err.Error())
+		case errors.ErrUserNotFound:
+			middleware.WriteError(w, http.StatusNotFound, err.Error())
+		default:
+			middleware.WriteError(w, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	h.log.WithFields(logrus.Fields{
+		"user_id":  userID,
+		"reset_by": actorID,
+	}).Info("2FA пользователя сброшена")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// VerifyEmail подтверждает почту по токену из письма
+func (h userHandler) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	h.log.Info("Запрос на подтверждение почты")
+
+	var req struct {
+		Token string `json:"token"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		middleware.WriteError(w, http.StatusBadRequest, errors.ErrJSONRequest.Error())
+		return
+	}
+	defer r.Body.Close()
+
+	if err := h.userService.VerifyEmail(ctx, req.Token); err != nil {
+		h.log.WithError(err).Error("Ошибка при подтверждении почты")
+		if err == errors.ErrInvalidUserToken {
+			middleware.WriteError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		middleware.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ResendVerification повторно отправляет письмо подтверждения текущему пользователю
+func (h userHandler) ResendVerification(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	h.log.Info("Запрос на повторную отправку письма подтверждения")
+
+	userID, ok := r.Context().Value("user_id").(int64)
+	if !ok {
+		h.log.Error("Ошибка: user_id не найден в контексте")
+		middleware.WriteError(w, http.StatusUnauthorized, errors.ErrWrongIDFromJWT.Error())
+		return
+	}
+
+	if err := h.userService.ResendVerification(ctx, userID); err != nil {
+		h.log.WithError(err).Error("Ошибка при отправке письма подтверждения")
+		switch err {
+		case errors.ErrEmailAlreadyVerified:
+			middleware.WriteError(w, http.StatusConflict, err.Error())
+		case errors.ErrUserNotFound:
+			middleware.WriteError(w, http.StatusNotFound, err.Error())
+		default:
+			middleware.WriteError(w, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// ForgotPassword отправляет ссылку сброса пароля. Ответ одинаковый для любого адреса,
+// чтобы по нему нельзя было проверить, зарегистрирован ли email
+func (h userHandler) ForgotPassword(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	h.log.Info("Запрос на сброс пароля")
+
+	var req struct {
+		Email string `json:"email"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		middleware.WriteError(w, http.StatusBadRequest, errors.ErrJSONRequest.Error())
+		return
+	}
+	defer r.Body.Close()
+
+	if err := h.userService.RequestPasswordReset(ctx, req.Email); err != nil {
+		if err == errors.ErrEmailEmpty {
+			middleware.WriteError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		h.log.WithError(err).Error("Ошибка при отправке ссылки сброса пароля")
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// ResetPassword задает новый пароль по токену из письма
+func (h userHandler) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	h.log.Info("Запрос на установку нового пароля")
+
+	var req struct {
+		Token    string `json:"token"`
+		Password string `json:"password"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		middleware.WriteError(w, http.StatusBadRequest, errors.ErrJSONRequest.Error())
+		return
+	}
+	defer r.Body.Close()
+
+	if err := h.userService.ResetPassword(ctx, req.Token, req.Password); err != nil {
+		h.log.WithError(err).Error("Ошибка при установке нового пароля")
+		switch err {
+		case errors.ErrInvalidUserToken, errors.ErrPasswordEmpty, errors.ErrPasswordTooShort:
+			middleware.WriteError(w, http.StatusBadRequest, err.Error())
+		default:
+			middleware.WriteError(w, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func parseUserFilter(r *http.Request) (models.UserFilter, error) {
+	query := r.URL.Query()
+	filter := models.UserFilter{
+		Query: query.Get("q"),
+		Role:  models.UserRole(query.Get("role")),
+	}
+
+	if value := query.Get("blocked"); value != "" {
+		blocked, err := strconv.ParseBool(value)
+		if err != nil {
+			return filter, errors.ErrInvalidUserFilter
+		}
+		filter.Blocked = &blocked
+	}
+	if value := query.Get("cursor"); value != "" {
+		cursor, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || cursor <= 0 {
+			return filter, errors.ErrInvalidUserFilter
+		}
+		filter.Cursor = cursor
+	}
+	if value := query.Get("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit <= 0 {
+			return filter, errors.ErrInvalidUserFilter
+		}
+		filter.Limit = limit
+	}
+	return filter, nil
 }
@@ -0,0 +1,206 @@
+package handlers
+
+import (
+	"computer-club/internal/middleware"
+	"computer-club/internal/repository/models"
+	"computer-club/internal/usecase"
+	"computer-club/pkg/errors"
+	"computer-club/pkg/money"
+	"encoding/json"
+	"github.com/go-chi/chi/v5"
+	"github.com/sirupsen/logrus"
+	"net/http"
+	"strconv"
+)
+
+type VoucherHandler interface {
+	CreateBatch(http.ResponseWriter, *http.Request)
+	GetBatch(http.ResponseWriter, *http.Request)
+	SellVoucher(http.ResponseWriter, *http.Request)
+	RedeemVoucher(http.ResponseWriter, *http.Request)
+}
+
+func NewVoucherHandler(voucherService usecase.VoucherService, log *logrus.Logger) VoucherHandler {
+	return &voucherHandler{voucherService: voucherService, log: log}
+}
+
+type voucherHandler struct {
+	voucherService usecase.VoucherService
+	log            *logrus.Logger
+}
+
+// staffID возвращает ID сотрудника из токена. Права проверяет RequirePermission на маршруте
+func (h voucherHandler) staffID(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	staffID, ok := r.Context().Value("user_id").(int64)
+	if !ok {
+		h.log.Error("Ошибка: user_id не найден в контексте")
+		middleware.WriteError(w, http.StatusUnauthorized, errors.ErrWrongIDFromJWT.Error())
+		return 0, false
+	}
+	return staffID, true
+}
+
+// CreateBatch выпускает партию подарочных карт и возвращает их коды
+func (h voucherHandler) CreateBatch(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	h.log.Info("Запрос на выпуск подарочных карт")
+
+	adminID, ok := h.staffID(w, r)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Name         string             `json:"name"`
+		Type         models.VoucherType `json:"type"`
+		Amount       money.Money        `json:"amount"`
+		Minutes      int64              `json:"minutes"`
+		Price        money.Money        `json:"price"`
+		Count        int                `json:"count"`
+		ValidityDays int                `json:"validity_days"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.log.WithError(err).Error("Ошибка декодирования JSON")
+		middleware.WriteError(w, http.StatusBadRequest, errors.ErrJSONRequest.Error())
+		return
+	}
+	defer r.Body.Close()
+
+	details, err := h.voucherService.CreateBatch(ctx, adminID, &models.VoucherBatch{
+		Name:    req.Name,
+		Type:    req.Type,
+		Amount:  req.Amount,
+		Minutes: req.Minutes,
+		Price:   req.Price,
+		Count:   req.Count,
+	}, req.ValidityDays)
+	if err != nil {
+		h.log.WithError(err).Error("Ошибка при выпуске подарочных карт")
+		switch err {
+		case errors.ErrInvalidVoucherBatch, errors.ErrCurrencyMismatch:
+			middleware.WriteError(w, http.StatusBadRequest, err.Error())
+		default:
+			middleware.WriteError(w, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(details)
+}
+
+// GetBatch возвращает партию со статусами карт
+func (h voucherHandler) GetBatch(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	h.log.Info("Запрос на получение партии подарочных карт")
+
+	if _, ok := h.staffID(w, r); !ok {
+		return
+	}
+	batchID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		h.log.WithError(err).Error("Ошибка: некорректный ID партии")
+		middleware.WriteError(w, http.StatusBadRequest, errors.ErrInvalidVoucherBatchID.Error())
+		return
+	}
+
+	details, err := h.voucherService.GetBatch(ctx, batchID)
+	if err != nil {
+		h.log.WithError(err).Error("Ошибка при получении партии")
+		switch err {
+		case errors.ErrVoucherBatchNotFound:
+			middleware.WriteError(w, http.StatusNotFound, err.Error())
+		default:
+			middleware.WriteError(w, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(details)
+}
+
+// SellVoucher отмечает продажу карты на кассе
+func (h voucherHandler) SellVoucher(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	h.log.Info("Запрос на продажу подарочной карты")
+
+	adminID, ok := h.staffID(w, r)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		PaymentMethod models.PaymentMethod `json:"payment_method"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.log.WithError(err).Error("Ошибка декодирования JSON")
+		middleware.WriteError(w, http.StatusBadRequest, errors.ErrJSONRequest.Error())
+		return
+	}
+	defer r.Body.Close()
+
+	voucher, err := h.voucherService.SellVoucher(ctx, adminID, chi.URLParam(r, "code"), req.PaymentMethod)
+	if err != nil {
+		h.log.WithError(err).Error("Ошибка при продаже подарочной карты")
+		switch err {
+		case errors.ErrInvalidPaymentMethod:
+			middleware.WriteError(w, http.StatusBadRequest, err.Error())
+		case errors.ErrVoucherInvalid:
+			middleware.WriteError(w, http.StatusNotFound, err.Error())
+		case errors.ErrVoucherNotSellable, errors.ErrVoucherExpired, errors.ErrNoOpenShift:
+			middleware.WriteError(w, http.StatusConflict, err.Error())
+		default:
+			middleware.WriteError(w, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(voucher)
+}
+
+// RedeemVoucher погашает подарочную карту на кошелек текущего пользователя
+func (h voucherHandler) RedeemVoucher(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	h.log.Info("Запрос на погашение подарочной карты")
+
+	userID, ok := r.Context().Value("user_id").(int64)
+	if !ok {
+		h.log.Error("Ошибка: user_id не найден в контексте")
+		middleware.WriteError(w, http.StatusUnauthorized, errors.ErrWrongIDFromJWT.Error())
+		return
+	}
+
+	var req struct {
+		Code string `json:"code"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.log.WithError(err).Error("Ошибка декодирования JSON")
+		middleware.WriteError(w, http.StatusBadRequest, errors.ErrJSONRequest.Error())
+		return
+	}
+	defer r.Body.Close()
+
+	redemption, err := h.voucherService.RedeemVoucher(ctx, userID, middleware.ClientIP(r), req.Code)
+	if err != nil {
+		h.log.WithError(err).Error("Ошибка при погашении подарочной карты")
+		switch err {
+		case errors.ErrVoucherInvalid:
+			middleware.WriteError(w, http.StatusNotFound, err.Error())
+		case errors.ErrVoucherRedeemed, errors.ErrVoucherExpired, errors.ErrCurrencyMismatch:
+			middleware.WriteError(w, http.StatusConflict, err.Error())
+		case errors.ErrTooManyAttempts:
+			middleware.WriteError(w, http.StatusTooManyRequests, err.Error())
+		case errors.ErrOutsideAllowedHours:
+			middleware.WriteError(w, http.StatusForbidden, err.Error())
+		default:
+			middleware.WriteError(w, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(redemption)
+}
@@ -5,13 +5,23 @@ import (
 	models2 "computer-club/internal/repository/models"
 	"computer-club/internal/usecase"
 	"computer-club/pkg/errors"
+	"computer-club/pkg/money"
+	"encoding/csv"
 	"encoding/json"
+	"github.com/go-chi/chi/v5"
 	"github.com/sirupsen/logrus"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 )
 
 type WalletHandler interface {
 	PutMoneyOnWallet(http.ResponseWriter, *http.Request)
+	GrantBonus(http.ResponseWriter, *http.Request)
+	GetTransactions(http.ResponseWriter, *http.Request)
+	AdminTransactions(http.ResponseWriter, *http.Request)
+	ReverseTransaction(http.ResponseWriter, *http.Request)
 }
 
 func NewWalletHandler(walletService usecase.WalletService, log *logrus.Logger) WalletHandler {
@@ -27,16 +37,59 @@ func (h walletHandler) PutMoneyOnWallet(w http.ResponseWriter, r *http.Request)
 	ctx := r.Context()
 	h.log.Info("Запрос на отправку средств на счет игрока")
 
-	role, ok := r.Context().Value("role").(string)
-	if !ok || role != string(models2.Admin) {
-		h.log.WithError(errors.ErrForbidden).Error("Ошибка при переводе: недостаточно прав")
-		middleware.WriteError(w, http.StatusForbidden, errors.ErrForbidden.Error())
+	var req struct {
+		UserID        int64                 `json:"user_id"`
+		Amount        money.Money           `json:"amount"`
+		PaymentMethod models2.PaymentMethod `json:"payment_method"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.log.WithError(err).Error("Ошибка декодирования JSON")
+		middleware.WriteError(w, http.StatusBadRequest, errors.ErrJSONRequest.Error())
 		return
 	}
+	defer r.Body.Close()
+
+	if req.PaymentMethod == "" {
+		req.PaymentMethod = models2.PaymentCash
+	}
+
+	adminID, ok := r.Context().Value("user_id").(int64)
+	if !ok {
+		h.log.WithError(errors.ErrWrongIDFromJWT).Error("Ошибка получения ID администратора")
+		middleware.WriteError(w, http.StatusUnauthorized, errors.ErrWrongIDFromJWT.Error())
+		return
+	}
+
+	transaction, err := h.walletService.Deposit(ctx, req.UserID, adminID, req.Amount, req.PaymentMethod)
+	if err != nil {
+		h.log.WithError(err).Error("Ошибка при передаче денег")
+		switch err {
+		case errors.ErrInvalidAmount, errors.ErrInvalidPaymentMethod, errors.ErrCurrencyMismatch:
+			middleware.WriteError(w, http.StatusBadRequest, err.Error())
+		case errors.ErrUserNotFound:
+			middleware.WriteError(w, http.StatusNotFound, err.Error())
+		case errors.ErrNoOpenShift:
+			middleware.WriteError(w, http.StatusConflict, err.Error())
+		default:
+			middleware.WriteError(w, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(transaction)
+}
+
+// GrantBonus начисляет игроку бонусы (право wallet.bonus)
+func (h walletHandler) GrantBonus(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	h.log.Info("Запрос на начисление бонусов")
 
 	var req struct {
-		UserID int64   `json:"user_id"`
-		Amount float64 `json:"amount"`
+		UserID  int64               `json:"user_id"`
+		Amount  money.Money         `json:"amount"`
+		Source  models2.BonusSource `json:"source"`
+		Comment string              `json:"comment"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		h.log.WithError(err).Error("Ошибка декодирования JSON")
@@ -45,19 +98,296 @@ func (h walletHandler) PutMoneyOnWallet(w http.ResponseWriter, r *http.Request)
 	}
 	defer r.Body.Close()
 
-	if err := h.walletService.Deposit(ctx, req.UserID, req.Amount); err != nil {
-		h.log.WithError(err).Error("Ошибка при передаче денег")
-		middleware.WriteError(w, http.StatusInternalServerError, err.Error())
+	if req.Source == "" {
+		req.Source = models2.BonusManual
+	}
+
+	grant, err := h.walletService.GrantBonus(ctx, req.UserID, req.Amount, req.Source, req.Comment)
+	if err != nil {
+		h.log.WithError(err).Error("Ошибка при начислении бонусов")
+		switch err {
+		case errors.ErrInvalidAmount, errors.ErrInvalidBonusSource:
+			middleware.WriteError(w, http.StatusBadRequest, err.Error())
+		case errors.ErrToDeposit:
+			middleware.WriteError(w, http.StatusNotFound, errors.ErrUserNotFound.Error())
+		default:
+			middleware.WriteError(w, http.StatusInternalServerError, err.Error())
+		}
 		return
 	}
 
-	transaction, err := h.walletService.CreateTransaction(ctx, req.UserID, req.Amount, string(models2.Add), -1)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(grant)
+}
+
+// ReverseTransaction сторнирует ошибочную транзакцию с указанием причины (право transaction.reverse)
+func (h walletHandler) ReverseTransaction(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	h.log.Info("Запрос на сторно транзакции")
+
+	adminID, ok := r.Context().Value("user_id").(int64)
+	if !ok {
+		h.log.WithError(errors.ErrWrongIDFromJWT).Error("Ошибка получения ID администратора")
+		middleware.WriteError(w, http.StatusUnauthorized, errors.ErrWrongIDFromJWT.Error())
+		return
+	}
+
+	transactionID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
 	if err != nil {
-		h.log.WithError(err).Error("Ошибка при создании модели транзакции")
-		middleware.WriteError(w, http.StatusInternalServerError, err.Error())
+		h.log.WithError(err).Error("Ошибка: некорректный ID транзакции")
+		middleware.WriteError(w, http.StatusBadRequest, errors.ErrInvalidTransactionID.Error())
 		return
 	}
 
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(transaction)
+	var req struct {
+		Reason string `json:"reason"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.log.WithError(err).Error("Ошибка декодирования JSON")
+		middleware.WriteError(w, http.StatusBadRequest, errors.ErrJSONRequest.Error())
+		return
+	}
+	defer r.Body.Close()
+
+	reversal, err := h.walletService.ReverseTransaction(ctx, transactionID, adminID, req.Reason)
+	if err != nil {
+		h.log.WithError(err).Error("Ошибка при сторно транзакции")
+		switch err {
+		case errors.ErrReversalReasonRequired, errors.ErrInsufficientFunds:
+			middleware.WriteError(w, http.StatusBadRequest, err.Error())
+		case errors.ErrTransactionNotFound:
+			middleware.WriteError(w, http.StatusNotFound, err.Error())
+		case errors.ErrTransactionAlreadyReversed, errors.ErrTransactionNotReversible, errors.ErrNoOpenShift:
+			middleware.WriteError(w, http.StatusConflict, err.Error())
+		default:
+			middleware.WriteError(w, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	h.log.WithFields(logrus.Fields{
+		"transaction_id": transactionID,
+		"reversal_id":    reversal.ID,
+		"admin_id":       adminID,
+	}).Info("Транзакция сторнирована")
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(reversal)
+}
+
+// GetTransactions возвращает историю транзакций текущего пользователя с фильтрами и постраничной разбивкой
+func (h walletHandler) GetTransactions(w http.ResponseWriter, r *http.Request) {
+	h.log.Info("Запрос на получение истории транзакций")
+
+	userID, ok := r.Context().Value("user_id").(int64)
+	if !ok {
+		h.log.Error("Ошибка: user_id не найден в контексте")
+		middleware.WriteError(w, http.StatusUnauthorized, errors.ErrWrongIDFromJWT.Error())
+		return
+	}
+
+	filter, err := parseTransactionFilter(r)
+	if err != nil {
+		h.log.WithError(err).Error("Ошибка разбора фильтра транзакций")
+		middleware.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	filter.UserID = &userID
+
+	h.writeTransactions(w, r, filter)
+}
+
+// AdminTransactions возвращает историю транзакций всех пользователей (право transaction.view.any).
+// Параметр user_id ограничивает выборку одним пользователем
+func (h walletHandler) AdminTransactions(w http.ResponseWriter, r *http.Request) {
+	h.log.Info("Запрос на получение истории транзакций всех пользователей")
+
+	filter, err := parseTransactionFilter(r)
+	if err != nil {
+		h.log.WithError(err).Error("Ошибка разбора фильтра транзакций")
+		middleware.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if value := r.URL.Query().Get("user_id"); value != "" {
+		userID, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			middleware.WriteError(w, http.StatusBadRequest, errors.ErrInvalidTransactionFilter.Error())
+			return
+		}
+		filter.UserID = &userID
+	}
+
+	h.writeTransactions(w, r, filter)
+}
+
+// writeTransactions отдает страницу транзакций в JSON или, при format=csv, все подходящие транзакции файлом CSV
+func (h walletHandler) writeTransactions(w http.ResponseWriter, r *http.Request, filter models2.TransactionFilter) {
+	ctx := r.Context()
+
+	switch r.URL.Query().Get("format") {
+	case "", "json":
+	case "csv":
+		h.exportTransactions(w, r, filter)
+		return
+	default:
+		middleware.WriteError(w, http.StatusBadRequest, errors.ErrInvalidTransactionFilter.Error())
+		return
+	}
+
+	page, err := h.walletService.ListTransactions(ctx, filter)
+	if err != nil {
+		h.log.WithError(err).Error("Ошибка при получении истории транзакций")
+		switch err {
+		case errors.ErrInvalidTransactionFilter:
+			middleware.WriteError(w, http.StatusBadRequest, err.Error())
+		default:
+			middleware.WriteError(w, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(page)
+}
+
+var transactionCSVHeader = []string{"id", "created_at", "user_id", "type", "amount", "currency", "bonus_amount",
+	"discount", "balance", "payment_method", "tariff_id", "package_id", "admin_id", "shift_id", "reversal_of", "reversed_by", "reason"}
+
+// exportTransactions пишет CSV по мере чтения строк из базы. Заголовки ответа отправляются
+// с первой строкой, чтобы ошибку запроса еще можно было вернуть статусом
+func (h walletHandler) exportTransactions(w http.ResponseWriter, r *http.Request, filter models2.TransactionFilter) {
+	writer := csv.NewWriter(w)
+	started := false
+	start := func() error {
+		if started {
+			return nil
+		}
+		started = true
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		w.Header().Set("Content-Disposition", `attachment; filename="transactions.csv"`)
+		return writer.Write(transactionCSVHeader)
+	}
+
+	rows := 0
+	err := h.walletService.ExportTransactions(r.Context(), filter, func(t *models2.Transaction) error {
+		if err := start(); err != nil {
+			return errors.ErrExportTransactions
+		}
+		err := writer.Write([]string{
+			strconv.FormatInt(t.ID, 10),
+			t.CreatedAt.Format(time.RFC3339),
+			strconv.FormatInt(t.UserID, 10),
+			string(t.Type),
+			t.Amount.Major(),
+			t.Amount.Currency,
+			t.BonusAmount.Major(),
+			t.Discount.Major(),
+			string(t.Balance),
+			string(t.PaymentMethod),
+			strconv.FormatInt(t.TariffID, 10),
+			optionalID(t.PackageID),
+			optionalID(t.AdminID),
+			optionalID(t.ShiftID),
+			optionalID(t.ReversalOf),
+			optionalID(t.ReversedBy),
+			t.Reason,
+		})
+		if err != nil {
+			return errors.ErrExportTransactions
+		}
+		if rows++; rows%500 == 0 {
+			writer.Flush()
+		}
+		return nil
+	})
+	if err != nil && !started {
+		h.log.WithError(err).Error("Ошибка выгрузки транзакций")
+		switch err {
+		case errors.ErrInvalidTransactionFilter:
+			middleware.WriteError(w, http.StatusBadRequest, err.Error())
+		default:
+			middleware.WriteError(w, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+	if err != nil {
+		// Часть файла уже отправлена, статус изменить нельзя
+		h.log.WithError(err).Error("Выгрузка транзакций прервана")
+		return
+	}
+	start()
+	writer.Flush()
+}
+
+// parseTransactionFilter разбирает параметры запроса: type (через запятую), from и to
+// (RFC 3339 или дата; дата в to включается целиком), min_amount и max_amount в рублях, cursor и limit
+func parseTransactionFilter(r *http.Request) (models2.TransactionFilter, error) {
+	query := r.URL.Query()
+	var filter models2.TransactionFilter
+
+	if value := query.Get("type"); value != "" {
+		for _, typ := range strings.Split(value, ",") {
+			filter.Types = append(filter.Types, models2.TransactionType(strings.TrimSpace(typ)))
+		}
+	}
+	for _, param := range []struct {
+		name   string
+		target **time.Time
+		end    bool
+	}{{"from", &filter.From, false}, {"to", &filter.To, true}} {
+		value := query.Get(param.name)
+		if value == "" {
+			continue
+		}
+		at, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			day, err := time.Parse(time.DateOnly, value)
+			if err != nil {
+				return filter, errors.ErrInvalidTransactionFilter
+			}
+			if param.end {
+				day = day.AddDate(0, 0, 1)
+			}
+			at = day
+		}
+		*param.target = &at
+	}
+	for _, param := range []struct {
+		name   string
+		target **money.Money
+	}{{"min_amount", &filter.MinAmount}, {"max_amount", &filter.MaxAmount}} {
+		value := query.Get(param.name)
+		if value == "" {
+			continue
+		}
+		amount, err := money.Parse(value)
+		if err != nil {
+			return filter, errors.ErrInvalidTransactionFilter
+		}
+		*param.target = &amount
+	}
+	if value := query.Get("cursor"); value != "" {
+		cursor, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || cursor <= 0 {
+			return filter, errors.ErrInvalidTransactionFilter
+		}
+		filter.Cursor = cursor
+	}
+	if value := query.Get("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit <= 0 {
+			return filter, errors.ErrInvalidTransactionFilter
+		}
+		filter.Limit = limit
+	}
+	return filter, nil
+}
+
+func optionalID(id *int64) string {
+	if id == nil {
+		return ""
+	}
+	return strconv.FormatInt(*id, 10)
 }
@@ -0,0 +1,35 @@
+package mailer
+
+import (
+	"computer-club/pkg/errors"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+)
+
+// FileMailer сохраняет каждое письмо в отдельный .eml файл в каталоге dir. Такие файлы открываются
+// любым почтовым клиентом, что удобно при локальной разработке
+type FileMailer struct {
+	dir     string
+	from    string
+	counter atomic.Int64
+}
+
+func NewFileMailer(dir, from string) Mailer {
+	return &FileMailer{dir: dir, from: from}
+}
+
+func (m *FileMailer) Send(ctx context.Context, msg Message) error {
+	if err := os.MkdirAll(m.dir, 0o755); err != nil {
+		return errors.ErrSendMail
+	}
+	now := time.Now()
+	name := fmt.Sprintf("%s-%d.eml", now.Format("20060102-150405"), m.counter.Add(1))
+	if err := os.WriteFile(filepath.Join(m.dir, name), format(m.from, msg, now), 0o644); err != nil {
+		return errors.ErrSendMail
+	}
+	return nil
+}
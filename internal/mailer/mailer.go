@@ -0,0 +1,37 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"mime"
+	"strings"
+	"time"
+)
+
+// Message - письмо пользователю. Body - простой текст
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer - отправка писем. SMTPMailer используется в рабочем окружении, FileMailer и MemoryMailer -
+// для локального запуска и проверок, когда почтового сервера нет
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// format собирает письмо в формате RFC 5322 с заголовками для текста в UTF-8
+func format(from string, msg Message, at time.Time) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", from)
+	fmt.Fprintf(&b, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.BEncoding.Encode("UTF-8", msg.Subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", at.Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	b.WriteString("Content-Transfer-Encoding: 8bit\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(msg.Body, "\n", "\r\n"))
+	return []byte(b.String())
+}
@@ -0,0 +1,44 @@
+package mailer
+
+import (
+	"computer-club/pkg/errors"
+	"context"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"time"
+)
+
+// SMTPMailer отправляет письма через SMTP-сервер. Если задан логин, используется PLAIN-аутентификация,
+// net/smtp сам включает STARTTLS, когда сервер его поддерживает
+type SMTPMailer struct {
+	addr string
+	auth smtp.Auth
+	from string
+}
+
+func NewSMTPMailer(host, port, username, password, from string) Mailer {
+	var auth smtp.Auth
+	if username != "" {
+		auth = smtp.PlainAuth("", username, password, host)
+	}
+	return &SMTPMailer{addr: net.JoinHostPort(host, port), auth: auth, from: from}
+}
+
+// envelopeFrom - адрес отправителя для SMTP-конверта: без отображаемого имени
+func (m *SMTPMailer) envelopeFrom() string {
+	if addr, err := mail.ParseAddress(m.from); err == nil {
+		return addr.Address
+	}
+	return m.from
+}
+
+func (m *SMTPMailer) Send(ctx context.Context, msg Message) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := smtp.SendMail(m.addr, m.auth, m.envelopeFrom(), []string{msg.To}, format(m.from, msg, time.Now())); err != nil {
+		return errors.ErrSendMail
+	}
+	return nil
+}
@@ -1,55 +1,95 @@
 package middleware
 
 import (
+	"computer-club/internal/repository"
+	"computer-club/internal/repository/models"
 	"computer-club/pkg/errors"
 	"context"
 	"github.com/golang-jwt/jwt/v4"
 	"net/http"
 	"os"
 	"strings"
+	"time"
 )
 
 var jwtKey = []byte(os.Getenv("JWT_SECRET"))
 
-func AuthMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		tokenString := r.Header.Get("Authorization")
-		if tokenString == "" {
-			WriteError(w, http.StatusUnauthorized, errors.ErrMissingToken.Error())
-			return
-		}
+// AuthMiddleware проверяет access-токен и отклоняет токены из списка отозванных
+func AuthMiddleware(revocationRepo repository.RevocationRepository) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			tokenString := r.Header.Get("Authorization")
+			if tokenString == "" {
+				WriteError(w, http.StatusUnauthorized, errors.ErrMissingToken.Error())
+				return
+			}
 
-		// Убираем "Bearer " из токена
-		tokenString = strings.TrimPrefix(tokenString, "Bearer ")
+			// Убираем "Bearer " из токена
+			tokenString = strings.TrimPrefix(tokenString, "Bearer ")
 
-		claims := jwt.MapClaims{}
-		token, err := jwt.ParseWithClaims(tokenString, &claims, func(token *jwt.Token) (interface{}, error) {
-			return jwtKey, nil
-		})
+			claims := jwt.MapClaims{}
+			token, err := jwt.ParseWithClaims(tokenString, &claims, func(token *jwt.Token) (interface{}, error) {
+				if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+					return nil, errors.ErrWrongToken
+				}
+				return jwtKey, nil
+			})
+
+			if err != nil || !token.Valid {
+				WriteError(w, http.StatusUnauthorized, errors.ErrWrongToken.Error())
+				return
+			}
+
+			// Проверяем user_id
+			userID, ok := claims["user_id"].(float64)
+			if !ok {
+				WriteError(w, http.StatusUnauthorized, errors.ErrWrongIDFromJWT.Error())
+				return
+			}
+
+			// Проверяем role
+			role, ok := claims["role"].(string)
+			if !ok {
+				WriteError(w, http.StatusUnauthorized, errors.ErrWrongRoleFromJWT.Error())
+				return
+			}
+
+			// Токены без jti, iat и exp выпускались до появления отзыва и больше не принимаются
+			jti, _ := claims["jti"].(string)
+			issuedAt, iatOK := claims["iat"].(float64)
+			expiresAt, expOK := claims["exp"].(float64)
+			if jti == "" || !iatOK || !expOK {
+				WriteError(w, http.StatusUnauthorized, errors.ErrWrongToken.Error())
+				return
+			}
 
-		if err != nil || !token.Valid {
-			WriteError(w, http.StatusUnauthorized, errors.ErrWrongToken.Error())
-			return
-		}
-
-		// Проверяем user_id
-		userID, ok := claims["user_id"].(float64)
-		if !ok {
-			WriteError(w, http.StatusUnauthorized, errors.ErrWrongIDFromJWT.Error())
-			return
-		}
-
-		// Проверяем role
-		role, ok := claims["role"].(string)
-		if !ok {
-			WriteError(w, http.StatusUnauthorized, errors.ErrWrongRoleFromJWT.Error())
-			return
-		}
-
-		// Добавляем в контекст
-		ctx := context.WithValue(r.Context(), "user_id", int64(userID))
-		ctx = context.WithValue(ctx, "role", role)
-
-		next.ServeHTTP(w, r.WithContext(ctx))
-	})
+			// mfa нет в токенах, выпущенных до появления 2FA: такие токены считаются входом без второго фактора
+			twoFactor, _ := claims["mfa"].(bool)
+
+			access := &models.AccessClaims{
+				UserID:    int64(userID),
+				Role:      role,
+				JTI:       jti,
+				IssuedAt:  time.Unix(int64(issuedAt), 0),
+				ExpiresAt: time.Unix(int64(expiresAt), 0),
+				TwoFactor: twoFactor,
+			}
+			revoked, err := revocationRepo.IsRevoked(r.Context(), access.JTI, access.UserID, access.IssuedAt)
+			if err != nil {
+				WriteError(w, http.StatusInternalServerError, err.Error())
+				return
+			}
+			if revoked {
+				WriteError(w, http.StatusUnauthorized, errors.ErrTokenRevoked.Error())
+				return
+			}
+
+			// Добавляем в контекст
+			ctx := context.WithValue(r.Context(), "user_id", access.UserID)
+			ctx = context.WithValue(ctx, "role", role)
+			ctx = context.WithValue(ctx, "claims", access)
+
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
 }
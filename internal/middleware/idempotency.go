@@ -0,0 +1,146 @@
+package middleware
+
+import (
+	"bytes"
+	"computer-club/internal/repository"
+	"computer-club/internal/repository/models"
+	"computer-club/pkg/errors"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+)
+
+const (
+	IdempotencyHeader = "Idempotency-Key"
+	// idempotencyLockTTL - сколько держится блокировка, если обработчик не успел ее снять
+	idempotencyLockTTL = 30 * time.Second
+	maxIdempotencyKey  = 255
+)
+
+// responseRecorder пишет ответ клиенту и одновременно запоминает его
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *responseRecorder) Write(data []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	r.body.Write(data)
+	return r.ResponseWriter.Write(data)
+}
+
+// IdempotencyMiddleware повторяет сохраненный ответ на запрос с уже использованным заголовком
+// Idempotency-Key вместо повторного выполнения. Ключ действует в рамках пользователя, метода и пути.
+// Должен подключаться после AuthMiddleware
+func IdempotencyMiddleware(store repository.IdempotencyRepository, ttl time.Duration) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get(IdempotencyHeader)
+			if key == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if len(key) > maxIdempotencyKey {
+				WriteError(w, http.StatusBadRequest, errors.ErrInvalidIdempotencyKey.Error())
+				return
+			}
+
+			userID, ok := r.Context().Value("user_id").(int64)
+			if !ok {
+				WriteError(w, http.StatusUnauthorized, errors.ErrWrongIDFromJWT.Error())
+				return
+			}
+
+			body, err := io.ReadAll(r.Body)
+			if err != nil {
+				WriteError(w, http.StatusBadRequest, errors.ErrJSONRequest.Error())
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+			hash := sha256.Sum256(body)
+			requestHash := hex.EncodeToString(hash[:])
+
+			ctx := r.Context()
+			scopedKey := fmt.Sprintf("%d:%s:%s:%s", userID, r.Method, r.URL.Path, key)
+
+			if replayed := replayResponse(ctx, w, store, scopedKey, requestHash); replayed {
+				return
+			}
+
+			locked, err := store.Lock(ctx, scopedKey, idempotencyLockTTL)
+			if err != nil {
+				WriteError(w, http.StatusInternalServerError, err.Error())
+				return
+			}
+			if !locked {
+				WriteError(w, http.StatusConflict, errors.ErrIdempotencyInProgress.Error())
+				return
+			}
+			defer func() {
+				if err := store.Unlock(context.Background(), scopedKey); err != nil {
+					log.Printf("Не удалось снять блокировку ключа идемпотентности: %v", err)
+				}
+			}()
+
+			// Ответ мог быть сохранен, пока мы ждали блокировку
+			if replayed := replayResponse(ctx, w, store, scopedKey, requestHash); replayed {
+				return
+			}
+
+			recorder := &responseRecorder{ResponseWriter: w}
+			next.ServeHTTP(recorder, r)
+
+			// Ошибки сервера не сохраняем, чтобы повтор запроса мог выполниться успешно
+			if recorder.status == 0 || recorder.status >= http.StatusInternalServerError {
+				return
+			}
+			response := &models.IdempotentResponse{
+				RequestHash: requestHash,
+				StatusCode:  recorder.status,
+				ContentType: recorder.Header().Get("Content-Type"),
+				Body:        recorder.body.Bytes(),
+				CreatedAt:   time.Now(),
+			}
+			if err := store.SaveResponse(context.Background(), scopedKey, response, ttl); err != nil {
+				log.Printf("Не удалось сохранить ответ для ключа идемпотентности: %v", err)
+			}
+		})
+	}
+}
+
+// replayResponse отдает сохраненный ответ, если он есть. Возвращает true, если ответ уже записан
+func replayResponse(ctx context.Context, w http.ResponseWriter, store repository.IdempotencyRepository, key, requestHash string) bool {
+	saved, err := store.GetResponse(ctx, key)
+	if err != nil {
+		WriteError(w, http.StatusInternalServerError, err.Error())
+		return true
+	}
+	if saved == nil {
+		return false
+	}
+	if saved.RequestHash != requestHash {
+		WriteError(w, http.StatusUnprocessableEntity, errors.ErrIdempotencyKeyReused.Error())
+		return true
+	}
+
+	if saved.ContentType != "" {
+		w.Header().Set("Content-Type", saved.ContentType)
+	}
+	w.Header().Set("Idempotent-Replayed", "true")
+	w.WriteHeader(saved.StatusCode)
+	w.Write(saved.Body)
+	return true
+}
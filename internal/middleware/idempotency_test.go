@@ -0,0 +1,178 @@
+package middleware
+
+import (
+	"computer-club/internal/repository/models"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memoryIdempotencyStore - хранилище ответов и блокировок в памяти вместо Redis
+type memoryIdempotencyStore struct {
+	mu        sync.Mutex
+	responses map[string]*models.IdempotentResponse
+	locks     map[string]bool
+}
+
+func newMemoryIdempotencyStore() *memoryIdempotencyStore {
+	return &memoryIdempotencyStore{responses: map[string]*models.IdempotentResponse{}, locks: map[string]bool{}}
+}
+
+func (s *memoryIdempotencyStore) GetResponse(ctx context.Context, key string) (*models.IdempotentResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.responses[key], nil
+}
+
+func (s *memoryIdempotencyStore) SaveResponse(ctx context.Context, key string, response *models.IdempotentResponse, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.responses[key] = response
+	return nil
+}
+
+func (s *memoryIdempotencyStore) Lock(ctx context.Context, key string, ttl time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.locks[key] {
+		return false, nil
+	}
+	s.locks[key] = true
+	return true, nil
+}
+
+func (s *memoryIdempotencyStore) Unlock(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.locks, key)
+	return nil
+}
+
+// countingHandler считает вызовы и отвечает заданным статусом с номером вызова в теле
+type countingHandler struct {
+	calls  int
+	status int
+}
+
+func (h *countingHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.calls++
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(h.status)
+	w.Write([]byte(`{"call":` + strconv.Itoa(h.calls) + `}`))
+}
+
+func idempotentRequest(userID int64, key, body string) *http.Request {
+	r := httptest.NewRequest(http.MethodPut, "/pay", strings.NewReader(body))
+	if key != "" {
+		r.Header.Set(IdempotencyHeader, key)
+	}
+	return r.WithContext(context.WithValue(r.Context(), "user_id", userID))
+}
+
+func serve(handler http.Handler, r *http.Request) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	return w
+}
+
+func TestIdempotencyReplaysSavedResponse(t *testing.T) {
+	next := &countingHandler{status: http.StatusCreated}
+	handler := IdempotencyMiddleware(newMemoryIdempotencyStore(), time.Hour)(next)
+
+	first := serve(handler, idempotentRequest(1, "key-1", `{"amount":100}`))
+	second := serve(handler, idempotentRequest(1, "key-1", `{"amount":100}`))
+
+	if next.calls != 1 {
+		t.Fatalf("обработчик вызван %d раз, want 1", next.calls)
+	}
+	if second.Code != http.StatusCreated || second.Body.String() != first.Body.String() {
+		t.Fatalf("повтор = %d %q, want %d %q", second.Code, second.Body.String(), first.Code, first.Body.String())
+	}
+	if second.Header().Get("Idempotent-Replayed") != "true" {
+		t.Fatal("повторный ответ не помечен Idempotent-Replayed")
+	}
+	if second.Header().Get("Content-Type") != "application/json" {
+		t.Fatalf("Content-Type = %q, want application/json", second.Header().Get("Content-Type"))
+	}
+}
+
+func TestIdempotencyRejectsKeyReuseWithDifferentBody(t *testing.T) {
+	next := &countingHandler{status: http.StatusOK}
+	handler := IdempotencyMiddleware(newMemoryIdempotencyStore(), time.Hour)(next)
+
+	serve(handler, idempotentRequest(1, "key-1", `{"amount":100}`))
+	w := serve(handler, idempotentRequest(1, "key-1", `{"amount":200}`))
+
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusUnprocessableEntity)
+	}
+	if next.calls != 1 {
+		t.Fatalf("обработчик вызван %d раз, want 1", next.calls)
+	}
+}
+
+func TestIdempotencyKeyIsScopedToUser(t *testing.T) {
+	next := &countingHandler{status: http.StatusOK}
+	handler := IdempotencyMiddleware(newMemoryIdempotencyStore(), time.Hour)(next)
+
+	serve(handler, idempotentRequest(1, "key-1", `{}`))
+	w := serve(handler, idempotentRequest(2, "key-1", `{}`))
+
+	if next.calls != 2 || w.Header().Get("Idempotent-Replayed") != "" {
+		t.Fatalf("ключ другого пользователя не должен повторять чужой ответ: calls = %d", next.calls)
+	}
+}
+
+func TestIdempotencyDoesNotSaveServerErrors(t *testing.T) {
+	next := &countingHandler{status: http.StatusInternalServerError}
+	handler := IdempotencyMiddleware(newMemoryIdempotencyStore(), time.Hour)(next)
+
+	serve(handler, idempotentRequest(1, "key-1", `{}`))
+	next.status = http.StatusOK
+	w := serve(handler, idempotentRequest(1, "key-1", `{}`))
+
+	if next.calls != 2 || w.Code != http.StatusOK {
+		t.Fatalf("после ошибки сервера запрос должен выполниться заново: calls = %d, status = %d", next.calls, w.Code)
+	}
+}
+
+func TestIdempotencyConflictWhileInProgress(t *testing.T) {
+	store := newMemoryIdempotencyStore()
+	next := &countingHandler{status: http.StatusOK}
+	handler := IdempotencyMiddleware(store, time.Hour)(next)
+
+	// Первый запрос с тем же ключом еще выполняется и держит блокировку
+	store.Lock(context.Background(), "1:PUT:/pay:key-1", time.Minute)
+	w := serve(handler, idempotentRequest(1, "key-1", `{}`))
+
+	if w.Code != http.StatusConflict || next.calls != 0 {
+		t.Fatalf("status = %d, calls = %d, want %d и 0", w.Code, next.calls, http.StatusConflict)
+	}
+}
+
+func TestIdempotencyWithoutKeyPassesThrough(t *testing.T) {
+	next := &countingHandler{status: http.StatusOK}
+	handler := IdempotencyMiddleware(newMemoryIdempotencyStore(), time.Hour)(next)
+
+	serve(handler, idempotentRequest(1, "", `{}`))
+	serve(handler, idempotentRequest(1, "", `{}`))
+
+	if next.calls != 2 {
+		t.Fatalf("обработчик вызван %d раз, want 2", next.calls)
+	}
+}
+
+func TestIdempotencyRejectsLongKey(t *testing.T) {
+	next := &countingHandler{status: http.StatusOK}
+	handler := IdempotencyMiddleware(newMemoryIdempotencyStore(), time.Hour)(next)
+
+	w := serve(handler, idempotentRequest(1, strings.Repeat("k", maxIdempotencyKey+1), `{}`))
+	if w.Code != http.StatusBadRequest || next.calls != 0 {
+		t.Fatalf("status = %d, calls = %d, want %d и 0", w.Code, next.calls, http.StatusBadRequest)
+	}
+}
@@ -0,0 +1,16 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+)
+
+// ClientIP возвращает IP клиента из адреса соединения. Заголовкам X-Forwarded-For не доверяем:
+// их может подставить сам клиент, чтобы обойти ограничения по IP
+func ClientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,44 @@
+package middleware
+
+import (
+	"computer-club/internal/repository/models"
+	"computer-club/pkg/errors"
+	"net/http"
+)
+
+// RequirePermission пропускает запрос, только если у роли из токена есть право permission.
+// Для прав на движение денег вход должен быть подтвержден кодом 2FA.
+// Должен подключаться после AuthMiddleware
+func RequirePermission(permission models.Permission) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !roleCan(r, permission) {
+				WriteError(w, http.StatusForbidden, errors.ErrForbidden.Error())
+				return
+			}
+			if !twoFactorSatisfied(r, permission) {
+				WriteError(w, http.StatusForbidden, errors.ErrTwoFactorRequired.Error())
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// HasPermission проверяет право в обработчиках, где доступ зависит и от владельца ресурса
+func HasPermission(r *http.Request, permission models.Permission) bool {
+	return roleCan(r, permission) && twoFactorSatisfied(r, permission)
+}
+
+func roleCan(r *http.Request, permission models.Permission) bool {
+	role, ok := r.Context().Value("role").(string)
+	return ok && models.UserRole(role).Can(permission)
+}
+
+func twoFactorSatisfied(r *http.Request, permission models.Permission) bool {
+	if !permission.RequiresTwoFactor() {
+		return true
+	}
+	claims, ok := r.Context().Value("claims").(*models.AccessClaims)
+	return ok && claims.TwoFactor
+}
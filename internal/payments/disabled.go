@@ -0,0 +1,32 @@
+package payments
+
+import (
+	"computer-club/pkg/errors"
+	"computer-club/pkg/money"
+	"context"
+	"net/http"
+)
+
+// DisabledProvider подключается, когда реального провайдера нет: онлайн-оплата отключена,
+// а уведомления на /payments/webhook отклоняются, чтобы их нельзя было подделать
+type DisabledProvider struct{}
+
+func NewDisabledProvider() Provider {
+	return DisabledProvider{}
+}
+
+func (DisabledProvider) Name() string {
+	return "disabled"
+}
+
+func (DisabledProvider) CreateIntent(ctx context.Context, reference int64, amount money.Money, description string) (*Intent, error) {
+	return nil, errors.ErrPaymentsDisabled
+}
+
+func (DisabledProvider) ParseWebhook(body []byte, header http.Header) (*WebhookEvent, error) {
+	return nil, errors.ErrPaymentsDisabled
+}
+
+func (DisabledProvider) Refund(ctx context.Context, providerID string, amount money.Money) error {
+	return errors.ErrPaymentsDisabled
+}
@@ -0,0 +1,122 @@
+package payments
+
+import (
+	"bytes"
+	"computer-club/pkg/errors"
+	"computer-club/pkg/money"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// SignatureHeader - заголовок с подписью уведомлений тестового провайдера
+const SignatureHeader = "X-Fakepay-Signature"
+
+// FakeIntentRequest и FakeIntentResponse - формат API тестового провайдера (cmd/fakepay)
+type FakeIntentRequest struct {
+	Reference   int64       `json:"reference"`
+	Amount      money.Money `json:"amount"`
+	Description string      `json:"description"`
+	CallbackURL string      `json:"callback_url"`
+}
+
+type FakeIntentResponse struct {
+	ID          string `json:"id"`
+	RedirectURL string `json:"redirect_url"`
+}
+
+// FakeWebhook - тело уведомления тестового провайдера
+type FakeWebhook struct {
+	IntentID  string      `json:"intent_id"`
+	Reference int64       `json:"reference"`
+	Status    EventStatus `json:"status"`
+	Amount    money.Money `json:"amount"`
+}
+
+// FakeProvider работает с локальным тестовым сервером cmd/fakepay,
+// чтобы весь цикл оплаты можно было проверить без реального провайдера
+type FakeProvider struct {
+	baseURL     string
+	secret      string
+	callbackURL string
+	client      *http.Client
+}
+
+func NewFakeProvider(baseURL, secret, callbackURL string) Provider {
+	return &FakeProvider{
+		baseURL:     baseURL,
+		secret:      secret,
+		callbackURL: callbackURL,
+		client:      &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (p *FakeProvider) Name() string {
+	return "fake"
+}
+
+func (p *FakeProvider) CreateIntent(ctx context.Context, reference int64, amount money.Money, description string) (*Intent, error) {
+	var resp FakeIntentResponse
+	err := p.post(ctx, "/intents", FakeIntentRequest{
+		Reference:   reference,
+		Amount:      amount,
+		Description: description,
+		CallbackURL: p.callbackURL,
+	}, &resp)
+	if err != nil {
+		return nil, err
+	}
+	return &Intent{ProviderID: resp.ID, RedirectURL: resp.RedirectURL}, nil
+}
+
+func (p *FakeProvider) ParseWebhook(body []byte, header http.Header) (*WebhookEvent, error) {
+	if !Verify(p.secret, body, header.Get(SignatureHeader)) {
+		return nil, errors.ErrInvalidSignature
+	}
+	var webhook FakeWebhook
+	if err := json.Unmarshal(body, &webhook); err != nil {
+		return nil, errors.ErrJSONRequest
+	}
+	return &WebhookEvent{
+		ProviderID: webhook.IntentID,
+		Reference:  webhook.Reference,
+		Status:     webhook.Status,
+		Amount:     webhook.Amount,
+	}, nil
+}
+
+func (p *FakeProvider) Refund(ctx context.Context, providerID string, amount money.Money) error {
+	return p.post(ctx, fmt.Sprintf("/intents/%s/refund", providerID), struct {
+		Amount money.Money `json:"amount"`
+	}{Amount: amount}, nil)
+}
+
+func (p *FakeProvider) post(ctx context.Context, path string, payload interface{}, out interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return errors.ErrPaymentProvider
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.baseURL+path, bytes.NewReader(body))
+	if err != nil {
+		return errors.ErrPaymentProvider
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(SignatureHeader, Sign(p.secret, body))
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return errors.ErrPaymentProvider
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= http.StatusBadRequest {
+		return errors.ErrPaymentProvider
+	}
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			return errors.ErrPaymentProvider
+		}
+	}
+	return nil
+}
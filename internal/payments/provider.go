@@ -0,0 +1,57 @@
+package payments
+
+import (
+	"computer-club/pkg/money"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+)
+
+type EventStatus string
+
+const (
+	EventSucceeded EventStatus = "succeeded"
+	EventFailed    EventStatus = "failed"
+)
+
+// Intent - платеж, созданный у провайдера. Клиента нужно отправить на RedirectURL
+type Intent struct {
+	ProviderID  string
+	RedirectURL string
+}
+
+// WebhookEvent - проверенное уведомление провайдера о результате платежа
+type WebhookEvent struct {
+	ProviderID string
+	Reference  int64
+	Status     EventStatus
+	Amount     money.Money
+}
+
+// Provider - платежный провайдер. Reference - ID платежа в клубе, провайдер возвращает его в уведомлении
+type Provider interface {
+	Name() string
+	CreateIntent(ctx context.Context, reference int64, amount money.Money, description string) (*Intent, error)
+	ParseWebhook(body []byte, header http.Header) (*WebhookEvent, error)
+	Refund(ctx context.Context, providerID string, amount money.Money) error
+}
+
+// Sign возвращает HMAC-SHA256 подпись тела запроса в hex
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify сравнивает подпись за постоянное время
+func Verify(secret string, body []byte, signature string) bool {
+	expected, err := hex.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hmac.Equal(mac.Sum(nil), expected)
+}
@@ -0,0 +1,91 @@
+package pricing
+
+import (
+	"computer-club/internal/repository"
+	"computer-club/internal/repository/models"
+	"computer-club/pkg/money"
+	"context"
+	"time"
+)
+
+// Input - данные, от которых зависит цена сессии
+type Input struct {
+	Tariff    *models.Tariff
+	Computer  *models.Computer
+	Occupancy float64
+	Time      time.Time
+	Tier      models.LoyaltyTier
+}
+
+// Rule - правило ценообразования. Возвращает множитель к цене и признак того, что правило сработало
+type Rule interface {
+	Name() string
+	Multiplier(input Input) (float64, bool)
+}
+
+// Result - итоговая цена с примененными поправками
+type Result struct {
+	BasePrice   money.Money
+	Price       money.Money
+	Adjustments []models.PriceAdjustment
+}
+
+// TierSource возвращает уровень лояльности пользователя
+type TierSource interface {
+	GetTier(ctx context.Context, userID int64) (models.LoyaltyTier, error)
+}
+
+// Engine считает цену сессии по базовому тарифу
+type Engine interface {
+	Calculate(ctx context.Context, userID int64, tariff *models.Tariff, computer *models.Computer, now time.Time) (*Result, error)
+}
+
+type RuleEngine struct {
+	computerRepo repository.ComputerRepository
+	tierSource   TierSource
+	rules        []Rule
+}
+
+func NewRuleEngine(computerRepo repository.ComputerRepository, tierSource TierSource, rules ...Rule) Engine {
+	return &RuleEngine{computerRepo: computerRepo, tierSource: tierSource, rules: rules}
+}
+
+// Calculate последовательно применяет правила к базовой цене тарифа
+func (e *RuleEngine) Calculate(ctx context.Context, userID int64, tariff *models.Tariff, computer *models.Computer, now time.Time) (*Result, error) {
+	occupancy, err := e.computerRepo.GetOccupancy(ctx)
+	if err != nil {
+		return nil, err
+	}
+	tier, err := e.tierSource.GetTier(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	input := Input{
+		Tariff:    tariff,
+		Computer:  computer,
+		Occupancy: occupancy,
+		Time:      now,
+		Tier:      tier,
+	}
+
+	result := &Result{
+		BasePrice:   tariff.Price,
+		Adjustments: []models.PriceAdjustment{},
+	}
+	factor := 1.0
+	for _, rule := range e.rules {
+		multiplier, ok := rule.Multiplier(input)
+		if !ok || multiplier == 1 {
+			continue
+		}
+		factor *= multiplier
+		result.Adjustments = append(result.Adjustments, models.PriceAdjustment{
+			Rule:       rule.Name(),
+			Multiplier: multiplier,
+		})
+	}
+	// Округляем один раз, чтобы не накапливать погрешность между правилами
+	result.Price = tariff.Price.MulFloat(factor)
+	return result, nil
+}
@@ -0,0 +1,89 @@
+package pricing
+
+import (
+	"computer-club/internal/repository/models"
+	"time"
+)
+
+// OccupancyRule снижает цену при низкой загрузке клуба и повышает при высокой
+type OccupancyRule struct {
+	Low            float64
+	LowMultiplier  float64
+	High           float64
+	HighMultiplier float64
+}
+
+func (r OccupancyRule) Name() string {
+	return "occupancy"
+}
+
+func (r OccupancyRule) Multiplier(input Input) (float64, bool) {
+	switch {
+	case input.Occupancy < r.Low:
+		return r.LowMultiplier, true
+	case input.Occupancy >= r.High:
+		return r.HighMultiplier, true
+	}
+	return 1, false
+}
+
+// TimeOfDayRule меняет цену в заданный интервал часов [FromHour, ToHour)
+type TimeOfDayRule struct {
+	RuleName     string
+	FromHour     int
+	ToHour       int
+	WeekdaysOnly bool
+	Factor       float64
+}
+
+func (r TimeOfDayRule) Name() string {
+	return r.RuleName
+}
+
+func (r TimeOfDayRule) Multiplier(input Input) (float64, bool) {
+	if r.WeekdaysOnly {
+		day := input.Time.Weekday()
+		if day == time.Saturday || day == time.Sunday {
+			return 1, false
+		}
+	}
+	hour := input.Time.Hour()
+	if hour >= r.FromHour && hour < r.ToHour {
+		return r.Factor, true
+	}
+	return 1, false
+}
+
+// ZoneRule задает наценку или скидку для зоны компьютера
+type ZoneRule struct {
+	Multipliers map[string]float64
+}
+
+func (r ZoneRule) Name() string {
+	return "zone"
+}
+
+func (r ZoneRule) Multiplier(input Input) (float64, bool) {
+	if input.Computer == nil {
+		return 1, false
+	}
+	multiplier, ok := r.Multipliers[input.Computer.Zone]
+	return multiplier, ok
+}
+
+// TierRule дает скидку по уровню лояльности. Discounts - доля скидки для каждого уровня
+type TierRule struct {
+	Discounts map[models.LoyaltyTier]float64
+}
+
+func (r TierRule) Name() string {
+	return "loyalty_tier"
+}
+
+func (r TierRule) Multiplier(input Input) (float64, bool) {
+	discount, ok := r.Discounts[input.Tier]
+	if !ok || discount <= 0 {
+		return 1, false
+	}
+	return 1 - discount, true
+}
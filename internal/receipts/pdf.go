@@ -0,0 +1,85 @@
+package receipts
+
+import (
+	"bytes"
+	"computer-club/internal/repository/models"
+	"fmt"
+	"strings"
+)
+
+// Встроенные шрифты PDF не содержат кириллицы, а подключать внешний шрифт ради чека
+// не хочется, поэтому текст PDF-чека транслитерируется
+var translit = map[rune]string{
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ё': "e", 'ж': "zh",
+	'з': "z", 'и': "i", 'й': "y", 'к': "k", 'л': "l", 'м': "m", 'н': "n", 'о': "o",
+	'п': "p", 'р': "r", 'с': "s", 'т': "t", 'у': "u", 'ф': "f", 'х': "kh", 'ц': "ts",
+	'ч': "ch", 'ш': "sh", 'щ': "shch", 'ъ': "", 'ы': "y", 'ь': "", 'э': "e", 'ю': "yu",
+	'я': "ya", '№': "No",
+}
+
+// RenderPDF возвращает чек одностраничным PDF моноширинным шрифтом Courier
+func RenderPDF(receipt *models.Receipt, clubName string) []byte {
+	lines := layout(receipt, clubName, transliterate)
+
+	const (
+		fontSize = 10
+		leading  = 14
+		margin   = 20
+	)
+	pageWidth := margin*2 + Width*fontSize*6/10
+	pageHeight := margin*2 + len(lines)*leading
+
+	var content bytes.Buffer
+	fmt.Fprintf(&content, "BT\n/F1 %d Tf\n%d TL\n%d %d Td\n", fontSize, leading, margin, pageHeight-margin)
+	for _, line := range lines {
+		fmt.Fprintf(&content, "(%s) '\n", escape(line))
+	}
+	content.WriteString("ET\n")
+
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %d %d] /Resources << /Font << /F1 4 0 R >> >> /Contents 5 0 R >>",
+			pageWidth, pageHeight),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Courier /Encoding /WinAnsiEncoding >>",
+		fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", content.Len(), content.String()),
+	}
+
+	var out bytes.Buffer
+	out.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, object := range objects {
+		offsets[i] = out.Len()
+		fmt.Fprintf(&out, "%d 0 obj\n%s\nendobj\n", i+1, object)
+	}
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+	return out.Bytes()
+}
+
+func transliterate(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		lower := []rune(strings.ToLower(string(r)))[0]
+		latin, ok := translit[lower]
+		switch {
+		case !ok && r < 128:
+			b.WriteRune(r)
+		case !ok:
+			b.WriteByte('?')
+		case lower != r && latin != "":
+			b.WriteString(strings.ToUpper(latin[:1]) + latin[1:])
+		default:
+			b.WriteString(latin)
+		}
+	}
+	return b.String()
+}
+
+func escape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "(", `\(`, ")", `\)`).Replace(s)
+}
@@ -0,0 +1,43 @@
+package receipts
+
+import (
+	"computer-club/internal/repository/models"
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// FiscalData - реквизиты, которые фискальный регистратор присваивает чеку
+type FiscalData struct {
+	Sign         string
+	Number       string
+	RegisteredAt time.Time
+}
+
+// FiscalRegistrar - фискальный регистратор (касса или ОФД). Реализация для конкретного
+// оборудования подключается в контейнере зависимостей вместо заглушки
+type FiscalRegistrar interface {
+	Register(ctx context.Context, receipt *models.Receipt) (*FiscalData, error)
+}
+
+// StubRegistrar - заглушка регистратора: ничего не отправляет, а выдает порядковый номер
+// документа и фискальный признак, вычисленный из номера и суммы чека
+type StubRegistrar struct {
+	counter atomic.Int64
+}
+
+func NewStubRegistrar() FiscalRegistrar {
+	return &StubRegistrar{}
+}
+
+func (s *StubRegistrar) Register(ctx context.Context, receipt *models.Receipt) (*FiscalData, error) {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s:%d:%s", receipt.Number, receipt.Total.Amount, receipt.Total.Currency)))
+	return &FiscalData{
+		Sign:         fmt.Sprintf("%010d", binary.BigEndian.Uint32(sum[:4])),
+		Number:       fmt.Sprintf("%d", s.counter.Add(1)),
+		RegisteredAt: time.Now(),
+	}, nil
+}
@@ -0,0 +1,78 @@
+package receipts
+
+import (
+	"computer-club/internal/repository/models"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// Width - ширина чека в символах, как у ленты кассового аппарата
+const Width = 40
+
+var kindTitles = map[models.ReceiptKind]string{
+	models.ReceiptDeposit: "Приход: пополнение кошелька",
+	models.ReceiptSale:    "Приход: оплата услуг",
+	models.ReceiptRefund:  "Возврат прихода",
+}
+
+// Lines возвращает строки чека
+func Lines(receipt *models.Receipt, clubName string) []string {
+	return layout(receipt, clubName, func(s string) string { return s })
+}
+
+// layout раскладывает чек по строкам. conv применяется к тексту до выравнивания,
+// чтобы колонки не съезжали после транслитерации в PDF
+func layout(receipt *models.Receipt, clubName string, conv func(string) string) []string {
+	center := func(s string) string { return centered(conv(s)) }
+	pair := func(label, value string) string { return paired(conv(label), conv(value)) }
+
+	separator := strings.Repeat("-", Width)
+	lines := []string{
+		center(clubName),
+		center("Кассовый чек № " + receipt.Number),
+		center(kindTitles[receipt.Kind]),
+		receipt.CreatedAt.Format("02.01.2006 15:04"),
+		separator,
+	}
+	for _, item := range receipt.Items {
+		lines = append(lines, conv(item.Name))
+		lines = append(lines, pair(fmt.Sprintf("  %d x %s", item.Quantity, item.Price.Major()), item.Total.Major()))
+	}
+	lines = append(lines, separator, pair("ИТОГО", receipt.Total.String()))
+	if receipt.PaidByBonus.IsPositive() {
+		lines = append(lines, pair("  в т.ч. бонусами", receipt.PaidByBonus.Major()))
+	}
+	if receipt.PaymentMethod != "" {
+		lines = append(lines, pair("Способ оплаты", string(receipt.PaymentMethod)))
+	}
+	lines = append(lines, separator)
+	if receipt.FiscalStatus == models.FiscalRegistered {
+		lines = append(lines, pair("ФД", receipt.FiscalNumber), pair("ФП", receipt.FiscalSign))
+	} else {
+		lines = append(lines, center("Фискализация: "+string(receipt.FiscalStatus)))
+	}
+	return lines
+}
+
+// RenderText возвращает чек в виде простого текста
+func RenderText(receipt *models.Receipt, clubName string) []byte {
+	return []byte(strings.Join(Lines(receipt, clubName), "\n") + "\n")
+}
+
+func centered(s string) string {
+	n := utf8.RuneCountInString(s)
+	if n >= Width {
+		return s
+	}
+	return strings.Repeat(" ", (Width-n)/2) + s
+}
+
+// paired выравнивает подпись по левому краю, а значение - по правому
+func paired(label, value string) string {
+	gap := Width - utf8.RuneCountInString(label) - utf8.RuneCountInString(value)
+	if gap < 1 {
+		gap = 1
+	}
+	return label + strings.Repeat(" ", gap) + value
+}
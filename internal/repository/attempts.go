@@ -0,0 +1,96 @@
+package repository
+
+import (
+	"computer-club/pkg/errors"
+	"context"
+	"github.com/redis/go-redis/v9"
+	"time"
+)
+
+// AttemptRepository считает неудачные попытки по ключу в окне от первой попытки,
+// чтобы ограничивать перебор кодов и паролей. Block запрещает попытки по ключу на время ttl
+type AttemptRepository interface {
+	Count(ctx context.Context, key string) (int64, error)
+	Add(ctx context.Context, key string, window time.Duration) (int64, error)
+	Reset(ctx context.Context, key string) error
+	Block(ctx context.Context, key string, ttl time.Duration) error
+	BlockedFor(ctx context.Context, key string) (time.Duration, error)
+}
+
+type RedisAttemptRepo struct {
+	redis *redis.Client
+}
+
+func NewRedisAttemptRepo(redis *redis.Client) AttemptRepository {
+	return &RedisAttemptRepo{redis: redis}
+}
+
+func (r *RedisAttemptRepo) Count(ctx context.Context, key string) (int64, error) {
+	count, err := r.redis.Get(ctx, getAttemptKey(key)).Int64()
+	if err == redis.Nil {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, errors.ErrAttemptStore
+	}
+	return count, nil
+}
+
+// Add увеличивает счетчик. Окно отсчитывается от первой неудачной попытки
+func (r *RedisAttemptRepo) Add(ctx context.Context, key string, window time.Duration) (int64, error) {
+	redisKey := getAttemptKey(key)
+	count, err := r.redis.Incr(ctx, redisKey).Result()
+	if err != nil {
+		return 0, errors.ErrAttemptStore
+	}
+	if count == 1 {
+		if err := r.redis.Expire(ctx, redisKey, window).Err(); err != nil {
+			return 0, errors.ErrAttemptStore
+		}
+	}
+	return count, nil
+}
+
+// Reset обнуляет счетчик и снимает блокировку по ключу
+func (r *RedisAttemptRepo) Reset(ctx context.Context, key string) error {
+	if err := r.redis.Del(ctx, getAttemptKey(key), getAttemptBlockKey(key)).Err(); err != nil {
+		return errors.ErrAttemptStore
+	}
+	return nil
+}
+
+// Block запрещает попытки по ключу на ttl. Более длинная действующая блокировка не сокращается
+func (r *RedisAttemptRepo) Block(ctx context.Context, key string, ttl time.Duration) error {
+	remaining, err := r.BlockedFor(ctx, key)
+	if err != nil {
+		return err
+	}
+	if remaining >= ttl {
+		return nil
+	}
+	if err := r.redis.Set(ctx, getAttemptBlockKey(key), 1, ttl).Err(); err != nil {
+		return errors.ErrAttemptStore
+	}
+	return nil
+}
+
+// BlockedFor возвращает, сколько еще действует блокировка, или 0, если ее нет
+func (r *RedisAttemptRepo) BlockedFor(ctx context.Context, key string) (time.Duration, error) {
+	ttl, err := r.redis.PTTL(ctx, getAttemptBlockKey(key)).Result()
+	if err != nil {
+		return 0, errors.ErrAttemptStore
+	}
+	// Для отсутствующего ключа Redis возвращает отрицательное значение
+	if ttl < 0 {
+		return 0, nil
+	}
+	return ttl, nil
+}
+
+func getAttemptKey(key string) string {
+	return "attempts:" + key
+}
+
+func getAttemptBlockKey(key string) string {
+	return "attempts:block:" + key
+}
@@ -0,0 +1,150 @@
+package repository
+
+import (
+	"computer-club/internal/repository/models"
+	"computer-club/pkg/errors"
+	"computer-club/pkg/money"
+	"context"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+type BonusRepository interface {
+	CreateGrant(tx *gorm.DB, grant *models.BonusGrant) error
+	GetActiveGrants(ctx context.Context, userID int64) ([]models.BonusGrant, error)
+	ConsumeGrants(tx *gorm.DB, userID int64, amount money.Money) error
+	GetExpiredGrants(ctx context.Context, now time.Time) ([]models.BonusGrant, error)
+	ExpireGrant(tx *gorm.DB, grantID int64) (*models.BonusGrant, error)
+	RevokeTransactionGrants(tx *gorm.DB, transactionID int64) ([]models.BonusGrant, error)
+}
+
+type PostgresBonusRepo struct {
+	db *gorm.DB
+}
+
+func NewPostgresBonusRepo(db *gorm.DB) BonusRepository {
+	return &PostgresBonusRepo{db: db}
+}
+
+func (r *PostgresBonusRepo) CreateGrant(tx *gorm.DB, grant *models.BonusGrant) error {
+	if tx == nil {
+		tx = r.db
+	}
+	if err := tx.Create(grant).Error; err != nil {
+		return errors.ErrCreateBonusGrant
+	}
+	return nil
+}
+
+func (r *PostgresBonusRepo) GetActiveGrants(ctx context.Context, userID int64) ([]models.BonusGrant, error) {
+	var grants []models.BonusGrant
+	err := r.db.WithContext(ctx).
+		Where("user_id = ? AND status = ?", userID, models.BonusActive).
+		Order("expires_at").
+		Find(&grants).Error
+	if err != nil {
+		return nil, errors.ErrFindBonusGrant
+	}
+	return grants, nil
+}
+
+// ConsumeGrants списывает сумму с активных начислений, начиная с ближайших к истечению
+func (r *PostgresBonusRepo) ConsumeGrants(tx *gorm.DB, userID int64, amount money.Money) error {
+	if tx == nil {
+		tx = r.db
+	}
+	var grants []models.BonusGrant
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("user_id = ? AND status = ?", userID, models.BonusActive).
+		Order("expires_at").
+		Find(&grants).Error
+	if err != nil {
+		return errors.ErrFindBonusGrant
+	}
+
+	left := amount
+	for _, grant := range grants {
+		if !left.IsPositive() {
+			break
+		}
+		take := grant.Remaining.Min(left)
+		remaining := grant.Remaining.Sub(take)
+		status := models.BonusActive
+		if remaining.IsZero() {
+			status = models.BonusSpent
+		}
+		err := tx.Model(&models.BonusGrant{}).
+			Where("id = ?", grant.ID).
+			Updates(map[string]interface{}{"remaining_amount": remaining.Amount, "status": status}).Error
+		if err != nil {
+			return errors.ErrUpdateBonusGrant
+		}
+		left = left.Sub(take)
+	}
+	if left.IsPositive() {
+		return errors.ErrInsufficientFunds
+	}
+	return nil
+}
+
+func (r *PostgresBonusRepo) GetExpiredGrants(ctx context.Context, now time.Time) ([]models.BonusGrant, error) {
+	var grants []models.BonusGrant
+	err := r.db.WithContext(ctx).
+		Where("status = ? AND expires_at <= ?", models.BonusActive, now).
+		Find(&grants).Error
+	if err != nil {
+		return nil, errors.ErrFindBonusGrant
+	}
+	return grants, nil
+}
+
+// ExpireGrant помечает начисление сгоревшим и возвращает его с остатком на момент сгорания.
+// Если начисление уже потрачено или сгорело, возвращается nil
+func (r *PostgresBonusRepo) ExpireGrant(tx *gorm.DB, grantID int64) (*models.BonusGrant, error) {
+	if tx == nil {
+		tx = r.db
+	}
+	var grant models.BonusGrant
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id = ? AND status = ?", grantID, models.BonusActive).
+		Limit(1).
+		Find(&grant).Error
+	if err != nil {
+		return nil, errors.ErrFindBonusGrant
+	}
+	if grant.ID == 0 {
+		return nil, nil
+	}
+	err = tx.Model(&models.BonusGrant{}).
+		Where("id = ?", grant.ID).
+		Updates(map[string]interface{}{"remaining_amount": 0, "status": models.BonusExpired}).Error
+	if err != nil {
+		return nil, errors.ErrUpdateBonusGrant
+	}
+	return &grant, nil
+}
+
+// RevokeTransactionGrants отзывает начисления, данные за оплату transactionID, и возвращает их
+// с остатком на момент отзыва. Сгоревшие начисления не трогаются: их остаток уже списан
+func (r *PostgresBonusRepo) RevokeTransactionGrants(tx *gorm.DB, transactionID int64) ([]models.BonusGrant, error) {
+	if tx == nil {
+		tx = r.db
+	}
+	var grants []models.BonusGrant
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("transaction_id = ? AND status IN ?", transactionID, []models.BonusGrantStatus{models.BonusActive, models.BonusSpent}).
+		Find(&grants).Error
+	if err != nil {
+		return nil, errors.ErrFindBonusGrant
+	}
+	for _, grant := range grants {
+		err := tx.Model(&models.BonusGrant{}).
+			Where("id = ?", grant.ID).
+			Updates(map[string]interface{}{"remaining_amount": 0, "status": models.BonusRevoked}).Error
+		if err != nil {
+			return nil, errors.ErrUpdateBonusGrant
+		}
+	}
+	return grants, nil
+}
@@ -11,6 +11,8 @@ type ComputerRepository interface {
 	GetComputers(ctx context.Context) ([]models.Computer, error)
 	UpdateStatus(ctx context.Context, number int, free models.ComputerStatus) error
 	IsComputerAvailable(ctx context.Context, number int) (bool, error)
+	GetComputerByNumber(ctx context.Context, number int) (*models.Computer, error)
+	GetOccupancy(ctx context.Context) (float64, error)
 }
 type PostgresComputerRepo struct {
 	db *gorm.DB
@@ -48,3 +50,26 @@ func (r *PostgresComputerRepo) IsComputerAvailable(ctx context.Context, number i
 	}
 	return count > 0, nil
 }
+
+func (r *PostgresComputerRepo) GetComputerByNumber(ctx context.Context, number int) (*models.Computer, error) {
+	var computer models.Computer
+	if err := r.db.WithContext(ctx).Where("pc_number = ?", number).First(&computer).Error; err != nil {
+		return nil, errors.ErrComputerNotFound
+	}
+	return &computer, nil
+}
+
+// GetOccupancy возвращает долю занятых компьютеров от 0 до 1
+func (r *PostgresComputerRepo) GetOccupancy(ctx context.Context) (float64, error) {
+	var total, busy int64
+	if err := r.db.WithContext(ctx).Model(&models.Computer{}).Count(&total).Error; err != nil {
+		return 0, errors.ErrFindComputer
+	}
+	if total == 0 {
+		return 0, nil
+	}
+	if err := r.db.WithContext(ctx).Model(&models.Computer{}).Where("status = ?", models.Busy).Count(&busy).Error; err != nil {
+		return 0, errors.ErrFindComputer
+	}
+	return float64(busy) / float64(total), nil
+}
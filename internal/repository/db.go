@@ -3,6 +3,8 @@ package repository
 import (
 	"computer-club/internal/config"
 	models2 "computer-club/internal/repository/models"
+	"computer-club/pkg/errors"
+	"computer-club/pkg/money"
 	"fmt"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
@@ -18,12 +20,67 @@ func NewPostgresDB(cfg *config.Config) *gorm.DB {
 }
 
 func Migrate(db *gorm.DB) {
+	// Денежные колонки переводятся в копейки до AutoMigrate, чтобы не потерять старые значения
+	if err := migrateMoneyColumns(db); err != nil {
+		log.Fatalf("failed to migrate money columns: %v", err)
+	}
+	hadPromotionAmount := db.Migrator().HasColumn(&models2.Promotion{}, "amount_amount")
+
 	db.AutoMigrate(&models2.User{},
 		&models2.Session{},
 		&models2.Computer{},
 		&models2.Tariff{},
 		&models2.Wallet{},
-		&models2.Transaction{})
+		&models2.Transaction{},
+		&models2.Promotion{},
+		&models2.PromotionUsage{},
+		&models2.Package{},
+		&models2.UserPackage{},
+		&models2.PackageUsage{},
+		&models2.LedgerAccount{},
+		&models2.JournalEntry{},
+		&models2.LedgerPosting{},
+		&models2.Transfer{},
+		&models2.BonusGrant{},
+		&models2.LoyaltyAccount{},
+		&models2.LoyaltyEvent{},
+		&models2.Payment{},
+		&models2.Shift{},
+		&models2.Receipt{},
+		&models2.UserLimits{},
+		&models2.VoucherBatch{},
+		&models2.Voucher{},
+		&models2.Organization{},
+		&models2.OrganizationMember{},
+		&models2.OrganizationInvoice{},
+		&models2.RefreshToken{},
+		&models2.UserToken{},
+		&models2.TwoFactor{},
+		&models2.BackupCode{},
+		&models2.LoginEvent{})
+
+	// Номера чеков выдаются последовательно
+	mustExec(db, "CREATE SEQUENCE IF NOT EXISTS receipt_number_seq")
+
+	// Колонки бонусов добавлены к существующим строкам без значения
+	mustExec(db, "UPDATE wallets SET bonus_amount = 0 WHERE bonus_amount IS NULL")
+	mustExec(db, "UPDATE transactions SET bonus_amount = 0 WHERE bonus_amount IS NULL")
+
+	// Цена продажи добавлена к подарочным картам позже: у карт на сумму она равна номиналу
+	mustExec(db, "UPDATE voucher_batches SET price_amount = amount_amount, price_currency = amount_currency WHERE type = ? AND price_amount IS NULL",
+		models2.VoucherValue)
+	mustExec(db, "UPDATE vouchers SET price_amount = amount_amount, price_currency = amount_currency WHERE type = ? AND price_amount IS NULL",
+		models2.VoucherValue)
+
+	// Фиксированные скидки промокодов раньше хранились в поле value в рублях
+	if !hadPromotionAmount {
+		mustExec(db, "UPDATE promotions SET amount_amount = ROUND(CAST(value AS numeric) * 100)::bigint, amount_currency = ? WHERE type = ?",
+			money.DefaultCurrency, models2.PromoFixed)
+	}
+
+	if err := bootstrapLedger(db); err != nil {
+		log.Fatalf("failed to bootstrap ledger: %v", err)
+	}
 
 	// Проверяем, есть ли компьютеры в базе
 	var count int64
@@ -31,9 +88,14 @@ func Migrate(db *gorm.DB) {
 	if count == 0 {
 		fmt.Println("Создаем 7 компьютеров...")
 		for i := 1; i <= 7; i++ {
+			zone := models2.ZoneStandard
+			if i > 5 {
+				zone = models2.ZoneVIP
+			}
 			db.Create(&models2.Computer{
 				PCNumber: i,
 				Status:   models2.Free,
+				Zone:     zone,
 			})
 		}
 	}
@@ -42,13 +104,13 @@ func Migrate(db *gorm.DB) {
 	db.Model(&models2.Tariff{}).Count(&countTariffs)
 	if countTariffs == 0 {
 		tariffs := []models2.Tariff{
-			{ID: 1, Name: "1 час", Price: 100, Duration: 60},
-			{ID: 2, Name: "3 часа", Price: 250, Duration: 180},
-			{ID: 3, Name: "5 часов", Price: 400, Duration: 300},
-			{ID: 4, Name: "8 часов", Price: 600, Duration: 480},
-			{ID: 5, Name: "Всю ночь", Price: 500, Duration: 480},
-			{ID: 6, Name: "Ночной (4ч)", Price: 350, Duration: 240},
-			{ID: 7, Name: "Ночной (6ч)", Price: 450, Duration: 360},
+			{ID: 1, Name: "1 час", Price: money.FromMajor(100), Duration: 60},
+			{ID: 2, Name: "3 часа", Price: money.FromMajor(250), Duration: 180},
+			{ID: 3, Name: "5 часов", Price: money.FromMajor(400), Duration: 300},
+			{ID: 4, Name: "8 часов", Price: money.FromMajor(600), Duration: 480},
+			{ID: 5, Name: "Всю ночь", Price: money.FromMajor(500), Duration: 480},
+			{ID: 6, Name: "Ночной (4ч)", Price: money.FromMajor(350), Duration: 240},
+			{ID: 7, Name: "Ночной (6ч)", Price: money.FromMajor(450), Duration: 360},
 		}
 
 		for _, tariff := range tariffs {
@@ -56,3 +118,89 @@ func Migrate(db *gorm.DB) {
 		}
 	}
 }
+
+// mustExec выполняет служебный запрос миграции и останавливает запуск, если он не прошел
+func mustExec(db *gorm.DB, sql string, values ...interface{}) {
+	if err := db.Exec(sql, values...).Error; err != nil {
+		log.Fatalf("failed to migrate: %q: %v", sql, err)
+	}
+}
+
+// moneyColumns - денежные колонки, которые раньше хранились как float в рублях
+var moneyColumns = []struct {
+	table  string
+	column string
+}{
+	{"wallets", "balance"},
+	{"transactions", "amount"},
+	{"transactions", "discount"},
+	{"tariffs", "price"},
+	{"packages", "price"},
+	{"promotion_usages", "discount"},
+}
+
+// migrateMoneyColumns переносит суммы из float-колонок в целые копейки с кодом валюты.
+// Значения округляются через numeric, поэтому накопленная погрешность float не переносится.
+func migrateMoneyColumns(db *gorm.DB) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, mc := range moneyColumns {
+			if !tx.Migrator().HasTable(mc.table) || !tx.Migrator().HasColumn(mc.table, mc.column) ||
+				tx.Migrator().HasColumn(mc.table, mc.column+"_amount") {
+				continue
+			}
+
+			statements := []string{
+				fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s_amount bigint NOT NULL DEFAULT 0", mc.table, mc.column),
+				fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s_currency varchar(3) NOT NULL DEFAULT '%s'", mc.table, mc.column, money.DefaultCurrency),
+				fmt.Sprintf("UPDATE %s SET %s_amount = ROUND(CAST(%s AS numeric) * 100)::bigint WHERE %s IS NOT NULL", mc.table, mc.column, mc.column, mc.column),
+				fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s", mc.table, mc.column),
+			}
+			for _, statement := range statements {
+				if err := tx.Exec(statement).Error; err != nil {
+					return fmt.Errorf("%w: %s.%s: %q: %v", errors.ErrMigrateMoney, mc.table, mc.column, statement, err)
+				}
+			}
+			log.Printf("Колонка %s.%s переведена в копейки", mc.table, mc.column)
+		}
+		return nil
+	})
+}
+
+// bootstrapLedger заводит счета журнала для кошельков, созданных до его появления,
+// и переносит их текущий баланс начальной проводкой
+func bootstrapLedger(db *gorm.DB) error {
+	var wallets []models2.Wallet
+	err := db.Where("NOT EXISTS (SELECT 1 FROM ledger_accounts a WHERE a.type = ? AND a.user_id = wallets.user_id)",
+		models2.AccountCustomerWallet).Find(&wallets).Error
+	if err != nil {
+		return err
+	}
+
+	ledgerRepo := NewPostgresLedgerRepo(db)
+	for _, wallet := range wallets {
+		err := db.Transaction(func(tx *gorm.DB) error {
+			customer, err := ledgerRepo.GetOrCreateAccount(tx, models2.CustomerAccount(wallet.UserID))
+			if err != nil {
+				return err
+			}
+			if wallet.Balance.IsZero() {
+				return nil
+			}
+			opening, err := ledgerRepo.GetOrCreateAccount(tx, models2.SystemAccount(models2.AccountOpeningBalance))
+			if err != nil {
+				return err
+			}
+			return ledgerRepo.CreateEntry(tx, &models2.JournalEntry{
+				Description: "Начальный остаток кошелька",
+				Postings: []models2.LedgerPosting{
+					{AccountID: opening.ID, Amount: wallet.Balance},
+					{AccountID: customer.ID, Amount: wallet.Balance.Neg()},
+				},
+			})
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package repository
+
+import (
+	"computer-club/internal/repository/models"
+	"computer-club/pkg/errors"
+	"context"
+	"encoding/json"
+	"github.com/redis/go-redis/v9"
+	"time"
+)
+
+type IdempotencyRepository interface {
+	GetResponse(ctx context.Context, key string) (*models.IdempotentResponse, error)
+	SaveResponse(ctx context.Context, key string, response *models.IdempotentResponse, ttl time.Duration) error
+	Lock(ctx context.Context, key string, ttl time.Duration) (bool, error)
+	Unlock(ctx context.Context, key string) error
+}
+
+type RedisIdempotencyRepo struct {
+	redis *redis.Client
+}
+
+func NewRedisIdempotencyRepo(redis *redis.Client) IdempotencyRepository {
+	return &RedisIdempotencyRepo{redis: redis}
+}
+
+// GetResponse возвращает сохраненный ответ или nil, если запрос с таким ключом еще не выполнялся
+func (r *RedisIdempotencyRepo) GetResponse(ctx context.Context, key string) (*models.IdempotentResponse, error) {
+	responseJSON, err := r.redis.Get(ctx, getIdempotencyKey(key)).Result()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.ErrIdempotencyStore
+	}
+	var response models.IdempotentResponse
+	if err := json.Unmarshal([]byte(responseJSON), &response); err != nil {
+		return nil, errors.ErrIdempotencyStore
+	}
+	return &response, nil
+}
+
+func (r *RedisIdempotencyRepo) SaveResponse(ctx context.Context, key string, response *models.IdempotentResponse, ttl time.Duration) error {
+	responseJSON, err := json.Marshal(response)
+	if err != nil {
+		return errors.ErrIdempotencyStore
+	}
+	if err := r.redis.Set(ctx, getIdempotencyKey(key), responseJSON, ttl).Err(); err != nil {
+		return errors.ErrIdempotencyStore
+	}
+	return nil
+}
+
+// Lock не дает выполнить два запроса с одним ключом одновременно
+func (r *RedisIdempotencyRepo) Lock(ctx context.Context, key string, ttl time.Duration) (bool, error) {
+	locked, err := r.redis.SetNX(ctx, getIdempotencyLockKey(key), 1, ttl).Result()
+	if err != nil {
+		return false, errors.ErrIdempotencyStore
+	}
+	return locked, nil
+}
+
+func (r *RedisIdempotencyRepo) Unlock(ctx context.Context, key string) error {
+	if err := r.redis.Del(ctx, getIdempotencyLockKey(key)).Err(); err != nil {
+		return errors.ErrDeleteRedis
+	}
+	return nil
+}
+
+func getIdempotencyKey(key string) string {
+	return "idempotency:" + key
+}
+
+func getIdempotencyLockKey(key string) string {
+	return "idempotency:lock:" + key
+}
@@ -0,0 +1,171 @@
+package repository
+
+import (
+	"computer-club/internal/repository/models"
+	"computer-club/pkg/errors"
+	"computer-club/pkg/money"
+	"context"
+	"fmt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type LedgerRepository interface {
+	GetOrCreateAccount(tx *gorm.DB, ref models.AccountRef) (*models.LedgerAccount, error)
+	CreateEntry(tx *gorm.DB, entry *models.JournalEntry) error
+	FindWalletMismatches(ctx context.Context) ([]models.WalletMismatch, error)
+	FindOrganizationMismatches(ctx context.Context) ([]models.OrganizationMismatch, error)
+	FindUnbalancedEntries(ctx context.Context) ([]models.UnbalancedEntry, error)
+}
+
+type PostgresLedgerRepo struct {
+	db *gorm.DB
+}
+
+func NewPostgresLedgerRepo(db *gorm.DB) LedgerRepository {
+	return &PostgresLedgerRepo{db: db}
+}
+
+func (r *PostgresLedgerRepo) GetOrCreateAccount(tx *gorm.DB, ref models.AccountRef) (*models.LedgerAccount, error) {
+	if tx == nil {
+		tx = r.db
+	}
+	account := models.LedgerAccount{
+		Code:   ref.Code(),
+		Type:   ref.Type,
+		UserID: ref.UserID,
+	}
+	// Счет мог быть создан параллельным запросом, поэтому вставка без ошибки при конфликте
+	if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&account).Error; err != nil {
+		return nil, errors.ErrLedgerAccount
+	}
+	if err := tx.Where("code = ?", ref.Code()).First(&account).Error; err != nil {
+		return nil, errors.ErrLedgerAccount
+	}
+	return &account, nil
+}
+
+// CreateEntry сохраняет проводку вместе со строками, предварительно проверив ее баланс
+func (r *PostgresLedgerRepo) CreateEntry(tx *gorm.DB, entry *models.JournalEntry) error {
+	if tx == nil {
+		tx = r.db
+	}
+	if len(entry.Postings) < 2 {
+		return errors.ErrUnbalancedEntry
+	}
+	sum := money.Money{Currency: entry.Postings[0].Amount.Currency}
+	for _, posting := range entry.Postings {
+		if posting.Amount.Currency != entry.Postings[0].Amount.Currency {
+			return errors.ErrCurrencyMismatch
+		}
+		sum = sum.Add(posting.Amount)
+	}
+	if !sum.IsZero() {
+		return errors.ErrUnbalancedEntry
+	}
+
+	if err := tx.Create(entry).Error; err != nil {
+		return errors.ErrCreateJournalEntry
+	}
+	return nil
+}
+
+// FindWalletMismatches сравнивает реальный и бонусный балансы каждого кошелька
+// с суммой строк соответствующего счета в журнале
+func (r *PostgresLedgerRepo) FindWalletMismatches(ctx context.Context) ([]models.WalletMismatch, error) {
+	balances := []struct {
+		kind    models.BalanceKind
+		column  string
+		account models.LedgerAccountType
+	}{
+		{models.BalanceReal, "balance", models.AccountCustomerWallet},
+		{models.BalanceBonus, "bonus", models.AccountCustomerBonus},
+	}
+
+	mismatches := make([]models.WalletMismatch, 0)
+	for _, balance := range balances {
+		var rows []struct {
+			UserID        int64
+			Currency      string
+			WalletBalance int64
+			LedgerBalance int64
+		}
+		err := r.db.WithContext(ctx).Raw(fmt.Sprintf(`
+			SELECT w.user_id, w.%[1]s_currency AS currency, w.%[1]s_amount AS wallet_balance,
+			       COALESCE(-SUM(p.amount_amount), 0) AS ledger_balance
+			FROM wallets w
+			LEFT JOIN ledger_accounts a ON a.type = ? AND a.user_id = w.user_id
+			LEFT JOIN ledger_postings p ON p.account_id = a.id
+			GROUP BY w.user_id, w.%[1]s_currency, w.%[1]s_amount
+			HAVING w.%[1]s_amount <> COALESCE(-SUM(p.amount_amount), 0)
+			ORDER BY w.user_id`, balance.column), balance.account).Scan(&rows).Error
+		if err != nil {
+			return nil, errors.ErrReconcile
+		}
+
+		for _, row := range rows {
+			mismatches = append(mismatches, models.WalletMismatch{
+				UserID:        row.UserID,
+				Balance:       balance.kind,
+				WalletBalance: money.Money{Amount: row.WalletBalance, Currency: row.Currency},
+				LedgerBalance: money.Money{Amount: row.LedgerBalance, Currency: row.Currency},
+			})
+		}
+	}
+	return mismatches, nil
+}
+
+// FindOrganizationMismatches сравнивает баланс кошелька каждой организации с суммой строк
+// ее счета в журнале
+func (r *PostgresLedgerRepo) FindOrganizationMismatches(ctx context.Context) ([]models.OrganizationMismatch, error) {
+	var rows []struct {
+		OrganizationID int64
+		Currency       string
+		Balance        int64
+		LedgerBalance  int64
+	}
+	err := r.db.WithContext(ctx).Raw(`
+		SELECT o.id AS organization_id, o.balance_currency AS currency, o.balance_amount AS balance,
+		       COALESCE(-SUM(p.amount_amount), 0) AS ledger_balance
+		FROM organizations o
+		LEFT JOIN ledger_accounts a ON a.type = ? AND a.user_id = o.id
+		LEFT JOIN ledger_postings p ON p.account_id = a.id
+		GROUP BY o.id, o.balance_currency, o.balance_amount
+		HAVING o.balance_amount <> COALESCE(-SUM(p.amount_amount), 0)
+		ORDER BY o.id`, models.AccountOrganizationWallet).Scan(&rows).Error
+	if err != nil {
+		return nil, errors.ErrReconcile
+	}
+
+	mismatches := make([]models.OrganizationMismatch, 0, len(rows))
+	for _, row := range rows {
+		mismatches = append(mismatches, models.OrganizationMismatch{
+			OrganizationID: row.OrganizationID,
+			Balance:        money.Money{Amount: row.Balance, Currency: row.Currency},
+			LedgerBalance:  money.Money{Amount: row.LedgerBalance, Currency: row.Currency},
+		})
+	}
+	return mismatches, nil
+}
+
+func (r *PostgresLedgerRepo) FindUnbalancedEntries(ctx context.Context) ([]models.UnbalancedEntry, error) {
+	var rows []struct {
+		EntryID int64
+		Sum     int64
+	}
+	err := r.db.WithContext(ctx).Raw(`
+		SELECT entry_id, SUM(amount_amount) AS sum
+		FROM ledger_postings
+		GROUP BY entry_id
+		HAVING SUM(amount_amount) <> 0
+		ORDER BY entry_id`).Scan(&rows).Error
+	if err != nil {
+		return nil, errors.ErrReconcile
+	}
+
+	entries := make([]models.UnbalancedEntry, 0, len(rows))
+	for _, row := range rows {
+		entries = append(entries, models.UnbalancedEntry{EntryID: row.EntryID, Sum: money.New(row.Sum)})
+	}
+	return entries, nil
+}
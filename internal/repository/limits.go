@@ -0,0 +1,113 @@
+package repository
+
+import (
+	"computer-club/internal/repository/models"
+	"computer-club/pkg/errors"
+	"computer-club/pkg/money"
+	"context"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+type LimitsRepository interface {
+	GetLimits(ctx context.Context, userID int64) (*models.UserLimits, error)
+	SaveLimits(ctx context.Context, limits *models.UserLimits) error
+	SetGuardian(ctx context.Context, userID int64, guardianID *int64) error
+	GetWards(ctx context.Context, guardianID int64) ([]models.UserLimits, error)
+	SumSpend(ctx context.Context, userID int64, since time.Time) (money.Money, error)
+	MinutesPlayed(ctx context.Context, userID int64, from, to time.Time) (int64, error)
+}
+
+type PostgresLimitsRepo struct {
+	db *gorm.DB
+}
+
+func NewPostgresLimitsRepo(db *gorm.DB) LimitsRepository {
+	return &PostgresLimitsRepo{db: db}
+}
+
+// GetLimits возвращает ограничения игрока. Если они не заданы, возвращаются пустые ограничения
+func (r *PostgresLimitsRepo) GetLimits(ctx context.Context, userID int64) (*models.UserLimits, error) {
+	var limits models.UserLimits
+	err := r.db.WithContext(ctx).Where("user_id = ?", userID).Limit(1).Find(&limits).Error
+	if err != nil {
+		return nil, errors.ErrFindLimits
+	}
+	limits.UserID = userID
+	if limits.AllowedTariffs == nil {
+		limits.AllowedTariffs = []int64{}
+	}
+	return &limits, nil
+}
+
+// SaveLimits создает или заменяет ограничения игрока, не трогая привязку к опекуну
+func (r *PostgresLimitsRepo) SaveLimits(ctx context.Context, limits *models.UserLimits) error {
+	err := r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{
+			"daily_spend_amount", "daily_spend_currency", "weekly_spend_amount", "weekly_spend_currency",
+			"daily_minutes", "allowed_from_hour", "allowed_to_hour", "allowed_tariffs", "updated_by", "updated_at",
+		}),
+	}).Omit("guardian_id").Create(limits).Error
+	if err != nil {
+		return errors.ErrSaveLimits
+	}
+	return nil
+}
+
+// SetGuardian привязывает игрока к опекуну или отвязывает при guardianID == nil
+func (r *PostgresLimitsRepo) SetGuardian(ctx context.Context, userID int64, guardianID *int64) error {
+	limits := models.UserLimits{UserID: userID, GuardianID: guardianID, AllowedTariffs: []int64{}, UpdatedAt: time.Now()}
+	err := r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"guardian_id", "updated_at"}),
+	}).Create(&limits).Error
+	if err != nil {
+		return errors.ErrSaveLimits
+	}
+	return nil
+}
+
+func (r *PostgresLimitsRepo) GetWards(ctx context.Context, guardianID int64) ([]models.UserLimits, error) {
+	var wards []models.UserLimits
+	err := r.db.WithContext(ctx).Where("guardian_id = ?", guardianID).Order("user_id").Find(&wards).Error
+	if err != nil {
+		return nil, errors.ErrFindLimits
+	}
+	return wards, nil
+}
+
+// SumSpend считает оплаты сессий и пакетов и отправленные игроком переводы с момента since
+// за вычетом сторно оплат и возвратов при отмене перевода. Сессии за счет организации
+// в личные лимиты не входят
+func (r *PostgresLimitsRepo) SumSpend(ctx context.Context, userID int64, since time.Time) (money.Money, error) {
+	var total int64
+	err := r.db.WithContext(ctx).Model(&models.Transaction{}).
+		Select("COALESCE(SUM(CASE WHEN type IN ? THEN -amount_amount ELSE amount_amount END), 0)",
+			[]models.TransactionType{models.Reversal, models.TransferIn}).
+		Where("user_id = ? AND created_at >= ? AND organization_id IS NULL", userID, since).
+		Where("type IN ? OR (type = ? AND COALESCE(payment_method, '') = '') OR (type IN ? AND transfer_id IN (?))",
+			[]models.TransactionType{models.Buy, models.PackagePurchase}, models.Reversal,
+			[]models.TransactionType{models.TransferOut, models.TransferIn},
+			r.db.Model(&models.Transfer{}).Select("id").Where("from_user_id = ?", userID)).
+		Scan(&total).Error
+	if err != nil {
+		return money.Money{}, errors.ErrFindLimits
+	}
+	return money.New(total), nil
+}
+
+// MinutesPlayed считает минуты сессий игрока, пересекающиеся с интервалом [from, to).
+// Для активных сессий учитывается время до to, а не плановое окончание
+func (r *PostgresLimitsRepo) MinutesPlayed(ctx context.Context, userID int64, from, to time.Time) (int64, error) {
+	var seconds float64
+	err := r.db.WithContext(ctx).Model(&models.Session{}).
+		Select("COALESCE(SUM(EXTRACT(EPOCH FROM LEAST(COALESCE(end_time, ?), ?) - GREATEST(start_time, ?))), 0)", to, to, from).
+		Where("user_id = ? AND start_time < ? AND COALESCE(end_time, ?) > ?", userID, to, to, from).
+		Scan(&seconds).Error
+	if err != nil {
+		return 0, errors.ErrFindLimits
+	}
+	return int64(seconds) / 60, nil
+}
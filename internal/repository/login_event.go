@@ -0,0 +1,53 @@
+package repository
+
+import (
+	"computer-club/internal/repository/models"
+	"computer-club/pkg/errors"
+	"context"
+	"gorm.io/gorm"
+)
+
+type LoginEventRepository interface {
+	CreateLoginEvent(ctx context.Context, event *models.LoginEvent) error
+	FindLoginEvents(ctx context.Context, filter models.LoginEventFilter) ([]models.LoginEvent, error)
+}
+
+type PostgresLoginEventRepo struct {
+	db *gorm.DB
+}
+
+func NewPostgresLoginEventRepo(db *gorm.DB) LoginEventRepository {
+	return &PostgresLoginEventRepo{db: db}
+}
+
+func (r *PostgresLoginEventRepo) CreateLoginEvent(ctx context.Context, event *models.LoginEvent) error {
+	if err := r.db.WithContext(ctx).Create(event).Error; err != nil {
+		return errors.ErrCreateLoginEvent
+	}
+	return nil
+}
+
+func (r *PostgresLoginEventRepo) FindLoginEvents(ctx context.Context, filter models.LoginEventFilter) ([]models.LoginEvent, error) {
+	query := r.db.WithContext(ctx).Order("id DESC")
+	if filter.UserID != nil {
+		query = query.Where("user_id = ?", *filter.UserID)
+	}
+	if filter.IP != "" {
+		query = query.Where("ip = ?", filter.IP)
+	}
+	if filter.Type != "" {
+		query = query.Where("type = ?", filter.Type)
+	}
+	if filter.Cursor > 0 {
+		query = query.Where("id < ?", filter.Cursor)
+	}
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit)
+	}
+
+	var events []models.LoginEvent
+	if err := query.Find(&events).Error; err != nil {
+		return nil, errors.ErrFindLoginEvent
+	}
+	return events, nil
+}
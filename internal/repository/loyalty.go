@@ -0,0 +1,118 @@
+package repository
+
+import (
+	"computer-club/internal/repository/models"
+	"computer-club/pkg/errors"
+	"computer-club/pkg/money"
+	"context"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+type LoyaltyRepository interface {
+	GetAccount(ctx context.Context, userID int64) (*models.LoyaltyAccount, error)
+	AddPoints(tx *gorm.DB, userID int64, points int64) error
+	SpendPoints(tx *gorm.DB, userID int64, points int64) error
+	CreateEvent(tx *gorm.DB, event *models.LoyaltyEvent) error
+	SumTransactionPoints(tx *gorm.DB, transactionID int64) (int64, error)
+	SumSpend(ctx context.Context, userID int64, since time.Time) (money.Money, error)
+}
+
+type PostgresLoyaltyRepo struct {
+	db *gorm.DB
+}
+
+func NewPostgresLoyaltyRepo(db *gorm.DB) LoyaltyRepository {
+	return &PostgresLoyaltyRepo{db: db}
+}
+
+// GetAccount возвращает счет баллов. Если пользователь еще не получал баллов, возвращается пустой счет
+func (r *PostgresLoyaltyRepo) GetAccount(ctx context.Context, userID int64) (*models.LoyaltyAccount, error) {
+	var account models.LoyaltyAccount
+	err := r.db.WithContext(ctx).Where("user_id = ?", userID).Limit(1).Find(&account).Error
+	if err != nil {
+		return nil, errors.ErrFindLoyalty
+	}
+	account.UserID = userID
+	return &account, nil
+}
+
+func (r *PostgresLoyaltyRepo) AddPoints(tx *gorm.DB, userID int64, points int64) error {
+	if tx == nil {
+		tx = r.db
+	}
+	account := models.LoyaltyAccount{UserID: userID, Points: points, UpdatedAt: time.Now()}
+	err := tx.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"points":     gorm.Expr("loyalty_accounts.points + ?", points),
+			"updated_at": account.UpdatedAt,
+		}),
+	}).Create(&account).Error
+	if err != nil {
+		return errors.ErrUpdateLoyalty
+	}
+	return nil
+}
+
+func (r *PostgresLoyaltyRepo) SpendPoints(tx *gorm.DB, userID int64, points int64) error {
+	if tx == nil {
+		tx = r.db
+	}
+	result := tx.Model(&models.LoyaltyAccount{}).
+		Where("user_id = ? AND points >= ?", userID, points).
+		Updates(map[string]interface{}{
+			"points":     gorm.Expr("points - ?", points),
+			"updated_at": time.Now(),
+		})
+	if result.Error != nil {
+		return errors.ErrUpdateLoyalty
+	}
+	if result.RowsAffected == 0 {
+		return errors.ErrNotEnoughPoints
+	}
+	return nil
+}
+
+func (r *PostgresLoyaltyRepo) CreateEvent(tx *gorm.DB, event *models.LoyaltyEvent) error {
+	if tx == nil {
+		tx = r.db
+	}
+	if err := tx.Create(event).Error; err != nil {
+		return errors.ErrUpdateLoyalty
+	}
+	return nil
+}
+
+// SumTransactionPoints возвращает баллы, начисленные и списанные по транзакции
+func (r *PostgresLoyaltyRepo) SumTransactionPoints(tx *gorm.DB, transactionID int64) (int64, error) {
+	if tx == nil {
+		tx = r.db
+	}
+	var points int64
+	err := tx.Model(&models.LoyaltyEvent{}).
+		Where("transaction_id = ?", transactionID).
+		Select("COALESCE(SUM(points), 0)").
+		Scan(&points).Error
+	if err != nil {
+		return 0, errors.ErrFindLoyalty
+	}
+	return points, nil
+}
+
+// SumSpend возвращает сумму оплат сессий и пакетов с since за вычетом сторно оплат.
+// Оплаты за счет организации не учитываются
+func (r *PostgresLoyaltyRepo) SumSpend(ctx context.Context, userID int64, since time.Time) (money.Money, error) {
+	var total int64
+	err := r.db.WithContext(ctx).Model(&models.Transaction{}).
+		Select("COALESCE(SUM(CASE WHEN type = ? THEN -amount_amount ELSE amount_amount END), 0)", models.Reversal).
+		Where("user_id = ? AND created_at >= ? AND organization_id IS NULL", userID, since).
+		Where("type IN ? OR (type = ? AND COALESCE(payment_method, '') = '')",
+			[]models.TransactionType{models.Buy, models.PackagePurchase}, models.Reversal).
+		Scan(&total).Error
+	if err != nil {
+		return money.Money{}, errors.ErrFindLoyalty
+	}
+	return money.New(total), nil
+}
@@ -0,0 +1,40 @@
+package models
+
+import (
+	"computer-club/pkg/money"
+	"time"
+)
+
+type BonusSource string
+
+const (
+	BonusFromPromotion BonusSource = "promotion"
+	BonusFromRefund    BonusSource = "refund"
+	BonusManual        BonusSource = "manual"
+)
+
+type BonusGrantStatus string
+
+const (
+	BonusActive  BonusGrantStatus = "active"
+	BonusSpent   BonusGrantStatus = "spent"
+	BonusExpired BonusGrantStatus = "expired"
+	// BonusRevoked - начисление отозвано сторно оплаты, за которую оно было дано
+	BonusRevoked BonusGrantStatus = "revoked"
+)
+
+// BonusGrant - начисление бонусов со своим сроком действия.
+// Бонусы тратятся начиная с начислений, которые истекают раньше
+type BonusGrant struct {
+	ID        int64            `json:"id" gorm:"primaryKey"`
+	UserID    int64            `json:"user_id" gorm:"index"`
+	Source    BonusSource      `json:"source"`
+	Comment   string           `json:"comment,omitempty"`
+	Amount    money.Money      `json:"amount" gorm:"embedded;embeddedPrefix:amount_"`
+	Remaining money.Money      `json:"remaining" gorm:"embedded;embeddedPrefix:remaining_"`
+	Status    BonusGrantStatus `json:"status" gorm:"index;default:active"`
+	// TransactionID - оплата, за которую начислены бонусы по промокоду. По ней начисление отзывается при сторно
+	TransactionID *int64    `json:"transaction_id,omitempty" gorm:"index"`
+	ExpiresAt     time.Time `json:"expires_at" gorm:"index"`
+	CreatedAt     time.Time `json:"created_at"`
+}
@@ -7,8 +7,14 @@ const (
 	Busy ComputerStatus = "busy"
 )
 
+const (
+	ZoneStandard = "standard"
+	ZoneVIP      = "vip"
+)
+
 type Computer struct {
 	ID       int64          `json:"id" gorm:"primaryKey;autoIncrement"`
 	PCNumber int            `gorm:"uniqueIndex" json:"pc_number"`
 	Status   ComputerStatus `json:"status"`
+	Zone     string         `json:"zone" gorm:"default:standard"`
 }
@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// IdempotentResponse - сохраненный ответ на запрос с ключом идемпотентности
+type IdempotentResponse struct {
+	RequestHash string    `json:"request_hash"`
+	StatusCode  int       `json:"status_code"`
+	ContentType string    `json:"content_type"`
+	Body        []byte    `json:"body"`
+	CreatedAt   time.Time `json:"created_at"`
+}
@@ -0,0 +1,133 @@
+package models
+
+import (
+	"computer-club/pkg/money"
+	"fmt"
+	"time"
+)
+
+type LedgerAccountType string
+
+const (
+	AccountCustomerWallet LedgerAccountType = "customer_wallet"
+	AccountCustomerBonus  LedgerAccountType = "customer_bonus"
+	AccountRevenue        LedgerAccountType = "revenue"
+	AccountCashDrawer     LedgerAccountType = "cash_drawer"
+	// AccountCardClearing и AccountTransferClearing - оплаты картой и переводом, еще не зачисленные
+	// банком на счет клуба. Сверяются с выписками эквайринга и банка отдельно от наличных
+	AccountCardClearing     LedgerAccountType = "card_clearing"
+	AccountTransferClearing LedgerAccountType = "transfer_clearing"
+	// AccountPaymentProvider - деньги, полученные через платежного провайдера
+	AccountPaymentProvider LedgerAccountType = "payment_provider"
+	AccountRefunds         LedgerAccountType = "refunds"
+	AccountBonus           LedgerAccountType = "bonus"
+	// AccountVouchers - обязательства по проданным, но еще не погашенным подарочным картам
+	AccountVouchers LedgerAccountType = "vouchers"
+	// AccountOrganizationWallet - кошелек организации, в UserID счета хранится ID организации
+	AccountOrganizationWallet LedgerAccountType = "organization_wallet"
+	// AccountOpeningBalance - начальные остатки кошельков, созданных до появления журнала
+	AccountOpeningBalance LedgerAccountType = "opening_balance"
+)
+
+// LedgerAccount - счет двойной записи. У клиентских кошельков заполнен UserID
+type LedgerAccount struct {
+	ID        int64             `json:"id" gorm:"primaryKey"`
+	Code      string            `json:"code" gorm:"uniqueIndex"`
+	Type      LedgerAccountType `json:"type" gorm:"index"`
+	UserID    *int64            `json:"user_id,omitempty" gorm:"index"`
+	CreatedAt time.Time         `json:"created_at"`
+}
+
+// JournalEntry - проводка, сумма всех строк которой равна нулю
+type JournalEntry struct {
+	ID            int64           `json:"id" gorm:"primaryKey"`
+	TransactionID *int64          `json:"transaction_id,omitempty" gorm:"index"`
+	Description   string          `json:"description"`
+	Postings      []LedgerPosting `json:"postings" gorm:"foreignKey:EntryID"`
+	CreatedAt     time.Time       `json:"created_at" gorm:"index"`
+}
+
+// LedgerPosting - строка проводки. Положительная сумма - дебет счета, отрицательная - кредит.
+// Кошелек клиента - обязательство клуба, поэтому его баланс равен сумме строк с обратным знаком
+type LedgerPosting struct {
+	ID        int64       `json:"id" gorm:"primaryKey"`
+	EntryID   int64       `json:"entry_id" gorm:"index"`
+	AccountID int64       `json:"account_id" gorm:"index"`
+	Amount    money.Money `json:"amount" gorm:"embedded;embeddedPrefix:amount_"`
+}
+
+// AccountRef - ссылка на счет по типу и владельцу
+type AccountRef struct {
+	Type   LedgerAccountType
+	UserID *int64
+}
+
+func CustomerAccount(userID int64) AccountRef {
+	return AccountRef{Type: AccountCustomerWallet, UserID: &userID}
+}
+
+func CustomerBonusAccount(userID int64) AccountRef {
+	return AccountRef{Type: AccountCustomerBonus, UserID: &userID}
+}
+
+func OrganizationAccount(organizationID int64) AccountRef {
+	return AccountRef{Type: AccountOrganizationWallet, UserID: &organizationID}
+}
+
+func SystemAccount(typ LedgerAccountType) AccountRef {
+	return AccountRef{Type: typ}
+}
+
+// PaymentAccount возвращает системный счет, на который поступают деньги выбранным способом оплаты
+func PaymentAccount(method PaymentMethod) AccountRef {
+	switch method {
+	case PaymentCard:
+		return SystemAccount(AccountCardClearing)
+	case PaymentTransfer:
+		return SystemAccount(AccountTransferClearing)
+	case PaymentOnline:
+		return SystemAccount(AccountPaymentProvider)
+	}
+	return SystemAccount(AccountCashDrawer)
+}
+
+// Code возвращает уникальный код счета
+func (a AccountRef) Code() string {
+	if a.UserID != nil {
+		return fmt.Sprintf("%s:%d", a.Type, *a.UserID)
+	}
+	return string(a.Type)
+}
+
+// WalletMismatch - расхождение баланса кошелька с журналом
+type WalletMismatch struct {
+	UserID        int64       `json:"user_id"`
+	Balance       BalanceKind `json:"balance"`
+	WalletBalance money.Money `json:"wallet_balance"`
+	LedgerBalance money.Money `json:"ledger_balance"`
+}
+
+// OrganizationMismatch - расхождение баланса кошелька организации с журналом
+type OrganizationMismatch struct {
+	OrganizationID int64       `json:"organization_id"`
+	Balance        money.Money `json:"balance"`
+	LedgerBalance  money.Money `json:"ledger_balance"`
+}
+
+// UnbalancedEntry - проводка, сумма строк которой не равна нулю
+type UnbalancedEntry struct {
+	EntryID int64       `json:"entry_id"`
+	Sum     money.Money `json:"sum"`
+}
+
+// ReconciliationReport - результат сверки кошельков игроков и организаций с журналом
+type ReconciliationReport struct {
+	CheckedAt              time.Time              `json:"checked_at"`
+	WalletMismatches       []WalletMismatch       `json:"wallet_mismatches"`
+	OrganizationMismatches []OrganizationMismatch `json:"organization_mismatches"`
+	UnbalancedEntries      []UnbalancedEntry      `json:"unbalanced_entries"`
+}
+
+func (r *ReconciliationReport) OK() bool {
+	return len(r.WalletMismatches) == 0 && len(r.OrganizationMismatches) == 0 && len(r.UnbalancedEntries) == 0
+}
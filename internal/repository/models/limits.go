@@ -0,0 +1,78 @@
+package models
+
+import (
+	"computer-club/pkg/money"
+	"time"
+)
+
+// UserLimits - ограничения игрока, которые задает администратор или привязанный опекун.
+// Нулевые суммы и минуты означают отсутствие ограничения, пустой список тарифов - любые тарифы.
+// Разрешенные часы - полуинтервал [AllowedFromHour, AllowedToHour), может переходить через полночь
+type UserLimits struct {
+	ID              int64       `json:"-" gorm:"primaryKey"`
+	UserID          int64       `json:"user_id" gorm:"uniqueIndex"`
+	GuardianID      *int64      `json:"guardian_id,omitempty" gorm:"index"`
+	DailySpend      money.Money `json:"daily_spend" gorm:"embedded;embeddedPrefix:daily_spend_"`
+	WeeklySpend     money.Money `json:"weekly_spend" gorm:"embedded;embeddedPrefix:weekly_spend_"`
+	DailyMinutes    int64       `json:"daily_minutes"`
+	AllowedFromHour *int        `json:"allowed_from_hour,omitempty"`
+	AllowedToHour   *int        `json:"allowed_to_hour,omitempty"`
+	AllowedTariffs  []int64     `json:"allowed_tariffs" gorm:"serializer:json"`
+	UpdatedBy       *int64      `json:"updated_by,omitempty"`
+	UpdatedAt       time.Time   `json:"updated_at"`
+}
+
+// AllowsTariff проверяет, разрешен ли тариф
+func (l *UserLimits) AllowsTariff(tariffID int64) bool {
+	if len(l.AllowedTariffs) == 0 {
+		return true
+	}
+	for _, id := range l.AllowedTariffs {
+		if id == tariffID {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowsTime проверяет, попадает ли момент в разрешенные часы
+func (l *UserLimits) AllowsTime(at time.Time) bool {
+	if l.AllowedFromHour == nil || l.AllowedToHour == nil {
+		return true
+	}
+	from, to, hour := *l.AllowedFromHour, *l.AllowedToHour, at.Hour()
+	if from < to {
+		return hour >= from && hour < to
+	}
+	return hour >= from || hour < to
+}
+
+// AllowsInterval проверяет, что интервал [start, end] целиком лежит в разрешенных часах:
+// начало попадает в окно, а конец не позже ближайшего часа AllowedToHour
+func (l *UserLimits) AllowsInterval(start, end time.Time) bool {
+	if l.AllowedFromHour == nil || l.AllowedToHour == nil {
+		return true
+	}
+	if !l.AllowsTime(start) {
+		return false
+	}
+	year, month, day := start.Date()
+	windowEnd := time.Date(year, month, day, *l.AllowedToHour, 0, 0, 0, start.Location())
+	if !windowEnd.After(start) {
+		windowEnd = windowEnd.AddDate(0, 0, 1)
+	}
+	return !end.After(windowEnd)
+}
+
+// LimitsUsage - сколько игрок уже потратил и наиграл в текущих сутках и неделе
+type LimitsUsage struct {
+	SpentToday    money.Money `json:"spent_today"`
+	SpentThisWeek money.Money `json:"spent_this_week"`
+	MinutesToday  int64       `json:"minutes_today"`
+}
+
+// LimitsStatus - ограничения игрока вместе с текущим расходом
+type LimitsStatus struct {
+	Limits *UserLimits `json:"limits"`
+	Usage  LimitsUsage `json:"usage"`
+}
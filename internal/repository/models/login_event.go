@@ -0,0 +1,56 @@
+package models
+
+import "time"
+
+// LoginEventType - вид подозрительной активности при входе
+type LoginEventType string
+
+const (
+	// LoginEventBackoff - серия неверных паролей, следующая попытка отложена
+	LoginEventBackoff LoginEventType = "login_backoff"
+	// LoginEventAccountLocked - аккаунт временно заблокирован после MaxAccountFailures неудач
+	LoginEventAccountLocked LoginEventType = "account_locked"
+	// LoginEventIPLocked - вход с IP временно запрещен после MaxIPFailures неудач
+	LoginEventIPLocked LoginEventType = "ip_locked"
+	// LoginEventSuccessAfterFailures - успешный вход после серии неудач, пароль мог быть подобран
+	LoginEventSuccessAfterFailures LoginEventType = "login_after_failures"
+	// LoginEventUnlocked - администратор снял блокировку входа
+	LoginEventUnlocked LoginEventType = "account_unlocked"
+)
+
+// LoginEvent - запись журнала безопасности входа. UserID пустой, если email не зарегистрирован,
+// ActorID - администратор, снявший блокировку
+type LoginEvent struct {
+	ID        int64          `json:"id" gorm:"primaryKey"`
+	Type      LoginEventType `json:"type" gorm:"index"`
+	UserID    *int64         `json:"user_id,omitempty" gorm:"index"`
+	Email     string         `json:"email,omitempty"`
+	IP        string         `json:"ip,omitempty" gorm:"index"`
+	Failures  int64          `json:"failures,omitempty"`
+	ActorID   *int64         `json:"actor_id,omitempty"`
+	CreatedAt time.Time      `json:"created_at"`
+}
+
+// LoginEventFilter - выборка журнала входа. Cursor - ID последней записи предыдущей страницы
+type LoginEventFilter struct {
+	UserID *int64
+	IP     string
+	Type   LoginEventType
+	Cursor int64
+	Limit  int
+}
+
+// LoginEventPage - страница журнала входа. NextCursor пустой, если страница последняя
+type LoginEventPage struct {
+	Events     []LoginEvent `json:"events"`
+	NextCursor *int64       `json:"next_cursor,omitempty"`
+}
+
+// Valid сообщает, известен ли вид события
+func (t LoginEventType) Valid() bool {
+	switch t {
+	case LoginEventBackoff, LoginEventAccountLocked, LoginEventIPLocked, LoginEventSuccessAfterFailures, LoginEventUnlocked:
+		return true
+	}
+	return false
+}
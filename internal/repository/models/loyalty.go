@@ -0,0 +1,50 @@
+package models
+
+import (
+	"computer-club/pkg/money"
+	"time"
+)
+
+type LoyaltyTier string
+
+const (
+	TierBronze LoyaltyTier = "bronze"
+	TierSilver LoyaltyTier = "silver"
+	TierGold   LoyaltyTier = "gold"
+)
+
+type LoyaltyEventType string
+
+const (
+	LoyaltyEarn     LoyaltyEventType = "earn"
+	LoyaltyRedeem   LoyaltyEventType = "redeem"
+	LoyaltyReversal LoyaltyEventType = "reversal"
+)
+
+// LoyaltyAccount - баллы лояльности пользователя
+type LoyaltyAccount struct {
+	ID        int64     `json:"id" gorm:"primaryKey"`
+	UserID    int64     `json:"user_id" gorm:"uniqueIndex"`
+	Points    int64     `json:"points"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// LoyaltyEvent - начисление или списание баллов. Points отрицательные при списании
+type LoyaltyEvent struct {
+	ID            int64            `json:"id" gorm:"primaryKey"`
+	UserID        int64            `json:"user_id" gorm:"index"`
+	Type          LoyaltyEventType `json:"type"`
+	Points        int64            `json:"points"`
+	TransactionID *int64           `json:"transaction_id,omitempty"`
+	CreatedAt     time.Time        `json:"created_at"`
+}
+
+// LoyaltyStatus - баллы и уровень пользователя. Уровень считается по тратам за скользящее окно
+type LoyaltyStatus struct {
+	Points       int64       `json:"points"`
+	Tier         LoyaltyTier `json:"tier"`
+	Discount     float64     `json:"discount"`
+	RollingSpend money.Money `json:"rolling_spend"`
+	NextTier     LoyaltyTier `json:"next_tier,omitempty"`
+	ToNextTier   money.Money `json:"to_next_tier"`
+}
@@ -0,0 +1,60 @@
+package models
+
+import (
+	"computer-club/pkg/money"
+	"time"
+)
+
+type OrganizationRole string
+
+const (
+	OrgRoleOwner  OrganizationRole = "owner"
+	OrgRoleMember OrganizationRole = "member"
+)
+
+// Organization - корпоративный или командный аккаунт со своим кошельком
+type Organization struct {
+	ID        int64       `json:"id" gorm:"primaryKey"`
+	Name      string      `json:"name"`
+	OwnerID   int64       `json:"owner_id" gorm:"index"`
+	Balance   money.Money `json:"balance" gorm:"embedded;embeddedPrefix:balance_"`
+	CreatedAt time.Time   `json:"created_at"`
+}
+
+// OrganizationMember - участник организации. MonthlyCap ограничивает расходы участника
+// за счет организации в календарном месяце, нулевое значение - без ограничения
+type OrganizationMember struct {
+	ID             int64            `json:"id" gorm:"primaryKey"`
+	OrganizationID int64            `json:"organization_id" gorm:"uniqueIndex:idx_organization_member"`
+	UserID         int64            `json:"user_id" gorm:"uniqueIndex:idx_organization_member;index"`
+	Role           OrganizationRole `json:"role"`
+	MonthlyCap     money.Money      `json:"monthly_cap" gorm:"embedded;embeddedPrefix:monthly_cap_"`
+	CreatedAt      time.Time        `json:"created_at"`
+}
+
+// OrganizationDetails - организация с участниками
+type OrganizationDetails struct {
+	Organization *Organization        `json:"organization"`
+	Members      []OrganizationMember `json:"members"`
+}
+
+// InvoiceLine - расходы одного участника за период счета
+type InvoiceLine struct {
+	UserID   int64       `json:"user_id"`
+	Sessions int64       `json:"sessions"`
+	Minutes  int64       `json:"minutes"`
+	Amount   money.Money `json:"amount"`
+}
+
+// OrganizationInvoice - ежемесячный счет организации по расходам участников.
+// Period - месяц в формате 2006-01
+type OrganizationInvoice struct {
+	ID             int64         `json:"id" gorm:"primaryKey"`
+	OrganizationID int64         `json:"organization_id" gorm:"uniqueIndex:idx_organization_invoice"`
+	Period         string        `json:"period" gorm:"uniqueIndex:idx_organization_invoice"`
+	From           time.Time     `json:"from" gorm:"column:period_from"`
+	To             time.Time     `json:"to" gorm:"column:period_to"`
+	Lines          []InvoiceLine `json:"lines" gorm:"serializer:json"`
+	Total          money.Money   `json:"total" gorm:"embedded;embeddedPrefix:total_"`
+	CreatedAt      time.Time     `json:"created_at"`
+}
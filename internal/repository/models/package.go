@@ -0,0 +1,57 @@
+package models
+
+import (
+	"computer-club/pkg/money"
+	"time"
+)
+
+type PackageType string
+
+const (
+	PackageMinutes   PackageType = "minutes"
+	PackageUnlimited PackageType = "unlimited"
+)
+
+type UserPackageStatus string
+
+const (
+	PackageActive    UserPackageStatus = "active"
+	PackageExhausted UserPackageStatus = "exhausted"
+	PackageExpired   UserPackageStatus = "expired"
+)
+
+// Package - пакет предоплаченного времени или абонемент, доступный для покупки
+type Package struct {
+	ID           int64       `json:"id" gorm:"primaryKey"`
+	Name         string      `json:"name"`
+	Type         PackageType `json:"type"`
+	Minutes      int64       `json:"minutes"`
+	ValidityDays int         `json:"validity_days"`
+	Price        money.Money `json:"price" gorm:"embedded;embeddedPrefix:price_"`
+	Active       bool        `json:"active"`
+	CreatedAt    time.Time   `json:"created_at"`
+}
+
+// UserPackage - купленный пользователем пакет с остатком минут
+type UserPackage struct {
+	ID           int64             `json:"id" gorm:"primaryKey"`
+	UserID       int64             `json:"user_id" gorm:"index"`
+	PackageID    int64             `json:"package_id"`
+	Name         string            `json:"name"`
+	Type         PackageType       `json:"type"`
+	MinutesTotal int64             `json:"minutes_total"`
+	MinutesLeft  int64             `json:"minutes_left"`
+	Status       UserPackageStatus `json:"status" gorm:"index"`
+	ExpiresAt    time.Time         `json:"expires_at"`
+	CreatedAt    time.Time         `json:"created_at"`
+}
+
+// PackageUsage - минуты, списанные с пакета при оплате TransactionID. По ним минуты
+// возвращаются в пакеты при сторно оплаты
+type PackageUsage struct {
+	ID            int64     `json:"id" gorm:"primaryKey"`
+	UserPackageID int64     `json:"user_package_id" gorm:"index"`
+	TransactionID int64     `json:"transaction_id" gorm:"index"`
+	Minutes       int64     `json:"minutes"`
+	CreatedAt     time.Time `json:"created_at"`
+}
@@ -0,0 +1,32 @@
+package models
+
+import (
+	"computer-club/pkg/money"
+	"time"
+)
+
+type PaymentStatus string
+
+const (
+	PaymentPending   PaymentStatus = "pending"
+	PaymentSucceeded PaymentStatus = "succeeded"
+	PaymentFailed    PaymentStatus = "failed"
+	PaymentRefunded  PaymentStatus = "refunded"
+	// PaymentRefunding - деньги уже списаны с кошелька, но провайдер еще не подтвердил возврат
+	PaymentRefunding PaymentStatus = "refunding"
+)
+
+// Payment - пополнение кошелька клиентом через платежного провайдера
+type Payment struct {
+	ID                  int64         `json:"id" gorm:"primaryKey"`
+	UserID              int64         `json:"user_id" gorm:"index"`
+	Provider            string        `json:"provider"`
+	ProviderID          string        `json:"provider_id" gorm:"index"`
+	Amount              money.Money   `json:"amount" gorm:"embedded;embeddedPrefix:amount_"`
+	Status              PaymentStatus `json:"status" gorm:"index;default:pending"`
+	RedirectURL         string        `json:"redirect_url"`
+	TransactionID       *int64        `json:"transaction_id,omitempty"`
+	RefundTransactionID *int64        `json:"refund_transaction_id,omitempty"`
+	CreatedAt           time.Time     `json:"created_at"`
+	UpdatedAt           time.Time     `json:"updated_at"`
+}
@@ -0,0 +1,137 @@
+package models
+
+// Permission - право на действие, которое проверяется на маршруте через middleware.RequirePermission
+type Permission string
+
+const (
+	PermWalletDeposit      Permission = "wallet.deposit"
+	PermWalletBonus        Permission = "wallet.bonus"
+	PermTransactionViewAny Permission = "transaction.view.any"
+	PermTransactionReverse Permission = "transaction.reverse"
+	PermPaymentRefund      Permission = "payment.refund"
+	PermSessionViewAny     Permission = "session.view.any"
+	PermSessionEndAny      Permission = "session.end.any"
+	PermComputerView       Permission = "computer.view"
+	PermTariffEdit         Permission = "tariff.edit"
+	PermPackageEdit        Permission = "package.edit"
+	PermPromotionManage    Permission = "promotion.manage"
+	PermShiftManage        Permission = "shift.manage"
+	PermReceiptViewAny     Permission = "receipt.view.any"
+	PermLimitsManage       Permission = "limits.manage"
+	PermVoucherIssue       Permission = "voucher.issue"
+	PermVoucherSell        Permission = "voucher.sell"
+	PermOrganizationManage Permission = "organization.manage"
+	PermUserRoleAssign     Permission = "user.role.assign"
+	PermUserTokensRevoke   Permission = "user.tokens.revoke"
+	PermUserView           Permission = "user.view"
+	PermUserManage         Permission = "user.manage"
+)
+
+// cashierPermissions - работа на кассе: пополнения, смены, чеки и сессии в зале
+var cashierPermissions = []Permission{
+	PermWalletDeposit,
+	PermSessionViewAny,
+	PermSessionEndAny,
+	PermComputerView,
+	PermShiftManage,
+	PermReceiptViewAny,
+	PermVoucherSell,
+	PermUserView,
+}
+
+// managerPermissions - кассир плюс управление ценами, акциями, возвратами и организациями
+var managerPermissions = append(append([]Permission{}, cashierPermissions...),
+	PermWalletBonus,
+	PermTransactionViewAny,
+	PermTransactionReverse,
+	PermPaymentRefund,
+	PermTariffEdit,
+	PermPackageEdit,
+	PermPromotionManage,
+	PermLimitsManage,
+	PermVoucherIssue,
+	PermOrganizationManage,
+	PermUserManage,
+)
+
+// ownerPermissions - менеджер плюс управление персоналом
+var ownerPermissions = append(append([]Permission{}, managerPermissions...),
+	PermUserRoleAssign,
+	PermUserTokensRevoke,
+)
+
+// moneyPermissions - права, которые двигают деньги. Сотрудник пользуется ими только после входа
+// с кодом 2FA, чтобы украденного пароля было недостаточно
+var moneyPermissions = map[Permission]bool{
+	PermWalletDeposit:      true,
+	PermWalletBonus:        true,
+	PermTransactionReverse: true,
+	PermPaymentRefund:      true,
+	PermVoucherIssue:       true,
+	PermVoucherSell:        true,
+	PermOrganizationManage: true,
+}
+
+// RequiresTwoFactor сообщает, требует ли право входа с 2FA
+func (p Permission) RequiresTwoFactor() bool {
+	return moneyPermissions[p]
+}
+
+// RolePermissions - права каждой роли. У клиента особых прав нет, admin сохранен для
+// существующих аккаунтов и равен владельцу
+var RolePermissions = map[UserRole][]Permission{
+	Customer: {},
+	Cashier:  cashierPermissions,
+	Manager:  managerPermissions,
+	Owner:    ownerPermissions,
+	Admin:    ownerPermissions,
+}
+
+// Valid сообщает, известна ли роль
+func (r UserRole) Valid() bool {
+	_, ok := RolePermissions[r]
+	return ok
+}
+
+// Can сообщает, есть ли у роли право
+func (r UserRole) Can(permission Permission) bool {
+	for _, p := range RolePermissions[r] {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// RequiresTwoFactor сообщает, есть ли у роли права, для которых нужен вход с 2FA
+func (r UserRole) RequiresTwoFactor() bool {
+	for _, p := range RolePermissions[r] {
+		if p.RequiresTwoFactor() {
+			return true
+		}
+	}
+	return false
+}
+
+// roleRanks - старшинство ролей. Admin равен владельцу
+var roleRanks = map[UserRole]int{
+	Customer: 0,
+	Cashier:  1,
+	Manager:  2,
+	Owner:    3,
+	Admin:    3,
+}
+
+// CanManage сообщает, может ли роль менять, блокировать и удалять пользователя с ролью target.
+// Без права назначать роли можно трогать только тех, кто младше по роли
+func (r UserRole) CanManage(target UserRole) bool {
+	if r.Can(PermUserRoleAssign) {
+		return true
+	}
+	return roleRanks[r] > roleRanks[target]
+}
+
+// Staff сообщает, относится ли роль к персоналу
+func (r UserRole) Staff() bool {
+	return roleRanks[r] > roleRanks[Customer]
+}
@@ -0,0 +1,50 @@
+package models
+
+import (
+	"computer-club/pkg/money"
+	"time"
+)
+
+type PromotionType string
+
+const (
+	PromoPercent     PromotionType = "percent"
+	PromoFixed       PromotionType = "fixed"
+	PromoFreeMinutes PromotionType = "free_minutes"
+	// PromoBonus не дает скидку, а начисляет Amount на бонусный баланс после оплаты сессии
+	PromoBonus PromotionType = "bonus"
+)
+
+// Promotion - промокод или акция со скидкой на запуск сессии.
+// Value - процент скидки или количество бесплатных минут, Amount - сумма фиксированной скидки или бонуса
+type Promotion struct {
+	ID             int64         `json:"id" gorm:"primaryKey"`
+	Code           string        `json:"code" gorm:"uniqueIndex"`
+	Description    string        `json:"description"`
+	Type           PromotionType `json:"type"`
+	Value          float64       `json:"value"`
+	Amount         money.Money   `json:"amount" gorm:"embedded;embeddedPrefix:amount_"`
+	MaxUses        int64         `json:"max_uses"`
+	MaxUsesPerUser int64         `json:"max_uses_per_user"`
+	UsedCount      int64         `json:"used_count"`
+	ValidFrom      *time.Time    `json:"valid_from,omitempty"`
+	ValidTo        *time.Time    `json:"valid_to,omitempty"`
+	Weekdays       string        `json:"weekdays"`
+	StartHour      *int          `json:"start_hour,omitempty"`
+	EndHour        *int          `json:"end_hour,omitempty"`
+	TariffID       *int64        `json:"tariff_id,omitempty"`
+	Zone           string        `json:"zone"`
+	NewUsersOnly   bool          `json:"new_users_only"`
+	Active         bool          `json:"active"`
+	CreatedAt      time.Time     `json:"created_at"`
+}
+
+// PromotionUsage - факт применения промокода пользователем при оплате TransactionID
+type PromotionUsage struct {
+	ID            int64       `json:"id" gorm:"primaryKey"`
+	PromotionID   int64       `json:"promotion_id" gorm:"index"`
+	UserID        int64       `json:"user_id" gorm:"index"`
+	TransactionID *int64      `json:"transaction_id,omitempty" gorm:"index"`
+	Discount      money.Money `json:"discount" gorm:"embedded;embeddedPrefix:discount_"`
+	CreatedAt     time.Time   `json:"created_at"`
+}
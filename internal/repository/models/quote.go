@@ -0,0 +1,48 @@
+package models
+
+import (
+	"computer-club/pkg/money"
+	"time"
+)
+
+// PriceAdjustment - поправка к базовой цене тарифа от правила ценообразования
+type PriceAdjustment struct {
+	Rule       string  `json:"rule"`
+	Multiplier float64 `json:"multiplier"`
+}
+
+type DiscountSource string
+
+const (
+	DiscountPackage   DiscountSource = "package"
+	DiscountPromotion DiscountSource = "promotion"
+)
+
+// AppliedDiscount - скидка, уменьшившая цену сессии
+type AppliedDiscount struct {
+	Source DiscountSource `json:"source"`
+	Code   string         `json:"code,omitempty"`
+	Amount money.Money    `json:"amount"`
+}
+
+// PriceQuote - расчет стоимости сессии, зафиксированный на короткое время
+type PriceQuote struct {
+	ID             string            `json:"quote_id"`
+	UserID         int64             `json:"user_id"`
+	PCNumber       int               `json:"pc_number"`
+	TariffID       int64             `json:"tariff_id"`
+	PromoCode      string            `json:"promo_code,omitempty"`
+	BasePrice      money.Money       `json:"base_price"`
+	DynamicPrice   money.Money       `json:"dynamic_price"`
+	Adjustments    []PriceAdjustment `json:"adjustments"`
+	PackageMinutes int64             `json:"package_minutes"`
+	PromoDiscount  money.Money       `json:"promo_discount"`
+	Discounts      []AppliedDiscount `json:"discounts"`
+	Price          money.Money       `json:"price"`
+	Balance        money.Money       `json:"balance"`
+	WalletCovers   bool              `json:"wallet_covers"`
+	StartTime      time.Time         `json:"start_time"`
+	EndTime        time.Time         `json:"end_time"`
+	CreatedAt      time.Time         `json:"created_at"`
+	ExpiresAt      time.Time         `json:"expires_at"`
+}
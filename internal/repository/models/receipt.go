@@ -0,0 +1,48 @@
+package models
+
+import (
+	"computer-club/pkg/money"
+	"time"
+)
+
+type ReceiptKind string
+
+const (
+	ReceiptDeposit ReceiptKind = "deposit"
+	ReceiptSale    ReceiptKind = "sale"
+	ReceiptRefund  ReceiptKind = "refund"
+)
+
+type FiscalStatus string
+
+const (
+	FiscalPending    FiscalStatus = "pending"
+	FiscalRegistered FiscalStatus = "registered"
+)
+
+// ReceiptItem - строка чека
+type ReceiptItem struct {
+	Name     string      `json:"name"`
+	Quantity int64       `json:"quantity"`
+	Price    money.Money `json:"price"`
+	Total    money.Money `json:"total"`
+}
+
+// Receipt - чек по денежной транзакции. Номер выдается по порядку,
+// фискальный признак заполняется после регистрации чека
+type Receipt struct {
+	ID            int64         `json:"id" gorm:"primaryKey"`
+	Number        string        `json:"number" gorm:"uniqueIndex"`
+	UserID        int64         `json:"user_id" gorm:"index"`
+	TransactionID int64         `json:"transaction_id" gorm:"uniqueIndex"`
+	Kind          ReceiptKind   `json:"kind"`
+	Items         []ReceiptItem `json:"items" gorm:"serializer:json"`
+	Total         money.Money   `json:"total" gorm:"embedded;embeddedPrefix:total_"`
+	PaidByBonus   money.Money   `json:"paid_by_bonus" gorm:"embedded;embeddedPrefix:paid_by_bonus_"`
+	PaymentMethod PaymentMethod `json:"payment_method,omitempty"`
+	FiscalStatus  FiscalStatus  `json:"fiscal_status" gorm:"index;default:pending"`
+	FiscalSign    string        `json:"fiscal_sign,omitempty"`
+	FiscalNumber  string        `json:"fiscal_number,omitempty"`
+	FiscalizedAt  *time.Time    `json:"fiscalized_at,omitempty"`
+	CreatedAt     time.Time     `json:"created_at"`
+}
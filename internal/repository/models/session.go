@@ -9,13 +9,15 @@ const (
 	Finished SessionStatus = "finished"
 )
 
-// Session - модель сессии компьютера
+// Session - модель сессии компьютера. TransactionID - оплата сессии, по ней сессия
+// завершается при сторно
 type Session struct {
-	ID        int64         `json:"id"`
-	UserID    int64         `json:"user_id"`
-	PCNumber  int           `json:"pc_number"`
-	TariffID  int64         `json:"tariff_id"`
-	Status    SessionStatus `json:"status"`
-	StartTime time.Time     `json:"start_time"`
-	EndTime   *time.Time    `json:"end_time,omitempty"`
+	ID            int64         `json:"id"`
+	UserID        int64         `json:"user_id"`
+	PCNumber      int           `json:"pc_number"`
+	TariffID      int64         `json:"tariff_id"`
+	TransactionID *int64        `json:"transaction_id,omitempty" gorm:"index"`
+	Status        SessionStatus `json:"status"`
+	StartTime     time.Time     `json:"start_time"`
+	EndTime       *time.Time    `json:"end_time,omitempty"`
 }
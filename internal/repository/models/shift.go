@@ -0,0 +1,69 @@
+package models
+
+import (
+	"computer-club/pkg/money"
+	"time"
+)
+
+type ShiftStatus string
+
+const (
+	ShiftOpen   ShiftStatus = "open"
+	ShiftClosed ShiftStatus = "closed"
+)
+
+type ShiftReportType string
+
+const (
+	// ReportX - промежуточный отчет по открытой смене, ReportZ - итоговый отчет при закрытии
+	ReportX ShiftReportType = "x"
+	ReportZ ShiftReportType = "z"
+)
+
+// Shift - кассовая смена. Одновременно может быть открыта только одна смена
+type Shift struct {
+	ID           int64       `json:"id" gorm:"primaryKey"`
+	Status       ShiftStatus `json:"status" gorm:"uniqueIndex:idx_shifts_open,where:status = 'open'"`
+	OpenedBy     int64       `json:"opened_by"`
+	ClosedBy     *int64      `json:"closed_by,omitempty"`
+	OpeningFloat money.Money `json:"opening_float" gorm:"embedded;embeddedPrefix:opening_float_"`
+	ExpectedCash money.Money `json:"expected_cash" gorm:"embedded;embeddedPrefix:expected_cash_"`
+	CountedCash  money.Money `json:"counted_cash" gorm:"embedded;embeddedPrefix:counted_cash_"`
+	Discrepancy  money.Money `json:"discrepancy" gorm:"embedded;embeddedPrefix:discrepancy_"`
+	Flagged      bool        `json:"flagged"`
+	Note         string      `json:"note,omitempty"`
+	OpenedAt     time.Time   `json:"opened_at"`
+	ClosedAt     *time.Time  `json:"closed_at,omitempty"`
+}
+
+// ShiftLine - сумма транзакций одного типа и способа оплаты за смену
+type ShiftLine struct {
+	Type          TransactionType `json:"type"`
+	PaymentMethod PaymentMethod   `json:"payment_method"`
+	Count         int64           `json:"count"`
+	Amount        money.Money     `json:"amount"`
+}
+
+// MethodTotals - пополнения и возвраты по одному способу оплаты
+type MethodTotals struct {
+	PaymentMethod PaymentMethod `json:"payment_method"`
+	Deposits      money.Money   `json:"deposits"`
+	Refunds       money.Money   `json:"refunds"`
+}
+
+// ShiftReport - X- или Z-отчет по смене. Flagged выставляется, если пересчитанная наличность
+// расходится с ожидаемой больше допустимого
+type ShiftReport struct {
+	Type         ShiftReportType `json:"type"`
+	Shift        *Shift          `json:"shift"`
+	From         time.Time       `json:"from"`
+	To           time.Time       `json:"to"`
+	Methods      []MethodTotals  `json:"methods"`
+	Sales        money.Money     `json:"sales"`
+	BonusSales   money.Money     `json:"bonus_sales"`
+	Lines        []ShiftLine     `json:"lines"`
+	ExpectedCash money.Money     `json:"expected_cash"`
+	CountedCash  *money.Money    `json:"counted_cash,omitempty"`
+	Discrepancy  *money.Money    `json:"discrepancy,omitempty"`
+	Flagged      bool            `json:"flagged"`
+}
@@ -1,11 +1,14 @@
 package models
 
-import "time"
+import (
+	"computer-club/pkg/money"
+	"time"
+)
 
 type Tariff struct {
-	ID        int64     `json:"id" gorm:"primaryKey"`
-	Name      string    `json:"name"`
-	Price     float64   `json:"price"`
-	Duration  int64     `json:"duration"`
-	CreatedAt time.Time `json:"created_at"`
+	ID        int64       `json:"id" gorm:"primaryKey"`
+	Name      string      `json:"name"`
+	Price     money.Money `json:"price" gorm:"embedded;embeddedPrefix:price_"`
+	Duration  int64       `json:"duration"`
+	CreatedAt time.Time   `json:"created_at"`
 }
@@ -0,0 +1,67 @@
+package models
+
+import "time"
+
+// RefreshToken - долгоживущий токен для выпуска новых access-токенов. В базе хранится только
+// хеш токена. При обновлении токен отзывается, ReplacedByID указывает на выданный взамен
+type RefreshToken struct {
+	ID           int64      `json:"id" gorm:"primaryKey"`
+	UserID       int64      `json:"user_id" gorm:"index"`
+	TokenHash    string     `json:"-" gorm:"uniqueIndex"`
+	ExpiresAt    time.Time  `json:"expires_at"`
+	RevokedAt    *time.Time `json:"revoked_at,omitempty"`
+	ReplacedByID *int64     `json:"replaced_by_id,omitempty"`
+	// TwoFactor - вход подтвержден кодом 2FA, признак переходит к токенам, выданным взамен
+	TwoFactor bool      `json:"two_factor"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Active сообщает, можно ли обменять токен на новую пару
+func (t *RefreshToken) Active(now time.Time) bool {
+	return t.RevokedAt == nil && now.Before(t.ExpiresAt)
+}
+
+// TokenPair - ответ на вход и обновление токенов. Token - access-токен для заголовка Authorization
+type TokenPair struct {
+	Token            string    `json:"token"`
+	ExpiresAt        time.Time `json:"expires_at"`
+	RefreshToken     string    `json:"refresh_token"`
+	RefreshExpiresAt time.Time `json:"refresh_expires_at"`
+}
+
+// AccessClaims - проверенные поля access-токена, которые AuthMiddleware кладет в контекст запроса
+type AccessClaims struct {
+	UserID    int64
+	Role      string
+	JTI       string
+	IssuedAt  time.Time
+	ExpiresAt time.Time
+	TwoFactor bool
+}
+
+// TokenPurpose - назначение одноразового токена из письма
+type TokenPurpose string
+
+const (
+	PurposeVerifyEmail   TokenPurpose = "verify_email"
+	PurposeResetPassword TokenPurpose = "reset_password"
+	// PurposeLoginChallenge - вход после проверки пароля, ожидающий код 2FA
+	PurposeLoginChallenge TokenPurpose = "login_challenge"
+)
+
+// UserToken - одноразовый токен подтверждения почты или сброса пароля. Как и у refresh-токена,
+// в базе хранится только хеш. После использования проставляется UsedAt
+type UserToken struct {
+	ID        int64        `json:"id" gorm:"primaryKey"`
+	UserID    int64        `json:"user_id" gorm:"index"`
+	Purpose   TokenPurpose `json:"purpose" gorm:"index"`
+	TokenHash string       `json:"-" gorm:"uniqueIndex"`
+	ExpiresAt time.Time    `json:"expires_at"`
+	UsedAt    *time.Time   `json:"used_at,omitempty"`
+	CreatedAt time.Time    `json:"created_at"`
+}
+
+// Usable сообщает, можно ли еще воспользоваться токеном
+func (t *UserToken) Usable(now time.Time) bool {
+	return t.UsedAt == nil && now.Before(t.ExpiresAt)
+}
@@ -1,19 +1,96 @@
 package models
 
-import "time"
+import (
+	"computer-club/pkg/money"
+	"time"
+)
 
 type TransactionType string
 
 const (
 	Add TransactionType = "add"
 	Buy TransactionType = "buy"
+	// PackagePurchase - покупка пакета минут или абонемента
+	PackagePurchase TransactionType = "package"
+	// TransferOut и TransferIn - списание и зачисление при переводе между игроками
+	TransferOut TransactionType = "transfer_out"
+	TransferIn  TransactionType = "transfer_in"
+	// BonusCredit и BonusExpire - начисление и сгорание бонусов
+	BonusCredit TransactionType = "bonus"
+	BonusExpire TransactionType = "bonus_expire"
+	// Refund - возврат онлайн-платежа, списывает сумму с кошелька
+	Refund TransactionType = "refund"
+	// Reversal - сторно ошибочной транзакции администратором, ReversalOf указывает на исходную
+	Reversal TransactionType = "reversal"
+	// VoucherRedeem - зачисление номинала подарочной карты
+	VoucherRedeem TransactionType = "voucher"
+	// VoucherSale - продажа подарочной карты на кассе, покупатель карты не привязан к пользователю
+	VoucherSale TransactionType = "voucher_sale"
 )
 
+// PaymentMethod - способ, которым клиент внес деньги на кошелек
+type PaymentMethod string
+
+const (
+	PaymentCash     PaymentMethod = "cash"
+	PaymentCard     PaymentMethod = "card"
+	PaymentTransfer PaymentMethod = "transfer"
+	// PaymentOnline - пополнение клиентом через платежного провайдера, администратор его не выбирает
+	PaymentOnline PaymentMethod = "online"
+)
+
+// Valid проверяет способ оплаты, который администратор указывает при пополнении
+func (m PaymentMethod) Valid() bool {
+	switch m {
+	case PaymentCash, PaymentCard, PaymentTransfer:
+		return true
+	}
+	return false
+}
+
 type Transaction struct {
-	ID        int64           `json:"id" gorm:"primaryKey"`
-	UserID    int64           `json:"user_id" gorm:"index"`
-	Amount    float64         `json:"amount"`
-	TariffID  int64           `json:"tariff_id"`
-	Type      TransactionType `json:"type"`
-	CreatedAt time.Time       `json:"created_at" gorm:"index"`
+	ID             int64           `json:"id" gorm:"primaryKey"`
+	UserID         int64           `json:"user_id" gorm:"index"`
+	Amount         money.Money     `json:"amount" gorm:"embedded;embeddedPrefix:amount_"`
+	Discount       money.Money     `json:"discount" gorm:"embedded;embeddedPrefix:discount_"`
+	Balance        BalanceKind     `json:"balance" gorm:"default:real"`
+	BonusAmount    money.Money     `json:"bonus_amount" gorm:"embedded;embeddedPrefix:bonus_"`
+	PromotionID    *int64          `json:"promotion_id,omitempty"`
+	PackageID      *int64          `json:"package_id,omitempty"`
+	PackageMinutes int64           `json:"package_minutes"`
+	TransferID     *int64          `json:"transfer_id,omitempty" gorm:"index"`
+	TariffID       int64           `json:"tariff_id"`
+	AdminID        *int64          `json:"admin_id,omitempty"`
+	PaymentMethod  PaymentMethod   `json:"payment_method,omitempty"`
+	ShiftID        *int64          `json:"shift_id,omitempty" gorm:"index"`
+	ReversalOf     *int64          `json:"reversal_of,omitempty" gorm:"uniqueIndex"`
+	OrganizationID *int64          `json:"organization_id,omitempty" gorm:"index"`
+	ReversedBy     *int64          `json:"reversed_by,omitempty"`
+	Reason         string          `json:"reason,omitempty"`
+	Type           TransactionType `json:"type"`
+	CreatedAt      time.Time       `json:"created_at" gorm:"index"`
+}
+
+// Reversed сообщает, сторнирована ли транзакция
+func (t *Transaction) Reversed() bool {
+	return t.ReversedBy != nil
+}
+
+// TransactionFilter - условия выборки истории транзакций. Пустые поля не ограничивают выборку.
+// Cursor - ID последней транзакции предыдущей страницы, страницы идут от новых к старым
+type TransactionFilter struct {
+	UserID    *int64
+	Types     []TransactionType
+	From      *time.Time
+	To        *time.Time
+	MinAmount *money.Money
+	MaxAmount *money.Money
+	Cursor    int64
+	Limit     int
+}
+
+// TransactionPage - страница истории. NextCursor пустой, если страница последняя
+type TransactionPage struct {
+	Transactions []Transaction `json:"transactions"`
+	NextCursor   *int64        `json:"next_cursor,omitempty"`
 }
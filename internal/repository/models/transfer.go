@@ -0,0 +1,27 @@
+package models
+
+import (
+	"computer-club/pkg/money"
+	"time"
+)
+
+type TransferStatus string
+
+const (
+	TransferCompleted TransferStatus = "completed"
+	TransferReversed  TransferStatus = "reversed"
+)
+
+// Transfer - перевод баланса от одного игрока другому.
+// Каждый перевод связан с парой транзакций transfer_out и transfer_in
+type Transfer struct {
+	ID         int64          `json:"id" gorm:"primaryKey"`
+	FromUserID int64          `json:"from_user_id" gorm:"index"`
+	ToUserID   int64          `json:"to_user_id" gorm:"index"`
+	Amount     money.Money    `json:"amount" gorm:"embedded;embeddedPrefix:amount_"`
+	Comment    string         `json:"comment,omitempty"`
+	Status     TransferStatus `json:"status" gorm:"default:completed"`
+	ReversedBy *int64         `json:"reversed_by,omitempty"`
+	ReversedAt *time.Time     `json:"reversed_at,omitempty"`
+	CreatedAt  time.Time      `json:"created_at" gorm:"index"`
+}
@@ -0,0 +1,49 @@
+package models
+
+import "time"
+
+// TwoFactor - настройка TOTP пользователя. Пока EnabledAt пуст, секрет только выдан и ждет
+// подтверждения кодом. LastStep - последний принятый шаг, код с этим шагом повторно не принимается
+type TwoFactor struct {
+	UserID    int64      `json:"user_id" gorm:"primaryKey"`
+	Secret    string     `json:"-"`
+	EnabledAt *time.Time `json:"enabled_at,omitempty"`
+	LastStep  int64      `json:"-"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// Enabled сообщает, подтверждена ли двухфакторная аутентификация
+func (t *TwoFactor) Enabled() bool {
+	return t.EnabledAt != nil
+}
+
+// BackupCode - резервный код входа на случай потери телефона. Хранится хеш, код одноразовый
+type BackupCode struct {
+	ID       int64      `json:"id" gorm:"primaryKey"`
+	UserID   int64      `json:"user_id" gorm:"index"`
+	CodeHash string     `json:"-" gorm:"uniqueIndex"`
+	UsedAt   *time.Time `json:"used_at,omitempty"`
+}
+
+// TwoFactorStatus - состояние 2FA для пользователя. Required - роль дает доступ к деньгам,
+// и без 2FA такие действия запрещены
+type TwoFactorStatus struct {
+	Enabled         bool       `json:"enabled"`
+	Required        bool       `json:"required"`
+	EnabledAt       *time.Time `json:"enabled_at,omitempty"`
+	BackupCodesLeft int64      `json:"backup_codes_left"`
+}
+
+// TwoFactorEnrollment - секрет для приложения-аутентификатора. URI клиент показывает QR-кодом
+type TwoFactorEnrollment struct {
+	Secret string `json:"secret"`
+	URI    string `json:"otpauth_uri"`
+}
+
+// LoginResult - ответ на вход. При включенной 2FA токены не выдаются: вместо них возвращается
+// ChallengeToken, который вместе с кодом передается в POST /login/2fa
+type LoginResult struct {
+	*TokenPair
+	TwoFactorRequired bool   `json:"two_factor_required,omitempty"`
+	ChallengeToken    string `json:"challenge_token,omitempty"`
+}
@@ -1,18 +1,68 @@
 package models
 
+import (
+	"gorm.io/gorm"
+	"time"
+)
+
 // UserRole - роли пользователей
 type UserRole string
 
 const (
 	Admin    UserRole = "admin"
 	Customer UserRole = "customer"
+	// Cashier, Manager и Owner - персонал клуба, права ролей описаны в RolePermissions
+	Cashier UserRole = "cashier"
+	Manager UserRole = "manager"
+	Owner   UserRole = "owner"
 )
 
-// User - модель пользователя
+// User - модель пользователя. Заблокированный пользователь не может войти и начать сессию,
+// удаленный (DeletedAt) скрыт из всех выборок
 type User struct {
 	ID       int64  `json:"id"`
 	Name     string `json:"name"`
 	Role     string `json:"role"`
 	Email    string `json:"email"`
+	Phone    string `json:"phone,omitempty" gorm:"index"`
 	Password string `json:"-"`
+	// EmailVerifiedAt - когда пользователь подтвердил почту по ссылке из письма
+	EmailVerifiedAt *time.Time     `json:"email_verified_at,omitempty"`
+	BlockedAt       *time.Time     `json:"blocked_at,omitempty"`
+	BlockedBy       *int64         `json:"blocked_by,omitempty"`
+	BlockReason     string         `json:"block_reason,omitempty"`
+	DeletedAt       gorm.DeletedAt `json:"-" gorm:"index"`
+}
+
+// EmailVerified сообщает, подтверждена ли почта
+func (u *User) EmailVerified() bool {
+	return u.EmailVerifiedAt != nil
+}
+
+// Blocked сообщает, заблокирован ли пользователь
+func (u *User) Blocked() bool {
+	return u.BlockedAt != nil
+}
+
+// UserFilter - поиск пользователей. Query ищется подстрокой в имени, почте и телефоне.
+// Cursor - ID последнего пользователя предыдущей страницы, страницы идут от новых к старым
+type UserFilter struct {
+	Query   string
+	Role    UserRole
+	Blocked *bool
+	Cursor  int64
+	Limit   int
+}
+
+// UserPage - страница поиска пользователей. NextCursor пустой, если страница последняя
+type UserPage struct {
+	Users      []User `json:"users"`
+	NextCursor *int64 `json:"next_cursor,omitempty"`
+}
+
+// UserProfile - изменяемые администратором поля профиля, пустые указатели не меняются
+type UserProfile struct {
+	Name  *string `json:"name"`
+	Email *string `json:"email"`
+	Phone *string `json:"phone"`
 }
@@ -0,0 +1,73 @@
+package models
+
+import (
+	"computer-club/pkg/money"
+	"time"
+)
+
+type VoucherType string
+
+const (
+	// VoucherValue - подарочная карта на сумму, зачисляется на кошелек
+	VoucherValue VoucherType = "value"
+	// VoucherMinutes - подарочная карта на минуты, превращается в пакет минут
+	VoucherMinutes VoucherType = "minutes"
+)
+
+type VoucherStatus string
+
+const (
+	VoucherIssued   VoucherStatus = "issued"
+	VoucherSold     VoucherStatus = "sold"
+	VoucherRedeemed VoucherStatus = "redeemed"
+	VoucherExpired  VoucherStatus = "expired"
+)
+
+// VoucherBatch - партия подарочных карт одного номинала. Price - цена продажи карты,
+// у карты на сумму она равна номиналу
+type VoucherBatch struct {
+	ID        int64       `json:"id" gorm:"primaryKey"`
+	Name      string      `json:"name"`
+	Type      VoucherType `json:"type"`
+	Amount    money.Money `json:"amount" gorm:"embedded;embeddedPrefix:amount_"`
+	Minutes   int64       `json:"minutes"`
+	Price     money.Money `json:"price" gorm:"embedded;embeddedPrefix:price_"`
+	Count     int         `json:"count"`
+	ExpiresAt time.Time   `json:"expires_at"`
+	CreatedBy int64       `json:"created_by"`
+	CreatedAt time.Time   `json:"created_at"`
+}
+
+// Voucher - подарочная карта. Погасить можно только проданную карту до истечения срока
+type Voucher struct {
+	ID            int64         `json:"id" gorm:"primaryKey"`
+	BatchID       int64         `json:"batch_id" gorm:"index"`
+	Code          string        `json:"code" gorm:"uniqueIndex"`
+	Type          VoucherType   `json:"type"`
+	Amount        money.Money   `json:"amount" gorm:"embedded;embeddedPrefix:amount_"`
+	Minutes       int64         `json:"minutes"`
+	Price         money.Money   `json:"price" gorm:"embedded;embeddedPrefix:price_"`
+	Status        VoucherStatus `json:"status" gorm:"index"`
+	SoldBy        *int64        `json:"sold_by,omitempty"`
+	SoldAt        *time.Time    `json:"sold_at,omitempty"`
+	SaleID        *int64        `json:"sale_id,omitempty"`
+	RedeemedBy    *int64        `json:"redeemed_by,omitempty"`
+	RedeemedAt    *time.Time    `json:"redeemed_at,omitempty"`
+	TransactionID *int64        `json:"transaction_id,omitempty"`
+	UserPackageID *int64        `json:"user_package_id,omitempty"`
+	ExpiresAt     time.Time     `json:"expires_at" gorm:"index"`
+	CreatedAt     time.Time     `json:"created_at"`
+}
+
+// VoucherBatchDetails - партия вместе с картами
+type VoucherBatchDetails struct {
+	Batch    *VoucherBatch `json:"batch"`
+	Vouchers []Voucher     `json:"vouchers"`
+}
+
+// VoucherRedemption - результат погашения: транзакция пополнения или пакет минут
+type VoucherRedemption struct {
+	Voucher     *Voucher     `json:"voucher"`
+	Transaction *Transaction `json:"transaction,omitempty"`
+	UserPackage *UserPackage `json:"user_package,omitempty"`
+}
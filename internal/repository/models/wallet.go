@@ -1,7 +1,27 @@
 package models
 
+import "computer-club/pkg/money"
+
+// BalanceKind - какой баланс кошелька изменила транзакция
+type BalanceKind string
+
+const (
+	BalanceReal  BalanceKind = "real"
+	BalanceBonus BalanceKind = "bonus"
+	// BalanceMixed - оплата частично реальными деньгами, частично бонусами
+	BalanceMixed BalanceKind = "mixed"
+)
+
+// Wallet хранит реальные деньги и бонусы раздельно. Бонусы нельзя вывести или перевести,
+// ими можно только оплатить сессию или пакет
 type Wallet struct {
-	ID      int64   `json:"id"`
-	UserID  int64   `json:"user_id"`
-	Balance float64 `json:"balance"`
+	ID      int64       `json:"id"`
+	UserID  int64       `json:"user_id"`
+	Balance money.Money `json:"balance" gorm:"embedded;embeddedPrefix:balance_"`
+	Bonus   money.Money `json:"bonus" gorm:"embedded;embeddedPrefix:bonus_"`
+}
+
+// Spendable возвращает сумму, которой можно оплатить сессию или пакет
+func (w *Wallet) Spendable() money.Money {
+	return w.Balance.Add(w.Bonus)
 }
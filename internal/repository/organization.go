@@ -0,0 +1,258 @@
+package repository
+
+import (
+	"computer-club/internal/repository/models"
+	"computer-club/pkg/errors"
+	"computer-club/pkg/money"
+	"context"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+type OrganizationRepository interface {
+	CreateOrganization(tx *gorm.DB, organization *models.Organization) error
+	GetOrganization(ctx context.Context, id int64) (*models.Organization, error)
+	GetOrganizationForUpdate(tx *gorm.DB, id int64) (*models.Organization, error)
+	GetOrganizationIDs(ctx context.Context) ([]int64, error)
+	Deposit(tx *gorm.DB, id int64, amount money.Money) error
+	Withdraw(tx *gorm.DB, id int64, amount money.Money) error
+	AddMember(tx *gorm.DB, member *models.OrganizationMember) error
+	GetMember(ctx context.Context, organizationID, userID int64) (*models.OrganizationMember, error)
+	GetMembers(ctx context.Context, organizationID int64) ([]models.OrganizationMember, error)
+	UpdateMemberCap(ctx context.Context, organizationID, userID int64, cap money.Money) error
+	RemoveMember(ctx context.Context, organizationID, userID int64) error
+	SumMemberSpend(tx *gorm.DB, organizationID, userID int64, since time.Time) (money.Money, error)
+	SummarizeUsage(ctx context.Context, organizationID int64, from, to time.Time) ([]models.InvoiceLine, error)
+	SaveInvoice(ctx context.Context, invoice *models.OrganizationInvoice) error
+	GetInvoice(ctx context.Context, organizationID int64, period string) (*models.OrganizationInvoice, error)
+	GetInvoices(ctx context.Context, organizationID int64) ([]models.OrganizationInvoice, error)
+}
+
+type PostgresOrganizationRepo struct {
+	db *gorm.DB
+}
+
+func NewPostgresOrganizationRepo(db *gorm.DB) OrganizationRepository {
+	return &PostgresOrganizationRepo{db: db}
+}
+
+func (r *PostgresOrganizationRepo) CreateOrganization(tx *gorm.DB, organization *models.Organization) error {
+	if tx == nil {
+		tx = r.db
+	}
+	if err := tx.Create(organization).Error; err != nil {
+		return errors.ErrCreateOrganization
+	}
+	return nil
+}
+
+func (r *PostgresOrganizationRepo) GetOrganization(ctx context.Context, id int64) (*models.Organization, error) {
+	var organization models.Organization
+	if err := r.db.WithContext(ctx).First(&organization, id).Error; err != nil {
+		return nil, errors.ErrOrganizationNotFound
+	}
+	return &organization, nil
+}
+
+func (r *PostgresOrganizationRepo) GetOrganizationForUpdate(tx *gorm.DB, id int64) (*models.Organization, error) {
+	if tx == nil {
+		tx = r.db
+	}
+	var organization models.Organization
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&organization, id).Error; err != nil {
+		return nil, errors.ErrOrganizationNotFound
+	}
+	return &organization, nil
+}
+
+func (r *PostgresOrganizationRepo) GetOrganizationIDs(ctx context.Context) ([]int64, error) {
+	var ids []int64
+	if err := r.db.WithContext(ctx).Model(&models.Organization{}).Order("id").Pluck("id", &ids).Error; err != nil {
+		return nil, errors.ErrFindOrganization
+	}
+	return ids, nil
+}
+
+func (r *PostgresOrganizationRepo) Deposit(tx *gorm.DB, id int64, amount money.Money) error {
+	if tx == nil {
+		tx = r.db
+	}
+	result := tx.Model(&models.Organization{}).
+		Where("id = ?", id).
+		Update("balance_amount", gorm.Expr("balance_amount + ?", amount.Amount))
+	if result.Error != nil {
+		return errors.ErrUpdateOrganization
+	}
+	if result.RowsAffected == 0 {
+		return errors.ErrOrganizationNotFound
+	}
+	return nil
+}
+
+func (r *PostgresOrganizationRepo) Withdraw(tx *gorm.DB, id int64, amount money.Money) error {
+	if tx == nil {
+		tx = r.db
+	}
+	result := tx.Model(&models.Organization{}).
+		Where("id = ? AND balance_amount >= ?", id, amount.Amount).
+		Update("balance_amount", gorm.Expr("balance_amount - ?", amount.Amount))
+	if result.Error != nil {
+		return errors.ErrUpdateOrganization
+	}
+	if result.RowsAffected == 0 {
+		return errors.ErrInsufficientFunds
+	}
+	return nil
+}
+
+func (r *PostgresOrganizationRepo) AddMember(tx *gorm.DB, member *models.OrganizationMember) error {
+	if tx == nil {
+		tx = r.db
+	}
+	result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(member)
+	if result.Error != nil {
+		return errors.ErrUpdateOrganization
+	}
+	if result.RowsAffected == 0 {
+		return errors.ErrAlreadyMember
+	}
+	return nil
+}
+
+func (r *PostgresOrganizationRepo) GetMember(ctx context.Context, organizationID, userID int64) (*models.OrganizationMember, error) {
+	var member models.OrganizationMember
+	err := r.db.WithContext(ctx).
+		Where("organization_id = ? AND user_id = ?", organizationID, userID).
+		First(&member).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, errors.ErrNotOrganizationMember
+	}
+	if err != nil {
+		return nil, errors.ErrFindOrganization
+	}
+	return &member, nil
+}
+
+func (r *PostgresOrganizationRepo) GetMembers(ctx context.Context, organizationID int64) ([]models.OrganizationMember, error) {
+	var members []models.OrganizationMember
+	err := r.db.WithContext(ctx).Where("organization_id = ?", organizationID).Order("id").Find(&members).Error
+	if err != nil {
+		return nil, errors.ErrFindOrganization
+	}
+	return members, nil
+}
+
+func (r *PostgresOrganizationRepo) UpdateMemberCap(ctx context.Context, organizationID, userID int64, cap money.Money) error {
+	result := r.db.WithContext(ctx).Model(&models.OrganizationMember{}).
+		Where("organization_id = ? AND user_id = ?", organizationID, userID).
+		Updates(map[string]interface{}{
+			"monthly_cap_amount":   cap.Amount,
+			"monthly_cap_currency": cap.Currency,
+		})
+	if result.Error != nil {
+		return errors.ErrUpdateOrganization
+	}
+	if result.RowsAffected == 0 {
+		return errors.ErrNotOrganizationMember
+	}
+	return nil
+}
+
+func (r *PostgresOrganizationRepo) RemoveMember(ctx context.Context, organizationID, userID int64) error {
+	result := r.db.WithContext(ctx).
+		Where("organization_id = ? AND user_id = ?", organizationID, userID).
+		Delete(&models.OrganizationMember{})
+	if result.Error != nil {
+		return errors.ErrUpdateOrganization
+	}
+	if result.RowsAffected == 0 {
+		return errors.ErrNotOrganizationMember
+	}
+	return nil
+}
+
+// SumMemberSpend возвращает сумму оплат участника за счет организации с since
+func (r *PostgresOrganizationRepo) SumMemberSpend(tx *gorm.DB, organizationID, userID int64, since time.Time) (money.Money, error) {
+	if tx == nil {
+		tx = r.db
+	}
+	var total int64
+	err := tx.Model(&models.Transaction{}).
+		Where("organization_id = ? AND user_id = ? AND type = ? AND created_at >= ?", organizationID, userID, models.Buy, since).
+		Select("COALESCE(SUM(amount_amount), 0)").
+		Scan(&total).Error
+	if err != nil {
+		return money.Money{}, errors.ErrFindOrganization
+	}
+	return money.New(total), nil
+}
+
+// SummarizeUsage группирует оплаты сессий за счет организации по участникам.
+// Минуты берутся из длительности тарифа каждой оплаченной сессии
+func (r *PostgresOrganizationRepo) SummarizeUsage(ctx context.Context, organizationID int64, from, to time.Time) ([]models.InvoiceLine, error) {
+	var rows []struct {
+		UserID   int64
+		Sessions int64
+		Minutes  int64
+		Amount   int64
+	}
+	err := r.db.WithContext(ctx).Table("transactions").
+		Select("transactions.user_id, COUNT(*) AS sessions, COALESCE(SUM(tariffs.duration), 0) AS minutes, "+
+			"COALESCE(SUM(transactions.amount_amount), 0) AS amount").
+		Joins("LEFT JOIN tariffs ON tariffs.id = transactions.tariff_id").
+		Where("transactions.organization_id = ? AND transactions.type = ?", organizationID, models.Buy).
+		Where("transactions.created_at >= ? AND transactions.created_at < ?", from, to).
+		Group("transactions.user_id").
+		Order("transactions.user_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, errors.ErrCreateInvoice
+	}
+
+	lines := make([]models.InvoiceLine, 0, len(rows))
+	for _, row := range rows {
+		lines = append(lines, models.InvoiceLine{
+			UserID:   row.UserID,
+			Sessions: row.Sessions,
+			Minutes:  row.Minutes,
+			Amount:   money.New(row.Amount),
+		})
+	}
+	return lines, nil
+}
+
+// SaveInvoice создает счет за период или пересчитывает существующий
+func (r *PostgresOrganizationRepo) SaveInvoice(ctx context.Context, invoice *models.OrganizationInvoice) error {
+	err := r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "organization_id"}, {Name: "period"}},
+		DoUpdates: clause.AssignmentColumns([]string{"period_from", "period_to", "lines", "total_amount", "total_currency", "created_at"}),
+	}).Create(invoice).Error
+	if err != nil {
+		return errors.ErrCreateInvoice
+	}
+	return nil
+}
+
+func (r *PostgresOrganizationRepo) GetInvoice(ctx context.Context, organizationID int64, period string) (*models.OrganizationInvoice, error) {
+	var invoice models.OrganizationInvoice
+	err := r.db.WithContext(ctx).
+		Where("organization_id = ? AND period = ?", organizationID, period).
+		First(&invoice).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, errors.ErrInvoiceNotFound
+	}
+	if err != nil {
+		return nil, errors.ErrFindOrganization
+	}
+	return &invoice, nil
+}
+
+func (r *PostgresOrganizationRepo) GetInvoices(ctx context.Context, organizationID int64) ([]models.OrganizationInvoice, error) {
+	var invoices []models.OrganizationInvoice
+	err := r.db.WithContext(ctx).Where("organization_id = ?", organizationID).Order("period DESC").Find(&invoices).Error
+	if err != nil {
+		return nil, errors.ErrFindOrganization
+	}
+	return invoices, nil
+}
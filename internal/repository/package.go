@@ -0,0 +1,146 @@
+package repository
+
+import (
+	"computer-club/internal/repository/models"
+	"computer-club/pkg/errors"
+	"context"
+	"gorm.io/gorm"
+	"time"
+)
+
+type PackageRepository interface {
+	CreatePackage(ctx context.Context, pack *models.Package) error
+	GetPackages(ctx context.Context) ([]models.Package, error)
+	GetPackageByID(ctx context.Context, id int64) (*models.Package, error)
+	CreateUserPackage(tx *gorm.DB, userPackage *models.UserPackage) error
+	GetActiveUserPackages(ctx context.Context, userID int64, now time.Time) ([]models.UserPackage, error)
+	ConsumeMinutes(tx *gorm.DB, userPackageID, transactionID int64, minutes int64) error
+	RestoreMinutes(tx *gorm.DB, transactionID int64) error
+	ExpireUserPackages(ctx context.Context, now time.Time) (int64, error)
+}
+
+type PostgresPackageRepo struct {
+	db *gorm.DB
+}
+
+func NewPostgresPackageRepo(db *gorm.DB) PackageRepository {
+	return &PostgresPackageRepo{db: db}
+}
+
+func (r *PostgresPackageRepo) CreatePackage(ctx context.Context, pack *models.Package) error {
+	if err := r.db.WithContext(ctx).Create(pack).Error; err != nil {
+		return errors.ErrCreatePackage
+	}
+	return nil
+}
+
+func (r *PostgresPackageRepo) GetPackages(ctx context.Context) ([]models.Package, error) {
+	var packages []models.Package
+	if err := r.db.WithContext(ctx).Where("active = ?", true).Order("id").Find(&packages).Error; err != nil {
+		return nil, errors.ErrFindPackage
+	}
+	return packages, nil
+}
+
+func (r *PostgresPackageRepo) GetPackageByID(ctx context.Context, id int64) (*models.Package, error) {
+	var pack models.Package
+	if err := r.db.WithContext(ctx).First(&pack, id).Error; err != nil {
+		return nil, errors.ErrPackageNotFound
+	}
+	return &pack, nil
+}
+
+func (r *PostgresPackageRepo) CreateUserPackage(tx *gorm.DB, userPackage *models.UserPackage) error {
+	if tx == nil {
+		tx = r.db
+	}
+	if err := tx.Create(userPackage).Error; err != nil {
+		return errors.ErrCreateUserPackage
+	}
+	return nil
+}
+
+// GetActiveUserPackages возвращает действующие пакеты пользователя, первыми идут те, что истекают раньше
+func (r *PostgresPackageRepo) GetActiveUserPackages(ctx context.Context, userID int64, now time.Time) ([]models.UserPackage, error) {
+	var packages []models.UserPackage
+	err := r.db.WithContext(ctx).
+		Where("user_id = ? AND status = ? AND expires_at > ?", userID, models.PackageActive, now).
+		Order("expires_at").
+		Find(&packages).Error
+	if err != nil {
+		return nil, errors.ErrFindPackage
+	}
+	return packages, nil
+}
+
+// ConsumeMinutes списывает минуты с пакета и помечает его исчерпанным, когда минуты закончились.
+// Списание запоминается вместе с оплатой, чтобы вернуть минуты при ее сторно
+func (r *PostgresPackageRepo) ConsumeMinutes(tx *gorm.DB, userPackageID, transactionID int64, minutes int64) error {
+	if tx == nil {
+		tx = r.db
+	}
+	result := tx.
+		Model(&models.UserPackage{}).
+		Where("id = ? AND status = ? AND minutes_left >= ?", userPackageID, models.PackageActive, minutes).
+		Updates(map[string]interface{}{
+			"minutes_left": gorm.Expr("minutes_left - ?", minutes),
+			"status": gorm.Expr("CASE WHEN minutes_left - ? <= 0 THEN ? ELSE status END",
+				minutes, models.PackageExhausted),
+		})
+	if result.Error != nil {
+		return errors.ErrConsumePackage
+	}
+	if result.RowsAffected == 0 {
+		return errors.ErrPackageExhausted
+	}
+
+	usage := models.PackageUsage{
+		UserPackageID: userPackageID,
+		TransactionID: transactionID,
+		Minutes:       minutes,
+	}
+	if err := tx.Create(&usage).Error; err != nil {
+		return errors.ErrConsumePackage
+	}
+	return nil
+}
+
+// RestoreMinutes возвращает в пакеты минуты, списанные при оплате. Исчерпанный пакет снова
+// становится активным, истекший остается истекшим
+func (r *PostgresPackageRepo) RestoreMinutes(tx *gorm.DB, transactionID int64) error {
+	if tx == nil {
+		tx = r.db
+	}
+
+	var usages []models.PackageUsage
+	if err := tx.Where("transaction_id = ?", transactionID).Find(&usages).Error; err != nil {
+		return errors.ErrFindPackage
+	}
+	for _, usage := range usages {
+		err := tx.Model(&models.UserPackage{}).
+			Where("id = ?", usage.UserPackageID).
+			Updates(map[string]interface{}{
+				"minutes_left": gorm.Expr("minutes_left + ?", usage.Minutes),
+				"status": gorm.Expr("CASE WHEN status = ? THEN ? ELSE status END",
+					models.PackageExhausted, models.PackageActive),
+			}).Error
+		if err != nil {
+			return errors.ErrConsumePackage
+		}
+	}
+	if err := tx.Where("transaction_id = ?", transactionID).Delete(&models.PackageUsage{}).Error; err != nil {
+		return errors.ErrConsumePackage
+	}
+	return nil
+}
+
+func (r *PostgresPackageRepo) ExpireUserPackages(ctx context.Context, now time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).
+		Model(&models.UserPackage{}).
+		Where("status = ? AND expires_at <= ?", models.PackageActive, now).
+		Update("status", models.PackageExpired)
+	if result.Error != nil {
+		return 0, errors.ErrExpirePackages
+	}
+	return result.RowsAffected, nil
+}
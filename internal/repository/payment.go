@@ -0,0 +1,63 @@
+package repository
+
+import (
+	"computer-club/internal/repository/models"
+	"computer-club/pkg/errors"
+	"context"
+	"gorm.io/gorm"
+)
+
+type PaymentRepository interface {
+	CreatePayment(ctx context.Context, payment *models.Payment) error
+	GetPaymentByID(ctx context.Context, id int64) (*models.Payment, error)
+	UpdatePayment(tx *gorm.DB, id int64, fields map[string]interface{}) error
+	TransitionStatus(tx *gorm.DB, id int64, from, to models.PaymentStatus) (bool, error)
+}
+
+type PostgresPaymentRepo struct {
+	db *gorm.DB
+}
+
+func NewPostgresPaymentRepo(db *gorm.DB) PaymentRepository {
+	return &PostgresPaymentRepo{db: db}
+}
+
+func (r *PostgresPaymentRepo) CreatePayment(ctx context.Context, payment *models.Payment) error {
+	if err := r.db.WithContext(ctx).Create(payment).Error; err != nil {
+		return errors.ErrCreatePayment
+	}
+	return nil
+}
+
+func (r *PostgresPaymentRepo) GetPaymentByID(ctx context.Context, id int64) (*models.Payment, error) {
+	var payment models.Payment
+	if err := r.db.WithContext(ctx).First(&payment, id).Error; err != nil {
+		return nil, errors.ErrPaymentNotFound
+	}
+	return &payment, nil
+}
+
+func (r *PostgresPaymentRepo) UpdatePayment(tx *gorm.DB, id int64, fields map[string]interface{}) error {
+	if tx == nil {
+		tx = r.db
+	}
+	if err := tx.Model(&models.Payment{}).Where("id = ?", id).Updates(fields).Error; err != nil {
+		return errors.ErrUpdatePayment
+	}
+	return nil
+}
+
+// TransitionStatus меняет статус, только если платеж находится в статусе from.
+// Возвращает false, если платеж уже обработан, поэтому повторное уведомление ничего не меняет
+func (r *PostgresPaymentRepo) TransitionStatus(tx *gorm.DB, id int64, from, to models.PaymentStatus) (bool, error) {
+	if tx == nil {
+		tx = r.db
+	}
+	result := tx.Model(&models.Payment{}).
+		Where("id = ? AND status = ?", id, from).
+		Update("status", to)
+	if result.Error != nil {
+		return false, errors.ErrUpdatePayment
+	}
+	return result.RowsAffected > 0, nil
+}
@@ -0,0 +1,133 @@
+package repository
+
+import (
+	"computer-club/internal/repository/models"
+	"computer-club/pkg/errors"
+	"computer-club/pkg/money"
+	"context"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"strings"
+)
+
+type PromotionRepository interface {
+	CreatePromotion(ctx context.Context, promotion *models.Promotion) error
+	GetPromotions(ctx context.Context) ([]models.Promotion, error)
+	GetPromotionByCode(ctx context.Context, code string) (*models.Promotion, error)
+	CountUserUsages(ctx context.Context, promotionID, userID int64) (int64, error)
+	RedeemPromotion(tx *gorm.DB, promotionID, userID, transactionID int64, discount money.Money) error
+	ReleasePromotion(tx *gorm.DB, transactionID int64) error
+}
+
+type PostgresPromotionRepo struct {
+	db *gorm.DB
+}
+
+func NewPostgresPromotionRepo(db *gorm.DB) PromotionRepository {
+	return &PostgresPromotionRepo{db: db}
+}
+
+func (r *PostgresPromotionRepo) CreatePromotion(ctx context.Context, promotion *models.Promotion) error {
+	promotion.Code = strings.ToUpper(promotion.Code)
+	if err := r.db.WithContext(ctx).Create(promotion).Error; err != nil {
+		return errors.ErrCreatePromotion
+	}
+	return nil
+}
+
+func (r *PostgresPromotionRepo) GetPromotions(ctx context.Context) ([]models.Promotion, error) {
+	var promotions []models.Promotion
+	if err := r.db.WithContext(ctx).Order("id").Find(&promotions).Error; err != nil {
+		return nil, errors.ErrFindPromotion
+	}
+	return promotions, nil
+}
+
+func (r *PostgresPromotionRepo) GetPromotionByCode(ctx context.Context, code string) (*models.Promotion, error) {
+	var promotion models.Promotion
+	if err := r.db.WithContext(ctx).Where("code = ?", strings.ToUpper(code)).First(&promotion).Error; err != nil {
+		return nil, errors.ErrPromotionNotFound
+	}
+	return &promotion, nil
+}
+
+func (r *PostgresPromotionRepo) CountUserUsages(ctx context.Context, promotionID, userID int64) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).
+		Model(&models.PromotionUsage{}).
+		Where("promotion_id = ? AND user_id = ?", promotionID, userID).
+		Count(&count).Error
+	if err != nil {
+		return 0, errors.ErrFindPromotion
+	}
+	return count, nil
+}
+
+// RedeemPromotion списывает использование промокода в транзакции оплаты. Строка промокода
+// блокируется, поэтому общий лимит и лимит на пользователя проверяются без гонок
+func (r *PostgresPromotionRepo) RedeemPromotion(tx *gorm.DB, promotionID, userID, transactionID int64, discount money.Money) error {
+	if tx == nil {
+		tx = r.db
+	}
+
+	var promotion models.Promotion
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&promotion, promotionID).Error; err != nil {
+		return errors.ErrPromotionNotFound
+	}
+	if promotion.MaxUses > 0 && promotion.UsedCount >= promotion.MaxUses {
+		return errors.ErrPromotionLimitReached
+	}
+	if promotion.MaxUsesPerUser > 0 {
+		var used int64
+		err := tx.Model(&models.PromotionUsage{}).
+			Where("promotion_id = ? AND user_id = ?", promotionID, userID).
+			Count(&used).Error
+		if err != nil {
+			return errors.ErrRedeemPromotion
+		}
+		if used >= promotion.MaxUsesPerUser {
+			return errors.ErrPromotionLimitReached
+		}
+	}
+
+	if err := tx.Model(&models.Promotion{}).
+		Where("id = ?", promotionID).
+		Update("used_count", gorm.Expr("used_count + 1")).Error; err != nil {
+		return errors.ErrRedeemPromotion
+	}
+
+	usage := models.PromotionUsage{
+		PromotionID:   promotionID,
+		UserID:        userID,
+		TransactionID: &transactionID,
+		Discount:      discount,
+	}
+	if err := tx.Create(&usage).Error; err != nil {
+		return errors.ErrRedeemPromotion
+	}
+	return nil
+}
+
+// ReleasePromotion отменяет использование промокода при сторно оплаты, чтобы оно не
+// расходовало лимиты промокода
+func (r *PostgresPromotionRepo) ReleasePromotion(tx *gorm.DB, transactionID int64) error {
+	if tx == nil {
+		tx = r.db
+	}
+
+	var usages []models.PromotionUsage
+	if err := tx.Where("transaction_id = ?", transactionID).Find(&usages).Error; err != nil {
+		return errors.ErrFindPromotion
+	}
+	for _, usage := range usages {
+		if err := tx.Model(&models.Promotion{}).
+			Where("id = ?", usage.PromotionID).
+			Update("used_count", gorm.Expr("GREATEST(used_count - 1, 0)")).Error; err != nil {
+			return errors.ErrRedeemPromotion
+		}
+		if err := tx.Delete(&usage).Error; err != nil {
+			return errors.ErrRedeemPromotion
+		}
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package repository
+
+import (
+	"computer-club/internal/repository/models"
+	"computer-club/pkg/errors"
+	"context"
+	"encoding/json"
+	"github.com/redis/go-redis/v9"
+	"time"
+)
+
+type QuoteRepository interface {
+	SaveQuote(ctx context.Context, quote *models.PriceQuote) error
+	GetQuote(ctx context.Context, quoteID string) (*models.PriceQuote, error)
+	DeleteQuote(ctx context.Context, quoteID string) error
+}
+
+type RedisQuoteRepo struct {
+	redis *redis.Client
+}
+
+func NewRedisQuoteRepo(redis *redis.Client) QuoteRepository {
+	return &RedisQuoteRepo{redis: redis}
+}
+
+// SaveQuote сохраняет котировку до момента ее истечения
+func (r *RedisQuoteRepo) SaveQuote(ctx context.Context, quote *models.PriceQuote) error {
+	quoteJSON, err := json.Marshal(quote)
+	if err != nil {
+		return errors.ErrSaveQuote
+	}
+	ttl := time.Until(quote.ExpiresAt)
+	if err := r.redis.Set(ctx, getQuoteKey(quote.ID), quoteJSON, ttl).Err(); err != nil {
+		return errors.ErrSaveQuote
+	}
+	return nil
+}
+
+func (r *RedisQuoteRepo) GetQuote(ctx context.Context, quoteID string) (*models.PriceQuote, error) {
+	quoteJSON, err := r.redis.Get(ctx, getQuoteKey(quoteID)).Result()
+	if err != nil {
+		return nil, errors.ErrQuoteNotFound
+	}
+	var quote models.PriceQuote
+	if err := json.Unmarshal([]byte(quoteJSON), &quote); err != nil {
+		return nil, errors.ErrQuoteNotFound
+	}
+	return &quote, nil
+}
+
+func (r *RedisQuoteRepo) DeleteQuote(ctx context.Context, quoteID string) error {
+	if err := r.redis.Del(ctx, getQuoteKey(quoteID)).Err(); err != nil {
+		return errors.ErrDeleteRedis
+	}
+	return nil
+}
+
+func getQuoteKey(quoteID string) string {
+	return "quote:" + quoteID
+}
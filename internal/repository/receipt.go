@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"computer-club/internal/repository/models"
+	"computer-club/pkg/errors"
+	"context"
+	"fmt"
+	"gorm.io/gorm"
+	"time"
+)
+
+type ReceiptRepository interface {
+	CreateReceipt(tx *gorm.DB, receipt *models.Receipt) error
+	GetReceiptByID(ctx context.Context, id int64) (*models.Receipt, error)
+	GetPendingReceipts(ctx context.Context, limit int) ([]models.Receipt, error)
+	SetFiscalData(ctx context.Context, id int64, status models.FiscalStatus, sign, number string, at time.Time) error
+}
+
+type PostgresReceiptRepo struct {
+	db *gorm.DB
+}
+
+func NewPostgresReceiptRepo(db *gorm.DB) ReceiptRepository {
+	return &PostgresReceiptRepo{db: db}
+}
+
+// CreateReceipt присваивает чеку следующий номер из последовательности receipt_number_seq.
+// Номер выдается в той же транзакции БД, что и сам платеж
+func (r *PostgresReceiptRepo) CreateReceipt(tx *gorm.DB, receipt *models.Receipt) error {
+	if tx == nil {
+		tx = r.db
+	}
+	var seq int64
+	if err := tx.Raw("SELECT nextval('receipt_number_seq')").Scan(&seq).Error; err != nil {
+		return errors.ErrCreateReceipt
+	}
+	receipt.Number = fmt.Sprintf("R-%08d", seq)
+	if err := tx.Create(receipt).Error; err != nil {
+		return errors.ErrCreateReceipt
+	}
+	return nil
+}
+
+func (r *PostgresReceiptRepo) GetReceiptByID(ctx context.Context, id int64) (*models.Receipt, error) {
+	var receipt models.Receipt
+	if err := r.db.WithContext(ctx).First(&receipt, id).Error; err != nil {
+		return nil, errors.ErrReceiptNotFound
+	}
+	return &receipt, nil
+}
+
+func (r *PostgresReceiptRepo) GetPendingReceipts(ctx context.Context, limit int) ([]models.Receipt, error) {
+	var receipts []models.Receipt
+	err := r.db.WithContext(ctx).
+		Where("fiscal_status = ?", models.FiscalPending).
+		Order("id").
+		Limit(limit).
+		Find(&receipts).Error
+	if err != nil {
+		return nil, errors.ErrFindReceipt
+	}
+	return receipts, nil
+}
+
+func (r *PostgresReceiptRepo) SetFiscalData(ctx context.Context, id int64, status models.FiscalStatus, sign, number string, at time.Time) error {
+	err := r.db.WithContext(ctx).Model(&models.Receipt{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"fiscal_status": status,
+			"fiscal_sign":   sign,
+			"fiscal_number": number,
+			"fiscalized_at": at,
+		}).Error
+	if err != nil {
+		return errors.ErrUpdateReceipt
+	}
+	return nil
+}
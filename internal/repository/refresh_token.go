@@ -0,0 +1,88 @@
+package repository
+
+import (
+	"computer-club/internal/repository/models"
+	"computer-club/pkg/errors"
+	"context"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+type RefreshTokenRepository interface {
+	CreateRefreshToken(tx *gorm.DB, token *models.RefreshToken) error
+	GetRefreshTokenForUpdate(tx *gorm.DB, tokenHash string) (*models.RefreshToken, error)
+	RevokeRefreshToken(tx *gorm.DB, id int64, replacedByID *int64) error
+	RevokeUserRefreshTokens(ctx context.Context, userID int64) error
+	DeleteExpiredRefreshTokens(ctx context.Context, before time.Time) (int64, error)
+}
+
+type PostgresRefreshTokenRepo struct {
+	db *gorm.DB
+}
+
+func NewPostgresRefreshTokenRepo(db *gorm.DB) RefreshTokenRepository {
+	return &PostgresRefreshTokenRepo{db: db}
+}
+
+func (r *PostgresRefreshTokenRepo) CreateRefreshToken(tx *gorm.DB, token *models.RefreshToken) error {
+	if tx == nil {
+		tx = r.db
+	}
+	if err := tx.Create(token).Error; err != nil {
+		return errors.ErrTokenGeneration
+	}
+	return nil
+}
+
+func (r *PostgresRefreshTokenRepo) GetRefreshTokenForUpdate(tx *gorm.DB, tokenHash string) (*models.RefreshToken, error) {
+	if tx == nil {
+		tx = r.db
+	}
+	var token models.RefreshToken
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("token_hash = ?", tokenHash).
+		First(&token).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, errors.ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return nil, errors.ErrFindRefreshToken
+	}
+	return &token, nil
+}
+
+func (r *PostgresRefreshTokenRepo) RevokeRefreshToken(tx *gorm.DB, id int64, replacedByID *int64) error {
+	if tx == nil {
+		tx = r.db
+	}
+	err := tx.Model(&models.RefreshToken{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Updates(map[string]interface{}{
+			"revoked_at":     time.Now(),
+			"replaced_by_id": replacedByID,
+		}).Error
+	if err != nil {
+		return errors.ErrRevokeToken
+	}
+	return nil
+}
+
+// RevokeUserRefreshTokens отзывает все действующие refresh-токены пользователя
+func (r *PostgresRefreshTokenRepo) RevokeUserRefreshTokens(ctx context.Context, userID int64) error {
+	err := r.db.WithContext(ctx).Model(&models.RefreshToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now()).Error
+	if err != nil {
+		return errors.ErrRevokeToken
+	}
+	return nil
+}
+
+func (r *PostgresRefreshTokenRepo) DeleteExpiredRefreshTokens(ctx context.Context, before time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).Where("expires_at < ?", before).Delete(&models.RefreshToken{})
+	if result.Error != nil {
+		return 0, errors.ErrRevokeToken
+	}
+	return result.RowsAffected, nil
+}
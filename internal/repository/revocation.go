@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"computer-club/pkg/errors"
+	"context"
+	"fmt"
+	"github.com/redis/go-redis/v9"
+	"strconv"
+	"time"
+)
+
+// RevocationRepository - список отозванных access-токенов в Redis. Записи живут,
+// пока отозванные токены еще не истекли сами
+type RevocationRepository interface {
+	RevokeToken(ctx context.Context, jti string, ttl time.Duration) error
+	RevokeUserTokens(ctx context.Context, userID int64, issuedBefore time.Time, ttl time.Duration) error
+	IsRevoked(ctx context.Context, jti string, userID int64, issuedAt time.Time) (bool, error)
+}
+
+type RedisRevocationRepo struct {
+	redis *redis.Client
+}
+
+func NewRedisRevocationRepo(redis *redis.Client) RevocationRepository {
+	return &RedisRevocationRepo{redis: redis}
+}
+
+func (r *RedisRevocationRepo) RevokeToken(ctx context.Context, jti string, ttl time.Duration) error {
+	if ttl <= 0 {
+		return nil
+	}
+	if err := r.redis.Set(ctx, getRevokedTokenKey(jti), 1, ttl).Err(); err != nil {
+		return errors.ErrRevocationStore
+	}
+	return nil
+}
+
+// RevokeUserTokens отзывает все access-токены пользователя, выпущенные до issuedBefore
+func (r *RedisRevocationRepo) RevokeUserTokens(ctx context.Context, userID int64, issuedBefore time.Time, ttl time.Duration) error {
+	if err := r.redis.Set(ctx, getRevokedUserKey(userID), issuedBefore.Unix(), ttl).Err(); err != nil {
+		return errors.ErrRevocationStore
+	}
+	return nil
+}
+
+func (r *RedisRevocationRepo) IsRevoked(ctx context.Context, jti string, userID int64, issuedAt time.Time) (bool, error) {
+	values, err := r.redis.MGet(ctx, getRevokedTokenKey(jti), getRevokedUserKey(userID)).Result()
+	if err != nil {
+		return false, errors.ErrRevocationStore
+	}
+	if values[0] != nil {
+		return true, nil
+	}
+	if values[1] == nil {
+		return false, nil
+	}
+	issuedBefore, err := strconv.ParseInt(values[1].(string), 10, 64)
+	if err != nil {
+		return false, errors.ErrRevocationStore
+	}
+	return issuedAt.Unix() <= issuedBefore, nil
+}
+
+func getRevokedTokenKey(jti string) string {
+	return "revoked:token:" + jti
+}
+
+func getRevokedUserKey(userID int64) string {
+	return fmt.Sprintf("revoked:user:%d", userID)
+}
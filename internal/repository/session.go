@@ -18,7 +18,9 @@ type SessionRepository interface {
 	GetSessionByID(ctx context.Context, sessionID int64) (*models.Session, error)
 	CheckStatus(session models.Session, status string) error
 	HasActiveSession(ctx context.Context, userID int64) (bool, error)
-	CreateSession(ctx context.Context, userID int64, pcNumber int, tariffID int64) (*models.Session, error)
+	CountUserSessions(ctx context.Context, userID int64) (int64, error)
+	CreateSession(tx *gorm.DB, userID int64, pcNumber int, tariffID int64, transactionID *int64, startTime, endTime time.Time) (*models.Session, error)
+	EndSessionByTransaction(tx *gorm.DB, transactionID int64) error
 }
 
 type PostgresSessionRepo struct {
@@ -45,6 +47,12 @@ func (r *PostgresSessionRepo) HasActiveSession(ctx context.Context, userID int64
 	return count > 0, err
 }
 
+func (r *PostgresSessionRepo) CountUserSessions(ctx context.Context, userID int64) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.Session{}).Where("user_id = ?", userID).Count(&count).Error
+	return count, err
+}
+
 func (r *PostgresSessionRepo) CheckStatus(sesion models.Session, status string) error {
 	if sesion.Status != models.SessionStatus(status) {
 		return errors.ErrFailedStatus
@@ -69,8 +77,11 @@ func (r *PostgresSessionRepo) EndSession(ctx context.Context, sessionID int64) e
 		return errors.ErrSessionNotFound
 	}
 
-	// Завершаем сессию
-	if err := tx.WithContext(ctx).Model(&models.Session{}).Where("id = ?", sessionID).Update("status", models.Finished).Error; err != nil {
+	// Завершаем сессию. Если она закончилась раньше плана, фиксируем фактическое время окончания
+	if err := tx.WithContext(ctx).Model(&models.Session{}).Where("id = ?", sessionID).Updates(map[string]interface{}{
+		"status":   models.Finished,
+		"end_time": gorm.Expr("LEAST(end_time, ?)", time.Now()),
+	}).Error; err != nil {
 		tx.Rollback()
 		return errors.ErrUpdateSession
 	}
@@ -120,25 +131,28 @@ func (r *PostgresSessionRepo) GetActiveSessions(ctx context.Context) []*models.S
 	return sessions
 }
 
-func (r *PostgresSessionRepo) CreateSession(ctx context.Context, userID int64, pcNumber int, tariffID int64) (*models.Session, error) {
-	startTime := time.Now()
-	endTime := startTime.Add(2 * time.Hour)
-
+// CreateSession создает сессию и занимает компьютер. Вызывается в транзакции оплаты, чтобы
+// оплаченная сессия не потерялась при сбое после списания
+func (r *PostgresSessionRepo) CreateSession(tx *gorm.DB, userID int64, pcNumber int, tariffID int64, transactionID *int64, startTime, endTime time.Time) (*models.Session, error) {
+	if tx == nil {
+		tx = r.db
+	}
 	session := &models.Session{
-		UserID:    userID,
-		PCNumber:  pcNumber,
-		TariffID:  tariffID,
-		Status:    models.Active,
-		StartTime: startTime,
-		EndTime:   &endTime,
+		UserID:        userID,
+		PCNumber:      pcNumber,
+		TariffID:      tariffID,
+		TransactionID: transactionID,
+		Status:        models.Active,
+		StartTime:     startTime,
+		EndTime:       &endTime,
 	}
 
-	if err := r.db.WithContext(ctx).Create(session).Error; err != nil {
+	if err := tx.Create(session).Error; err != nil {
 		return nil, errors.ErrCreatedSession
 	}
 
 	// Обновляем статус ПК
-	if err := r.db.WithContext(ctx).Model(&models.Computer{}).
+	if err := tx.Model(&models.Computer{}).
 		Where("pc_number = ?", pcNumber).
 		Update("status", models.Busy).Error; err != nil {
 		return nil, errors.ErrUpdateComputerStatus
@@ -147,6 +161,38 @@ func (r *PostgresSessionRepo) CreateSession(ctx context.Context, userID int64, p
 	return session, nil
 }
 
+// EndSessionByTransaction завершает активную сессию, оплаченную транзакцией, и освобождает
+// компьютер. Если сессия уже закончилась, ничего не делает
+func (r *PostgresSessionRepo) EndSessionByTransaction(tx *gorm.DB, transactionID int64) error {
+	if tx == nil {
+		tx = r.db
+	}
+
+	var session models.Session
+	err := tx.Where("transaction_id = ? AND status = ?", transactionID, models.Active).
+		Limit(1).Find(&session).Error
+	if err != nil {
+		return errors.ErrSessionNotFound
+	}
+	if session.ID == 0 {
+		return nil
+	}
+
+	if err := tx.Model(&models.Session{}).Where("id = ?", session.ID).Updates(map[string]interface{}{
+		"status":   models.Finished,
+		"end_time": gorm.Expr("LEAST(end_time, ?)", time.Now()),
+	}).Error; err != nil {
+		return errors.ErrUpdateSession
+	}
+	if err := tx.Model(&models.Computer{}).Where("pc_number = ?", session.PCNumber).Update("status", models.Free).Error; err != nil {
+		return errors.ErrUpdateComputer
+	}
+	if err := r.redis.Del(tx.Statement.Context, getSessionKey(session.ID)).Err(); err != nil {
+		return errors.ErrDeleteRedis
+	}
+	return nil
+}
+
 // Вспомогательная функция для генерации ключа Redis
 func getSessionKey(sessionID int64) string {
 	return "session:" + fmt.Sprint(sessionID)
@@ -0,0 +1,149 @@
+package repository
+
+import (
+	"computer-club/internal/repository/models"
+	"computer-club/pkg/errors"
+	"computer-club/pkg/money"
+	"context"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+type ShiftRepository interface {
+	CreateShift(ctx context.Context, shift *models.Shift) error
+	GetOpenShift(ctx context.Context) (*models.Shift, error)
+	LockOpenShift(tx *gorm.DB) (*models.Shift, error)
+	LockOpenShiftForClose(tx *gorm.DB) (*models.Shift, error)
+	GetShiftByID(ctx context.Context, id int64) (*models.Shift, error)
+	CloseShift(tx *gorm.DB, shift *models.Shift) error
+	SummarizeTransactions(ctx context.Context, from, to time.Time) ([]models.ShiftLine, money.Money, error)
+}
+
+type PostgresShiftRepo struct {
+	db *gorm.DB
+}
+
+func NewPostgresShiftRepo(db *gorm.DB) ShiftRepository {
+	return &PostgresShiftRepo{db: db}
+}
+
+// CreateShift открывает смену. Частичный уникальный индекс не дает открыть вторую смену одновременно
+func (r *PostgresShiftRepo) CreateShift(ctx context.Context, shift *models.Shift) error {
+	result := r.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(shift)
+	if result.Error != nil {
+		return errors.ErrCreateShift
+	}
+	if result.RowsAffected == 0 {
+		return errors.ErrShiftAlreadyOpen
+	}
+	return nil
+}
+
+func (r *PostgresShiftRepo) GetOpenShift(ctx context.Context) (*models.Shift, error) {
+	return r.findOpenShift(r.db.WithContext(ctx))
+}
+
+// LockOpenShift читает открытую смену с разделяемой блокировкой, чтобы смену нельзя было
+// закрыть, пока в нее записывается пополнение
+func (r *PostgresShiftRepo) LockOpenShift(tx *gorm.DB) (*models.Shift, error) {
+	if tx == nil {
+		tx = r.db
+	}
+	return r.findOpenShift(tx.Clauses(clause.Locking{Strength: "SHARE"}))
+}
+
+// LockOpenShiftForClose ждет завершения пополнений, записываемых в смену, и блокирует новые
+func (r *PostgresShiftRepo) LockOpenShiftForClose(tx *gorm.DB) (*models.Shift, error) {
+	if tx == nil {
+		tx = r.db
+	}
+	return r.findOpenShift(tx.Clauses(clause.Locking{Strength: "UPDATE"}))
+}
+
+func (r *PostgresShiftRepo) findOpenShift(db *gorm.DB) (*models.Shift, error) {
+	var shift models.Shift
+	if err := db.Where("status = ?", models.ShiftOpen).Limit(1).Find(&shift).Error; err != nil {
+		return nil, errors.ErrFindShift
+	}
+	if shift.ID == 0 {
+		return nil, errors.ErrNoOpenShift
+	}
+	return &shift, nil
+}
+
+func (r *PostgresShiftRepo) GetShiftByID(ctx context.Context, id int64) (*models.Shift, error) {
+	var shift models.Shift
+	if err := r.db.WithContext(ctx).First(&shift, id).Error; err != nil {
+		return nil, errors.ErrShiftNotFound
+	}
+	return &shift, nil
+}
+
+func (r *PostgresShiftRepo) CloseShift(tx *gorm.DB, shift *models.Shift) error {
+	if tx == nil {
+		tx = r.db
+	}
+	result := tx.Model(&models.Shift{}).
+		Where("id = ? AND status = ?", shift.ID, models.ShiftOpen).
+		Updates(map[string]interface{}{
+			"status":                 models.ShiftClosed,
+			"closed_by":              shift.ClosedBy,
+			"closed_at":              shift.ClosedAt,
+			"expected_cash_amount":   shift.ExpectedCash.Amount,
+			"expected_cash_currency": shift.ExpectedCash.Currency,
+			"counted_cash_amount":    shift.CountedCash.Amount,
+			"counted_cash_currency":  shift.CountedCash.Currency,
+			"discrepancy_amount":     shift.Discrepancy.Amount,
+			"discrepancy_currency":   shift.Discrepancy.Currency,
+			"flagged":                shift.Flagged,
+			"note":                   shift.Note,
+		})
+	if result.Error != nil {
+		return errors.ErrCloseShift
+	}
+	if result.RowsAffected == 0 {
+		return errors.ErrNoOpenShift
+	}
+	return nil
+}
+
+// SummarizeTransactions группирует транзакции за период по типу и способу оплаты.
+// Вторым значением возвращается часть продаж, оплаченная бонусами
+func (r *PostgresShiftRepo) SummarizeTransactions(ctx context.Context, from, to time.Time) ([]models.ShiftLine, money.Money, error) {
+	var rows []struct {
+		Type          models.TransactionType
+		PaymentMethod models.PaymentMethod
+		Count         int64
+		Amount        int64
+		Bonus         int64
+	}
+	err := r.db.WithContext(ctx).Model(&models.Transaction{}).
+		Select("type, COALESCE(payment_method, '') AS payment_method, COUNT(*) AS count, "+
+			"COALESCE(SUM(amount_amount), 0) AS amount, COALESCE(SUM(bonus_amount), 0) AS bonus").
+		Where("created_at >= ? AND created_at < ?", from, to).
+		Group("type, payment_method").
+		Order("type, payment_method").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, money.Money{}, errors.ErrShiftReport
+	}
+
+	lines := make([]models.ShiftLine, 0, len(rows))
+	bonusSales := money.Zero()
+	for _, row := range rows {
+		lines = append(lines, models.ShiftLine{
+			Type:          row.Type,
+			PaymentMethod: row.PaymentMethod,
+			Count:         row.Count,
+			Amount:        money.New(row.Amount),
+		})
+		switch {
+		case row.Type == models.Buy || row.Type == models.PackagePurchase:
+			bonusSales = bonusSales.Add(money.New(row.Bonus))
+		case row.Type == models.Reversal && row.PaymentMethod == "":
+			bonusSales = bonusSales.Sub(money.New(row.Bonus))
+		}
+	}
+	return lines, bonusSales, nil
+}
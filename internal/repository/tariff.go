@@ -10,6 +10,8 @@ import (
 type TariffRepository interface {
 	GetTariff(ctx context.Context) ([]models.Tariff, error)
 	GetTariffByID(ctx context.Context, id int64) (*models.Tariff, error)
+	CreateTariff(ctx context.Context, tariff *models.Tariff) error
+	UpdateTariff(ctx context.Context, tariff *models.Tariff) error
 }
 
 type TariffRepositoryPostgres struct {
@@ -37,3 +39,29 @@ func (r *TariffRepositoryPostgres) GetTariffByID(ctx context.Context, id int64)
 	}
 	return &tariff, nil
 }
+
+func (r *TariffRepositoryPostgres) CreateTariff(ctx context.Context, tariff *models.Tariff) error {
+	if err := r.db.WithContext(ctx).Create(tariff).Error; err != nil {
+		return errors.ErrCreateTariff
+	}
+	return nil
+}
+
+// UpdateTariff меняет название, цену и длительность тарифа. Уже оплаченные сессии не пересчитываются
+func (r *TariffRepositoryPostgres) UpdateTariff(ctx context.Context, tariff *models.Tariff) error {
+	result := r.db.WithContext(ctx).Model(&models.Tariff{}).
+		Where("id = ?", tariff.ID).
+		Updates(map[string]interface{}{
+			"name":           tariff.Name,
+			"price_amount":   tariff.Price.Amount,
+			"price_currency": tariff.Price.Currency,
+			"duration":       tariff.Duration,
+		})
+	if result.Error != nil {
+		return errors.ErrUpdateTariff
+	}
+	if result.RowsAffected == 0 {
+		return errors.ErrTariffNotFound
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package repository
+
+import (
+	"computer-club/internal/repository/models"
+	"computer-club/pkg/errors"
+	"computer-club/pkg/money"
+	"context"
+	"gorm.io/gorm"
+	"time"
+)
+
+type TransferRepository interface {
+	CreateTransfer(tx *gorm.DB, transfer *models.Transfer) error
+	GetTransferByID(ctx context.Context, id int64) (*models.Transfer, error)
+	SumOutgoing(tx *gorm.DB, userID int64, since time.Time) (money.Money, error)
+	MarkReversed(tx *gorm.DB, id, adminID int64, now time.Time) error
+}
+
+type PostgresTransferRepo struct {
+	db *gorm.DB
+}
+
+func NewPostgresTransferRepo(db *gorm.DB) TransferRepository {
+	return &PostgresTransferRepo{db: db}
+}
+
+func (r *PostgresTransferRepo) CreateTransfer(tx *gorm.DB, transfer *models.Transfer) error {
+	if tx == nil {
+		tx = r.db
+	}
+	if err := tx.Create(transfer).Error; err != nil {
+		return errors.ErrCreateTransfer
+	}
+	return nil
+}
+
+func (r *PostgresTransferRepo) GetTransferByID(ctx context.Context, id int64) (*models.Transfer, error) {
+	var transfer models.Transfer
+	if err := r.db.WithContext(ctx).First(&transfer, id).Error; err != nil {
+		return nil, errors.ErrTransferNotFound
+	}
+	return &transfer, nil
+}
+
+// SumOutgoing возвращает сумму переводов пользователя начиная с since. Отмененные переводы не учитываются
+func (r *PostgresTransferRepo) SumOutgoing(tx *gorm.DB, userID int64, since time.Time) (money.Money, error) {
+	if tx == nil {
+		tx = r.db
+	}
+	var total int64
+	err := tx.Model(&models.Transfer{}).
+		Where("from_user_id = ? AND created_at >= ? AND status = ?", userID, since, models.TransferCompleted).
+		Select("COALESCE(SUM(amount_amount), 0)").
+		Scan(&total).Error
+	if err != nil {
+		return money.Money{}, errors.ErrFindTransfer
+	}
+	return money.New(total), nil
+}
+
+// MarkReversed отмечает перевод отмененным. Условие на статус не дает отменить перевод дважды
+func (r *PostgresTransferRepo) MarkReversed(tx *gorm.DB, id, adminID int64, now time.Time) error {
+	if tx == nil {
+		tx = r.db
+	}
+	result := tx.Model(&models.Transfer{}).
+		Where("id = ? AND status = ?", id, models.TransferCompleted).
+		Updates(map[string]interface{}{
+			"status":      models.TransferReversed,
+			"reversed_by": adminID,
+			"reversed_at": now,
+		})
+	if result.Error != nil {
+		return errors.ErrUpdateTransfer
+	}
+	if result.RowsAffected == 0 {
+		return errors.ErrTransferAlreadyReversed
+	}
+	return nil
+}
@@ -0,0 +1,159 @@
+package repository
+
+import (
+	"computer-club/internal/repository/models"
+	"computer-club/pkg/errors"
+	"context"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+type TwoFactorRepository interface {
+	GetTwoFactor(ctx context.Context, userID int64) (*models.TwoFactor, error)
+	GetTwoFactorForUpdate(tx *gorm.DB, userID int64) (*models.TwoFactor, error)
+	SaveTwoFactor(tx *gorm.DB, twoFactor *models.TwoFactor) error
+	EnableTwoFactor(tx *gorm.DB, userID int64, at time.Time, step int64) error
+	UpdateLastStep(tx *gorm.DB, userID int64, step int64) error
+	DeleteTwoFactor(tx *gorm.DB, userID int64) error
+	ReplaceBackupCodes(tx *gorm.DB, userID int64, codeHashes []string) error
+	UseBackupCode(tx *gorm.DB, userID int64, codeHash string, at time.Time) error
+	CountBackupCodes(ctx context.Context, userID int64) (int64, error)
+}
+
+type PostgresTwoFactorRepo struct {
+	db *gorm.DB
+}
+
+func NewPostgresTwoFactorRepo(db *gorm.DB) TwoFactorRepository {
+	return &PostgresTwoFactorRepo{db: db}
+}
+
+func (r *PostgresTwoFactorRepo) GetTwoFactor(ctx context.Context, userID int64) (*models.TwoFactor, error) {
+	var twoFactor models.TwoFactor
+	err := r.db.WithContext(ctx).Where("user_id = ?", userID).First(&twoFactor).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, errors.ErrTwoFactorNotEnabled
+	}
+	if err != nil {
+		return nil, errors.ErrFindTwoFactor
+	}
+	return &twoFactor, nil
+}
+
+func (r *PostgresTwoFactorRepo) GetTwoFactorForUpdate(tx *gorm.DB, userID int64) (*models.TwoFactor, error) {
+	if tx == nil {
+		tx = r.db
+	}
+	var twoFactor models.TwoFactor
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("user_id = ?", userID).
+		First(&twoFactor).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, errors.ErrTwoFactorNotEnabled
+	}
+	if err != nil {
+		return nil, errors.ErrFindTwoFactor
+	}
+	return &twoFactor, nil
+}
+
+// SaveTwoFactor сохраняет новый секрет, заменяя неподтвержденный
+func (r *PostgresTwoFactorRepo) SaveTwoFactor(tx *gorm.DB, twoFactor *models.TwoFactor) error {
+	if tx == nil {
+		tx = r.db
+	}
+	err := tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"secret", "enabled_at", "last_step", "created_at"}),
+	}).Create(twoFactor).Error
+	if err != nil {
+		return errors.ErrUpdateTwoFactor
+	}
+	return nil
+}
+
+func (r *PostgresTwoFactorRepo) EnableTwoFactor(tx *gorm.DB, userID int64, at time.Time, step int64) error {
+	if tx == nil {
+		tx = r.db
+	}
+	err := tx.Model(&models.TwoFactor{}).Where("user_id = ?", userID).Updates(map[string]interface{}{
+		"enabled_at": at,
+		"last_step":  step,
+	}).Error
+	if err != nil {
+		return errors.ErrUpdateTwoFactor
+	}
+	return nil
+}
+
+func (r *PostgresTwoFactorRepo) UpdateLastStep(tx *gorm.DB, userID int64, step int64) error {
+	if tx == nil {
+		tx = r.db
+	}
+	err := tx.Model(&models.TwoFactor{}).Where("user_id = ?", userID).Update("last_step", step).Error
+	if err != nil {
+		return errors.ErrUpdateTwoFactor
+	}
+	return nil
+}
+
+// DeleteTwoFactor отключает 2FA вместе с резервными кодами
+func (r *PostgresTwoFactorRepo) DeleteTwoFactor(tx *gorm.DB, userID int64) error {
+	if tx == nil {
+		tx = r.db
+	}
+	if err := tx.Where("user_id = ?", userID).Delete(&models.BackupCode{}).Error; err != nil {
+		return errors.ErrUpdateTwoFactor
+	}
+	if err := tx.Where("user_id = ?", userID).Delete(&models.TwoFactor{}).Error; err != nil {
+		return errors.ErrUpdateTwoFactor
+	}
+	return nil
+}
+
+// ReplaceBackupCodes удаляет прежние резервные коды и сохраняет новые
+func (r *PostgresTwoFactorRepo) ReplaceBackupCodes(tx *gorm.DB, userID int64, codeHashes []string) error {
+	if tx == nil {
+		tx = r.db
+	}
+	if err := tx.Where("user_id = ?", userID).Delete(&models.BackupCode{}).Error; err != nil {
+		return errors.ErrUpdateTwoFactor
+	}
+	codes := make([]models.BackupCode, 0, len(codeHashes))
+	for _, hash := range codeHashes {
+		codes = append(codes, models.BackupCode{UserID: userID, CodeHash: hash})
+	}
+	if err := tx.Create(&codes).Error; err != nil {
+		return errors.ErrUpdateTwoFactor
+	}
+	return nil
+}
+
+func (r *PostgresTwoFactorRepo) UseBackupCode(tx *gorm.DB, userID int64, codeHash string, at time.Time) error {
+	if tx == nil {
+		tx = r.db
+	}
+	result := tx.Model(&models.BackupCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, codeHash).
+		Update("used_at", at)
+	if result.Error != nil {
+		return errors.ErrUpdateTwoFactor
+	}
+	if result.RowsAffected == 0 {
+		return errors.ErrInvalidTwoFactorCode
+	}
+	return nil
+}
+
+// CountBackupCodes возвращает число неиспользованных резервных кодов
+func (r *PostgresTwoFactorRepo) CountBackupCodes(ctx context.Context, userID int64) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.BackupCode{}).
+		Where("user_id = ? AND used_at IS NULL", userID).
+		Count(&count).Error
+	if err != nil {
+		return 0, errors.ErrFindTwoFactor
+	}
+	return count, nil
+}
@@ -0,0 +1,24 @@
+package repository
+
+import (
+	"context"
+	"gorm.io/gorm"
+)
+
+// TxManager выполняет функцию в одной транзакции базы данных
+type TxManager interface {
+	WithTransaction(ctx context.Context, fn func(tx *gorm.DB) error) error
+}
+
+type GormTxManager struct {
+	db *gorm.DB
+}
+
+func NewTxManager(db *gorm.DB) TxManager {
+	return &GormTxManager{db: db}
+}
+
+// WithTransaction откатывает транзакцию, если fn вернула ошибку, и возвращает эту ошибку без изменений
+func (m *GormTxManager) WithTransaction(ctx context.Context, fn func(tx *gorm.DB) error) error {
+	return m.db.WithContext(ctx).Transaction(fn)
+}
@@ -5,6 +5,8 @@ import (
 	"computer-club/pkg/errors"
 	"context"
 	"gorm.io/gorm"
+	"strings"
+	"time"
 )
 
 type UserRepository interface {
@@ -12,6 +14,14 @@ type UserRepository interface {
 	GetUserByID(ctx context.Context, id int64) (*models.User, error)
 	GetUserByEmail(ctx context.Context, email string) (*models.User, error)
 	GetUserByName(ctx context.Context, name string) (*models.User, error)
+	UpdateRole(ctx context.Context, id int64, role models.UserRole) error
+	GetUserByPhone(ctx context.Context, phone string) (*models.User, error)
+	FindUsers(ctx context.Context, filter models.UserFilter) ([]models.User, error)
+	UpdateProfile(ctx context.Context, id int64, profile models.UserProfile) error
+	SetBlocked(ctx context.Context, id int64, blockedAt *time.Time, blockedBy *int64, reason string) error
+	DeleteUser(ctx context.Context, id int64) error
+	SetEmailVerified(tx *gorm.DB, id int64, at time.Time) error
+	UpdatePassword(tx *gorm.DB, id int64, passwordHash string) error
 }
 
 type PostgresUserRepo struct {
@@ -56,3 +66,138 @@ func (r *PostgresUserRepo) GetUserByName(ctx context.Context, name string) (*mod
 	}
 	return &user, nil
 }
+
+func (r *PostgresUserRepo) UpdateRole(ctx context.Context, id int64, role models.UserRole) error {
+	result := r.db.WithContext(ctx).Model(&models.User{}).Where("id = ?", id).Update("role", string(role))
+	if result.Error != nil {
+		return errors.ErrUpdateUser
+	}
+	if result.RowsAffected == 0 {
+		return errors.ErrUserNotFound
+	}
+	return nil
+}
+
+func (r *PostgresUserRepo) GetUserByPhone(ctx context.Context, phone string) (*models.User, error) {
+	var user models.User
+	err := r.db.WithContext(ctx).Where("phone = ?", phone).First(&user).Error
+	if err != nil {
+		return nil, errors.ErrFindUser
+	}
+	return &user, nil
+}
+
+func (r *PostgresUserRepo) FindUsers(ctx context.Context, filter models.UserFilter) ([]models.User, error) {
+	query := r.db.WithContext(ctx).Order("id DESC")
+	if filter.Query != "" {
+		pattern := "%" + likeEscaper.Replace(filter.Query) + "%"
+		query = query.Where("name ILIKE ? OR email ILIKE ? OR phone ILIKE ?", pattern, pattern, pattern)
+	}
+	if filter.Role != "" {
+		query = query.Where("role = ?", string(filter.Role))
+	}
+	if filter.Blocked != nil {
+		if *filter.Blocked {
+			query = query.Where("blocked_at IS NOT NULL")
+		} else {
+			query = query.Where("blocked_at IS NULL")
+		}
+	}
+	if filter.Cursor > 0 {
+		query = query.Where("id < ?", filter.Cursor)
+	}
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit)
+	}
+
+	var users []models.User
+	if err := query.Find(&users).Error; err != nil {
+		return nil, errors.ErrFindUser
+	}
+	return users, nil
+}
+
+// likeEscaper экранирует спецсимволы LIKE, чтобы поисковая строка искалась буквально
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+func (r *PostgresUserRepo) UpdateProfile(ctx context.Context, id int64, profile models.UserProfile) error {
+	updates := map[string]interface{}{}
+	if profile.Name != nil {
+		updates["name"] = *profile.Name
+	}
+	if profile.Email != nil {
+		// Новый адрес нужно подтвердить заново
+		updates["email"] = *profile.Email
+		updates["email_verified_at"] = nil
+	}
+	if profile.Phone != nil {
+		updates["phone"] = *profile.Phone
+	}
+	if len(updates) == 0 {
+		return nil
+	}
+	result := r.db.WithContext(ctx).Model(&models.User{}).Where("id = ?", id).Updates(updates)
+	if result.Error != nil {
+		return errors.ErrUpdateUser
+	}
+	if result.RowsAffected == 0 {
+		return errors.ErrUserNotFound
+	}
+	return nil
+}
+
+// SetBlocked блокирует пользователя или, при blockedAt == nil, снимает блокировку
+func (r *PostgresUserRepo) SetBlocked(ctx context.Context, id int64, blockedAt *time.Time, blockedBy *int64, reason string) error {
+	result := r.db.WithContext(ctx).Model(&models.User{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"blocked_at":   blockedAt,
+		"blocked_by":   blockedBy,
+		"block_reason": reason,
+	})
+	if result.Error != nil {
+		return errors.ErrUpdateUser
+	}
+	if result.RowsAffected == 0 {
+		return errors.ErrUserNotFound
+	}
+	return nil
+}
+
+// DeleteUser мягко удаляет пользователя: запись остается для истории операций
+func (r *PostgresUserRepo) DeleteUser(ctx context.Context, id int64) error {
+	result := r.db.WithContext(ctx).Delete(&models.User{}, id)
+	if result.Error != nil {
+		return errors.ErrUpdateUser
+	}
+	if result.RowsAffected == 0 {
+		return errors.ErrUserNotFound
+	}
+	return nil
+}
+
+// SetEmailVerified отмечает почту подтвержденной, повторное подтверждение дату не меняет
+func (r *PostgresUserRepo) SetEmailVerified(tx *gorm.DB, id int64, at time.Time) error {
+	if tx == nil {
+		tx = r.db
+	}
+	err := tx.Model(&models.User{}).
+		Where("id = ? AND email_verified_at IS NULL", id).
+		Update("email_verified_at", at).Error
+	if err != nil {
+		return errors.ErrUpdateUser
+	}
+	return nil
+}
+
+func (r *PostgresUserRepo) UpdatePassword(tx *gorm.DB, id int64, passwordHash string) error {
+	if tx == nil {
+		tx = r.db
+	}
+	result := tx.Model(&models.User{}).Where("id = ?", id).Update("password", passwordHash)
+	if result.Error != nil {
+		return errors.ErrUpdateUser
+	}
+	if result.RowsAffected == 0 {
+		return errors.ErrUserNotFound
+	}
+	return nil
+}
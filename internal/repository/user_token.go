@@ -0,0 +1,107 @@
+package repository
+
+import (
+	"computer-club/internal/repository/models"
+	"computer-club/pkg/errors"
+	"context"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+type UserTokenRepository interface {
+	CreateUserToken(tx *gorm.DB, token *models.UserToken) error
+	GetUserToken(ctx context.Context, tokenHash string, purpose models.TokenPurpose) (*models.UserToken, error)
+	GetUserTokenForUpdate(tx *gorm.DB, tokenHash string, purpose models.TokenPurpose) (*models.UserToken, error)
+	UseUserToken(tx *gorm.DB, id int64, at time.Time) error
+	InvalidateUserTokens(tx *gorm.DB, userID int64, purpose models.TokenPurpose) error
+	DeleteExpiredUserTokens(ctx context.Context, before time.Time) (int64, error)
+}
+
+type PostgresUserTokenRepo struct {
+	db *gorm.DB
+}
+
+func NewPostgresUserTokenRepo(db *gorm.DB) UserTokenRepository {
+	return &PostgresUserTokenRepo{db: db}
+}
+
+func (r *PostgresUserTokenRepo) CreateUserToken(tx *gorm.DB, token *models.UserToken) error {
+	if tx == nil {
+		tx = r.db
+	}
+	if err := tx.Create(token).Error; err != nil {
+		return errors.ErrTokenGeneration
+	}
+	return nil
+}
+
+func (r *PostgresUserTokenRepo) GetUserToken(ctx context.Context, tokenHash string, purpose models.TokenPurpose) (*models.UserToken, error) {
+	var token models.UserToken
+	err := r.db.WithContext(ctx).
+		Where("token_hash = ? AND purpose = ?", tokenHash, purpose).
+		First(&token).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, errors.ErrInvalidUserToken
+	}
+	if err != nil {
+		return nil, errors.ErrFindUserToken
+	}
+	return &token, nil
+}
+
+func (r *PostgresUserTokenRepo) GetUserTokenForUpdate(tx *gorm.DB, tokenHash string, purpose models.TokenPurpose) (*models.UserToken, error) {
+	if tx == nil {
+		tx = r.db
+	}
+	var token models.UserToken
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("token_hash = ? AND purpose = ?", tokenHash, purpose).
+		First(&token).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, errors.ErrInvalidUserToken
+	}
+	if err != nil {
+		return nil, errors.ErrFindUserToken
+	}
+	return &token, nil
+}
+
+func (r *PostgresUserTokenRepo) UseUserToken(tx *gorm.DB, id int64, at time.Time) error {
+	if tx == nil {
+		tx = r.db
+	}
+	result := tx.Model(&models.UserToken{}).
+		Where("id = ? AND used_at IS NULL", id).
+		Update("used_at", at)
+	if result.Error != nil {
+		return errors.ErrFindUserToken
+	}
+	if result.RowsAffected == 0 {
+		return errors.ErrInvalidUserToken
+	}
+	return nil
+}
+
+// InvalidateUserTokens гасит неиспользованные токены пользователя с тем же назначением,
+// чтобы действовала только последняя отправленная ссылка
+func (r *PostgresUserTokenRepo) InvalidateUserTokens(tx *gorm.DB, userID int64, purpose models.TokenPurpose) error {
+	if tx == nil {
+		tx = r.db
+	}
+	err := tx.Model(&models.UserToken{}).
+		Where("user_id = ? AND purpose = ? AND used_at IS NULL", userID, purpose).
+		Update("used_at", time.Now()).Error
+	if err != nil {
+		return errors.ErrFindUserToken
+	}
+	return nil
+}
+
+func (r *PostgresUserTokenRepo) DeleteExpiredUserTokens(ctx context.Context, before time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).Where("expires_at < ?", before).Delete(&models.UserToken{})
+	if result.Error != nil {
+		return 0, errors.ErrFindUserToken
+	}
+	return result.RowsAffected, nil
+}
@@ -0,0 +1,96 @@
+package repository
+
+import (
+	"computer-club/internal/repository/models"
+	"computer-club/pkg/errors"
+	"context"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+type VoucherRepository interface {
+	CreateBatch(tx *gorm.DB, batch *models.VoucherBatch, vouchers []models.Voucher) error
+	GetBatch(ctx context.Context, id int64) (*models.VoucherBatch, error)
+	GetBatchVouchers(ctx context.Context, batchID int64) ([]models.Voucher, error)
+	GetVoucherForUpdate(tx *gorm.DB, code string) (*models.Voucher, error)
+	UpdateVoucher(tx *gorm.DB, id int64, fields map[string]interface{}) error
+	ExpireVouchers(ctx context.Context, now time.Time) (int64, error)
+}
+
+type PostgresVoucherRepo struct {
+	db *gorm.DB
+}
+
+func NewPostgresVoucherRepo(db *gorm.DB) VoucherRepository {
+	return &PostgresVoucherRepo{db: db}
+}
+
+// CreateBatch сохраняет партию и ее карты. Совпадение кода с существующим отменяет всю партию
+func (r *PostgresVoucherRepo) CreateBatch(tx *gorm.DB, batch *models.VoucherBatch, vouchers []models.Voucher) error {
+	if tx == nil {
+		tx = r.db
+	}
+	if err := tx.Create(batch).Error; err != nil {
+		return errors.ErrCreateVoucher
+	}
+	for i := range vouchers {
+		vouchers[i].BatchID = batch.ID
+	}
+	if err := tx.CreateInBatches(vouchers, 500).Error; err != nil {
+		return errors.ErrCreateVoucher
+	}
+	return nil
+}
+
+func (r *PostgresVoucherRepo) GetBatch(ctx context.Context, id int64) (*models.VoucherBatch, error) {
+	var batch models.VoucherBatch
+	if err := r.db.WithContext(ctx).First(&batch, id).Error; err != nil {
+		return nil, errors.ErrVoucherBatchNotFound
+	}
+	return &batch, nil
+}
+
+func (r *PostgresVoucherRepo) GetBatchVouchers(ctx context.Context, batchID int64) ([]models.Voucher, error) {
+	var vouchers []models.Voucher
+	if err := r.db.WithContext(ctx).Where("batch_id = ?", batchID).Order("id").Find(&vouchers).Error; err != nil {
+		return nil, errors.ErrFindVoucher
+	}
+	return vouchers, nil
+}
+
+func (r *PostgresVoucherRepo) GetVoucherForUpdate(tx *gorm.DB, code string) (*models.Voucher, error) {
+	if tx == nil {
+		tx = r.db
+	}
+	var voucher models.Voucher
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("code = ?", code).First(&voucher).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, errors.ErrVoucherInvalid
+	}
+	if err != nil {
+		return nil, errors.ErrFindVoucher
+	}
+	return &voucher, nil
+}
+
+func (r *PostgresVoucherRepo) UpdateVoucher(tx *gorm.DB, id int64, fields map[string]interface{}) error {
+	if tx == nil {
+		tx = r.db
+	}
+	if err := tx.Model(&models.Voucher{}).Where("id = ?", id).Updates(fields).Error; err != nil {
+		return errors.ErrUpdateVoucher
+	}
+	return nil
+}
+
+// ExpireVouchers переводит непогашенные карты с истекшим сроком в статус expired
+func (r *PostgresVoucherRepo) ExpireVouchers(ctx context.Context, now time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).Model(&models.Voucher{}).
+		Where("status IN ? AND expires_at <= ?", []models.VoucherStatus{models.VoucherIssued, models.VoucherSold}, now).
+		Update("status", models.VoucherExpired)
+	if result.Error != nil {
+		return 0, errors.ErrUpdateVoucher
+	}
+	return result.RowsAffected, nil
+}
@@ -3,17 +3,28 @@ package repository
 import (
 	models2 "computer-club/internal/repository/models"
 	"computer-club/pkg/errors"
+	"computer-club/pkg/money"
 	"context"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type WalletRepository interface {
-	GetBalance(ctx context.Context, userID int64) (float64, error)
+	GetBalance(ctx context.Context, userID int64) (money.Money, error)
+	GetWallet(ctx context.Context, userID int64) (*models2.Wallet, error)
+	GetWalletForUpdate(tx *gorm.DB, userID int64) (*models2.Wallet, error)
 	GetTransactions(ctx context.Context, userID int64) ([]models2.Transaction, error)
+	FindTransactions(ctx context.Context, filter models2.TransactionFilter) ([]models2.Transaction, error)
+	StreamTransactions(ctx context.Context, filter models2.TransactionFilter, fn func(*models2.Transaction) error) error
 	CreateWallet(ctx context.Context, wallet *models2.Wallet) error
-	Deposit(ctx context.Context, userID int64, amount float64) error
-	Withdraw(tx *gorm.DB, userID int64, amount float64) error
-	CreateTransaction(tx *gorm.DB, userID int64, amount float64, typ string, tariff *models2.Tariff) (*models2.Transaction, error)
+	Deposit(tx *gorm.DB, userID int64, amount money.Money) error
+	Withdraw(tx *gorm.DB, userID int64, amount money.Money) error
+	DepositBonus(tx *gorm.DB, userID int64, amount money.Money) error
+	WithdrawBonus(tx *gorm.DB, userID int64, amount money.Money) error
+	CreateTransaction(tx *gorm.DB, transaction *models2.Transaction) error
+	LockWallets(tx *gorm.DB, userIDs ...int64) error
+	GetTransactionForUpdate(tx *gorm.DB, id int64) (*models2.Transaction, error)
+	MarkReversed(tx *gorm.DB, id, reversalID int64) (bool, error)
 }
 
 type PostgresWalletRepo struct {
@@ -24,61 +35,126 @@ func NewPostgresWalletRepo(db *gorm.DB) WalletRepository {
 	return &PostgresWalletRepo{db: db}
 }
 
-func (r *PostgresWalletRepo) CreateTransaction(tx *gorm.DB, userID int64, amount float64, typ string, tariff *models2.Tariff) (*models2.Transaction, error) {
+func (r *PostgresWalletRepo) CreateTransaction(tx *gorm.DB, transaction *models2.Transaction) error {
 	if tx == nil {
 		tx = r.db
 	}
-	var tariffID int64
-	if tariff == nil {
-		tariffID = -1
-	} else {
-		tariffID = tariff.ID
+	if err := tx.Create(transaction).Error; err != nil {
+		return errors.ErrCreateTransaction
 	}
+	return nil
+}
 
-	transaction := models2.Transaction{
-		UserID:   userID,
-		Amount:   amount,
-		Type:     models2.TransactionType(typ),
-		TariffID: tariffID,
+func (r *PostgresWalletRepo) Deposit(tx *gorm.DB, userID int64, amount money.Money) error {
+	if tx == nil {
+		tx = r.db
 	}
-	if err := tx.Create(&transaction).Error; err != nil {
-		return nil, errors.ErrCreateTransaction
+	result := tx.Model(&models2.Wallet{}).
+		Where("user_id = ?", userID).
+		Update("balance_amount", gorm.Expr("balance_amount + ?", amount.Amount))
+	if result.Error != nil || result.RowsAffected == 0 {
+		return errors.ErrToDeposit
 	}
-	return &transaction, nil
+	return nil
 }
 
-func (r *PostgresWalletRepo) Deposit(ctx context.Context, userID int64, amount float64) error {
-	err := r.db.WithContext(ctx).
-		Model(&models2.Wallet{}).
+func (r *PostgresWalletRepo) Withdraw(tx *gorm.DB, userID int64, amount money.Money) error {
+	if tx == nil {
+		tx = r.db
+	}
+	result := tx.Model(&models2.Wallet{}).
+		Where("user_id = ? AND balance_amount >= ?", userID, amount.Amount).
+		Update("balance_amount", gorm.Expr("balance_amount - ?", amount.Amount))
+	if result.Error != nil {
+		return errors.ErrWithdraw
+	}
+	// Баланс мог уменьшиться между проверкой и списанием
+	if result.RowsAffected == 0 {
+		return errors.ErrInsufficientFunds
+	}
+	return nil
+}
+
+func (r *PostgresWalletRepo) DepositBonus(tx *gorm.DB, userID int64, amount money.Money) error {
+	if tx == nil {
+		tx = r.db
+	}
+	result := tx.Model(&models2.Wallet{}).
 		Where("user_id = ?", userID).
-		Update("balance", gorm.Expr("balance + ?", amount)).Error
-	if err != nil {
+		Update("bonus_amount", gorm.Expr("bonus_amount + ?", amount.Amount))
+	if result.Error != nil || result.RowsAffected == 0 {
 		return errors.ErrToDeposit
 	}
 	return nil
 }
 
-func (r *PostgresWalletRepo) Withdraw(tx *gorm.DB, userID int64, amount float64) error {
+func (r *PostgresWalletRepo) WithdrawBonus(tx *gorm.DB, userID int64, amount money.Money) error {
 	if tx == nil {
 		tx = r.db
 	}
-	err := tx.Model(&models2.Wallet{}).
-		Where("user_id = ? AND balance >= ?", userID, amount).
-		Update("balance", gorm.Expr("balance - ?", amount)).Error
-	if err != nil {
+	result := tx.Model(&models2.Wallet{}).
+		Where("user_id = ? AND bonus_amount >= ?", userID, amount.Amount).
+		Update("bonus_amount", gorm.Expr("bonus_amount - ?", amount.Amount))
+	if result.Error != nil {
 		return errors.ErrWithdraw
 	}
+	if result.RowsAffected == 0 {
+		return errors.ErrInsufficientFunds
+	}
+	return nil
+}
+
+// LockWallets блокирует строки кошельков до конца транзакции.
+// Строки блокируются в порядке user_id, чтобы встречные переводы не приводили к взаимной блокировке
+func (r *PostgresWalletRepo) LockWallets(tx *gorm.DB, userIDs ...int64) error {
+	if tx == nil {
+		tx = r.db
+	}
+	var wallets []models2.Wallet
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("user_id IN ?", userIDs).
+		Order("user_id").
+		Find(&wallets).Error
+	if err != nil {
+		return errors.ErrLockWallet
+	}
+	if len(wallets) != len(userIDs) {
+		return errors.ErrCheckBalance
+	}
 	return nil
 }
 
-func (r *PostgresWalletRepo) GetBalance(ctx context.Context, userID int64) (float64, error) {
+func (r *PostgresWalletRepo) GetBalance(ctx context.Context, userID int64) (money.Money, error) {
 	var wallet models2.Wallet
 	if err := r.db.WithContext(ctx).Where("user_id = ?", userID).First(&wallet).Error; err != nil {
-		return 0, errors.ErrCheckBalance
+		return money.Money{}, errors.ErrCheckBalance
 	}
 	return wallet.Balance, nil
 }
 
+func (r *PostgresWalletRepo) GetWallet(ctx context.Context, userID int64) (*models2.Wallet, error) {
+	var wallet models2.Wallet
+	if err := r.db.WithContext(ctx).Where("user_id = ?", userID).First(&wallet).Error; err != nil {
+		return nil, errors.ErrCheckBalance
+	}
+	return &wallet, nil
+}
+
+// GetWalletForUpdate читает кошелек с блокировкой строки до конца транзакции
+func (r *PostgresWalletRepo) GetWalletForUpdate(tx *gorm.DB, userID int64) (*models2.Wallet, error) {
+	if tx == nil {
+		tx = r.db
+	}
+	var wallet models2.Wallet
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("user_id = ?", userID).
+		First(&wallet).Error
+	if err != nil {
+		return nil, errors.ErrCheckBalance
+	}
+	return &wallet, nil
+}
+
 func (r *PostgresWalletRepo) GetTransactions(ctx context.Context, userID int64) ([]models2.Transaction, error) {
 	var transactions []models2.Transaction
 	err := r.db.WithContext(ctx).
@@ -92,6 +168,98 @@ func (r *PostgresWalletRepo) GetTransactions(ctx context.Context, userID int64)
 	return transactions, nil
 }
 
+func (r *PostgresWalletRepo) GetTransactionForUpdate(tx *gorm.DB, id int64) (*models2.Transaction, error) {
+	if tx == nil {
+		tx = r.db
+	}
+	var transaction models2.Transaction
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&transaction, id).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, errors.ErrTransactionNotFound
+	}
+	if err != nil {
+		return nil, errors.ErrCheckTransaction
+	}
+	return &transaction, nil
+}
+
+// MarkReversed связывает транзакцию со сторнирующей, если она еще не сторнирована
+func (r *PostgresWalletRepo) MarkReversed(tx *gorm.DB, id, reversalID int64) (bool, error) {
+	if tx == nil {
+		tx = r.db
+	}
+	result := tx.Model(&models2.Transaction{}).
+		Where("id = ? AND reversed_by IS NULL", id).
+		Update("reversed_by", reversalID)
+	if result.Error != nil {
+		return false, errors.ErrUpdateTransaction
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// FindTransactions возвращает до filter.Limit транзакций, подходящих под фильтр, от новых к старым
+func (r *PostgresWalletRepo) FindTransactions(ctx context.Context, filter models2.TransactionFilter) ([]models2.Transaction, error) {
+	var transactions []models2.Transaction
+	query := applyTransactionFilter(r.db.WithContext(ctx), filter)
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit)
+	}
+	if err := query.Find(&transactions).Error; err != nil {
+		return nil, errors.ErrCheckTransaction
+	}
+	return transactions, nil
+}
+
+// StreamTransactions построчно читает все транзакции под фильтром и передает их в fn,
+// не загружая выборку в память целиком. Limit не применяется
+func (r *PostgresWalletRepo) StreamTransactions(ctx context.Context, filter models2.TransactionFilter, fn func(*models2.Transaction) error) error {
+	db := r.db.WithContext(ctx)
+	rows, err := applyTransactionFilter(db.Model(&models2.Transaction{}), filter).Rows()
+	if err != nil {
+		return errors.ErrCheckTransaction
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var transaction models2.Transaction
+		if err := db.ScanRows(rows, &transaction); err != nil {
+			return errors.ErrCheckTransaction
+		}
+		if err := fn(&transaction); err != nil {
+			return err
+		}
+	}
+	if rows.Err() != nil {
+		return errors.ErrCheckTransaction
+	}
+	return nil
+}
+
+func applyTransactionFilter(query *gorm.DB, filter models2.TransactionFilter) *gorm.DB {
+	if filter.UserID != nil {
+		query = query.Where("user_id = ?", *filter.UserID)
+	}
+	if len(filter.Types) > 0 {
+		query = query.Where("type IN ?", filter.Types)
+	}
+	if filter.From != nil {
+		query = query.Where("created_at >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		query = query.Where("created_at < ?", *filter.To)
+	}
+	if filter.MinAmount != nil {
+		query = query.Where("amount_amount >= ?", filter.MinAmount.Amount)
+	}
+	if filter.MaxAmount != nil {
+		query = query.Where("amount_amount <= ?", filter.MaxAmount.Amount)
+	}
+	if filter.Cursor > 0 {
+		query = query.Where("id < ?", filter.Cursor)
+	}
+	return query.Order("id DESC")
+}
+
 func (r *PostgresWalletRepo) CreateWallet(ctx context.Context, wallet *models2.Wallet) error {
 	if err := r.db.WithContext(ctx).Create(wallet).Error; err != nil {
 		return errors.ErrCreateWallet
@@ -40,6 +40,21 @@ func (s *Server) Run() {
 		}
 	}()
 
+	// Фоновое завершение истекших сессий и сессий сверх ограничений аккаунта
+	go (*s.container.SessionUsecase).MonitorSessions(ctx)
+	// Фоновое истечение купленных пакетов
+	go (*s.container.PackageUsecase).MonitorPackages(ctx)
+	// Фоновое сгорание истекших бонусов
+	go (*s.container.WalletUsecase).MonitorBonuses(ctx)
+	// Фоновая регистрация чеков в фискальном регистраторе
+	go (*s.container.ReceiptUsecase).MonitorReceipts(ctx)
+	// Фоновое истечение подарочных карт
+	go (*s.container.VoucherUsecase).MonitorVouchers(ctx)
+	// Фоновое удаление истекших refresh-токенов
+	go (*s.container.UserUsecase).MonitorTokens(ctx)
+	// Ежемесячные счета организациям
+	go (*s.container.OrganizationUsecase).MonitorInvoices(ctx)
+
 	<-ctx.Done()
 
 	s.GracefulShutdown()
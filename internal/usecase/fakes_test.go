@@ -0,0 +1,252 @@
+package usecase
+
+import (
+	"computer-club/internal/repository"
+	"computer-club/internal/repository/models"
+	"computer-club/pkg/errors"
+	"computer-club/pkg/money"
+	"context"
+	"gorm.io/gorm"
+)
+
+// Хранилища в памяти для проверки сценариев usecase без базы. Фейки встраивают интерфейс
+// репозитория и реализуют только нужные сценарию методы: вызов остальных паникует
+
+// snapshotter - хранилище, состояние которого fakeTxManager откатывает при ошибке
+type snapshotter interface {
+	snapshot() (restore func())
+}
+
+// fakeTxManager выполняет fn без базы и откатывает подключенные хранилища, если fn вернула ошибку
+type fakeTxManager struct {
+	stores []snapshotter
+}
+
+func (m *fakeTxManager) WithTransaction(ctx context.Context, fn func(tx *gorm.DB) error) error {
+	restores := make([]func(), 0, len(m.stores))
+	for _, store := range m.stores {
+		restores = append(restores, store.snapshot())
+	}
+	err := fn(nil)
+	if err != nil {
+		for _, restore := range restores {
+			restore()
+		}
+	}
+	return err
+}
+
+// fakeLedgerRepo хранит счета и проводки журнала и, как и база, не принимает несбалансированные проводки
+type fakeLedgerRepo struct {
+	repository.LedgerRepository
+	accounts map[string]*models.LedgerAccount
+	entries  []models.JournalEntry
+}
+
+func newFakeLedgerRepo() *fakeLedgerRepo {
+	return &fakeLedgerRepo{accounts: map[string]*models.LedgerAccount{}}
+}
+
+func (r *fakeLedgerRepo) GetOrCreateAccount(tx *gorm.DB, ref models.AccountRef) (*models.LedgerAccount, error) {
+	if account, ok := r.accounts[ref.Code()]; ok {
+		return account, nil
+	}
+	account := &models.LedgerAccount{ID: int64(len(r.accounts) + 1), Code: ref.Code(), Type: ref.Type, UserID: ref.UserID}
+	r.accounts[ref.Code()] = account
+	return account, nil
+}
+
+func (r *fakeLedgerRepo) CreateEntry(tx *gorm.DB, entry *models.JournalEntry) error {
+	if len(entry.Postings) < 2 {
+		return errors.ErrUnbalancedEntry
+	}
+	sum := money.Zero()
+	for _, posting := range entry.Postings {
+		sum = sum.Add(posting.Amount)
+	}
+	if !sum.IsZero() {
+		return errors.ErrUnbalancedEntry
+	}
+	entry.ID = int64(len(r.entries) + 1)
+	r.entries = append(r.entries, *entry)
+	return nil
+}
+
+// balance возвращает сальдо счета: дебет с плюсом, кредит с минусом
+func (r *fakeLedgerRepo) balance(ref models.AccountRef) int64 {
+	account, ok := r.accounts[ref.Code()]
+	if !ok {
+		return 0
+	}
+	var total int64
+	for _, entry := range r.entries {
+		for _, posting := range entry.Postings {
+			if posting.AccountID == account.ID {
+				total += posting.Amount.Amount
+			}
+		}
+	}
+	return total
+}
+
+func (r *fakeLedgerRepo) snapshot() func() {
+	entries := append([]models.JournalEntry(nil), r.entries...)
+	return func() { r.entries = entries }
+}
+
+// fakeWalletRepo хранит кошельки и транзакции в памяти
+type fakeWalletRepo struct {
+	repository.WalletRepository
+	wallets      map[int64]*models.Wallet
+	transactions map[int64]*models.Transaction
+	nextID       int64
+}
+
+func newFakeWalletRepo(wallets ...models.Wallet) *fakeWalletRepo {
+	r := &fakeWalletRepo{wallets: map[int64]*models.Wallet{}, transactions: map[int64]*models.Transaction{}}
+	for i := range wallets {
+		wallet := wallets[i]
+		r.wallets[wallet.UserID] = &wallet
+	}
+	return r
+}
+
+func (r *fakeWalletRepo) wallet(userID int64) (*models.Wallet, error) {
+	wallet, ok := r.wallets[userID]
+	if !ok {
+		return nil, errors.ErrCheckBalance
+	}
+	return wallet, nil
+}
+
+func (r *fakeWalletRepo) GetBalance(ctx context.Context, userID int64) (money.Money, error) {
+	wallet, err := r.wallet(userID)
+	if err != nil {
+		return money.Money{}, err
+	}
+	return wallet.Balance, nil
+}
+
+func (r *fakeWalletRepo) GetWalletForUpdate(tx *gorm.DB, userID int64) (*models.Wallet, error) {
+	wallet, err := r.wallet(userID)
+	if err != nil {
+		return nil, err
+	}
+	copied := *wallet
+	return &copied, nil
+}
+
+func (r *fakeWalletRepo) LockWallets(tx *gorm.DB, userIDs ...int64) error {
+	for _, userID := range userIDs {
+		if _, err := r.wallet(userID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *fakeWalletRepo) Deposit(tx *gorm.DB, userID int64, amount money.Money) error {
+	wallet, err := r.wallet(userID)
+	if err != nil {
+		return errors.ErrToDeposit
+	}
+	wallet.Balance = wallet.Balance.Add(amount)
+	return nil
+}
+
+func (r *fakeWalletRepo) Withdraw(tx *gorm.DB, userID int64, amount money.Money) error {
+	wallet, err := r.wallet(userID)
+	if err != nil {
+		return errors.ErrWithdraw
+	}
+	if wallet.Balance.LessThan(amount) {
+		return errors.ErrInsufficientFunds
+	}
+	wallet.Balance = wallet.Balance.Sub(amount)
+	return nil
+}
+
+func (r *fakeWalletRepo) DepositBonus(tx *gorm.DB, userID int64, amount money.Money) error {
+	wallet, err := r.wallet(userID)
+	if err != nil {
+		return errors.ErrToDeposit
+	}
+	wallet.Bonus = wallet.Bonus.Add(amount)
+	return nil
+}
+
+func (r *fakeWalletRepo) WithdrawBonus(tx *gorm.DB, userID int64, amount money.Money) error {
+	wallet, err := r.wallet(userID)
+	if err != nil {
+		return errors.ErrWithdraw
+	}
+	if wallet.Bonus.LessThan(amount) {
+		return errors.ErrInsufficientFunds
+	}
+	wallet.Bonus = wallet.Bonus.Sub(amount)
+	return nil
+}
+
+func (r *fakeWalletRepo) CreateTransaction(tx *gorm.DB, transaction *models.Transaction) error {
+	r.nextID++
+	transaction.ID = r.nextID
+	stored := *transaction
+	r.transactions[transaction.ID] = &stored
+	return nil
+}
+
+func (r *fakeWalletRepo) GetTransactionForUpdate(tx *gorm.DB, id int64) (*models.Transaction, error) {
+	transaction, ok := r.transactions[id]
+	if !ok {
+		return nil, errors.ErrTransactionNotFound
+	}
+	copied := *transaction
+	return &copied, nil
+}
+
+func (r *fakeWalletRepo) MarkReversed(tx *gorm.DB, id, reversalID int64) (bool, error) {
+	transaction, ok := r.transactions[id]
+	if !ok || transaction.ReversedBy != nil {
+		return false, nil
+	}
+	transaction.ReversedBy = &reversalID
+	return true, nil
+}
+
+// ofType возвращает транзакции пользователя заданного типа
+func (r *fakeWalletRepo) ofType(userID int64, typ models.TransactionType) []models.Transaction {
+	var result []models.Transaction
+	for id := int64(1); id <= r.nextID; id++ {
+		if transaction, ok := r.transactions[id]; ok && transaction.UserID == userID && transaction.Type == typ {
+			result = append(result, *transaction)
+		}
+	}
+	return result
+}
+
+func (r *fakeWalletRepo) snapshot() func() {
+	wallets := make(map[int64]models.Wallet, len(r.wallets))
+	for userID, wallet := range r.wallets {
+		wallets[userID] = *wallet
+	}
+	transactions := make(map[int64]models.Transaction, len(r.transactions))
+	for id, transaction := range r.transactions {
+		transactions[id] = *transaction
+	}
+	nextID := r.nextID
+	return func() {
+		for userID, wallet := range wallets {
+			*r.wallets[userID] = wallet
+		}
+		r.transactions = map[int64]*models.Transaction{}
+		for id := range transactions {
+			transaction := transactions[id]
+			r.transactions[id] = &transaction
+		}
+		r.nextID = nextID
+	}
+}
+
+func rub(amount int64) money.Money {
+	return money.New(amount)
+}
@@ -0,0 +1,74 @@
+package usecase
+
+import (
+	"computer-club/internal/repository"
+	"computer-club/internal/repository/models"
+	"computer-club/pkg/errors"
+	"computer-club/pkg/money"
+	"context"
+	"gorm.io/gorm"
+	"time"
+)
+
+type LedgerService interface {
+	Move(tx *gorm.DB, debit, credit models.AccountRef, amount money.Money, description string, transactionID *int64) error
+	Reconcile(ctx context.Context) (*models.ReconciliationReport, error)
+}
+
+type LedgerUsecase struct {
+	ledgerRepo repository.LedgerRepository
+}
+
+func NewLedgerUsecase(ledgerRepo repository.LedgerRepository) LedgerService {
+	return &LedgerUsecase{ledgerRepo: ledgerRepo}
+}
+
+// Move записывает проводку из двух строк: дебет одного счета и кредит другого на одну сумму.
+// Вызывается внутри той же транзакции БД, что и изменение баланса кошелька
+func (u *LedgerUsecase) Move(tx *gorm.DB, debit, credit models.AccountRef, amount money.Money, description string, transactionID *int64) error {
+	if !amount.IsPositive() {
+		return errors.ErrInvalidAmount
+	}
+
+	debitAccount, err := u.ledgerRepo.GetOrCreateAccount(tx, debit)
+	if err != nil {
+		return err
+	}
+	creditAccount, err := u.ledgerRepo.GetOrCreateAccount(tx, credit)
+	if err != nil {
+		return err
+	}
+
+	entry := &models.JournalEntry{
+		TransactionID: transactionID,
+		Description:   description,
+		Postings: []models.LedgerPosting{
+			{AccountID: debitAccount.ID, Amount: amount},
+			{AccountID: creditAccount.ID, Amount: amount.Neg()},
+		},
+	}
+	return u.ledgerRepo.CreateEntry(tx, entry)
+}
+
+// Reconcile сверяет балансы кошельков игроков и организаций с журналом и проверяет,
+// что все проводки сбалансированы
+func (u *LedgerUsecase) Reconcile(ctx context.Context) (*models.ReconciliationReport, error) {
+	mismatches, err := u.ledgerRepo.FindWalletMismatches(ctx)
+	if err != nil {
+		return nil, err
+	}
+	organizations, err := u.ledgerRepo.FindOrganizationMismatches(ctx)
+	if err != nil {
+		return nil, err
+	}
+	unbalanced, err := u.ledgerRepo.FindUnbalancedEntries(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &models.ReconciliationReport{
+		CheckedAt:              time.Now(),
+		WalletMismatches:       mismatches,
+		OrganizationMismatches: organizations,
+		UnbalancedEntries:      unbalanced,
+	}, nil
+}
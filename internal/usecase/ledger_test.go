@@ -0,0 +1,65 @@
+package usecase
+
+import (
+	"computer-club/internal/repository/models"
+	"computer-club/pkg/errors"
+	"testing"
+)
+
+func TestLedgerMovePostsBalancedEntry(t *testing.T) {
+	ledgerRepo := newFakeLedgerRepo()
+	ledger := NewLedgerUsecase(ledgerRepo)
+	txID := int64(42)
+
+	customer := models.CustomerAccount(7)
+	drawer := models.SystemAccount(models.AccountCashDrawer)
+	if err := ledger.Move(nil, drawer, customer, rub(50000), "Пополнение", &txID); err != nil {
+		t.Fatalf("Move() error: %v", err)
+	}
+
+	if len(ledgerRepo.entries) != 1 {
+		t.Fatalf("entries = %d, want 1", len(ledgerRepo.entries))
+	}
+	entry := ledgerRepo.entries[0]
+	if entry.TransactionID == nil || *entry.TransactionID != txID || entry.Description != "Пополнение" {
+		t.Fatalf("entry = %+v, want transaction %d", entry, txID)
+	}
+	if got := ledgerRepo.balance(drawer); got != 50000 {
+		t.Errorf("дебет кассы = %d, want 50000", got)
+	}
+	if got := ledgerRepo.balance(customer); got != -50000 {
+		t.Errorf("кредит кошелька = %d, want -50000", got)
+	}
+}
+
+func TestLedgerMoveRejectsNonPositiveAmount(t *testing.T) {
+	ledgerRepo := newFakeLedgerRepo()
+	ledger := NewLedgerUsecase(ledgerRepo)
+
+	for _, amount := range []int64{0, -100} {
+		err := ledger.Move(nil, models.CustomerAccount(1), models.SystemAccount(models.AccountRevenue), rub(amount), "", nil)
+		if err != errors.ErrInvalidAmount {
+			t.Errorf("Move(%d) error = %v, want %v", amount, err, errors.ErrInvalidAmount)
+		}
+	}
+	if len(ledgerRepo.entries) != 0 {
+		t.Fatalf("entries = %d, want 0", len(ledgerRepo.entries))
+	}
+}
+
+func TestPaymentAccount(t *testing.T) {
+	tests := []struct {
+		method models.PaymentMethod
+		want   models.LedgerAccountType
+	}{
+		{method: models.PaymentCash, want: models.AccountCashDrawer},
+		{method: models.PaymentCard, want: models.AccountCardClearing},
+		{method: models.PaymentTransfer, want: models.AccountTransferClearing},
+		{method: models.PaymentOnline, want: models.AccountPaymentProvider},
+	}
+	for _, tt := range tests {
+		if got := models.PaymentAccount(tt.method); got.Type != tt.want || got.UserID != nil {
+			t.Errorf("PaymentAccount(%s) = %+v, want %s", tt.method, got, tt.want)
+		}
+	}
+}
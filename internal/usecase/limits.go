@@ -0,0 +1,249 @@
+package usecase
+
+import (
+	"computer-club/internal/repository"
+	"computer-club/internal/repository/models"
+	"computer-club/pkg/errors"
+	"computer-club/pkg/money"
+	"context"
+	"time"
+)
+
+type LimitsService interface {
+	GetStatus(ctx context.Context, userID int64) (*models.LimitsStatus, error)
+	SetLimits(ctx context.Context, actorID int64, isAdmin bool, limits *models.UserLimits) (*models.UserLimits, error)
+	SetGuardian(ctx context.Context, userID int64, guardianID *int64) error
+	GetWards(ctx context.Context, guardianID int64) ([]models.LimitsStatus, error)
+	CanManage(ctx context.Context, actorID int64, isAdmin bool, userID int64) (bool, error)
+	CheckSession(ctx context.Context, userID int64, tariff *models.Tariff, price money.Money, now time.Time) error
+	CheckPurchase(ctx context.Context, userID int64, amount money.Money, now time.Time) error
+	CheckRunningSession(ctx context.Context, session *models.Session, now time.Time) error
+}
+
+type LimitsUsecase struct {
+	limitsRepo repository.LimitsRepository
+	userRepo   repository.UserRepository
+}
+
+func NewLimitsUsecase(limitsRepo repository.LimitsRepository, userRepo repository.UserRepository) LimitsService {
+	return &LimitsUsecase{limitsRepo: limitsRepo, userRepo: userRepo}
+}
+
+func (u *LimitsUsecase) GetStatus(ctx context.Context, userID int64) (*models.LimitsStatus, error) {
+	limits, err := u.limitsRepo.GetLimits(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	usage, err := u.usage(ctx, userID, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	return &models.LimitsStatus{Limits: limits, Usage: *usage}, nil
+}
+
+// SetLimits заменяет ограничения игрока. Менять их может администратор или опекун игрока
+func (u *LimitsUsecase) SetLimits(ctx context.Context, actorID int64, isAdmin bool, limits *models.UserLimits) (*models.UserLimits, error) {
+	allowed, err := u.CanManage(ctx, actorID, isAdmin, limits.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if !allowed {
+		return nil, errors.ErrForbidden
+	}
+	if err := validateLimits(limits); err != nil {
+		return nil, err
+	}
+	if _, err := u.userRepo.GetUserByID(ctx, limits.UserID); err != nil {
+		return nil, errors.ErrUserNotFound
+	}
+
+	if limits.DailySpend.IsZero() {
+		limits.DailySpend = money.Zero()
+	}
+	if limits.WeeklySpend.IsZero() {
+		limits.WeeklySpend = money.Zero()
+	}
+	if limits.AllowedTariffs == nil {
+		limits.AllowedTariffs = []int64{}
+	}
+	limits.UpdatedBy = &actorID
+	limits.UpdatedAt = time.Now()
+	if err := u.limitsRepo.SaveLimits(ctx, limits); err != nil {
+		return nil, err
+	}
+	return u.limitsRepo.GetLimits(ctx, limits.UserID)
+}
+
+// SetGuardian привязывает игрока к опекуну. guardianID == nil снимает привязку
+func (u *LimitsUsecase) SetGuardian(ctx context.Context, userID int64, guardianID *int64) error {
+	if _, err := u.userRepo.GetUserByID(ctx, userID); err != nil {
+		return errors.ErrUserNotFound
+	}
+	if guardianID != nil {
+		if *guardianID == userID {
+			return errors.ErrInvalidGuardian
+		}
+		if _, err := u.userRepo.GetUserByID(ctx, *guardianID); err != nil {
+			return errors.ErrInvalidGuardian
+		}
+	}
+	return u.limitsRepo.SetGuardian(ctx, userID, guardianID)
+}
+
+// GetWards возвращает подопечных опекуна вместе с их текущим расходом
+func (u *LimitsUsecase) GetWards(ctx context.Context, guardianID int64) ([]models.LimitsStatus, error) {
+	wards, err := u.limitsRepo.GetWards(ctx, guardianID)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	statuses := make([]models.LimitsStatus, 0, len(wards))
+	for i := range wards {
+		usage, err := u.usage(ctx, wards[i].UserID, now)
+		if err != nil {
+			return nil, err
+		}
+		statuses = append(statuses, models.LimitsStatus{Limits: &wards[i], Usage: *usage})
+	}
+	return statuses, nil
+}
+
+// CanManage проверяет, может ли actorID менять ограничения игрока userID
+func (u *LimitsUsecase) CanManage(ctx context.Context, actorID int64, isAdmin bool, userID int64) (bool, error) {
+	if isAdmin {
+		return true, nil
+	}
+	limits, err := u.limitsRepo.GetLimits(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	return limits.GuardianID != nil && *limits.GuardianID == actorID, nil
+}
+
+// CheckSession проверяет, что игрок может начать сессию по тарифу за указанную цену.
+// Сессия должна целиком уложиться в разрешенные часы, а не только начаться в них
+func (u *LimitsUsecase) CheckSession(ctx context.Context, userID int64, tariff *models.Tariff, price money.Money, now time.Time) error {
+	limits, err := u.limitsRepo.GetLimits(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !limits.AllowsTariff(tariff.ID) {
+		return errors.ErrTariffNotAllowed
+	}
+	if !limits.AllowsInterval(now, now.Add(time.Duration(tariff.Duration)*time.Minute)) {
+		return errors.ErrOutsideAllowedHours
+	}
+	if limits.DailyMinutes == 0 && limits.DailySpend.IsZero() && limits.WeeklySpend.IsZero() {
+		return nil
+	}
+
+	usage, err := u.usage(ctx, userID, now)
+	if err != nil {
+		return err
+	}
+	if limits.DailyMinutes > 0 && usage.MinutesToday+tariff.Duration > limits.DailyMinutes {
+		return errors.ErrDailyMinutesLimit
+	}
+	return checkSpend(limits, usage, price)
+}
+
+// CheckPurchase проверяет операцию вне сессии: покупку пакета, перевод, обмен баллов или
+// погашение карты. Вне разрешенных часов такие операции запрещены, а траты входят в дневной
+// и недельный лимиты наравне с оплатой сессий
+func (u *LimitsUsecase) CheckPurchase(ctx context.Context, userID int64, amount money.Money, now time.Time) error {
+	limits, err := u.limitsRepo.GetLimits(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !limits.AllowsTime(now) {
+		return errors.ErrOutsideAllowedHours
+	}
+	if !amount.IsPositive() || (limits.DailySpend.IsZero() && limits.WeeklySpend.IsZero()) {
+		return nil
+	}
+
+	usage, err := u.usage(ctx, userID, now)
+	if err != nil {
+		return err
+	}
+	return checkSpend(limits, usage, amount)
+}
+
+// checkSpend проверяет, что трата amount не выводит расход за дневной и недельный лимиты
+func checkSpend(limits *models.UserLimits, usage *models.LimitsUsage, amount money.Money) error {
+	if limits.DailySpend.IsPositive() && limits.DailySpend.LessThan(usage.SpentToday.Add(amount)) {
+		return errors.ErrDailySpendLimit
+	}
+	if limits.WeeklySpend.IsPositive() && limits.WeeklySpend.LessThan(usage.SpentThisWeek.Add(amount)) {
+		return errors.ErrWeeklySpendLimit
+	}
+	return nil
+}
+
+// CheckRunningSession проверяет идущую сессию: возвращает ErrOutsideAllowedHours, если закончились
+// разрешенные часы, и ErrDailyMinutesLimit, если исчерпан дневной лимит минут
+func (u *LimitsUsecase) CheckRunningSession(ctx context.Context, session *models.Session, now time.Time) error {
+	limits, err := u.limitsRepo.GetLimits(ctx, session.UserID)
+	if err != nil {
+		return err
+	}
+	if !limits.AllowsTime(now) {
+		return errors.ErrOutsideAllowedHours
+	}
+	if limits.DailyMinutes > 0 {
+		minutes, err := u.limitsRepo.MinutesPlayed(ctx, session.UserID, startOfDay(now), now)
+		if err != nil {
+			return err
+		}
+		if minutes >= limits.DailyMinutes {
+			return errors.ErrDailyMinutesLimit
+		}
+	}
+	return nil
+}
+
+func (u *LimitsUsecase) usage(ctx context.Context, userID int64, now time.Time) (*models.LimitsUsage, error) {
+	day := startOfDay(now)
+	spentToday, err := u.limitsRepo.SumSpend(ctx, userID, day)
+	if err != nil {
+		return nil, err
+	}
+	spentThisWeek, err := u.limitsRepo.SumSpend(ctx, userID, startOfWeek(now))
+	if err != nil {
+		return nil, err
+	}
+	minutes, err := u.limitsRepo.MinutesPlayed(ctx, userID, day, now)
+	if err != nil {
+		return nil, err
+	}
+	return &models.LimitsUsage{SpentToday: spentToday, SpentThisWeek: spentThisWeek, MinutesToday: minutes}, nil
+}
+
+func validateLimits(limits *models.UserLimits) error {
+	if limits.DailySpend.IsNegative() || limits.WeeklySpend.IsNegative() || limits.DailyMinutes < 0 {
+		return errors.ErrInvalidLimits
+	}
+	from, to := limits.AllowedFromHour, limits.AllowedToHour
+	if (from == nil) != (to == nil) {
+		return errors.ErrInvalidLimits
+	}
+	if from != nil && (*from < 0 || *from > 23 || *to < 0 || *to > 23 || *from == *to) {
+		return errors.ErrInvalidLimits
+	}
+	return nil
+}
+
+func startOfDay(t time.Time) time.Time {
+	year, month, day := t.Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, t.Location())
+}
+
+// startOfWeek возвращает начало понедельника текущей недели
+func startOfWeek(t time.Time) time.Time {
+	offset := (int(t.Weekday()) + 6) % 7
+	return startOfDay(t).AddDate(0, 0, -offset)
+}
+
+func startOfMonth(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location())
+}
@@ -0,0 +1,233 @@
+package usecase
+
+import (
+	"computer-club/internal/repository"
+	"computer-club/internal/repository/models"
+	"computer-club/pkg/errors"
+	"context"
+	"log"
+	"strings"
+	"time"
+)
+
+type LoginGuardService interface {
+	Check(ctx context.Context, email, ip string) error
+	RecordFailure(ctx context.Context, userID *int64, email, ip string) error
+	RecordSuccess(ctx context.Context, user *models.User, ip string) error
+	Unlock(ctx context.Context, actorID, userID int64) error
+	ListEvents(ctx context.Context, filter models.LoginEventFilter) (*models.LoginEventPage, error)
+}
+
+// LoginPolicy - защита входа от перебора паролей. Неудачи считаются в окне Window отдельно
+// по аккаунту и по IP. С BackoffAfter неудач подряд аккаунт ждет перед следующей попыткой
+// BaseDelay, и пауза удваивается с каждой неудачей до MaxDelay. После MaxAccountFailures
+// аккаунт, а после MaxIPFailures IP блокируются на LockoutDuration. Порог по IP выше, потому
+// что из зала клуба все игроки входят с одного адреса
+type LoginPolicy struct {
+	Window             time.Duration
+	BackoffAfter       int64
+	BaseDelay          time.Duration
+	MaxDelay           time.Duration
+	MaxAccountFailures int64
+	MaxIPFailures      int64
+	LockoutDuration    time.Duration
+}
+
+// Размер страницы журнала входа по умолчанию и максимальный
+const (
+	DefaultLoginEventsLimit = 50
+	MaxLoginEventsLimit     = 200
+)
+
+type LoginGuardUsecase struct {
+	attemptRepo    repository.AttemptRepository
+	loginEventRepo repository.LoginEventRepository
+	userRepo       repository.UserRepository
+	policy         LoginPolicy
+}
+
+func NewLoginGuardUsecase(attemptRepo repository.AttemptRepository,
+	loginEventRepo repository.LoginEventRepository,
+	userRepo repository.UserRepository,
+	policy LoginPolicy) LoginGuardService {
+	return &LoginGuardUsecase{
+		attemptRepo:    attemptRepo,
+		loginEventRepo: loginEventRepo,
+		userRepo:       userRepo,
+		policy:         policy,
+	}
+}
+
+// Check отклоняет вход, пока действует пауза после неудач или блокировка аккаунта либо IP
+func (u *LoginGuardUsecase) Check(ctx context.Context, email, ip string) error {
+	account := normalizeLoginEmail(email)
+	if account != "" {
+		locked, err := u.attemptRepo.BlockedFor(ctx, accountLockoutKey(account))
+		if err != nil {
+			return err
+		}
+		if locked > 0 {
+			return errors.ErrAccountLocked
+		}
+		wait, err := u.attemptRepo.BlockedFor(ctx, accountAttemptKey(account))
+		if err != nil {
+			return err
+		}
+		if wait > 0 {
+			return errors.ErrLoginThrottled
+		}
+	}
+	if ip != "" {
+		locked, err := u.attemptRepo.BlockedFor(ctx, ipLockoutKey(ip))
+		if err != nil {
+			return err
+		}
+		if locked > 0 {
+			return errors.ErrLoginThrottled
+		}
+	}
+	return nil
+}
+
+// RecordFailure учитывает неверный пароль и назначает паузу или блокировку. userID пустой,
+// если такого email нет: неудачи по нему считаются так же, чтобы по ответам нельзя было
+// отличить существующий аккаунт
+func (u *LoginGuardUsecase) RecordFailure(ctx context.Context, userID *int64, email, ip string) error {
+	account := normalizeLoginEmail(email)
+	if account != "" {
+		failures, err := u.attemptRepo.Add(ctx, accountAttemptKey(account), u.policy.Window)
+		if err != nil {
+			return err
+		}
+		switch {
+		case failures >= u.policy.MaxAccountFailures:
+			if err := u.attemptRepo.Block(ctx, accountLockoutKey(account), u.policy.LockoutDuration); err != nil {
+				return err
+			}
+			u.audit(ctx, &models.LoginEvent{Type: models.LoginEventAccountLocked, UserID: userID, Email: account, IP: ip, Failures: failures})
+		case failures >= u.policy.BackoffAfter:
+			if err := u.attemptRepo.Block(ctx, accountAttemptKey(account), u.backoff(failures)); err != nil {
+				return err
+			}
+			u.audit(ctx, &models.LoginEvent{Type: models.LoginEventBackoff, UserID: userID, Email: account, IP: ip, Failures: failures})
+		}
+	}
+
+	if ip != "" {
+		failures, err := u.attemptRepo.Add(ctx, ipAttemptKey(ip), u.policy.Window)
+		if err != nil {
+			return err
+		}
+		if failures >= u.policy.MaxIPFailures {
+			if err := u.attemptRepo.Block(ctx, ipLockoutKey(ip), u.policy.LockoutDuration); err != nil {
+				return err
+			}
+			u.audit(ctx, &models.LoginEvent{Type: models.LoginEventIPLocked, IP: ip, Failures: failures})
+		}
+	}
+	return nil
+}
+
+// RecordSuccess обнуляет счетчик аккаунта. Счетчик IP не обнуляется: иначе перебор чужих
+// паролей можно было бы прерывать входом в свой аккаунт. Вход после серии неудач попадает
+// в журнал: пароль мог быть подобран
+func (u *LoginGuardUsecase) RecordSuccess(ctx context.Context, user *models.User, ip string) error {
+	account := normalizeLoginEmail(user.Email)
+	failures, err := u.attemptRepo.Count(ctx, accountAttemptKey(account))
+	if err != nil {
+		return err
+	}
+	if failures == 0 {
+		return nil
+	}
+	if err := u.attemptRepo.Reset(ctx, accountAttemptKey(account)); err != nil {
+		return err
+	}
+	if failures >= u.policy.BackoffAfter {
+		u.audit(ctx, &models.LoginEvent{Type: models.LoginEventSuccessAfterFailures, UserID: &user.ID, Email: account, IP: ip, Failures: failures})
+	}
+	return nil
+}
+
+// Unlock снимает паузу и блокировку входа с аккаунта пользователя
+func (u *LoginGuardUsecase) Unlock(ctx context.Context, actorID, userID int64) error {
+	user, err := u.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return errors.ErrUserNotFound
+	}
+	account := normalizeLoginEmail(user.Email)
+	if err := u.attemptRepo.Reset(ctx, accountAttemptKey(account)); err != nil {
+		return err
+	}
+	if err := u.attemptRepo.Reset(ctx, accountLockoutKey(account)); err != nil {
+		return err
+	}
+	u.audit(ctx, &models.LoginEvent{Type: models.LoginEventUnlocked, UserID: &user.ID, Email: account, ActorID: &actorID})
+	return nil
+}
+
+// ListEvents возвращает страницу журнала входа, новые записи первыми
+func (u *LoginGuardUsecase) ListEvents(ctx context.Context, filter models.LoginEventFilter) (*models.LoginEventPage, error) {
+	if filter.Type != "" && !filter.Type.Valid() {
+		return nil, errors.ErrInvalidLoginEventFilter
+	}
+	switch {
+	case filter.Limit <= 0:
+		filter.Limit = DefaultLoginEventsLimit
+	case filter.Limit > MaxLoginEventsLimit:
+		filter.Limit = MaxLoginEventsLimit
+	}
+	limit := filter.Limit
+	filter.Limit++
+
+	events, err := u.loginEventRepo.FindLoginEvents(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	page := &models.LoginEventPage{Events: events}
+	if len(events) > limit {
+		page.Events = events[:limit]
+		page.NextCursor = &page.Events[limit-1].ID
+	}
+	return page, nil
+}
+
+// backoff - пауза после failures неудач: BaseDelay, удваиваемая с каждой неудачей сверх BackoffAfter
+func (u *LoginGuardUsecase) backoff(failures int64) time.Duration {
+	delay := u.policy.BaseDelay
+	for i := u.policy.BackoffAfter; i < failures && delay < u.policy.MaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > u.policy.MaxDelay {
+		delay = u.policy.MaxDelay
+	}
+	return delay
+}
+
+// audit пишет событие в журнал. Сбой журнала не должен мешать входу, поэтому ошибка только логируется
+func (u *LoginGuardUsecase) audit(ctx context.Context, event *models.LoginEvent) {
+	event.CreatedAt = time.Now()
+	if err := u.loginEventRepo.CreateLoginEvent(ctx, event); err != nil {
+		log.Printf("Не удалось записать событие входа %s: %v", event.Type, err)
+	}
+}
+
+func normalizeLoginEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+func accountAttemptKey(email string) string {
+	return "login:account:" + email
+}
+
+func accountLockoutKey(email string) string {
+	return "login:lockout:account:" + email
+}
+
+func ipAttemptKey(ip string) string {
+	return "login:ip:" + ip
+}
+
+func ipLockoutKey(ip string) string {
+	return "login:lockout:ip:" + ip
+}
@@ -0,0 +1,158 @@
+package usecase
+
+import (
+	"computer-club/internal/repository"
+	"computer-club/internal/repository/models"
+	"computer-club/pkg/errors"
+	"computer-club/pkg/money"
+	"context"
+	"gorm.io/gorm"
+	"math"
+	"time"
+)
+
+type LoyaltyService interface {
+	Award(tx *gorm.DB, userID int64, spent money.Money, minutes int64, transactionID *int64) error
+	GetTier(ctx context.Context, userID int64) (models.LoyaltyTier, error)
+	GetStatus(ctx context.Context, userID int64) (*models.LoyaltyStatus, error)
+	RedeemPoints(ctx context.Context, userID int64, minutes int64) (*models.UserPackage, error)
+}
+
+// LoyaltyPolicy - правила начисления баллов, пороги уровней и обмен баллов на время
+type LoyaltyPolicy struct {
+	PointsPerRouble       float64
+	PointsPerMinute       float64
+	Window                time.Duration
+	SilverSpend           money.Money
+	GoldSpend             money.Money
+	SilverDiscount        float64
+	GoldDiscount          float64
+	RedeemPointsPerMinute int64
+	RedeemValidityDays    int
+}
+
+type LoyaltyUsecase struct {
+	loyaltyRepo   repository.LoyaltyRepository
+	packageRepo   repository.PackageRepository
+	limitsService LimitsService
+	txManager     repository.TxManager
+	policy        LoyaltyPolicy
+}
+
+func NewLoyaltyUsecase(loyaltyRepo repository.LoyaltyRepository,
+	packageRepo repository.PackageRepository,
+	limitsService LimitsService,
+	txManager repository.TxManager,
+	policy LoyaltyPolicy) LoyaltyService {
+	return &LoyaltyUsecase{loyaltyRepo: loyaltyRepo,
+		packageRepo:   packageRepo,
+		limitsService: limitsService,
+		txManager:     txManager,
+		policy:        policy}
+}
+
+// Award начисляет баллы за оплаченную реальными деньгами сумму и сыгранные минуты.
+// Вызывается в транзакции оплаты, чтобы баллы не начислялись за несостоявшуюся покупку
+func (u *LoyaltyUsecase) Award(tx *gorm.DB, userID int64, spent money.Money, minutes int64, transactionID *int64) error {
+	points := int64(math.Floor(float64(spent.Amount)/100*u.policy.PointsPerRouble +
+		float64(minutes)*u.policy.PointsPerMinute))
+	if points <= 0 {
+		return nil
+	}
+	if err := u.loyaltyRepo.AddPoints(tx, userID, points); err != nil {
+		return err
+	}
+	return u.loyaltyRepo.CreateEvent(tx, &models.LoyaltyEvent{
+		UserID:        userID,
+		Type:          models.LoyaltyEarn,
+		Points:        points,
+		TransactionID: transactionID,
+	})
+}
+
+// GetTier определяет уровень по тратам за последние policy.Window
+func (u *LoyaltyUsecase) GetTier(ctx context.Context, userID int64) (models.LoyaltyTier, error) {
+	spend, err := u.loyaltyRepo.SumSpend(ctx, userID, time.Now().Add(-u.policy.Window))
+	if err != nil {
+		return "", err
+	}
+	return u.tierFor(spend), nil
+}
+
+func (u *LoyaltyUsecase) GetStatus(ctx context.Context, userID int64) (*models.LoyaltyStatus, error) {
+	account, err := u.loyaltyRepo.GetAccount(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	spend, err := u.loyaltyRepo.SumSpend(ctx, userID, time.Now().Add(-u.policy.Window))
+	if err != nil {
+		return nil, err
+	}
+
+	status := &models.LoyaltyStatus{
+		Points:       account.Points,
+		Tier:         u.tierFor(spend),
+		RollingSpend: spend,
+		ToNextTier:   money.Zero(),
+	}
+	switch status.Tier {
+	case models.TierBronze:
+		status.NextTier = models.TierSilver
+		status.ToNextTier = u.policy.SilverSpend.Sub(spend)
+	case models.TierSilver:
+		status.Discount = u.policy.SilverDiscount
+		status.NextTier = models.TierGold
+		status.ToNextTier = u.policy.GoldSpend.Sub(spend)
+	case models.TierGold:
+		status.Discount = u.policy.GoldDiscount
+	}
+	return status, nil
+}
+
+// RedeemPoints обменивает баллы на пакет минут
+func (u *LoyaltyUsecase) RedeemPoints(ctx context.Context, userID int64, minutes int64) (*models.UserPackage, error) {
+	if minutes <= 0 {
+		return nil, errors.ErrInvalidMinutes
+	}
+	if err := u.limitsService.CheckPurchase(ctx, userID, money.Zero(), time.Now()); err != nil {
+		return nil, err
+	}
+	points := minutes * u.policy.RedeemPointsPerMinute
+
+	userPackage := &models.UserPackage{
+		UserID:       userID,
+		Name:         "Обмен баллов лояльности",
+		Type:         models.PackageMinutes,
+		MinutesTotal: minutes,
+		MinutesLeft:  minutes,
+		Status:       models.PackageActive,
+		ExpiresAt:    time.Now().AddDate(0, 0, u.policy.RedeemValidityDays),
+	}
+	err := u.txManager.WithTransaction(ctx, func(tx *gorm.DB) error {
+		if err := u.loyaltyRepo.SpendPoints(tx, userID, points); err != nil {
+			return err
+		}
+		if err := u.loyaltyRepo.CreateEvent(tx, &models.LoyaltyEvent{
+			UserID: userID,
+			Type:   models.LoyaltyRedeem,
+			Points: -points,
+		}); err != nil {
+			return err
+		}
+		return u.packageRepo.CreateUserPackage(tx, userPackage)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return userPackage, nil
+}
+
+func (u *LoyaltyUsecase) tierFor(spend money.Money) models.LoyaltyTier {
+	switch {
+	case !spend.LessThan(u.policy.GoldSpend):
+		return models.TierGold
+	case !spend.LessThan(u.policy.SilverSpend):
+		return models.TierSilver
+	}
+	return models.TierBronze
+}
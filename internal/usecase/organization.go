@@ -0,0 +1,350 @@
+package usecase
+
+import (
+	"computer-club/internal/repository"
+	"computer-club/internal/repository/models"
+	"computer-club/pkg/errors"
+	"computer-club/pkg/money"
+	"context"
+	"fmt"
+	"gorm.io/gorm"
+	"log"
+	"strings"
+	"time"
+)
+
+// InvoicePeriodLayout - формат периода ежемесячного счета организации
+const InvoicePeriodLayout = "2006-01"
+
+type OrganizationService interface {
+	Create(ctx context.Context, name string, ownerID int64) (*models.OrganizationDetails, error)
+	GetDetails(ctx context.Context, organizationID, actorID int64, isAdmin bool) (*models.OrganizationDetails, error)
+	AddMember(ctx context.Context, organizationID, actorID int64, isAdmin bool, userID int64, cap money.Money) (*models.OrganizationMember, error)
+	UpdateMember(ctx context.Context, organizationID, actorID int64, isAdmin bool, userID int64, cap money.Money) error
+	RemoveMember(ctx context.Context, organizationID, actorID int64, isAdmin bool, userID int64) error
+	Deposit(ctx context.Context, organizationID, adminID int64, amount money.Money, method models.PaymentMethod) (*models.Transaction, error)
+	Charge(tx *gorm.DB, transaction *models.Transaction) error
+	GenerateInvoice(ctx context.Context, organizationID int64, period string) (*models.OrganizationInvoice, error)
+	GetInvoices(ctx context.Context, organizationID, actorID int64, isAdmin bool) ([]models.OrganizationInvoice, error)
+	MonitorInvoices(ctx context.Context)
+}
+
+type OrganizationUsecase struct {
+	organizationRepo repository.OrganizationRepository
+	userRepo         repository.UserRepository
+	shiftRepo        repository.ShiftRepository
+	walletRepo       repository.WalletRepository
+	ledgerService    LedgerService
+	receiptService   ReceiptService
+	txManager        repository.TxManager
+}
+
+func NewOrganizationUsecase(organizationRepo repository.OrganizationRepository,
+	userRepo repository.UserRepository,
+	shiftRepo repository.ShiftRepository,
+	walletRepo repository.WalletRepository,
+	ledgerService LedgerService,
+	receiptService ReceiptService,
+	txManager repository.TxManager) OrganizationService {
+	return &OrganizationUsecase{organizationRepo: organizationRepo,
+		userRepo:       userRepo,
+		shiftRepo:      shiftRepo,
+		walletRepo:     walletRepo,
+		ledgerService:  ledgerService,
+		receiptService: receiptService,
+		txManager:      txManager}
+}
+
+// Create заводит организацию с пустым кошельком, владелец сразу становится ее участником без лимита
+func (u *OrganizationUsecase) Create(ctx context.Context, name string, ownerID int64) (*models.OrganizationDetails, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || ownerID <= 0 {
+		return nil, errors.ErrInvalidOrganization
+	}
+	if _, err := u.userRepo.GetUserByID(ctx, ownerID); err != nil {
+		return nil, errors.ErrUserNotFound
+	}
+
+	organization := &models.Organization{
+		Name:    name,
+		OwnerID: ownerID,
+		Balance: money.Zero(),
+	}
+	owner := models.OrganizationMember{
+		UserID:     ownerID,
+		Role:       models.OrgRoleOwner,
+		MonthlyCap: money.Zero(),
+	}
+	err := u.txManager.WithTransaction(ctx, func(tx *gorm.DB) error {
+		if err := u.organizationRepo.CreateOrganization(tx, organization); err != nil {
+			return err
+		}
+		owner.OrganizationID = organization.ID
+		return u.organizationRepo.AddMember(tx, &owner)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &models.OrganizationDetails{Organization: organization, Members: []models.OrganizationMember{owner}}, nil
+}
+
+// GetDetails возвращает организацию с участниками администратору или участнику организации
+func (u *OrganizationUsecase) GetDetails(ctx context.Context, organizationID, actorID int64, isAdmin bool) (*models.OrganizationDetails, error) {
+	organization, err := u.organizationRepo.GetOrganization(ctx, organizationID)
+	if err != nil {
+		return nil, err
+	}
+	if !isAdmin {
+		if _, err := u.organizationRepo.GetMember(ctx, organizationID, actorID); err != nil {
+			return nil, err
+		}
+	}
+	members, err := u.organizationRepo.GetMembers(ctx, organizationID)
+	if err != nil {
+		return nil, err
+	}
+	return &models.OrganizationDetails{Organization: organization, Members: members}, nil
+}
+
+func (u *OrganizationUsecase) AddMember(ctx context.Context, organizationID, actorID int64, isAdmin bool, userID int64, cap money.Money) (*models.OrganizationMember, error) {
+	if _, err := u.authorizeOwner(ctx, organizationID, actorID, isAdmin); err != nil {
+		return nil, err
+	}
+	if cap.IsNegative() {
+		return nil, errors.ErrInvalidAmount
+	}
+	if _, err := u.userRepo.GetUserByID(ctx, userID); err != nil {
+		return nil, errors.ErrUserNotFound
+	}
+
+	member := &models.OrganizationMember{
+		OrganizationID: organizationID,
+		UserID:         userID,
+		Role:           models.OrgRoleMember,
+		MonthlyCap:     cap,
+	}
+	if err := u.organizationRepo.AddMember(nil, member); err != nil {
+		return nil, err
+	}
+	return member, nil
+}
+
+func (u *OrganizationUsecase) UpdateMember(ctx context.Context, organizationID, actorID int64, isAdmin bool, userID int64, cap money.Money) error {
+	if _, err := u.authorizeOwner(ctx, organizationID, actorID, isAdmin); err != nil {
+		return err
+	}
+	if cap.IsNegative() {
+		return errors.ErrInvalidAmount
+	}
+	return u.organizationRepo.UpdateMemberCap(ctx, organizationID, userID, cap)
+}
+
+func (u *OrganizationUsecase) RemoveMember(ctx context.Context, organizationID, actorID int64, isAdmin bool, userID int64) error {
+	organization, err := u.authorizeOwner(ctx, organizationID, actorID, isAdmin)
+	if err != nil {
+		return err
+	}
+	if userID == organization.OwnerID {
+		return errors.ErrCannotRemoveOwner
+	}
+	return u.organizationRepo.RemoveMember(ctx, organizationID, userID)
+}
+
+// authorizeOwner пропускает администратора клуба и владельца организации
+func (u *OrganizationUsecase) authorizeOwner(ctx context.Context, organizationID, actorID int64, isAdmin bool) (*models.Organization, error) {
+	organization, err := u.organizationRepo.GetOrganization(ctx, organizationID)
+	if err != nil {
+		return nil, err
+	}
+	if !isAdmin && organization.OwnerID != actorID {
+		return nil, errors.ErrForbidden
+	}
+	return organization, nil
+}
+
+// Deposit пополняет кошелек организации. Транзакция записывается на владельца с пометкой организации,
+// правило смены то же, что и при пополнении личного кошелька: наличные только при открытой смене
+func (u *OrganizationUsecase) Deposit(ctx context.Context, organizationID, adminID int64, amount money.Money, method models.PaymentMethod) (*models.Transaction, error) {
+	if !amount.IsPositive() {
+		return nil, errors.ErrInvalidAmount
+	}
+	if !method.Valid() {
+		return nil, errors.ErrInvalidPaymentMethod
+	}
+
+	var transaction *models.Transaction
+	err := u.txManager.WithTransaction(ctx, func(tx *gorm.DB) error {
+		organization, err := u.organizationRepo.GetOrganizationForUpdate(tx, organizationID)
+		if err != nil {
+			return err
+		}
+		if organization.Balance.Currency != amount.Currency {
+			return errors.ErrCurrencyMismatch
+		}
+
+		transaction = &models.Transaction{
+			UserID:         organization.OwnerID,
+			OrganizationID: &organization.ID,
+			Amount:         amount,
+			Type:           models.Add,
+			TariffID:       -1,
+			AdminID:        &adminID,
+			PaymentMethod:  method,
+		}
+		shift, err := u.shiftRepo.LockOpenShift(tx)
+		switch {
+		case err == nil:
+			transaction.ShiftID = &shift.ID
+		case err != errors.ErrNoOpenShift || method == models.PaymentCash:
+			return err
+		}
+
+		if err := u.organizationRepo.Deposit(tx, organization.ID, amount); err != nil {
+			return err
+		}
+		if err := u.walletRepo.CreateTransaction(tx, transaction); err != nil {
+			return err
+		}
+		if err := u.ledgerService.Move(tx, models.PaymentAccount(method), models.OrganizationAccount(organization.ID),
+			amount, fmt.Sprintf("Пополнение кошелька организации %d", organization.ID), &transaction.ID); err != nil {
+			return err
+		}
+		_, err = u.receiptService.Issue(tx, transaction)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return transaction, nil
+}
+
+// Charge оплачивает сессию участника с кошелька организации в переданной транзакции БД.
+// Организация блокируется до конца транзакции, поэтому лимит участника и баланс
+// проверяются без гонок между параллельными запусками сессий
+func (u *OrganizationUsecase) Charge(tx *gorm.DB, transaction *models.Transaction) error {
+	if transaction.OrganizationID == nil {
+		return errors.ErrInvalidOrganizationID
+	}
+	organization, err := u.organizationRepo.GetOrganizationForUpdate(tx, *transaction.OrganizationID)
+	if err != nil {
+		return err
+	}
+	member, err := u.organizationRepo.GetMember(tx.Statement.Context, organization.ID, transaction.UserID)
+	if err != nil {
+		return err
+	}
+
+	amount := transaction.Amount
+	transaction.Balance = models.BalanceReal
+	if !amount.IsPositive() {
+		return u.walletRepo.CreateTransaction(tx, transaction)
+	}
+
+	if member.MonthlyCap.IsPositive() {
+		spent, err := u.organizationRepo.SumMemberSpend(tx, organization.ID, member.UserID, startOfMonth(time.Now()))
+		if err != nil {
+			return err
+		}
+		if member.MonthlyCap.LessThan(spent.Add(amount)) {
+			return errors.ErrMemberCapExceeded
+		}
+	}
+	if organization.Balance.LessThan(amount) {
+		return errors.ErrInsufficientFunds
+	}
+
+	if err := u.organizationRepo.Withdraw(tx, organization.ID, amount); err != nil {
+		return err
+	}
+	if err := u.walletRepo.CreateTransaction(tx, transaction); err != nil {
+		return err
+	}
+	if err := u.ledgerService.Move(tx, models.OrganizationAccount(organization.ID), models.SystemAccount(models.AccountRevenue),
+		amount, fmt.Sprintf("Оплата сессии участника %d", member.UserID), &transaction.ID); err != nil {
+		return err
+	}
+	_, err = u.receiptService.Issue(tx, transaction)
+	return err
+}
+
+// GenerateInvoice формирует или пересчитывает счет организации за месяц period
+func (u *OrganizationUsecase) GenerateInvoice(ctx context.Context, organizationID int64, period string) (*models.OrganizationInvoice, error) {
+	from, err := time.ParseInLocation(InvoicePeriodLayout, period, time.Local)
+	if err != nil {
+		return nil, errors.ErrInvalidPeriod
+	}
+	if _, err := u.organizationRepo.GetOrganization(ctx, organizationID); err != nil {
+		return nil, err
+	}
+	to := from.AddDate(0, 1, 0)
+
+	lines, err := u.organizationRepo.SummarizeUsage(ctx, organizationID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	total := money.Zero()
+	for _, line := range lines {
+		total = total.Add(line.Amount)
+	}
+
+	invoice := &models.OrganizationInvoice{
+		OrganizationID: organizationID,
+		Period:         period,
+		From:           from,
+		To:             to,
+		Lines:          lines,
+		Total:          total,
+		CreatedAt:      time.Now(),
+	}
+	if err := u.organizationRepo.SaveInvoice(ctx, invoice); err != nil {
+		return nil, err
+	}
+	return invoice, nil
+}
+
+func (u *OrganizationUsecase) GetInvoices(ctx context.Context, organizationID, actorID int64, isAdmin bool) ([]models.OrganizationInvoice, error) {
+	if _, err := u.authorizeOwner(ctx, organizationID, actorID, isAdmin); err != nil {
+		return nil, err
+	}
+	return u.organizationRepo.GetInvoices(ctx, organizationID)
+}
+
+// MonitorInvoices раз в час выставляет счета за прошедший месяц организациям, у которых их еще нет
+func (u *OrganizationUsecase) MonitorInvoices(ctx context.Context) {
+	ticker := time.NewTicker(1 * time.Hour)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Println("Остановка выставления счетов организациям")
+			return
+		case <-ticker.C:
+			u.issueMonthlyInvoices(ctx)
+		}
+	}
+}
+
+func (u *OrganizationUsecase) issueMonthlyInvoices(ctx context.Context) {
+	period := startOfMonth(time.Now()).AddDate(0, -1, 0).Format(InvoicePeriodLayout)
+	ids, err := u.organizationRepo.GetOrganizationIDs(ctx)
+	if err != nil {
+		log.Printf("Не удалось получить организации для выставления счетов: %v", err)
+		return
+	}
+	for _, id := range ids {
+		_, err := u.organizationRepo.GetInvoice(ctx, id, period)
+		if err == nil {
+			continue
+		}
+		if err != errors.ErrInvoiceNotFound {
+			log.Printf("Не удалось проверить счет организации %d за %s: %v", id, period, err)
+			continue
+		}
+		if _, err := u.GenerateInvoice(ctx, id, period); err != nil {
+			log.Printf("Не удалось выставить счет организации %d за %s: %v", id, period, err)
+			continue
+		}
+		log.Printf("Выставлен счет организации %d за %s", id, period)
+	}
+}
@@ -0,0 +1,203 @@
+package usecase
+
+import (
+	"computer-club/internal/repository"
+	"computer-club/internal/repository/models"
+	"computer-club/pkg/errors"
+	"context"
+	"gorm.io/gorm"
+	"log"
+	"time"
+)
+
+type PackageService interface {
+	CreatePackage(ctx context.Context, pack *models.Package) error
+	GetPackages(ctx context.Context) ([]models.Package, error)
+	BuyPackage(ctx context.Context, userID int64, packageID int64) (*models.UserPackage, error)
+	GetUserPackages(ctx context.Context, userID int64) ([]models.UserPackage, error)
+	PlanCoverage(ctx context.Context, userID int64, tariff *models.Tariff, now time.Time) (*PackageCoverage, error)
+	ConsumeCoverage(tx *gorm.DB, coverage *PackageCoverage, transactionID int64) error
+	MonitorPackages(ctx context.Context)
+}
+
+// PackageAllocation - сколько минут будет списано с конкретного пакета
+type PackageAllocation struct {
+	UserPackageID int64
+	Minutes       int64
+}
+
+// PackageCoverage - часть сессии, покрываемая пакетами пользователя
+type PackageCoverage struct {
+	Minutes     int64
+	Unlimited   bool
+	Allocations []PackageAllocation
+}
+
+type PackageUsecase struct {
+	packageRepo    repository.PackageRepository
+	walletService  WalletService
+	loyaltyService LoyaltyService
+	limitsService  LimitsService
+	txManager      repository.TxManager
+}
+
+func NewPackageUsecase(packageRepo repository.PackageRepository,
+	walletService WalletService,
+	loyaltyService LoyaltyService,
+	limitsService LimitsService,
+	txManager repository.TxManager) PackageService {
+	return &PackageUsecase{packageRepo: packageRepo,
+		walletService:  walletService,
+		loyaltyService: loyaltyService,
+		limitsService:  limitsService,
+		txManager:      txManager}
+}
+
+func (u *PackageUsecase) CreatePackage(ctx context.Context, pack *models.Package) error {
+	if pack.Name == "" || !pack.Price.IsPositive() || pack.ValidityDays <= 0 {
+		return errors.ErrInvalidPackage
+	}
+	switch pack.Type {
+	case models.PackageMinutes:
+		if pack.Minutes <= 0 {
+			return errors.ErrInvalidPackage
+		}
+	case models.PackageUnlimited:
+		pack.Minutes = 0
+	default:
+		return errors.ErrInvalidPackage
+	}
+	return u.packageRepo.CreatePackage(ctx, pack)
+}
+
+func (u *PackageUsecase) GetPackages(ctx context.Context) ([]models.Package, error) {
+	return u.packageRepo.GetPackages(ctx)
+}
+
+func (u *PackageUsecase) BuyPackage(ctx context.Context, userID int64, packageID int64) (*models.UserPackage, error) {
+	pack, err := u.packageRepo.GetPackageByID(ctx, packageID)
+	if err != nil {
+		return nil, err
+	}
+	if !pack.Active {
+		return nil, errors.ErrPackageInactive
+	}
+
+	wallet, err := u.walletService.GetWallet(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if wallet.Spendable().LessThan(pack.Price) {
+		return nil, errors.ErrInsufficientFunds
+	}
+	if err := u.limitsService.CheckPurchase(ctx, userID, pack.Price, time.Now()); err != nil {
+		return nil, err
+	}
+
+	transaction := &models.Transaction{
+		UserID:    userID,
+		Amount:    pack.Price,
+		TariffID:  -1,
+		PackageID: &pack.ID,
+		Type:      models.PackagePurchase,
+	}
+	userPackage := &models.UserPackage{
+		UserID:       userID,
+		PackageID:    pack.ID,
+		Name:         pack.Name,
+		Type:         pack.Type,
+		MinutesTotal: pack.Minutes,
+		MinutesLeft:  pack.Minutes,
+		Status:       models.PackageActive,
+		ExpiresAt:    time.Now().AddDate(0, 0, pack.ValidityDays),
+	}
+
+	err = u.txManager.WithTransaction(ctx, func(tx *gorm.DB) error {
+		if err := u.walletService.Charge(tx, transaction, "Покупка пакета"); err != nil {
+			return err
+		}
+		if err := u.loyaltyService.Award(tx, userID, transaction.Amount.Sub(transaction.BonusAmount), 0, &transaction.ID); err != nil {
+			return err
+		}
+		return u.packageRepo.CreateUserPackage(tx, userPackage)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return userPackage, nil
+}
+
+func (u *PackageUsecase) GetUserPackages(ctx context.Context, userID int64) ([]models.UserPackage, error) {
+	return u.packageRepo.GetActiveUserPackages(ctx, userID, time.Now())
+}
+
+// PlanCoverage считает, сколько минут тарифа покрывают пакеты пользователя.
+// Безлимитный абонемент покрывает сессию целиком, пакеты минут расходуются начиная с ближайших к истечению.
+func (u *PackageUsecase) PlanCoverage(ctx context.Context, userID int64, tariff *models.Tariff, now time.Time) (*PackageCoverage, error) {
+	packages, err := u.packageRepo.GetActiveUserPackages(ctx, userID, now)
+	if err != nil {
+		return nil, err
+	}
+
+	coverage := &PackageCoverage{}
+	for _, pack := range packages {
+		if pack.Type == models.PackageUnlimited {
+			return &PackageCoverage{Minutes: tariff.Duration, Unlimited: true}, nil
+		}
+	}
+
+	remaining := tariff.Duration
+	for _, pack := range packages {
+		if remaining == 0 {
+			break
+		}
+		if pack.MinutesLeft <= 0 {
+			continue
+		}
+		minutes := pack.MinutesLeft
+		if minutes > remaining {
+			minutes = remaining
+		}
+		coverage.Allocations = append(coverage.Allocations, PackageAllocation{UserPackageID: pack.ID, Minutes: minutes})
+		coverage.Minutes += minutes
+		remaining -= minutes
+	}
+	return coverage, nil
+}
+
+// ConsumeCoverage списывает минуты пакетов по плану PlanCoverage. Вызывается в транзакции оплаты,
+// чтобы при ошибке списания денег минуты остались в пакетах
+func (u *PackageUsecase) ConsumeCoverage(tx *gorm.DB, coverage *PackageCoverage, transactionID int64) error {
+	for _, allocation := range coverage.Allocations {
+		if err := u.packageRepo.ConsumeMinutes(tx, allocation.UserPackageID, transactionID, allocation.Minutes); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (u *PackageUsecase) MonitorPackages(ctx context.Context) {
+	ticker := time.NewTicker(1 * time.Minute)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Println("Остановка мониторинга пакетов")
+			return
+		case <-ticker.C:
+			u.expirePackages(ctx)
+		}
+	}
+}
+
+func (u *PackageUsecase) expirePackages(ctx context.Context) {
+	expired, err := u.packageRepo.ExpireUserPackages(ctx, time.Now())
+	if err != nil {
+		log.Printf("Не удалось обработать истекшие пакеты: %v", err)
+		return
+	}
+	if expired > 0 {
+		log.Printf("Истекло пакетов: %d", expired)
+	}
+}
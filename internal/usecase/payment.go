@@ -0,0 +1,217 @@
+package usecase
+
+import (
+	"computer-club/internal/payments"
+	"computer-club/internal/repository"
+	"computer-club/internal/repository/models"
+	"computer-club/pkg/errors"
+	"computer-club/pkg/money"
+	"context"
+	"gorm.io/gorm"
+	"log"
+	"net/http"
+)
+
+type PaymentService interface {
+	CreatePayment(ctx context.Context, userID int64, amount money.Money) (*models.Payment, error)
+	GetPayment(ctx context.Context, userID, paymentID int64) (*models.Payment, error)
+	HandleWebhook(ctx context.Context, body []byte, header http.Header) error
+	RefundPayment(ctx context.Context, paymentID, adminID int64) (*models.Payment, error)
+}
+
+type PaymentUsecase struct {
+	paymentRepo    repository.PaymentRepository
+	walletRepo     repository.WalletRepository
+	txManager      repository.TxManager
+	ledgerService  LedgerService
+	receiptService ReceiptService
+	provider       payments.Provider
+}
+
+func NewPaymentUsecase(paymentRepo repository.PaymentRepository,
+	walletRepo repository.WalletRepository,
+	txManager repository.TxManager,
+	ledgerService LedgerService,
+	receiptService ReceiptService,
+	provider payments.Provider) PaymentService {
+	return &PaymentUsecase{paymentRepo: paymentRepo,
+		walletRepo:     walletRepo,
+		txManager:      txManager,
+		ledgerService:  ledgerService,
+		receiptService: receiptService,
+		provider:       provider}
+}
+
+// CreatePayment создает платеж у провайдера. Кошелек пополняется только после уведомления об оплате
+func (u *PaymentUsecase) CreatePayment(ctx context.Context, userID int64, amount money.Money) (*models.Payment, error) {
+	if !amount.IsPositive() {
+		return nil, errors.ErrInvalidAmount
+	}
+	balance, err := u.walletRepo.GetBalance(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if balance.Currency != amount.Currency {
+		return nil, errors.ErrCurrencyMismatch
+	}
+
+	payment := &models.Payment{
+		UserID:   userID,
+		Provider: u.provider.Name(),
+		Amount:   amount,
+		Status:   models.PaymentPending,
+	}
+	if err := u.paymentRepo.CreatePayment(ctx, payment); err != nil {
+		return nil, err
+	}
+
+	intent, err := u.provider.CreateIntent(ctx, payment.ID, amount, "Пополнение кошелька")
+	if err != nil {
+		if _, err := u.paymentRepo.TransitionStatus(nil, payment.ID, models.PaymentPending, models.PaymentFailed); err != nil {
+			log.Printf("Не удалось отметить платеж %d неуспешным: %v", payment.ID, err)
+		}
+		return nil, errors.ErrPaymentProvider
+	}
+
+	payment.ProviderID = intent.ProviderID
+	payment.RedirectURL = intent.RedirectURL
+	if err := u.paymentRepo.UpdatePayment(nil, payment.ID, map[string]interface{}{
+		"provider_id":  payment.ProviderID,
+		"redirect_url": payment.RedirectURL,
+	}); err != nil {
+		return nil, err
+	}
+	return payment, nil
+}
+
+func (u *PaymentUsecase) GetPayment(ctx context.Context, userID, paymentID int64) (*models.Payment, error) {
+	payment, err := u.paymentRepo.GetPaymentByID(ctx, paymentID)
+	if err != nil {
+		return nil, err
+	}
+	if payment.UserID != userID {
+		return nil, errors.ErrPaymentNotFound
+	}
+	return payment, nil
+}
+
+// HandleWebhook проверяет подпись уведомления и зачисляет платеж. Статус меняется условно,
+// поэтому повторное уведомление о том же платеже не пополняет кошелек второй раз
+func (u *PaymentUsecase) HandleWebhook(ctx context.Context, body []byte, header http.Header) error {
+	event, err := u.provider.ParseWebhook(body, header)
+	if err != nil {
+		return err
+	}
+	payment, err := u.paymentRepo.GetPaymentByID(ctx, event.Reference)
+	if err != nil {
+		return err
+	}
+	if payment.ProviderID != event.ProviderID || payment.Amount != event.Amount {
+		return errors.ErrPaymentMismatch
+	}
+
+	if event.Status != payments.EventSucceeded {
+		_, err := u.paymentRepo.TransitionStatus(nil, payment.ID, models.PaymentPending, models.PaymentFailed)
+		return err
+	}
+
+	return u.txManager.WithTransaction(ctx, func(tx *gorm.DB) error {
+		applied, err := u.paymentRepo.TransitionStatus(tx, payment.ID, models.PaymentPending, models.PaymentSucceeded)
+		if err != nil || !applied {
+			return err
+		}
+		if err := u.walletRepo.Deposit(tx, payment.UserID, payment.Amount); err != nil {
+			return err
+		}
+		transaction := &models.Transaction{
+			UserID:        payment.UserID,
+			Amount:        payment.Amount,
+			Type:          models.Add,
+			TariffID:      -1,
+			PaymentMethod: models.PaymentOnline,
+		}
+		if err := u.walletRepo.CreateTransaction(tx, transaction); err != nil {
+			return err
+		}
+		if err := u.ledgerService.Move(tx, models.SystemAccount(models.AccountPaymentProvider), models.CustomerAccount(payment.UserID),
+			payment.Amount, "Онлайн-пополнение кошелька", &transaction.ID); err != nil {
+			return err
+		}
+		if _, err := u.receiptService.Issue(tx, transaction); err != nil {
+			return err
+		}
+		return u.paymentRepo.UpdatePayment(tx, payment.ID, map[string]interface{}{"transaction_id": transaction.ID})
+	})
+}
+
+// RefundPayment списывает платеж с кошелька и возвращает его через провайдера.
+// Списание фиксируется отдельной транзакцией в статусе refunding, и только после ее коммита
+// вызывается провайдер. Если провайдер не ответил, платеж остается в refunding и повторный
+// вызов лишь повторяет запрос к провайдеру
+func (u *PaymentUsecase) RefundPayment(ctx context.Context, paymentID, adminID int64) (*models.Payment, error) {
+	payment, err := u.paymentRepo.GetPaymentByID(ctx, paymentID)
+	if err != nil {
+		return nil, err
+	}
+
+	switch payment.Status {
+	case models.PaymentSucceeded:
+		if err := u.holdRefund(ctx, payment, adminID); err != nil {
+			return nil, err
+		}
+	case models.PaymentRefunding:
+	default:
+		return nil, errors.ErrPaymentNotRefundable
+	}
+
+	if err := u.provider.Refund(ctx, payment.ProviderID, payment.Amount); err != nil {
+		return nil, err
+	}
+	// Параллельный повтор мог уже завершить возврат - тогда статус уже refunded
+	if _, err := u.paymentRepo.TransitionStatus(nil, payment.ID, models.PaymentRefunding, models.PaymentRefunded); err != nil {
+		return nil, err
+	}
+
+	payment.Status = models.PaymentRefunded
+	return payment, nil
+}
+
+// holdRefund переводит платеж в refunding и списывает сумму с кошелька транзакцией возврата
+func (u *PaymentUsecase) holdRefund(ctx context.Context, payment *models.Payment, adminID int64) error {
+	return u.txManager.WithTransaction(ctx, func(tx *gorm.DB) error {
+		applied, err := u.paymentRepo.TransitionStatus(tx, payment.ID, models.PaymentSucceeded, models.PaymentRefunding)
+		if err != nil {
+			return err
+		}
+		if !applied {
+			return errors.ErrPaymentNotRefundable
+		}
+		if err := u.walletRepo.Withdraw(tx, payment.UserID, payment.Amount); err != nil {
+			return err
+		}
+		transaction := &models.Transaction{
+			UserID:        payment.UserID,
+			Amount:        payment.Amount,
+			Type:          models.Refund,
+			TariffID:      -1,
+			AdminID:       &adminID,
+			PaymentMethod: models.PaymentOnline,
+		}
+		if err := u.walletRepo.CreateTransaction(tx, transaction); err != nil {
+			return err
+		}
+		if err := u.ledgerService.Move(tx, models.CustomerAccount(payment.UserID), models.SystemAccount(models.AccountPaymentProvider),
+			payment.Amount, "Возврат онлайн-платежа", &transaction.ID); err != nil {
+			return err
+		}
+		if _, err := u.receiptService.Issue(tx, transaction); err != nil {
+			return err
+		}
+		if err := u.paymentRepo.UpdatePayment(tx, payment.ID, map[string]interface{}{"refund_transaction_id": transaction.ID}); err != nil {
+			return err
+		}
+		payment.RefundTransactionID = &transaction.ID
+		payment.Status = models.PaymentRefunding
+		return nil
+	})
+}
@@ -0,0 +1,189 @@
+package usecase
+
+import (
+	"computer-club/internal/repository"
+	"computer-club/internal/repository/models"
+	"computer-club/pkg/errors"
+	"computer-club/pkg/money"
+	"context"
+	"gorm.io/gorm"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type PromotionService interface {
+	CreatePromotion(ctx context.Context, promotion *models.Promotion) error
+	GetPromotions(ctx context.Context) ([]models.Promotion, error)
+	ApplyPromotion(ctx context.Context, code string, userID int64, tariff *models.Tariff, computer *models.Computer, price money.Money, now time.Time) (*models.Promotion, money.Money, error)
+	RedeemPromotion(tx *gorm.DB, promotionID, userID, transactionID int64, discount money.Money) error
+}
+
+type PromotionUsecase struct {
+	promotionRepo repository.PromotionRepository
+	sessionRepo   repository.SessionRepository
+}
+
+func NewPromotionUsecase(promotionRepo repository.PromotionRepository,
+	sessionRepo repository.SessionRepository) PromotionService {
+	return &PromotionUsecase{promotionRepo: promotionRepo, sessionRepo: sessionRepo}
+}
+
+func (u *PromotionUsecase) CreatePromotion(ctx context.Context, promotion *models.Promotion) error {
+	if promotion.Code == "" {
+		return errors.ErrInvalidPromotion
+	}
+	switch promotion.Type {
+	case models.PromoPercent:
+		if promotion.Value <= 0 || promotion.Value > 100 {
+			return errors.ErrInvalidPromotion
+		}
+	case models.PromoFreeMinutes:
+		if promotion.Value <= 0 {
+			return errors.ErrInvalidPromotion
+		}
+	case models.PromoFixed, models.PromoBonus:
+		if !promotion.Amount.IsPositive() {
+			return errors.ErrInvalidPromotion
+		}
+	default:
+		return errors.ErrInvalidPromotion
+	}
+	if promotion.ValidFrom != nil && promotion.ValidTo != nil && promotion.ValidTo.Before(*promotion.ValidFrom) {
+		return errors.ErrInvalidPromotion
+	}
+	if !validHour(promotion.StartHour) || !validHour(promotion.EndHour) {
+		return errors.ErrInvalidPromotion
+	}
+	if _, err := parseWeekdays(promotion.Weekdays); err != nil {
+		return errors.ErrInvalidPromotion
+	}
+
+	promotion.UsedCount = 0
+	return u.promotionRepo.CreatePromotion(ctx, promotion)
+}
+
+func (u *PromotionUsecase) GetPromotions(ctx context.Context) ([]models.Promotion, error) {
+	return u.promotionRepo.GetPromotions(ctx)
+}
+
+// ApplyPromotion проверяет условия промокода и считает скидку для цены тарифа.
+// Сам промокод не списывается — для этого используется RedeemPromotion.
+func (u *PromotionUsecase) ApplyPromotion(ctx context.Context, code string, userID int64, tariff *models.Tariff, computer *models.Computer, price money.Money, now time.Time) (*models.Promotion, money.Money, error) {
+	promotion, err := u.promotionRepo.GetPromotionByCode(ctx, code)
+	if err != nil {
+		return nil, money.Money{}, err
+	}
+
+	if !promotion.Active {
+		return nil, money.Money{}, errors.ErrPromotionInactive
+	}
+	if promotion.ValidFrom != nil && now.Before(*promotion.ValidFrom) {
+		return nil, money.Money{}, errors.ErrPromotionExpired
+	}
+	if promotion.ValidTo != nil && now.After(*promotion.ValidTo) {
+		return nil, money.Money{}, errors.ErrPromotionExpired
+	}
+
+	// Ограничения по дням недели и времени суток
+	weekdays, err := parseWeekdays(promotion.Weekdays)
+	if err != nil {
+		return nil, money.Money{}, errors.ErrInvalidPromotion
+	}
+	if len(weekdays) > 0 && !weekdays[isoWeekday(now)] {
+		return nil, money.Money{}, errors.ErrPromotionNotApplicable
+	}
+	if promotion.StartHour != nil && now.Hour() < *promotion.StartHour {
+		return nil, money.Money{}, errors.ErrPromotionNotApplicable
+	}
+	if promotion.EndHour != nil && now.Hour() >= *promotion.EndHour {
+		return nil, money.Money{}, errors.ErrPromotionNotApplicable
+	}
+
+	// Ограничения по тарифу и зоне
+	if promotion.TariffID != nil && *promotion.TariffID != tariff.ID {
+		return nil, money.Money{}, errors.ErrPromotionNotApplicable
+	}
+	if promotion.Zone != "" && promotion.Zone != computer.Zone {
+		return nil, money.Money{}, errors.ErrPromotionNotApplicable
+	}
+
+	if promotion.NewUsersOnly {
+		sessions, err := u.sessionRepo.CountUserSessions(ctx, userID)
+		if err != nil {
+			return nil, money.Money{}, errors.ErrFindPromotion
+		}
+		if sessions > 0 {
+			return nil, money.Money{}, errors.ErrPromotionNotApplicable
+		}
+	}
+
+	// Лимиты использования
+	if promotion.MaxUses > 0 && promotion.UsedCount >= promotion.MaxUses {
+		return nil, money.Money{}, errors.ErrPromotionLimitReached
+	}
+	if promotion.MaxUsesPerUser > 0 {
+		used, err := u.promotionRepo.CountUserUsages(ctx, promotion.ID, userID)
+		if err != nil {
+			return nil, money.Money{}, err
+		}
+		if used >= promotion.MaxUsesPerUser {
+			return nil, money.Money{}, errors.ErrPromotionLimitReached
+		}
+	}
+
+	return promotion, calculateDiscount(promotion, tariff, price), nil
+}
+
+// RedeemPromotion списывает использование промокода. Вызывается в транзакции оплаты, чтобы
+// при ошибке списания промокод не считался использованным
+func (u *PromotionUsecase) RedeemPromotion(tx *gorm.DB, promotionID, userID, transactionID int64, discount money.Money) error {
+	return u.promotionRepo.RedeemPromotion(tx, promotionID, userID, transactionID, discount)
+}
+
+// calculateDiscount возвращает размер скидки, не превышающий цену
+func calculateDiscount(promotion *models.Promotion, tariff *models.Tariff, price money.Money) money.Money {
+	discount := money.Zero()
+	switch promotion.Type {
+	case models.PromoPercent:
+		discount = price.MulFloat(promotion.Value / 100)
+	case models.PromoFixed:
+		discount = promotion.Amount
+	case models.PromoFreeMinutes:
+		if tariff.Duration > 0 {
+			minutes := int64(promotion.Value)
+			if minutes > tariff.Duration {
+				minutes = tariff.Duration
+			}
+			discount = price.MulRatio(minutes, tariff.Duration)
+		}
+	}
+	return discount.Min(price)
+}
+
+// parseWeekdays разбирает список дней недели вида "1,2,3" (1 - понедельник, 7 - воскресенье)
+func parseWeekdays(value string) (map[int]bool, error) {
+	days := make(map[int]bool)
+	if strings.TrimSpace(value) == "" {
+		return days, nil
+	}
+	for _, part := range strings.Split(value, ",") {
+		day, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil || day < 1 || day > 7 {
+			return nil, errors.ErrInvalidPromotion
+		}
+		days[day] = true
+	}
+	return days, nil
+}
+
+func isoWeekday(t time.Time) int {
+	if t.Weekday() == time.Sunday {
+		return 7
+	}
+	return int(t.Weekday())
+}
+
+func validHour(hour *int) bool {
+	return hour == nil || (*hour >= 0 && *hour <= 24)
+}
@@ -0,0 +1,135 @@
+package usecase
+
+import (
+	"computer-club/internal/receipts"
+	"computer-club/internal/repository"
+	"computer-club/internal/repository/models"
+	"context"
+	"fmt"
+	"gorm.io/gorm"
+	"log"
+	"time"
+)
+
+type ReceiptService interface {
+	Issue(tx *gorm.DB, transaction *models.Transaction) (*models.Receipt, error)
+	GetReceipt(ctx context.Context, id int64) (*models.Receipt, error)
+	RenderText(receipt *models.Receipt) []byte
+	RenderPDF(receipt *models.Receipt) []byte
+	MonitorReceipts(ctx context.Context)
+}
+
+type ReceiptUsecase struct {
+	receiptRepo repository.ReceiptRepository
+	registrar   receipts.FiscalRegistrar
+	clubName    string
+}
+
+func NewReceiptUsecase(receiptRepo repository.ReceiptRepository, registrar receipts.FiscalRegistrar, clubName string) ReceiptService {
+	return &ReceiptUsecase{receiptRepo: receiptRepo,
+		registrar: registrar,
+		clubName:  clubName}
+}
+
+// Issue выписывает чек по транзакции в той же транзакции БД: пополнения, оплаты сессий и пакетов,
+// продажи подарочных карт, возвраты и сторно. Для остальных типов и нулевых сумм чек не нужен и возвращается nil
+func (u *ReceiptUsecase) Issue(tx *gorm.DB, transaction *models.Transaction) (*models.Receipt, error) {
+	if !transaction.Amount.IsPositive() {
+		return nil, nil
+	}
+
+	var kind models.ReceiptKind
+	var name string
+	switch transaction.Type {
+	case models.Add:
+		kind, name = models.ReceiptDeposit, "Пополнение кошелька"
+	case models.Buy:
+		kind, name = models.ReceiptSale, fmt.Sprintf("Игровая сессия, тариф %d", transaction.TariffID)
+	case models.PackagePurchase:
+		kind, name = models.ReceiptSale, "Пакет игрового времени"
+		if transaction.PackageID != nil {
+			name = fmt.Sprintf("Пакет игрового времени № %d", *transaction.PackageID)
+		}
+	case models.VoucherSale:
+		kind, name = models.ReceiptSale, "Подарочная карта"
+	case models.Refund:
+		kind, name = models.ReceiptRefund, "Возврат пополнения кошелька"
+	case models.Reversal:
+		kind, name = models.ReceiptRefund, "Сторно ошибочной операции"
+		if transaction.ReversalOf != nil {
+			name = fmt.Sprintf("Сторно операции № %d", *transaction.ReversalOf)
+		}
+	default:
+		return nil, nil
+	}
+
+	receipt := &models.Receipt{
+		UserID:        transaction.UserID,
+		TransactionID: transaction.ID,
+		Kind:          kind,
+		Items: []models.ReceiptItem{{
+			Name:     name,
+			Quantity: 1,
+			Price:    transaction.Amount,
+			Total:    transaction.Amount,
+		}},
+		Total:         transaction.Amount,
+		PaidByBonus:   transaction.BonusAmount,
+		PaymentMethod: transaction.PaymentMethod,
+		FiscalStatus:  models.FiscalPending,
+	}
+	if err := u.receiptRepo.CreateReceipt(tx, receipt); err != nil {
+		return nil, err
+	}
+	return receipt, nil
+}
+
+func (u *ReceiptUsecase) GetReceipt(ctx context.Context, id int64) (*models.Receipt, error) {
+	return u.receiptRepo.GetReceiptByID(ctx, id)
+}
+
+func (u *ReceiptUsecase) RenderText(receipt *models.Receipt) []byte {
+	return receipts.RenderText(receipt, u.clubName)
+}
+
+func (u *ReceiptUsecase) RenderPDF(receipt *models.Receipt) []byte {
+	return receipts.RenderPDF(receipt, u.clubName)
+}
+
+// MonitorReceipts раз в минуту отправляет новые чеки в фискальный регистратор.
+// Регистрация идет отдельно от оплаты, чтобы недоступность регистратора не блокировала кассу
+func (u *ReceiptUsecase) MonitorReceipts(ctx context.Context) {
+	ticker := time.NewTicker(1 * time.Minute)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Println("Остановка фискализации чеков")
+			return
+		case <-ticker.C:
+			u.fiscalizeReceipts(ctx)
+		}
+	}
+}
+
+func (u *ReceiptUsecase) fiscalizeReceipts(ctx context.Context) {
+	pending, err := u.receiptRepo.GetPendingReceipts(ctx, 100)
+	if err != nil {
+		log.Printf("Не удалось получить чеки для фискализации: %v", err)
+		return
+	}
+
+	for i := range pending {
+		receipt := &pending[i]
+		data, err := u.registrar.Register(ctx, receipt)
+		if err != nil {
+			// Чек останется в статусе pending и будет отправлен повторно
+			log.Printf("Не удалось зарегистрировать чек %s: %v", receipt.Number, err)
+			continue
+		}
+		if err := u.receiptRepo.SetFiscalData(ctx, receipt.ID, models.FiscalRegistered, data.Sign, data.Number, data.RegisteredAt); err != nil {
+			log.Printf("Не удалось сохранить фискальные данные чека %s: %v", receipt.Number, err)
+		}
+	}
+}
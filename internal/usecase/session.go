@@ -1,98 +1,401 @@
 package usecase
 
 import (
+	"computer-club/internal/pricing"
 	"computer-club/internal/repository"
 	"computer-club/internal/repository/models"
 	"computer-club/pkg/errors"
+	"computer-club/pkg/money"
 	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"gorm.io/gorm"
 	"log"
 	"time"
 )
 
 type SessionService interface {
-	StartSession(ctx context.Context, userID int64, pcNumber int, tariffID int64) (*models.Session, error)
+	StartSession(ctx context.Context, userID int64, pcNumber int, tariffID int64, promoCode string, quoteID string) (*models.Session, error)
+	StartOrganizationSession(ctx context.Context, userID, organizationID int64, pcNumber int, tariffID int64) (*models.Session, error)
+	QuoteSession(ctx context.Context, userID int64, pcNumber int, tariffID int64, promoCode string) (*models.PriceQuote, error)
 	EndSession(ctx context.Context, sessionID int64) error
+	EndUserSession(ctx context.Context, sessionID, actorID int64, canEndAny bool) error
 	GetActiveSessions(ctx context.Context) []*models.Session
 	MonitorSessions(ctx context.Context)
 }
 
 type SessionUsecase struct {
-	sessionRepository repository.SessionRepository
-	userRepo          repository.UserRepository
-	computerRepo      repository.ComputerRepository
-	tariffRepo        repository.TariffRepository
-	walletRepo        repository.WalletRepository
+	sessionRepository   repository.SessionRepository
+	userRepo            repository.UserRepository
+	computerRepo        repository.ComputerRepository
+	tariffRepo          repository.TariffRepository
+	walletService       WalletService
+	promotionService    PromotionService
+	packageService      PackageService
+	loyaltyService      LoyaltyService
+	limitsService       LimitsService
+	organizationService OrganizationService
+	pricingEngine       pricing.Engine
+	quoteRepo           repository.QuoteRepository
+	quoteTTL            time.Duration
+	txManager           repository.TxManager
+}
+
+// sessionPlan - расчет стоимости сессии и то, что нужно списать при ее запуске
+type sessionPlan struct {
+	quote     *models.PriceQuote
+	coverage  *PackageCoverage
+	promotion *models.Promotion
 }
 
 func NewSessionUsecase(sessionRepository repository.SessionRepository,
 	userRepo repository.UserRepository,
 	computerRepo repository.ComputerRepository,
 	tariffRepo repository.TariffRepository,
-	walletRepo repository.WalletRepository) SessionService {
+	walletService WalletService,
+	promotionService PromotionService,
+	packageService PackageService,
+	loyaltyService LoyaltyService,
+	limitsService LimitsService,
+	organizationService OrganizationService,
+	pricingEngine pricing.Engine,
+	quoteRepo repository.QuoteRepository,
+	quoteTTL time.Duration,
+	txManager repository.TxManager) SessionService {
 	return &SessionUsecase{sessionRepository: sessionRepository,
-		userRepo:     userRepo,
-		computerRepo: computerRepo,
-		tariffRepo:   tariffRepo,
-		walletRepo:   walletRepo}
+		userRepo:            userRepo,
+		computerRepo:        computerRepo,
+		tariffRepo:          tariffRepo,
+		walletService:       walletService,
+		promotionService:    promotionService,
+		packageService:      packageService,
+		loyaltyService:      loyaltyService,
+		limitsService:       limitsService,
+		organizationService: organizationService,
+		pricingEngine:       pricingEngine,
+		quoteRepo:           quoteRepo,
+		quoteTTL:            quoteTTL,
+		txManager:           txManager}
 }
 
-func (u *SessionUsecase) StartSession(ctx context.Context, userID int64, pcNumber int, tariffID int64) (*models.Session, error) {
-	_, err := u.userRepo.GetUserByID(ctx, userID)
+func (u *SessionUsecase) StartSession(ctx context.Context, userID int64, pcNumber int, tariffID int64, promoCode string, quoteID string) (*models.Session, error) {
+	now := time.Now()
+
+	// Если передана котировка, параметры сессии и динамическая цена берутся из нее
+	var locked *models.PriceQuote
+	if quoteID != "" {
+		quote, err := u.quoteRepo.GetQuote(ctx, quoteID)
+		if err != nil {
+			return nil, err
+		}
+		if quote.UserID != userID || !quote.ExpiresAt.After(now) {
+			return nil, errors.ErrQuoteNotFound
+		}
+		if pcNumber == 0 {
+			pcNumber = quote.PCNumber
+		}
+		if tariffID == 0 {
+			tariffID = quote.TariffID
+		}
+		if promoCode == "" {
+			promoCode = quote.PromoCode
+		}
+		if pcNumber != quote.PCNumber || tariffID != quote.TariffID || promoCode != quote.PromoCode {
+			return nil, errors.ErrQuoteMismatch
+		}
+		locked = quote
+	}
+
+	computer, tariff, err := u.checkSessionAvailable(ctx, userID, pcNumber, tariffID)
 	if err != nil {
-		return nil, errors.ErrUserNotFound
+		return nil, err
 	}
 
-	exists, err := u.sessionRepository.HasActiveSession(ctx, userID)
+	var rates *pricing.Result
+	if locked != nil {
+		rates = &pricing.Result{
+			BasePrice:   locked.BasePrice,
+			Price:       locked.DynamicPrice,
+			Adjustments: locked.Adjustments,
+		}
+	} else {
+		rates, err = u.pricingEngine.Calculate(ctx, userID, tariff, computer, now)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	plan, err := u.priceSession(ctx, userID, computer, tariff, promoCode, rates, now)
 	if err != nil {
 		return nil, err
 	}
-	if exists {
-		return nil, errors.ErrSessionActive
+	price := plan.quote.Price
+	if locked != nil && price.Amount != locked.Price.Amount {
+		return nil, errors.ErrQuoteChanged
+	}
+	if !plan.quote.WalletCovers {
+		return nil, errors.ErrInsufficientFunds
+	}
+	// Ограничения аккаунта: тарифы, часы, дневные минуты и расходы
+	if err := u.limitsService.CheckSession(ctx, userID, tariff, price, now); err != nil {
+		return nil, err
 	}
 
-	pcExists, err := u.computerRepo.IsComputerAvailable(ctx, pcNumber)
+	transaction := &models.Transaction{
+		UserID:         userID,
+		Amount:         price,
+		Discount:       plan.quote.PromoDiscount,
+		PackageMinutes: plan.coverage.Minutes,
+		TariffID:       tariff.ID,
+		Type:           models.Buy,
+	}
+	if plan.promotion != nil {
+		transaction.PromotionID = &plan.promotion.ID
+	}
+
+	// Промокод с его бонусами, минуты пакетов, списание, транзакция, проводка в журнале и сама сессия
+	// записываются атомарно
+	var session *models.Session
+	err = u.txManager.WithTransaction(ctx, func(tx *gorm.DB) error {
+		if err := u.walletService.Charge(tx, transaction, "Оплата сессии"); err != nil {
+			return err
+		}
+		// Промокод и минуты пакетов привязываются к оплате, чтобы вернуть их при сторно
+		if plan.promotion != nil {
+			if err := u.promotionService.RedeemPromotion(tx, plan.promotion.ID, userID, transaction.ID, plan.quote.PromoDiscount); err != nil {
+				return err
+			}
+			if plan.promotion.Type == models.PromoBonus {
+				if _, err := u.walletService.GrantBonusTx(tx, userID, plan.promotion.Amount, models.BonusFromPromotion, plan.promotion.Code, &transaction.ID); err != nil {
+					return err
+				}
+			}
+		}
+		if err := u.packageService.ConsumeCoverage(tx, plan.coverage, transaction.ID); err != nil {
+			return err
+		}
+		// Баллы начисляются только за реальные деньги, но за все минуты тарифа
+		if err := u.loyaltyService.Award(tx, userID, transaction.Amount.Sub(transaction.BonusAmount), tariff.Duration, &transaction.ID); err != nil {
+			return err
+		}
+		var err error
+		session, err = u.sessionRepository.CreateSession(tx, userID, pcNumber, tariffID, &transaction.ID, now, plan.quote.EndTime)
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
-	if !pcExists {
-		return nil, errors.ErrComputerNotFound
+
+	if locked != nil {
+		if err := u.quoteRepo.DeleteQuote(ctx, locked.ID); err != nil {
+			log.Printf("Не удалось удалить котировку %s: %v", locked.ID, err)
+		}
 	}
 
-	tariff, err := u.tariffRepo.GetTariffByID(ctx, tariffID)
+	return session, nil
+}
+
+// StartOrganizationSession запускает сессию участника за счет кошелька организации по динамической
+// цене тарифа. Пакеты, промокоды, бонусы и баллы лояльности к таким сессиям не применяются,
+// личные лимиты расходов тоже: расходы участника ограничивает месячный лимит организации
+func (u *SessionUsecase) StartOrganizationSession(ctx context.Context, userID, organizationID int64, pcNumber int, tariffID int64) (*models.Session, error) {
+	now := time.Now()
+
+	computer, tariff, err := u.checkSessionAvailable(ctx, userID, pcNumber, tariffID)
 	if err != nil {
-		return nil, errors.ErrTariffNotFound
+		return nil, err
+	}
+	rates, err := u.pricingEngine.Calculate(ctx, userID, tariff, computer, now)
+	if err != nil {
+		return nil, err
+	}
+	if err := u.limitsService.CheckSession(ctx, userID, tariff, money.Zero(), now); err != nil {
+		return nil, err
 	}
 
-	balance, err := u.walletRepo.GetBalance(ctx, userID)
+	transaction := &models.Transaction{
+		UserID:         userID,
+		OrganizationID: &organizationID,
+		Amount:         rates.Price,
+		TariffID:       tariff.ID,
+		Type:           models.Buy,
+	}
+	var session *models.Session
+	err = u.txManager.WithTransaction(ctx, func(tx *gorm.DB) error {
+		if err := u.organizationService.Charge(tx, transaction); err != nil {
+			return err
+		}
+		var err error
+		session, err = u.sessionRepository.CreateSession(tx, userID, pcNumber, tariffID, &transaction.ID, now, now.Add(time.Duration(tariff.Duration)*time.Minute))
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
-	if balance < tariff.Price {
-		return nil, errors.ErrInsufficientFunds
+	return session, nil
+}
+
+// QuoteSession считает итоговую цену сессии тем же способом, что и StartSession,
+// и фиксирует динамическую цену на время действия котировки
+func (u *SessionUsecase) QuoteSession(ctx context.Context, userID int64, pcNumber int, tariffID int64, promoCode string) (*models.PriceQuote, error) {
+	computer, tariff, err := u.checkSessionAvailable(ctx, userID, pcNumber, tariffID)
+	if err != nil {
+		return nil, err
 	}
 
-	err = u.walletRepo.Withdraw(nil, userID, tariff.Price)
+	now := time.Now()
+	rates, err := u.pricingEngine.Calculate(ctx, userID, tariff, computer, now)
 	if err != nil {
 		return nil, err
 	}
 
-	_, err = u.walletRepo.CreateTransaction(nil, userID, tariff.Price, string(models.Buy), tariff)
+	plan, err := u.priceSession(ctx, userID, computer, tariff, promoCode, rates, now)
 	if err != nil {
 		return nil, err
 	}
 
-	session, err := u.sessionRepository.CreateSession(ctx, userID, pcNumber, tariffID)
+	quote := plan.quote
+	quote.ID, err = newQuoteID()
 	if err != nil {
 		return nil, err
 	}
+	quote.ExpiresAt = now.Add(u.quoteTTL)
+	if err := u.quoteRepo.SaveQuote(ctx, quote); err != nil {
+		return nil, err
+	}
+	return quote, nil
+}
 
-	return session, nil
+// checkSessionAvailable проверяет, что пользователь может занять компьютер по выбранному тарифу
+func (u *SessionUsecase) checkSessionAvailable(ctx context.Context, userID int64, pcNumber int, tariffID int64) (*models.Computer, *models.Tariff, error) {
+	user, err := u.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, nil, errors.ErrUserNotFound
+	}
+	if user.Blocked() {
+		return nil, nil, errors.ErrUserBlocked
+	}
+
+	exists, err := u.sessionRepository.HasActiveSession(ctx, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if exists {
+		return nil, nil, errors.ErrSessionActive
+	}
+
+	pcExists, err := u.computerRepo.IsComputerAvailable(ctx, pcNumber)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !pcExists {
+		return nil, nil, errors.ErrComputerNotFound
+	}
+
+	computer, err := u.computerRepo.GetComputerByNumber(ctx, pcNumber)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	tariff, err := u.tariffRepo.GetTariffByID(ctx, tariffID)
+	if err != nil {
+		return nil, nil, errors.ErrTariffNotFound
+	}
+	return computer, tariff, nil
+}
+
+// priceSession считает итоговую цену: динамическая цена тарифа, затем минуты из пакетов,
+// затем промокод. Ничего не списывает
+func (u *SessionUsecase) priceSession(ctx context.Context, userID int64, computer *models.Computer, tariff *models.Tariff, promoCode string, rates *pricing.Result, now time.Time) (*sessionPlan, error) {
+	quote := &models.PriceQuote{
+		UserID:       userID,
+		PCNumber:     computer.PCNumber,
+		TariffID:     tariff.ID,
+		PromoCode:    promoCode,
+		BasePrice:    rates.BasePrice,
+		DynamicPrice: rates.Price,
+		Adjustments:  rates.Adjustments,
+		Discounts:    []models.AppliedDiscount{},
+		StartTime:    now,
+		EndTime:      now.Add(time.Duration(tariff.Duration) * time.Minute),
+		CreatedAt:    now,
+	}
+	price := rates.Price
+
+	// Сначала списываем минуты из пакетов, деньгами оплачивается только остаток
+	coverage, err := u.packageService.PlanCoverage(ctx, userID, tariff, now)
+	if err != nil {
+		return nil, err
+	}
+	if coverage.Minutes > 0 && tariff.Duration > 0 {
+		paidMinutes := tariff.Duration - coverage.Minutes
+		paid := price.MulRatio(paidMinutes, tariff.Duration)
+		quote.PackageMinutes = coverage.Minutes
+		quote.Discounts = append(quote.Discounts, models.AppliedDiscount{
+			Source: models.DiscountPackage,
+			Amount: price.Sub(paid),
+		})
+		price = paid
+	}
+
+	// Применяем промокод, если он передан
+	var promotion *models.Promotion
+	if promoCode != "" && price.IsPositive() {
+		var discount money.Money
+		promotion, discount, err = u.promotionService.ApplyPromotion(ctx, promoCode, userID, tariff, computer, price, now)
+		if err != nil {
+			return nil, err
+		}
+		price = price.Sub(discount)
+		quote.PromoDiscount = discount
+		if promotion.Type != models.PromoBonus {
+			quote.Discounts = append(quote.Discounts, models.AppliedDiscount{
+				Source: models.DiscountPromotion,
+				Code:   promotion.Code,
+				Amount: discount,
+			})
+		}
+	}
+	quote.Price = price
+
+	// Сессию можно оплатить и реальными деньгами, и бонусами
+	wallet, err := u.walletService.GetWallet(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	quote.Balance = wallet.Spendable()
+	quote.WalletCovers = !quote.Balance.LessThan(price)
+
+	return &sessionPlan{quote: quote, coverage: coverage, promotion: promotion}, nil
+}
+
+func newQuoteID() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", errors.ErrSaveQuote
+	}
+	return hex.EncodeToString(buf), nil
 }
 
 func (u *SessionUsecase) EndSession(ctx context.Context, sessionID int64) error {
 	return u.sessionRepository.EndSession(ctx, sessionID)
 }
 
+// EndUserSession завершает сессию по запросу: игрок может завершить только свою сессию,
+// сотрудник с правом session.end.any - любую
+func (u *SessionUsecase) EndUserSession(ctx context.Context, sessionID, actorID int64, canEndAny bool) error {
+	if !canEndAny {
+		session, err := u.sessionRepository.GetSessionByID(ctx, sessionID)
+		if err != nil {
+			return errors.ErrSessionNotFound
+		}
+		if session.UserID != actorID {
+			return errors.ErrForbidden
+		}
+	}
+	return u.sessionRepository.EndSession(ctx, sessionID)
+}
+
 func (u *SessionUsecase) GetActiveSessions(ctx context.Context) []*models.Session {
 	return u.sessionRepository.GetActiveSessions(ctx)
 }
@@ -112,12 +415,24 @@ func (u *SessionUsecase) MonitorSessions(ctx context.Context) {
 	}
 }
 
+// checkAndCloseExpiredSessions завершает истекшие сессии и сессии, нарушающие ограничения аккаунта
 func (u *SessionUsecase) checkAndCloseExpiredSessions(ctx context.Context) {
 	sessions := u.sessionRepository.GetActiveSessions(ctx)
 	now := time.Now()
 
 	for _, session := range sessions {
-		if session.EndTime.Before(now) {
+		expired := session.EndTime.Before(now)
+		if !expired {
+			switch err := u.limitsService.CheckRunningSession(ctx, session, now); err {
+			case nil:
+			case errors.ErrOutsideAllowedHours, errors.ErrDailyMinutesLimit:
+				log.Printf("Сессия %d нарушает ограничения пользователя %d: %v", session.ID, session.UserID, err)
+				expired = true
+			default:
+				log.Printf("Не удалось проверить ограничения для сессии %d: %v", session.ID, err)
+			}
+		}
+		if expired {
 			log.Printf("Завершаем сессию %d (пользователь %d)", session.ID, session.UserID)
 
 			// Обновление статуса компьютера
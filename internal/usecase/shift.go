@@ -0,0 +1,197 @@
+package usecase
+
+import (
+	"computer-club/internal/repository"
+	"computer-club/internal/repository/models"
+	"computer-club/pkg/errors"
+	"computer-club/pkg/money"
+	"context"
+	"gorm.io/gorm"
+	"time"
+)
+
+type ShiftService interface {
+	OpenShift(ctx context.Context, adminID int64, openingFloat money.Money) (*models.Shift, error)
+	CloseShift(ctx context.Context, adminID int64, countedCash money.Money, note string) (*models.ShiftReport, error)
+	CurrentReport(ctx context.Context) (*models.ShiftReport, error)
+	GetReport(ctx context.Context, shiftID int64) (*models.ShiftReport, error)
+}
+
+type ShiftUsecase struct {
+	shiftRepo repository.ShiftRepository
+	txManager repository.TxManager
+	tolerance money.Money
+}
+
+func NewShiftUsecase(shiftRepo repository.ShiftRepository, txManager repository.TxManager, tolerance money.Money) ShiftService {
+	return &ShiftUsecase{shiftRepo: shiftRepo, txManager: txManager, tolerance: tolerance}
+}
+
+func (u *ShiftUsecase) OpenShift(ctx context.Context, adminID int64, openingFloat money.Money) (*models.Shift, error) {
+	if openingFloat.IsNegative() {
+		return nil, errors.ErrInvalidAmount
+	}
+	if openingFloat.Currency == "" {
+		openingFloat = money.New(openingFloat.Amount)
+	}
+
+	zero := money.Zero()
+	shift := &models.Shift{
+		Status:       models.ShiftOpen,
+		OpenedBy:     adminID,
+		OpeningFloat: openingFloat,
+		ExpectedCash: zero,
+		CountedCash:  zero,
+		Discrepancy:  zero,
+		OpenedAt:     time.Now(),
+	}
+	if err := u.shiftRepo.CreateShift(ctx, shift); err != nil {
+		return nil, err
+	}
+	return shift, nil
+}
+
+// CloseShift закрывает открытую смену с пересчитанной наличностью и возвращает Z-отчет
+func (u *ShiftUsecase) CloseShift(ctx context.Context, adminID int64, countedCash money.Money, note string) (*models.ShiftReport, error) {
+	if countedCash.IsNegative() {
+		return nil, errors.ErrInvalidAmount
+	}
+	if countedCash.Currency == "" {
+		countedCash = money.New(countedCash.Amount)
+	}
+
+	// Отчет строится под блокировкой смены, чтобы в него попали все пополнения смены
+	var report *models.ShiftReport
+	err := u.txManager.WithTransaction(ctx, func(tx *gorm.DB) error {
+		shift, err := u.shiftRepo.LockOpenShiftForClose(tx)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		report, err = u.buildReport(ctx, shift, now)
+		if err != nil {
+			return err
+		}
+		discrepancy := countedCash.Sub(report.ExpectedCash)
+
+		shift.Status = models.ShiftClosed
+		shift.ClosedBy = &adminID
+		shift.ClosedAt = &now
+		shift.ExpectedCash = report.ExpectedCash
+		shift.CountedCash = countedCash
+		shift.Discrepancy = discrepancy
+		shift.Flagged = u.exceedsTolerance(discrepancy)
+		shift.Note = note
+		return u.shiftRepo.CloseShift(tx, shift)
+	})
+	if err != nil {
+		return nil, err
+	}
+	shift := report.Shift
+
+	report.Type = models.ReportZ
+	report.CountedCash = &shift.CountedCash
+	report.Discrepancy = &shift.Discrepancy
+	report.Flagged = shift.Flagged
+	return report, nil
+}
+
+// CurrentReport возвращает X-отчет по открытой смене
+func (u *ShiftUsecase) CurrentReport(ctx context.Context) (*models.ShiftReport, error) {
+	shift, err := u.shiftRepo.GetOpenShift(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return u.buildReport(ctx, shift, time.Now())
+}
+
+// GetReport возвращает Z-отчет по закрытой смене или X-отчет по открытой
+func (u *ShiftUsecase) GetReport(ctx context.Context, shiftID int64) (*models.ShiftReport, error) {
+	shift, err := u.shiftRepo.GetShiftByID(ctx, shiftID)
+	if err != nil {
+		return nil, err
+	}
+	if shift.Status == models.ShiftOpen {
+		return u.buildReport(ctx, shift, time.Now())
+	}
+
+	report, err := u.buildReport(ctx, shift, *shift.ClosedAt)
+	if err != nil {
+		return nil, err
+	}
+	report.Type = models.ReportZ
+	report.ExpectedCash = shift.ExpectedCash
+	report.CountedCash = &shift.CountedCash
+	report.Discrepancy = &shift.Discrepancy
+	report.Flagged = shift.Flagged
+	return report, nil
+}
+
+// buildReport суммирует транзакции смены: пополнения и возвраты по способам оплаты и продажи.
+// Ожидаемая наличность - размен на начало смены плюс пополнения наличными минус возвраты наличными
+func (u *ShiftUsecase) buildReport(ctx context.Context, shift *models.Shift, to time.Time) (*models.ShiftReport, error) {
+	lines, bonusSales, err := u.shiftRepo.SummarizeTransactions(ctx, shift.OpenedAt, to)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &models.ShiftReport{
+		Type:       models.ReportX,
+		Shift:      shift,
+		From:       shift.OpenedAt,
+		To:         to,
+		Methods:    []models.MethodTotals{},
+		Sales:      money.Zero(),
+		BonusSales: bonusSales,
+		Lines:      lines,
+	}
+	var order []models.PaymentMethod
+	methods := make(map[models.PaymentMethod]*models.MethodTotals)
+	method := func(m models.PaymentMethod) *models.MethodTotals {
+		if _, ok := methods[m]; !ok {
+			order = append(order, m)
+			methods[m] = &models.MethodTotals{PaymentMethod: m, Deposits: money.Zero(), Refunds: money.Zero()}
+		}
+		return methods[m]
+	}
+
+	for _, line := range lines {
+		switch line.Type {
+		case models.Add, models.VoucherSale:
+			// Продажа подарочной карты приносит деньги в кассу так же, как пополнение
+			totals := method(line.PaymentMethod)
+			totals.Deposits = totals.Deposits.Add(line.Amount)
+		case models.Refund:
+			totals := method(line.PaymentMethod)
+			totals.Refunds = totals.Refunds.Add(line.Amount)
+		case models.Reversal:
+			// Сторно пополнения сохраняет способ оплаты и считается возвратом, сторно оплаты уменьшает продажи
+			if line.PaymentMethod != "" {
+				totals := method(line.PaymentMethod)
+				totals.Refunds = totals.Refunds.Add(line.Amount)
+			} else {
+				report.Sales = report.Sales.Sub(line.Amount)
+			}
+		case models.Buy, models.PackagePurchase:
+			report.Sales = report.Sales.Add(line.Amount)
+		}
+	}
+	for _, m := range order {
+		report.Methods = append(report.Methods, *methods[m])
+	}
+
+	expected := shift.OpeningFloat
+	if cash, ok := methods[models.PaymentCash]; ok {
+		expected = expected.Add(cash.Deposits).Sub(cash.Refunds)
+	}
+	report.ExpectedCash = expected
+	return report, nil
+}
+
+func (u *ShiftUsecase) exceedsTolerance(discrepancy money.Money) bool {
+	if discrepancy.IsNegative() {
+		discrepancy = discrepancy.Neg()
+	}
+	return u.tolerance.LessThan(discrepancy)
+}
@@ -3,12 +3,15 @@ package usecase
 import (
 	"computer-club/internal/repository"
 	"computer-club/internal/repository/models"
+	"computer-club/pkg/errors"
 	"context"
 )
 
 type TariffService interface {
 	GetTariff(ctx context.Context) ([]models.Tariff, error)
 	GetTariffByID(ctx context.Context, id int64) (*models.Tariff, error)
+	CreateTariff(ctx context.Context, tariff *models.Tariff) error
+	UpdateTariff(ctx context.Context, tariff *models.Tariff) error
 }
 type TariffUsecase struct {
 	tariffRepository repository.TariffRepository
@@ -25,3 +28,24 @@ func (u *TariffUsecase) GetTariff(ctx context.Context) ([]models.Tariff, error)
 func (u *TariffUsecase) GetTariffByID(ctx context.Context, id int64) (*models.Tariff, error) {
 	return u.tariffRepository.GetTariffByID(ctx, id)
 }
+
+func (u *TariffUsecase) CreateTariff(ctx context.Context, tariff *models.Tariff) error {
+	if err := validateTariff(tariff); err != nil {
+		return err
+	}
+	return u.tariffRepository.CreateTariff(ctx, tariff)
+}
+
+func (u *TariffUsecase) UpdateTariff(ctx context.Context, tariff *models.Tariff) error {
+	if err := validateTariff(tariff); err != nil {
+		return err
+	}
+	return u.tariffRepository.UpdateTariff(ctx, tariff)
+}
+
+func validateTariff(tariff *models.Tariff) error {
+	if tariff.Name == "" || !tariff.Price.IsPositive() || tariff.Duration <= 0 {
+		return errors.ErrInvalidTariff
+	}
+	return tariff.Price.CheckCurrency()
+}
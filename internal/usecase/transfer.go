@@ -0,0 +1,164 @@
+package usecase
+
+import (
+	"computer-club/internal/repository"
+	"computer-club/internal/repository/models"
+	"computer-club/pkg/errors"
+	"computer-club/pkg/money"
+	"context"
+	"gorm.io/gorm"
+	"time"
+)
+
+type TransferService interface {
+	Transfer(ctx context.Context, fromUserID, toUserID int64, amount money.Money, comment string) (*models.Transfer, error)
+	ReverseTransfer(ctx context.Context, transferID, adminID int64) (*models.Transfer, error)
+}
+
+type TransferUsecase struct {
+	transferRepo  repository.TransferRepository
+	walletRepo    repository.WalletRepository
+	userRepo      repository.UserRepository
+	txManager     repository.TxManager
+	ledgerService LedgerService
+	limitsService LimitsService
+	minAmount     money.Money
+	dailyLimit    money.Money
+}
+
+func NewTransferUsecase(transferRepo repository.TransferRepository,
+	walletRepo repository.WalletRepository,
+	userRepo repository.UserRepository,
+	txManager repository.TxManager,
+	ledgerService LedgerService,
+	limitsService LimitsService,
+	minAmount, dailyLimit money.Money) TransferService {
+	return &TransferUsecase{transferRepo: transferRepo,
+		walletRepo:    walletRepo,
+		userRepo:      userRepo,
+		txManager:     txManager,
+		ledgerService: ledgerService,
+		limitsService: limitsService,
+		minAmount:     minAmount,
+		dailyLimit:    dailyLimit}
+}
+
+// Transfer переводит средства между кошельками игроков в одной транзакции БД.
+// Кошельки блокируются до конца транзакции, поэтому дневной лимит проверяется уже под блокировкой
+func (u *TransferUsecase) Transfer(ctx context.Context, fromUserID, toUserID int64, amount money.Money, comment string) (*models.Transfer, error) {
+	if !amount.IsPositive() {
+		return nil, errors.ErrInvalidAmount
+	}
+	if fromUserID == toUserID {
+		return nil, errors.ErrTransferToSelf
+	}
+	if amount.Currency != u.minAmount.Currency {
+		return nil, errors.ErrCurrencyMismatch
+	}
+	if amount.LessThan(u.minAmount) {
+		return nil, errors.ErrTransferTooSmall
+	}
+	if _, err := u.userRepo.GetUserByID(ctx, toUserID); err != nil {
+		return nil, errors.ErrUserNotFound
+	}
+
+	now := time.Now()
+	// Перевод другу не должен обходить лимиты расходов, заданные опекуном
+	if err := u.limitsService.CheckPurchase(ctx, fromUserID, amount, now); err != nil {
+		return nil, err
+	}
+	transfer := &models.Transfer{
+		FromUserID: fromUserID,
+		ToUserID:   toUserID,
+		Amount:     amount,
+		Comment:    comment,
+		Status:     models.TransferCompleted,
+		CreatedAt:  now,
+	}
+	dayStart := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+
+	err := u.txManager.WithTransaction(ctx, func(tx *gorm.DB) error {
+		if err := u.walletRepo.LockWallets(tx, fromUserID, toUserID); err != nil {
+			return err
+		}
+		sent, err := u.transferRepo.SumOutgoing(tx, fromUserID, dayStart)
+		if err != nil {
+			return err
+		}
+		if u.dailyLimit.LessThan(sent.Add(amount)) {
+			return errors.ErrTransferDailyLimit
+		}
+		if err := u.transferRepo.CreateTransfer(tx, transfer); err != nil {
+			return err
+		}
+		return u.move(tx, transfer, fromUserID, toUserID, "Перевод игроку")
+	})
+	if err != nil {
+		return nil, err
+	}
+	return transfer, nil
+}
+
+// ReverseTransfer возвращает средства отправителю. Если получатель уже потратил их, отмена невозможна
+func (u *TransferUsecase) ReverseTransfer(ctx context.Context, transferID, adminID int64) (*models.Transfer, error) {
+	transfer, err := u.transferRepo.GetTransferByID(ctx, transferID)
+	if err != nil {
+		return nil, err
+	}
+	if transfer.Status == models.TransferReversed {
+		return nil, errors.ErrTransferAlreadyReversed
+	}
+
+	now := time.Now()
+	err = u.txManager.WithTransaction(ctx, func(tx *gorm.DB) error {
+		if err := u.walletRepo.LockWallets(tx, transfer.FromUserID, transfer.ToUserID); err != nil {
+			return err
+		}
+		if err := u.transferRepo.MarkReversed(tx, transfer.ID, adminID, now); err != nil {
+			return err
+		}
+		return u.move(tx, transfer, transfer.ToUserID, transfer.FromUserID, "Отмена перевода")
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	transfer.Status = models.TransferReversed
+	transfer.ReversedBy = &adminID
+	transfer.ReversedAt = &now
+	return transfer, nil
+}
+
+// move списывает сумму перевода у from и зачисляет to, записывая связанные транзакции и проводку
+func (u *TransferUsecase) move(tx *gorm.DB, transfer *models.Transfer, from, to int64, description string) error {
+	if err := u.walletRepo.Withdraw(tx, from, transfer.Amount); err != nil {
+		return err
+	}
+	if err := u.walletRepo.Deposit(tx, to, transfer.Amount); err != nil {
+		return err
+	}
+
+	out := &models.Transaction{
+		UserID:     from,
+		Amount:     transfer.Amount,
+		TariffID:   -1,
+		TransferID: &transfer.ID,
+		Type:       models.TransferOut,
+	}
+	if err := u.walletRepo.CreateTransaction(tx, out); err != nil {
+		return err
+	}
+	in := &models.Transaction{
+		UserID:     to,
+		Amount:     transfer.Amount,
+		TariffID:   -1,
+		TransferID: &transfer.ID,
+		Type:       models.TransferIn,
+	}
+	if err := u.walletRepo.CreateTransaction(tx, in); err != nil {
+		return err
+	}
+
+	return u.ledgerService.Move(tx, models.CustomerAccount(from), models.CustomerAccount(to),
+		transfer.Amount, description, &out.ID)
+}
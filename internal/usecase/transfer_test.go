@@ -0,0 +1,255 @@
+package usecase
+
+import (
+	"computer-club/internal/repository"
+	"computer-club/internal/repository/models"
+	"computer-club/pkg/errors"
+	"computer-club/pkg/money"
+	"context"
+	"gorm.io/gorm"
+	"testing"
+	"time"
+)
+
+// fakeTransferRepo хранит переводы в памяти
+type fakeTransferRepo struct {
+	repository.TransferRepository
+	transfers map[int64]*models.Transfer
+}
+
+func (r *fakeTransferRepo) CreateTransfer(tx *gorm.DB, transfer *models.Transfer) error {
+	transfer.ID = int64(len(r.transfers) + 1)
+	stored := *transfer
+	r.transfers[transfer.ID] = &stored
+	return nil
+}
+
+func (r *fakeTransferRepo) GetTransferByID(ctx context.Context, id int64) (*models.Transfer, error) {
+	transfer, ok := r.transfers[id]
+	if !ok {
+		return nil, errors.ErrTransferNotFound
+	}
+	copied := *transfer
+	return &copied, nil
+}
+
+func (r *fakeTransferRepo) SumOutgoing(tx *gorm.DB, userID int64, since time.Time) (money.Money, error) {
+	sum := money.Zero()
+	for _, transfer := range r.transfers {
+		if transfer.FromUserID == userID && transfer.Status == models.TransferCompleted && !transfer.CreatedAt.Before(since) {
+			sum = sum.Add(transfer.Amount)
+		}
+	}
+	return sum, nil
+}
+
+func (r *fakeTransferRepo) MarkReversed(tx *gorm.DB, id, adminID int64, now time.Time) error {
+	transfer, ok := r.transfers[id]
+	if !ok || transfer.Status == models.TransferReversed {
+		return errors.ErrTransferAlreadyReversed
+	}
+	transfer.Status = models.TransferReversed
+	transfer.ReversedBy = &adminID
+	transfer.ReversedAt = &now
+	return nil
+}
+
+func (r *fakeTransferRepo) snapshot() func() {
+	transfers := make(map[int64]models.Transfer, len(r.transfers))
+	for id, transfer := range r.transfers {
+		transfers[id] = *transfer
+	}
+	return func() {
+		r.transfers = map[int64]*models.Transfer{}
+		for id := range transfers {
+			transfer := transfers[id]
+			r.transfers[id] = &transfer
+		}
+	}
+}
+
+// fakeUserRepo знает только о существовании пользователей
+type fakeUserRepo struct {
+	repository.UserRepository
+	users map[int64]*models.User
+}
+
+func (r *fakeUserRepo) GetUserByID(ctx context.Context, id int64) (*models.User, error) {
+	user, ok := r.users[id]
+	if !ok {
+		return nil, errors.ErrUserNotFound
+	}
+	return user, nil
+}
+
+// fakeLimits пропускает покупки, пока не задана ошибка
+type fakeLimits struct {
+	LimitsService
+	err error
+}
+
+func (l *fakeLimits) CheckPurchase(ctx context.Context, userID int64, amount money.Money, now time.Time) error {
+	return l.err
+}
+
+type transferFixture struct {
+	usecase   TransferService
+	wallets   *fakeWalletRepo
+	transfers *fakeTransferRepo
+	ledger    *fakeLedgerRepo
+	limits    *fakeLimits
+}
+
+// newTransferFixture заводит двух игроков: у первого 1000 ₽, у второго пусто.
+// Минимальный перевод - 10 ₽, дневной лимит - 1500 ₽
+func newTransferFixture() *transferFixture {
+	wallets := newFakeWalletRepo(
+		models.Wallet{UserID: 1, Balance: rub(100000), Bonus: rub(0)},
+		models.Wallet{UserID: 2, Balance: rub(0), Bonus: rub(0)},
+	)
+	transfers := &fakeTransferRepo{transfers: map[int64]*models.Transfer{}}
+	ledger := newFakeLedgerRepo()
+	limits := &fakeLimits{}
+	users := &fakeUserRepo{users: map[int64]*models.User{1: {ID: 1}, 2: {ID: 2}}}
+	txManager := &fakeTxManager{stores: []snapshotter{wallets, transfers, ledger}}
+	return &transferFixture{
+		usecase:   NewTransferUsecase(transfers, wallets, users, txManager, NewLedgerUsecase(ledger), limits, rub(1000), rub(150000)),
+		wallets:   wallets,
+		transfers: transfers,
+		ledger:    ledger,
+		limits:    limits,
+	}
+}
+
+func (f *transferFixture) balances(t *testing.T, from, to int64) {
+	t.Helper()
+	if got := f.wallets.wallets[1].Balance.Amount; got != from {
+		t.Errorf("баланс отправителя = %d, want %d", got, from)
+	}
+	if got := f.wallets.wallets[2].Balance.Amount; got != to {
+		t.Errorf("баланс получателя = %d, want %d", got, to)
+	}
+	// Журнал должен сходиться с кошельками: кредит счета клиента - рост его баланса от начального
+	if got := -f.ledger.balance(models.CustomerAccount(1)); got != from-100000 {
+		t.Errorf("изменение счета отправителя в журнале = %d, want %d", got, from-100000)
+	}
+	if got := -f.ledger.balance(models.CustomerAccount(2)); got != to {
+		t.Errorf("изменение счета получателя в журнале = %d, want %d", got, to)
+	}
+}
+
+func TestTransferMovesMoneyAndRecordsBothSides(t *testing.T) {
+	f := newTransferFixture()
+
+	transfer, err := f.usecase.Transfer(context.Background(), 1, 2, rub(30000), "за пиццу")
+	if err != nil {
+		t.Fatalf("Transfer() error: %v", err)
+	}
+	f.balances(t, 70000, 30000)
+
+	out := f.wallets.ofType(1, models.TransferOut)
+	in := f.wallets.ofType(2, models.TransferIn)
+	if len(out) != 1 || len(in) != 1 {
+		t.Fatalf("transfer_out = %d, transfer_in = %d, want по одной", len(out), len(in))
+	}
+	if *out[0].TransferID != transfer.ID || *in[0].TransferID != transfer.ID {
+		t.Fatal("транзакции перевода не связаны с переводом")
+	}
+	if len(f.ledger.entries) != 1 || *f.ledger.entries[0].TransactionID != out[0].ID {
+		t.Fatalf("ожидалась одна проводка по transfer_out, got %+v", f.ledger.entries)
+	}
+}
+
+func TestTransferValidation(t *testing.T) {
+	tests := []struct {
+		name   string
+		from   int64
+		to     int64
+		amount money.Money
+		want   error
+	}{
+		{name: "нулевая сумма", from: 1, to: 2, amount: rub(0), want: errors.ErrInvalidAmount},
+		{name: "себе", from: 1, to: 1, amount: rub(5000), want: errors.ErrTransferToSelf},
+		{name: "меньше минимума", from: 1, to: 2, amount: rub(999), want: errors.ErrTransferTooSmall},
+		{name: "другая валюта", from: 1, to: 2, amount: money.Money{Amount: 5000, Currency: "USD"}, want: errors.ErrCurrencyMismatch},
+		{name: "нет получателя", from: 1, to: 3, amount: rub(5000), want: errors.ErrUserNotFound},
+		{name: "больше баланса", from: 1, to: 2, amount: rub(100001), want: errors.ErrInsufficientFunds},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newTransferFixture()
+			if _, err := f.usecase.Transfer(context.Background(), tt.from, tt.to, tt.amount, ""); err != tt.want {
+				t.Fatalf("Transfer() error = %v, want %v", err, tt.want)
+			}
+			f.balances(t, 100000, 0)
+			if len(f.transfers.transfers) != 0 || len(f.ledger.entries) != 0 {
+				t.Fatal("неудачный перевод оставил записи")
+			}
+		})
+	}
+}
+
+func TestTransferDailyLimit(t *testing.T) {
+	f := newTransferFixture()
+	f.wallets.wallets[1].Balance = rub(500000)
+
+	if _, err := f.usecase.Transfer(context.Background(), 1, 2, rub(100000), ""); err != nil {
+		t.Fatalf("первый перевод: %v", err)
+	}
+	if _, err := f.usecase.Transfer(context.Background(), 1, 2, rub(50001), ""); err != errors.ErrTransferDailyLimit {
+		t.Fatalf("перевод сверх лимита: error = %v, want %v", err, errors.ErrTransferDailyLimit)
+	}
+	if _, err := f.usecase.Transfer(context.Background(), 1, 2, rub(50000), ""); err != nil {
+		t.Fatalf("перевод до лимита: %v", err)
+	}
+}
+
+func TestTransferRespectsSpendingLimits(t *testing.T) {
+	f := newTransferFixture()
+	f.limits.err = errors.ErrDailySpendLimit
+
+	if _, err := f.usecase.Transfer(context.Background(), 1, 2, rub(5000), ""); err != errors.ErrDailySpendLimit {
+		t.Fatalf("Transfer() error = %v, want %v", err, errors.ErrDailySpendLimit)
+	}
+	f.balances(t, 100000, 0)
+}
+
+func TestReverseTransferReturnsMoney(t *testing.T) {
+	f := newTransferFixture()
+	transfer, err := f.usecase.Transfer(context.Background(), 1, 2, rub(30000), "")
+	if err != nil {
+		t.Fatalf("Transfer() error: %v", err)
+	}
+
+	reversed, err := f.usecase.ReverseTransfer(context.Background(), transfer.ID, 99)
+	if err != nil {
+		t.Fatalf("ReverseTransfer() error: %v", err)
+	}
+	if reversed.Status != models.TransferReversed || *reversed.ReversedBy != 99 {
+		t.Fatalf("перевод = %+v, want reversed администратором 99", reversed)
+	}
+	f.balances(t, 100000, 0)
+
+	if _, err := f.usecase.ReverseTransfer(context.Background(), transfer.ID, 99); err != errors.ErrTransferAlreadyReversed {
+		t.Fatalf("повторная отмена: error = %v, want %v", err, errors.ErrTransferAlreadyReversed)
+	}
+}
+
+func TestReverseTransferFailsWhenRecipientSpent(t *testing.T) {
+	f := newTransferFixture()
+	transfer, err := f.usecase.Transfer(context.Background(), 1, 2, rub(30000), "")
+	if err != nil {
+		t.Fatalf("Transfer() error: %v", err)
+	}
+	f.wallets.wallets[2].Balance = rub(10000)
+
+	if _, err := f.usecase.ReverseTransfer(context.Background(), transfer.ID, 99); err != errors.ErrInsufficientFunds {
+		t.Fatalf("ReverseTransfer() error = %v, want %v", err, errors.ErrInsufficientFunds)
+	}
+	if f.transfers.transfers[transfer.ID].Status != models.TransferCompleted {
+		t.Fatal("неудачная отмена должна откатить статус перевода")
+	}
+	if f.wallets.wallets[1].Balance.Amount != 70000 {
+		t.Fatalf("баланс отправителя = %d, want 70000", f.wallets.wallets[1].Balance.Amount)
+	}
+}
@@ -0,0 +1,296 @@
+package usecase
+
+import (
+	"computer-club/internal/repository"
+	"computer-club/internal/repository/models"
+	"computer-club/pkg/errors"
+	"computer-club/pkg/totp"
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"fmt"
+	"gorm.io/gorm"
+	"log"
+	"strings"
+	"time"
+)
+
+type TwoFactorService interface {
+	GetStatus(ctx context.Context, userID int64) (*models.TwoFactorStatus, error)
+	Enroll(ctx context.Context, userID int64) (*models.TwoFactorEnrollment, error)
+	Confirm(ctx context.Context, userID int64, code string) ([]string, error)
+	RegenerateBackupCodes(ctx context.Context, userID int64, code string) ([]string, error)
+	Disable(ctx context.Context, userID int64, code string) error
+	Enabled(ctx context.Context, userID int64) (bool, error)
+	Verify(ctx context.Context, userID int64, code string) error
+	Reset(ctx context.Context, userID int64) error
+}
+
+// TwoFactorPolicy - название сервиса в приложении-аутентификаторе, число резервных кодов
+// и ограничение перебора: MaxAttempts неверных кодов за окно AttemptWindow
+type TwoFactorPolicy struct {
+	Issuer        string
+	BackupCodes   int
+	MaxAttempts   int64
+	AttemptWindow time.Duration
+}
+
+// totpSkew - сколько соседних шагов принимается на расхождение часов телефона и сервера
+const totpSkew = 1
+
+type TwoFactorUsecase struct {
+	twoFactorRepo repository.TwoFactorRepository
+	userRepo      repository.UserRepository
+	attemptRepo   repository.AttemptRepository
+	txManager     repository.TxManager
+	policy        TwoFactorPolicy
+}
+
+func NewTwoFactorUsecase(twoFactorRepo repository.TwoFactorRepository,
+	userRepo repository.UserRepository,
+	attemptRepo repository.AttemptRepository,
+	txManager repository.TxManager,
+	policy TwoFactorPolicy) TwoFactorService {
+	return &TwoFactorUsecase{
+		twoFactorRepo: twoFactorRepo,
+		userRepo:      userRepo,
+		attemptRepo:   attemptRepo,
+		txManager:     txManager,
+		policy:        policy,
+	}
+}
+
+func (u *TwoFactorUsecase) GetStatus(ctx context.Context, userID int64) (*models.TwoFactorStatus, error) {
+	user, err := u.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, errors.ErrUserNotFound
+	}
+	status := &models.TwoFactorStatus{Required: models.UserRole(user.Role).RequiresTwoFactor()}
+
+	twoFactor, err := u.twoFactorRepo.GetTwoFactor(ctx, userID)
+	if err == errors.ErrTwoFactorNotEnabled {
+		return status, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !twoFactor.Enabled() {
+		return status, nil
+	}
+	status.Enabled = true
+	status.EnabledAt = twoFactor.EnabledAt
+	status.BackupCodesLeft, err = u.twoFactorRepo.CountBackupCodes(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return status, nil
+}
+
+// Enroll выдает новый секрет. Он начинает действовать только после Confirm, поэтому
+// повторный Enroll до подтверждения просто заменяет секрет
+func (u *TwoFactorUsecase) Enroll(ctx context.Context, userID int64) (*models.TwoFactorEnrollment, error) {
+	user, err := u.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, errors.ErrUserNotFound
+	}
+	existing, err := u.twoFactorRepo.GetTwoFactor(ctx, userID)
+	if err != nil && err != errors.ErrTwoFactorNotEnabled {
+		return nil, err
+	}
+	if existing != nil && existing.Enabled() {
+		return nil, errors.ErrTwoFactorEnabled
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, errors.ErrTokenGeneration
+	}
+	if err := u.twoFactorRepo.SaveTwoFactor(nil, &models.TwoFactor{
+		UserID:    userID,
+		Secret:    secret,
+		CreatedAt: time.Now(),
+	}); err != nil {
+		return nil, err
+	}
+	return &models.TwoFactorEnrollment{
+		Secret: secret,
+		URI:    totp.URI(u.policy.Issuer, user.Email, secret),
+	}, nil
+}
+
+// Confirm включает 2FA по первому коду из приложения и возвращает резервные коды.
+// Коды показываются один раз, в базе хранятся только их хеши
+func (u *TwoFactorUsecase) Confirm(ctx context.Context, userID int64, code string) ([]string, error) {
+	if err := u.checkAttempts(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	var codes []string
+	err := u.txManager.WithTransaction(ctx, func(tx *gorm.DB) error {
+		twoFactor, err := u.twoFactorRepo.GetTwoFactorForUpdate(tx, userID)
+		if err == errors.ErrTwoFactorNotEnabled {
+			return errors.ErrTwoFactorNotEnrolled
+		}
+		if err != nil {
+			return err
+		}
+		if twoFactor.Enabled() {
+			return errors.ErrTwoFactorEnabled
+		}
+
+		now := time.Now()
+		step, ok := totp.Validate(twoFactor.Secret, normalizeCode(code), now, totpSkew)
+		if !ok {
+			return errors.ErrInvalidTwoFactorCode
+		}
+		if err := u.twoFactorRepo.EnableTwoFactor(tx, userID, now, step); err != nil {
+			return err
+		}
+		codes, err = u.replaceBackupCodes(tx, userID)
+		return err
+	})
+	if err != nil {
+		u.recordFailure(ctx, userID, err)
+		return nil, err
+	}
+	return codes, nil
+}
+
+// RegenerateBackupCodes выпускает новый набор резервных кодов взамен прежнего
+func (u *TwoFactorUsecase) RegenerateBackupCodes(ctx context.Context, userID int64, code string) ([]string, error) {
+	if err := u.Verify(ctx, userID, code); err != nil {
+		return nil, err
+	}
+	var codes []string
+	err := u.txManager.WithTransaction(ctx, func(tx *gorm.DB) error {
+		var err error
+		codes, err = u.replaceBackupCodes(tx, userID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// Disable отключает 2FA. Нужен действующий код, чтобы отключить защиту не мог тот,
+// у кого есть только access-токен
+func (u *TwoFactorUsecase) Disable(ctx context.Context, userID int64, code string) error {
+	if err := u.Verify(ctx, userID, code); err != nil {
+		return err
+	}
+	return u.twoFactorRepo.DeleteTwoFactor(nil, userID)
+}
+
+func (u *TwoFactorUsecase) Enabled(ctx context.Context, userID int64) (bool, error) {
+	twoFactor, err := u.twoFactorRepo.GetTwoFactor(ctx, userID)
+	if err == errors.ErrTwoFactorNotEnabled {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return twoFactor.Enabled(), nil
+}
+
+// Verify принимает код из приложения или резервный код. Код из приложения действует один раз:
+// шаг, который уже был принят, повторно не проходит. После MaxAttempts неверных кодов проверка
+// блокируется до конца окна AttemptWindow
+func (u *TwoFactorUsecase) Verify(ctx context.Context, userID int64, code string) error {
+	if err := u.checkAttempts(ctx, userID); err != nil {
+		return err
+	}
+
+	code = normalizeCode(code)
+	err := u.txManager.WithTransaction(ctx, func(tx *gorm.DB) error {
+		twoFactor, err := u.twoFactorRepo.GetTwoFactorForUpdate(tx, userID)
+		if err != nil {
+			return err
+		}
+		if !twoFactor.Enabled() {
+			return errors.ErrTwoFactorNotEnabled
+		}
+
+		now := time.Now()
+		if len(code) == totp.Digits {
+			step, ok := totp.Validate(twoFactor.Secret, code, now, totpSkew)
+			if !ok || step <= twoFactor.LastStep {
+				return errors.ErrInvalidTwoFactorCode
+			}
+			return u.twoFactorRepo.UpdateLastStep(tx, userID, step)
+		}
+		return u.twoFactorRepo.UseBackupCode(tx, userID, hashToken(code), now)
+	})
+	if err != nil {
+		u.recordFailure(ctx, userID, err)
+		return err
+	}
+	if err := u.attemptRepo.Reset(ctx, twoFactorAttemptKey(userID)); err != nil {
+		log.Printf("Не удалось сбросить счетчик попыток 2FA пользователя %d: %v", userID, err)
+	}
+	return nil
+}
+
+// Reset отключает 2FA без кода, когда сотрудник потерял телефон и резервные коды
+func (u *TwoFactorUsecase) Reset(ctx context.Context, userID int64) error {
+	if err := u.twoFactorRepo.DeleteTwoFactor(nil, userID); err != nil {
+		return err
+	}
+	return u.attemptRepo.Reset(ctx, twoFactorAttemptKey(userID))
+}
+
+func (u *TwoFactorUsecase) checkAttempts(ctx context.Context, userID int64) error {
+	count, err := u.attemptRepo.Count(ctx, twoFactorAttemptKey(userID))
+	if err != nil {
+		return err
+	}
+	if count >= u.policy.MaxAttempts {
+		return errors.ErrTooManyAttempts
+	}
+	return nil
+}
+
+func (u *TwoFactorUsecase) recordFailure(ctx context.Context, userID int64, err error) {
+	if err != errors.ErrInvalidTwoFactorCode {
+		return
+	}
+	if _, err := u.attemptRepo.Add(ctx, twoFactorAttemptKey(userID), u.policy.AttemptWindow); err != nil {
+		log.Printf("Не удалось учесть попытку ввода кода 2FA пользователя %d: %v", userID, err)
+	}
+}
+
+func (u *TwoFactorUsecase) replaceBackupCodes(tx *gorm.DB, userID int64) ([]string, error) {
+	codes := make([]string, 0, u.policy.BackupCodes)
+	hashes := make([]string, 0, u.policy.BackupCodes)
+	for i := 0; i < u.policy.BackupCodes; i++ {
+		code, err := newBackupCode()
+		if err != nil {
+			return nil, err
+		}
+		codes = append(codes, code)
+		hashes = append(hashes, hashToken(normalizeCode(code)))
+	}
+	if err := u.twoFactorRepo.ReplaceBackupCodes(tx, userID, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+func twoFactorAttemptKey(userID int64) string {
+	return fmt.Sprintf("2fa:user:%d", userID)
+}
+
+// newBackupCode возвращает код вида abcd-efgh: 40 случайных бит в base32
+func newBackupCode() (string, error) {
+	buf := make([]byte, 5)
+	if _, err := rand.Read(buf); err != nil {
+		return "", errors.ErrTokenGeneration
+	}
+	code := strings.ToLower(base32.StdEncoding.EncodeToString(buf))
+	return code[:4] + "-" + code[4:], nil
+}
+
+// normalizeCode убирает пробелы и дефисы, которые пользователь мог ввести вместе с кодом
+func normalizeCode(code string) string {
+	return strings.ToLower(strings.NewReplacer(" ", "", "-", "").Replace(strings.TrimSpace(code)))
+}
@@ -1,34 +1,110 @@
 package usecase
 
 import (
+	"computer-club/internal/mailer"
 	"computer-club/internal/repository"
 	"computer-club/internal/repository/models"
 	"computer-club/pkg/errors"
 	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
 	"github.com/golang-jwt/jwt/v4"
 	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+	"log"
+	"net/url"
 	"os"
+	"strings"
 	"time"
 )
 
 type UserService interface {
-	RegisterUser(ctx context.Context, name, email, password string, role models.UserRole) (*models.User, error)
-	LoginUser(ctx context.Context, name string, password string) (string, error)
+	RegisterUser(ctx context.Context, name, email, password string) (*models.User, error)
+	SetRole(ctx context.Context, actorID, userID int64, role models.UserRole) (*models.User, error)
+	LoginUser(ctx context.Context, email, password, ip string) (*models.LoginResult, error)
+	LoginTwoFactor(ctx context.Context, challengeToken, code string) (*models.TokenPair, error)
+	ResetTwoFactor(ctx context.Context, actorID, userID int64) error
+	RefreshToken(ctx context.Context, refreshToken string) (*models.TokenPair, error)
+	Logout(ctx context.Context, claims *models.AccessClaims, refreshToken string, all bool) error
+	RevokeUserTokens(ctx context.Context, userID int64) error
+	MonitorTokens(ctx context.Context)
 	GetUserByEmail(ctx context.Context, email string) (*models.User, error)
 	GetUserByID(ctx context.Context, id int64) (*models.User, error)
+	ListUsers(ctx context.Context, filter models.UserFilter) (*models.UserPage, error)
+	UpdateProfile(ctx context.Context, actorID, userID int64, profile models.UserProfile) (*models.User, error)
+	BlockUser(ctx context.Context, actorID, userID int64, reason string) (*models.User, error)
+	UnblockUser(ctx context.Context, actorID, userID int64) (*models.User, error)
+	DeleteUser(ctx context.Context, actorID, userID int64) error
+	ResendVerification(ctx context.Context, userID int64) error
+	VerifyEmail(ctx context.Context, token string) error
+	RequestPasswordReset(ctx context.Context, email string) error
+	ResetPassword(ctx context.Context, token, password string) error
+}
+
+// Размер страницы списка пользователей по умолчанию и максимальный
+const (
+	DefaultUsersLimit = 50
+	MaxUsersLimit     = 200
+)
+
+// TokenPolicy - время жизни access- и refresh-токенов и того, сколько вход ждет код 2FA после пароля
+type TokenPolicy struct {
+	AccessTTL    time.Duration
+	RefreshTTL   time.Duration
+	ChallengeTTL time.Duration
+}
+
+// AccountPolicy - письма с одноразовыми ссылками: срок действия ссылок подтверждения почты
+// и сброса пароля, адрес клиентского приложения для ссылок и обязательность подтверждения для входа
+type AccountPolicy struct {
+	VerificationTTL      time.Duration
+	ResetTTL             time.Duration
+	LinkBaseURL          string
+	RequireVerifiedEmail bool
 }
 
 type UserUsecase struct {
-	userRepo      repository.UserRepository
-	walletService WalletService
+	userRepo         repository.UserRepository
+	walletService    WalletService
+	refreshTokenRepo repository.RefreshTokenRepository
+	revocationRepo   repository.RevocationRepository
+	userTokenRepo    repository.UserTokenRepository
+	mailer           mailer.Mailer
+	twoFactorService TwoFactorService
+	loginGuard       LoginGuardService
+	txManager        repository.TxManager
+	tokenPolicy      TokenPolicy
+	accountPolicy    AccountPolicy
 }
 
 func NewUserUsecase(userRepo repository.UserRepository,
-	walletService WalletService) UserService {
-	return &UserUsecase{userRepo: userRepo, walletService: walletService}
+	walletService WalletService,
+	refreshTokenRepo repository.RefreshTokenRepository,
+	revocationRepo repository.RevocationRepository,
+	userTokenRepo repository.UserTokenRepository,
+	mailer mailer.Mailer,
+	twoFactorService TwoFactorService,
+	loginGuard LoginGuardService,
+	txManager repository.TxManager,
+	tokenPolicy TokenPolicy,
+	accountPolicy AccountPolicy) UserService {
+	return &UserUsecase{userRepo: userRepo,
+		walletService:    walletService,
+		refreshTokenRepo: refreshTokenRepo,
+		revocationRepo:   revocationRepo,
+		userTokenRepo:    userTokenRepo,
+		mailer:           mailer,
+		twoFactorService: twoFactorService,
+		loginGuard:       loginGuard,
+		txManager:        txManager,
+		tokenPolicy:      tokenPolicy,
+		accountPolicy:    accountPolicy}
 }
 
-func (u *UserUsecase) RegisterUser(ctx context.Context, name, email, password string, role models.UserRole) (*models.User, error) {
+// RegisterUser регистрирует клиента. Роли персонала назначаются только через SetRole
+func (u *UserUsecase) RegisterUser(ctx context.Context, name, email, password string) (*models.User, error) {
 	// Проверки на пустые поля
 	if name == "" {
 		return nil, errors.ErrNameEmpty
@@ -43,11 +119,6 @@ func (u *UserUsecase) RegisterUser(ctx context.Context, name, email, password st
 		return nil, errors.ErrPasswordTooShort
 	}
 
-	// Если роль не передана — ставим Customer по умолчанию
-	if role == "" {
-		role = models.Customer
-	}
-
 	// Проверяем, существует ли пользователь с таким email
 	existingUser, _ := u.userRepo.GetUserByEmail(ctx, email)
 	if existingUser != nil {
@@ -70,7 +141,7 @@ func (u *UserUsecase) RegisterUser(ctx context.Context, name, email, password st
 		Name:     name,
 		Email:    email,
 		Password: string(hashedPassword),
-		Role:     string(role),
+		Role:     string(models.Customer),
 	}
 
 	// Сохраняем пользователя в БД
@@ -80,40 +151,337 @@ func (u *UserUsecase) RegisterUser(ctx context.Context, name, email, password st
 	if err := u.walletService.CreateWallet(ctx, user.ID); err != nil {
 		return nil, err
 	}
+
+	// Письмо не должно ломать регистрацию: ссылку можно запросить повторно
+	if err := u.sendVerification(ctx, user); err != nil {
+		log.Printf("Не удалось отправить письмо подтверждения пользователю %d: %v", user.ID, err)
+	}
 	return user, nil
 }
 
-func (u *UserUsecase) LoginUser(ctx context.Context, email string, password string) (string, error) {
+// LoginUser проверяет пароль. Если у пользователя включена 2FA, токены не выдаются: вход
+// завершается в LoginTwoFactor по одноразовому challenge-токену и коду. Неудачи учитывает
+// loginGuard: после серии неверных паролей вход откладывается, затем блокируется
+func (u *UserUsecase) LoginUser(ctx context.Context, email, password, ip string) (*models.LoginResult, error) {
+	if err := u.loginGuard.Check(ctx, email, ip); err != nil {
+		return nil, err
+	}
+
 	user, err := u.userRepo.GetUserByEmail(ctx, email)
 	if err != nil {
-		return "", errors.ErrInvalidCredentials
+		u.recordLoginFailure(ctx, nil, email, ip)
+		return nil, errors.ErrInvalidCredentials
 	}
 
 	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
-		return "", errors.ErrInvalidCredentials
+		u.recordLoginFailure(ctx, &user.ID, email, ip)
+		return nil, errors.ErrInvalidCredentials
+	}
+	if err := u.loginGuard.RecordSuccess(ctx, user, ip); err != nil {
+		log.Printf("Не удалось сбросить счетчик неудачных входов пользователя %d: %v", user.ID, err)
+	}
+	if user.Blocked() {
+		return nil, errors.ErrUserBlocked
+	}
+	if u.accountPolicy.RequireVerifiedEmail && !user.EmailVerified() {
+		return nil, errors.ErrEmailNotVerified
 	}
 
-	token, err := generateJWT(user)
+	enabled, err := u.twoFactorService.Enabled(ctx, user.ID)
 	if err != nil {
-		return "", errors.ErrTokenGeneration
+		return nil, err
+	}
+	if enabled {
+		challenge, err := u.createUserToken(ctx, user, models.PurposeLoginChallenge, u.tokenPolicy.ChallengeTTL)
+		if err != nil {
+			return nil, err
+		}
+		return &models.LoginResult{TwoFactorRequired: true, ChallengeToken: challenge}, nil
 	}
 
-	return token, nil
+	pair, _, err := u.issueTokens(user, nil, false)
+	if err != nil {
+		return nil, err
+	}
+	return &models.LoginResult{TokenPair: pair}, nil
+}
+
+func (u *UserUsecase) recordLoginFailure(ctx context.Context, userID *int64, email, ip string) {
+	if err := u.loginGuard.RecordFailure(ctx, userID, email, ip); err != nil {
+		log.Printf("Не удалось учесть неудачный вход %s с %s: %v", email, ip, err)
+	}
+}
+
+// LoginTwoFactor завершает вход кодом из приложения или резервным кодом. Неверный код
+// challenge-токен не гасит, число попыток ограничивает TwoFactorService. Токен до проверки кода
+// читается без блокировки: одноразовость обеспечивает useToken в транзакции выдачи токенов
+func (u *UserUsecase) LoginTwoFactor(ctx context.Context, challengeToken, code string) (*models.TokenPair, error) {
+	if challengeToken == "" {
+		return nil, errors.ErrInvalidChallenge
+	}
+	challenge, err := u.userTokenRepo.GetUserToken(ctx, hashToken(challengeToken), models.PurposeLoginChallenge)
+	if err == errors.ErrInvalidUserToken {
+		return nil, errors.ErrInvalidChallenge
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !challenge.Usable(time.Now()) {
+		return nil, errors.ErrInvalidChallenge
+	}
+
+	if err := u.twoFactorService.Verify(ctx, challenge.UserID, code); err != nil {
+		return nil, err
+	}
+
+	var pair *models.TokenPair
+	err = u.txManager.WithTransaction(ctx, func(tx *gorm.DB) error {
+		if _, err := u.useToken(tx, challengeToken, models.PurposeLoginChallenge); err != nil {
+			return errors.ErrInvalidChallenge
+		}
+		user, err := u.userRepo.GetUserByID(ctx, challenge.UserID)
+		if err != nil {
+			return errors.ErrInvalidChallenge
+		}
+		if user.Blocked() {
+			return errors.ErrUserBlocked
+		}
+		pair, _, err = u.issueTokens(user, tx, true)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return pair, nil
+}
+
+// ResetTwoFactor отключает 2FA сотрудника, потерявшего телефон, и отзывает его токены.
+// Себе сбросить нельзя: иначе украденный токен позволил бы обойти второй фактор. Без права
+// назначать роли сбросить можно только сотруднику младше по роли, как и при других действиях
+// над пользователями
+func (u *UserUsecase) ResetTwoFactor(ctx context.Context, actorID, userID int64) error {
+	if actorID == userID {
+		return errors.ErrCannotModifySelf
+	}
+	user, err := u.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return errors.ErrUserNotFound
+	}
+	if _, err := u.checkCanManage(ctx, actorID, user); err != nil {
+		return err
+	}
+	if err := u.twoFactorService.Reset(ctx, userID); err != nil {
+		return err
+	}
+	return u.RevokeUserTokens(ctx, userID)
+}
+
+// SetRole назначает пользователю роль. Свою роль менять нельзя, чтобы не лишиться доступа
+// к управлению персоналом. Токены пользователя отзываются, чтобы новая роль действовала сразу
+func (u *UserUsecase) SetRole(ctx context.Context, actorID, userID int64, role models.UserRole) (*models.User, error) {
+	if !role.Valid() {
+		return nil, errors.ErrInvalidRole
+	}
+	if actorID == userID {
+		return nil, errors.ErrCannotChangeOwnRole
+	}
+	user, err := u.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, errors.ErrUserNotFound
+	}
+	if _, err := u.checkCanManage(ctx, actorID, user); err != nil {
+		return nil, err
+	}
+	if err := u.userRepo.UpdateRole(ctx, userID, role); err != nil {
+		return nil, err
+	}
+	if err := u.RevokeUserTokens(ctx, userID); err != nil {
+		return nil, err
+	}
+	return u.userRepo.GetUserByID(ctx, userID)
+}
+
+// RefreshToken меняет refresh-токен на новую пару токенов. Старый refresh-токен отзывается.
+// Повторное предъявление уже отозванного токена означает, что он утек: тогда отзываются
+// все токены пользователя
+func (u *UserUsecase) RefreshToken(ctx context.Context, refreshToken string) (*models.TokenPair, error) {
+	if refreshToken == "" {
+		return nil, errors.ErrInvalidRefreshToken
+	}
+
+	var pair *models.TokenPair
+	var reusedBy int64
+	err := u.txManager.WithTransaction(ctx, func(tx *gorm.DB) error {
+		stored, err := u.refreshTokenRepo.GetRefreshTokenForUpdate(tx, hashToken(refreshToken))
+		if err != nil {
+			return err
+		}
+		if stored.RevokedAt != nil {
+			reusedBy = stored.UserID
+			return errors.ErrInvalidRefreshToken
+		}
+		if !stored.Active(time.Now()) {
+			return errors.ErrInvalidRefreshToken
+		}
+
+		// Роль берется из базы, чтобы новый токен отражал ее текущее значение
+		user, err := u.userRepo.GetUserByID(ctx, stored.UserID)
+		if err != nil {
+			return errors.ErrInvalidRefreshToken
+		}
+		if user.Blocked() {
+			return errors.ErrUserBlocked
+		}
+		var next *models.RefreshToken
+		pair, next, err = u.issueTokens(user, tx, stored.TwoFactor)
+		if err != nil {
+			return err
+		}
+		return u.refreshTokenRepo.RevokeRefreshToken(tx, stored.ID, &next.ID)
+	})
+	if reusedBy != 0 {
+		log.Printf("Повторное использование отозванного refresh-токена пользователя %d, отзываем все токены", reusedBy)
+		if err := u.RevokeUserTokens(ctx, reusedBy); err != nil {
+			log.Printf("Не удалось отозвать токены пользователя %d: %v", reusedBy, err)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return pair, nil
+}
+
+// Logout отзывает текущий access-токен и переданный refresh-токен. С all отзываются
+// все токены пользователя на всех устройствах
+func (u *UserUsecase) Logout(ctx context.Context, claims *models.AccessClaims, refreshToken string, all bool) error {
+	if all {
+		return u.RevokeUserTokens(ctx, claims.UserID)
+	}
+
+	if refreshToken != "" {
+		err := u.txManager.WithTransaction(ctx, func(tx *gorm.DB) error {
+			stored, err := u.refreshTokenRepo.GetRefreshTokenForUpdate(tx, hashToken(refreshToken))
+			if err != nil {
+				return err
+			}
+			if stored.UserID != claims.UserID {
+				return errors.ErrInvalidRefreshToken
+			}
+			return u.refreshTokenRepo.RevokeRefreshToken(tx, stored.ID, nil)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return u.revocationRepo.RevokeToken(ctx, claims.JTI, time.Until(claims.ExpiresAt))
+}
+
+// RevokeUserTokens отзывает все refresh-токены пользователя и все выпущенные ему access-токены.
+// Нужен, например, когда сотрудник увольняется
+func (u *UserUsecase) RevokeUserTokens(ctx context.Context, userID int64) error {
+	if err := u.refreshTokenRepo.RevokeUserRefreshTokens(ctx, userID); err != nil {
+		return err
+	}
+	return u.revocationRepo.RevokeUserTokens(ctx, userID, time.Now(), u.tokenPolicy.AccessTTL)
+}
+
+// MonitorTokens раз в сутки удаляет истекшие refresh-токены и одноразовые токены из писем
+func (u *UserUsecase) MonitorTokens(ctx context.Context) {
+	ticker := time.NewTicker(24 * time.Hour)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Println("Остановка очистки refresh-токенов")
+			return
+		case <-ticker.C:
+			deleted, err := u.refreshTokenRepo.DeleteExpiredRefreshTokens(ctx, time.Now())
+			if err != nil {
+				log.Printf("Не удалось удалить истекшие refresh-токены: %v", err)
+			} else if deleted > 0 {
+				log.Printf("Удалено истекших refresh-токенов: %d", deleted)
+			}
+
+			deleted, err = u.userTokenRepo.DeleteExpiredUserTokens(ctx, time.Now())
+			if err != nil {
+				log.Printf("Не удалось удалить истекшие ссылки из писем: %v", err)
+			} else if deleted > 0 {
+				log.Printf("Удалено истекших ссылок из писем: %d", deleted)
+			}
+		}
+	}
 }
 
 var jwtKey = []byte(os.Getenv("JWT_SECRET"))
 
-func generateJWT(user *models.User) (string, error) {
+// issueTokens выпускает короткоживущий access-токен и сохраняет хеш нового refresh-токена.
+// twoFactor - вход подтвержден кодом 2FA, без этого права на движение денег не действуют
+func (u *UserUsecase) issueTokens(user *models.User, tx *gorm.DB, twoFactor bool) (*models.TokenPair, *models.RefreshToken, error) {
+	now := time.Now()
+	accessExpiresAt := now.Add(u.tokenPolicy.AccessTTL)
+	access, err := generateJWT(user, now, accessExpiresAt, twoFactor)
+	if err != nil {
+		return nil, nil, errors.ErrTokenGeneration
+	}
+
+	refresh, err := newRandomToken()
+	if err != nil {
+		return nil, nil, err
+	}
+	stored := &models.RefreshToken{
+		UserID:    user.ID,
+		TokenHash: hashToken(refresh),
+		ExpiresAt: now.Add(u.tokenPolicy.RefreshTTL),
+		TwoFactor: twoFactor,
+		CreatedAt: now,
+	}
+	if err := u.refreshTokenRepo.CreateRefreshToken(tx, stored); err != nil {
+		return nil, nil, err
+	}
+
+	return &models.TokenPair{
+		Token:            access,
+		ExpiresAt:        accessExpiresAt,
+		RefreshToken:     refresh,
+		RefreshExpiresAt: stored.ExpiresAt,
+	}, stored, nil
+}
+
+// generateJWT подписывает access-токен. jti нужен, чтобы отозвать конкретный токен при выходе,
+// mfa - что вход подтвержден вторым фактором
+func generateJWT(user *models.User, issuedAt, expiresAt time.Time, twoFactor bool) (string, error) {
+	jti, err := newRandomToken()
+	if err != nil {
+		return "", err
+	}
 	claims := jwt.MapClaims{
 		"user_id": user.ID,
 		"role":    user.Role,
-		"exp":     time.Now().Add(time.Hour * 24).Unix(), // Токен живёт 24 часа
+		"jti":     jti,
+		"iat":     issuedAt.Unix(),
+		"exp":     expiresAt.Unix(),
+		"mfa":     twoFactor,
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	return token.SignedString(jwtKey)
 }
 
+func newRandomToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", errors.ErrTokenGeneration
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// hashToken - в базе хранится только хеш refresh-токена, сам токен знает лишь клиент
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
 func (u *UserUsecase) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
 	return u.userRepo.GetUserByEmail(ctx, email)
 }
@@ -121,3 +489,323 @@ func (u *UserUsecase) GetUserByEmail(ctx context.Context, email string) (*models
 func (u *UserUsecase) GetUserByID(ctx context.Context, id int64) (*models.User, error) {
 	return u.userRepo.GetUserByID(ctx, id)
 }
+
+// ListUsers ищет пользователей для администратора. Запрашивается на одну запись больше,
+// чтобы понять, есть ли следующая страница
+func (u *UserUsecase) ListUsers(ctx context.Context, filter models.UserFilter) (*models.UserPage, error) {
+	filter.Query = strings.TrimSpace(filter.Query)
+	if filter.Role != "" && !filter.Role.Valid() {
+		return nil, errors.ErrInvalidUserFilter
+	}
+	switch {
+	case filter.Limit <= 0:
+		filter.Limit = DefaultUsersLimit
+	case filter.Limit > MaxUsersLimit:
+		filter.Limit = MaxUsersLimit
+	}
+	limit := filter.Limit
+	filter.Limit++
+
+	users, err := u.userRepo.FindUsers(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	page := &models.UserPage{Users: users}
+	if len(users) > limit {
+		page.Users = users[:limit]
+		page.NextCursor = &page.Users[limit-1].ID
+	}
+	return page, nil
+}
+
+// checkCanManage проверяет, что сотрудник может действовать над пользователем: без права
+// назначать роли трогать можно только тех, кто младше по роли. Роль сотрудника берется из базы,
+// а не из токена, чтобы понижение действовало сразу
+func (u *UserUsecase) checkCanManage(ctx context.Context, actorID int64, target *models.User) (*models.User, error) {
+	actor, err := u.userRepo.GetUserByID(ctx, actorID)
+	if err != nil {
+		return nil, errors.ErrUserNotFound
+	}
+	if !models.UserRole(actor.Role).CanManage(models.UserRole(target.Role)) {
+		return nil, errors.ErrRoleTooHigh
+	}
+	return actor, nil
+}
+
+// UpdateProfile меняет имя, почту или телефон пользователя с теми же проверками уникальности,
+// что и при регистрации. Почту сотрудника меняет только владелец: через нее восстанавливается
+// пароль, и смена почты равна захвату аккаунта
+func (u *UserUsecase) UpdateProfile(ctx context.Context, actorID, userID int64, profile models.UserProfile) (*models.User, error) {
+	user, err := u.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, errors.ErrUserNotFound
+	}
+	actor, err := u.checkCanManage(ctx, actorID, user)
+	if err != nil {
+		return nil, err
+	}
+
+	if profile.Name != nil {
+		name := strings.TrimSpace(*profile.Name)
+		if name == "" {
+			return nil, errors.ErrNameEmpty
+		}
+		if existing, _ := u.userRepo.GetUserByName(ctx, name); existing != nil && existing.ID != userID {
+			return nil, errors.ErrUsernameTaken
+		}
+		profile.Name = &name
+	}
+	if profile.Email != nil {
+		email := strings.TrimSpace(*profile.Email)
+		if email == "" {
+			return nil, errors.ErrEmailEmpty
+		}
+		if existing, _ := u.userRepo.GetUserByEmail(ctx, email); existing != nil && existing.ID != userID {
+			return nil, errors.ErrUserAlreadyExists
+		}
+		profile.Email = &email
+		// Тот же адрес не трогаем, иначе сбросится подтверждение почты
+		if email == user.Email {
+			profile.Email = nil
+		} else if models.UserRole(user.Role).Staff() && !models.UserRole(actor.Role).Can(models.PermUserRoleAssign) {
+			return nil, errors.ErrStaffEmailChange
+		}
+	}
+	if profile.Phone != nil {
+		phone := strings.TrimSpace(*profile.Phone)
+		if phone != "" {
+			if existing, _ := u.userRepo.GetUserByPhone(ctx, phone); existing != nil && existing.ID != userID {
+				return nil, errors.ErrPhoneTaken
+			}
+		}
+		profile.Phone = &phone
+	}
+
+	if err := u.userRepo.UpdateProfile(ctx, userID, profile); err != nil {
+		return nil, err
+	}
+	return u.userRepo.GetUserByID(ctx, userID)
+}
+
+// BlockUser блокирует пользователя с указанием причины. Все его токены отзываются,
+// поэтому блокировка действует сразу, а не после истечения access-токена
+func (u *UserUsecase) BlockUser(ctx context.Context, actorID, userID int64, reason string) (*models.User, error) {
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return nil, errors.ErrBlockReasonRequired
+	}
+	if actorID == userID {
+		return nil, errors.ErrCannotModifySelf
+	}
+	user, err := u.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, errors.ErrUserNotFound
+	}
+	if _, err := u.checkCanManage(ctx, actorID, user); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if err := u.userRepo.SetBlocked(ctx, userID, &now, &actorID, reason); err != nil {
+		return nil, err
+	}
+	if err := u.RevokeUserTokens(ctx, userID); err != nil {
+		return nil, err
+	}
+	return u.userRepo.GetUserByID(ctx, userID)
+}
+
+// UnblockUser снимает блокировку. Новые токены пользователь получит при следующем входе
+func (u *UserUsecase) UnblockUser(ctx context.Context, actorID, userID int64) (*models.User, error) {
+	user, err := u.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, errors.ErrUserNotFound
+	}
+	if _, err := u.checkCanManage(ctx, actorID, user); err != nil {
+		return nil, err
+	}
+	if !user.Blocked() {
+		return nil, errors.ErrUserNotBlocked
+	}
+	if err := u.userRepo.SetBlocked(ctx, userID, nil, nil, ""); err != nil {
+		return nil, err
+	}
+	return u.userRepo.GetUserByID(ctx, userID)
+}
+
+// DeleteUser мягко удаляет пользователя и отзывает его токены. Кошелек и история операций
+// остаются для отчетности
+func (u *UserUsecase) DeleteUser(ctx context.Context, actorID, userID int64) error {
+	if actorID == userID {
+		return errors.ErrCannotModifySelf
+	}
+	user, err := u.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return errors.ErrUserNotFound
+	}
+	if _, err := u.checkCanManage(ctx, actorID, user); err != nil {
+		return err
+	}
+	if err := u.userRepo.DeleteUser(ctx, userID); err != nil {
+		return err
+	}
+	return u.RevokeUserTokens(ctx, userID)
+}
+
+// ResendVerification повторно отправляет письмо подтверждения, прежняя ссылка перестает действовать
+func (u *UserUsecase) ResendVerification(ctx context.Context, userID int64) error {
+	user, err := u.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return errors.ErrUserNotFound
+	}
+	if user.EmailVerified() {
+		return errors.ErrEmailAlreadyVerified
+	}
+	return u.sendVerification(ctx, user)
+}
+
+// VerifyEmail подтверждает почту по токену из письма
+func (u *UserUsecase) VerifyEmail(ctx context.Context, token string) error {
+	if token == "" {
+		return errors.ErrInvalidUserToken
+	}
+	return u.txManager.WithTransaction(ctx, func(tx *gorm.DB) error {
+		stored, err := u.useToken(tx, token, models.PurposeVerifyEmail)
+		if err != nil {
+			return err
+		}
+		return u.userRepo.SetEmailVerified(tx, stored.UserID, time.Now())
+	})
+}
+
+// RequestPasswordReset отправляет ссылку сброса пароля. Для неизвестного адреса ошибка не возвращается,
+// чтобы по ответу нельзя было узнать, зарегистрирован ли email
+func (u *UserUsecase) RequestPasswordReset(ctx context.Context, email string) error {
+	email = strings.TrimSpace(email)
+	if email == "" {
+		return errors.ErrEmailEmpty
+	}
+	user, err := u.userRepo.GetUserByEmail(ctx, email)
+	if err != nil {
+		return nil
+	}
+
+	link, err := u.createLink(ctx, user, models.PurposeResetPassword, u.accountPolicy.ResetTTL, "/reset-password")
+	if err != nil {
+		return err
+	}
+	return u.mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Сброс пароля",
+		Body: fmt.Sprintf("Здравствуйте, %s!\n\nЧтобы задать новый пароль, перейдите по ссылке:\n%s\n\n"+
+			"Ссылка действует %s и сработает один раз. Если вы не запрашивали сброс, просто проигнорируйте письмо.\n",
+			user.Name, link, describeTTL(u.accountPolicy.ResetTTL)),
+	})
+}
+
+// ResetPassword задает новый пароль по токену из письма. Ссылка пришла на почту пользователя,
+// поэтому почта заодно считается подтвержденной. Все токены пользователя отзываются,
+// чтобы выйти из сессий, которые мог открыть злоумышленник
+func (u *UserUsecase) ResetPassword(ctx context.Context, token, password string) error {
+	if token == "" {
+		return errors.ErrInvalidUserToken
+	}
+	if password == "" {
+		return errors.ErrPasswordEmpty
+	}
+	if len(password) < 6 {
+		return errors.ErrPasswordTooShort
+	}
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return errors.ErrHashedPassword
+	}
+
+	var userID int64
+	err = u.txManager.WithTransaction(ctx, func(tx *gorm.DB) error {
+		stored, err := u.useToken(tx, token, models.PurposeResetPassword)
+		if err != nil {
+			return err
+		}
+		userID = stored.UserID
+		if err := u.userRepo.UpdatePassword(tx, stored.UserID, string(hashedPassword)); err != nil {
+			return err
+		}
+		return u.userRepo.SetEmailVerified(tx, stored.UserID, time.Now())
+	})
+	if err != nil {
+		return err
+	}
+	return u.RevokeUserTokens(ctx, userID)
+}
+
+func (u *UserUsecase) sendVerification(ctx context.Context, user *models.User) error {
+	link, err := u.createLink(ctx, user, models.PurposeVerifyEmail, u.accountPolicy.VerificationTTL, "/verify-email")
+	if err != nil {
+		return err
+	}
+	return u.mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Подтверждение почты",
+		Body: fmt.Sprintf("Здравствуйте, %s!\n\nПодтвердите адрес почты, перейдя по ссылке:\n%s\n\nСсылка действует %s.\n",
+			user.Name, link, describeTTL(u.accountPolicy.VerificationTTL)),
+	})
+}
+
+// createLink выпускает одноразовый токен и возвращает ссылку на клиентское приложение
+func (u *UserUsecase) createLink(ctx context.Context, user *models.User, purpose models.TokenPurpose, ttl time.Duration, path string) (string, error) {
+	token, err := u.createUserToken(ctx, user, purpose, ttl)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(u.accountPolicy.LinkBaseURL, "/") + path + "?token=" + url.QueryEscape(token), nil
+}
+
+// createUserToken выпускает одноразовый токен. Прежние неиспользованные токены того же назначения гасятся
+func (u *UserUsecase) createUserToken(ctx context.Context, user *models.User, purpose models.TokenPurpose, ttl time.Duration) (string, error) {
+	token, err := newRandomToken()
+	if err != nil {
+		return "", err
+	}
+	now := time.Now()
+	err = u.txManager.WithTransaction(ctx, func(tx *gorm.DB) error {
+		if err := u.userTokenRepo.InvalidateUserTokens(tx, user.ID, purpose); err != nil {
+			return err
+		}
+		return u.userTokenRepo.CreateUserToken(tx, &models.UserToken{
+			UserID:    user.ID,
+			Purpose:   purpose,
+			TokenHash: hashToken(token),
+			ExpiresAt: now.Add(ttl),
+			CreatedAt: now,
+		})
+	})
+	if err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// useToken проверяет одноразовый токен и отмечает его использованным
+func (u *UserUsecase) useToken(tx *gorm.DB, token string, purpose models.TokenPurpose) (*models.UserToken, error) {
+	stored, err := u.userTokenRepo.GetUserTokenForUpdate(tx, hashToken(token), purpose)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	if !stored.Usable(now) {
+		return nil, errors.ErrInvalidUserToken
+	}
+	if err := u.userTokenRepo.UseUserToken(tx, stored.ID, now); err != nil {
+		return nil, err
+	}
+	return stored, nil
+}
+
+// describeTTL - срок действия ссылки для текста письма
+func describeTTL(ttl time.Duration) string {
+	if ttl >= time.Hour && ttl%time.Hour == 0 {
+		return fmt.Sprintf("%d ч", int(ttl.Hours()))
+	}
+	return fmt.Sprintf("%d мин", int(ttl.Minutes()))
+}
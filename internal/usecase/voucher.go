@@ -0,0 +1,378 @@
+package usecase
+
+import (
+	"computer-club/internal/repository"
+	"computer-club/internal/repository/models"
+	"computer-club/pkg/errors"
+	"computer-club/pkg/money"
+	"context"
+	"crypto/rand"
+	"fmt"
+	"gorm.io/gorm"
+	"log"
+	"strings"
+	"time"
+)
+
+// voucherAlphabet - символы кода без легко путаемых 0/O и 1/I
+const voucherAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+// voucherCodeLength - длина кода без дефисов: 12 символов из 32 дают 60 бит случайности
+const voucherCodeLength = 12
+
+type VoucherService interface {
+	CreateBatch(ctx context.Context, adminID int64, batch *models.VoucherBatch, validityDays int) (*models.VoucherBatchDetails, error)
+	GetBatch(ctx context.Context, batchID int64) (*models.VoucherBatchDetails, error)
+	SellVoucher(ctx context.Context, adminID int64, code string, method models.PaymentMethod) (*models.Voucher, error)
+	RedeemVoucher(ctx context.Context, userID int64, ip, code string) (*models.VoucherRedemption, error)
+	MonitorVouchers(ctx context.Context)
+}
+
+// VoucherPolicy - ограничения выпуска и погашения подарочных карт
+type VoucherPolicy struct {
+	MaxBatchSize        int
+	DefaultValidityDays int
+	PackageValidityDays int
+	MaxAttempts         int64
+	AttemptWindow       time.Duration
+}
+
+type VoucherUsecase struct {
+	voucherRepo    repository.VoucherRepository
+	walletRepo     repository.WalletRepository
+	packageRepo    repository.PackageRepository
+	attemptRepo    repository.AttemptRepository
+	shiftRepo      repository.ShiftRepository
+	ledgerService  LedgerService
+	limitsService  LimitsService
+	receiptService ReceiptService
+	txManager      repository.TxManager
+	policy         VoucherPolicy
+}
+
+func NewVoucherUsecase(voucherRepo repository.VoucherRepository,
+	walletRepo repository.WalletRepository,
+	packageRepo repository.PackageRepository,
+	attemptRepo repository.AttemptRepository,
+	shiftRepo repository.ShiftRepository,
+	ledgerService LedgerService,
+	limitsService LimitsService,
+	receiptService ReceiptService,
+	txManager repository.TxManager,
+	policy VoucherPolicy) VoucherService {
+	return &VoucherUsecase{voucherRepo: voucherRepo,
+		walletRepo:     walletRepo,
+		packageRepo:    packageRepo,
+		attemptRepo:    attemptRepo,
+		shiftRepo:      shiftRepo,
+		ledgerService:  ledgerService,
+		limitsService:  limitsService,
+		receiptService: receiptService,
+		txManager:      txManager,
+		policy:         policy}
+}
+
+// CreateBatch выпускает партию карт со случайными кодами в статусе issued
+func (u *VoucherUsecase) CreateBatch(ctx context.Context, adminID int64, batch *models.VoucherBatch, validityDays int) (*models.VoucherBatchDetails, error) {
+	if batch.Count <= 0 || batch.Count > u.policy.MaxBatchSize {
+		return nil, errors.ErrInvalidVoucherBatch
+	}
+	if err := batch.Amount.CheckCurrency(); err != nil {
+		return nil, err
+	}
+	if err := batch.Price.CheckCurrency(); err != nil {
+		return nil, err
+	}
+	switch batch.Type {
+	case models.VoucherValue:
+		if !batch.Amount.IsPositive() {
+			return nil, errors.ErrInvalidVoucherBatch
+		}
+		batch.Minutes = 0
+		batch.Price = batch.Amount
+	case models.VoucherMinutes:
+		if batch.Minutes <= 0 || !batch.Price.IsPositive() {
+			return nil, errors.ErrInvalidVoucherBatch
+		}
+		batch.Amount = money.Zero()
+	default:
+		return nil, errors.ErrInvalidVoucherBatch
+	}
+	if validityDays <= 0 {
+		validityDays = u.policy.DefaultValidityDays
+	}
+
+	now := time.Now()
+	batch.CreatedBy = adminID
+	batch.CreatedAt = now
+	batch.ExpiresAt = now.AddDate(0, 0, validityDays)
+
+	vouchers := make([]models.Voucher, 0, batch.Count)
+	seen := make(map[string]bool, batch.Count)
+	for len(vouchers) < batch.Count {
+		code, err := newVoucherCode()
+		if err != nil {
+			return nil, err
+		}
+		if seen[code] {
+			continue
+		}
+		seen[code] = true
+		vouchers = append(vouchers, models.Voucher{
+			Code:      code,
+			Type:      batch.Type,
+			Amount:    batch.Amount,
+			Minutes:   batch.Minutes,
+			Price:     batch.Price,
+			Status:    models.VoucherIssued,
+			ExpiresAt: batch.ExpiresAt,
+			CreatedAt: now,
+		})
+	}
+
+	err := u.txManager.WithTransaction(ctx, func(tx *gorm.DB) error {
+		return u.voucherRepo.CreateBatch(tx, batch, vouchers)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &models.VoucherBatchDetails{Batch: batch, Vouchers: vouchers}, nil
+}
+
+func (u *VoucherUsecase) GetBatch(ctx context.Context, batchID int64) (*models.VoucherBatchDetails, error) {
+	batch, err := u.voucherRepo.GetBatch(ctx, batchID)
+	if err != nil {
+		return nil, err
+	}
+	vouchers, err := u.voucherRepo.GetBatchVouchers(ctx, batchID)
+	if err != nil {
+		return nil, err
+	}
+	return &models.VoucherBatchDetails{Batch: batch, Vouchers: vouchers}, nil
+}
+
+// SellVoucher отмечает продажу карты на кассе. Продажа возможна только в открытую смену
+// и записывается транзакцией voucher_sale со способом оплаты, чтобы попасть в кассовый отчет.
+// Деньги за карту учитываются как обязательство клуба до погашения
+func (u *VoucherUsecase) SellVoucher(ctx context.Context, adminID int64, code string, method models.PaymentMethod) (*models.Voucher, error) {
+	if !method.Valid() {
+		return nil, errors.ErrInvalidPaymentMethod
+	}
+	code = NormalizeVoucherCode(code)
+	var voucher *models.Voucher
+	err := u.txManager.WithTransaction(ctx, func(tx *gorm.DB) error {
+		shift, err := u.shiftRepo.LockOpenShift(tx)
+		if err != nil {
+			return err
+		}
+		voucher, err = u.voucherRepo.GetVoucherForUpdate(tx, code)
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+		// Карты на минуты, выпущенные без цены, продать нельзя: продажу не с чем провести
+		if voucher.Status != models.VoucherIssued || !voucher.Price.IsPositive() {
+			return errors.ErrVoucherNotSellable
+		}
+		if !voucher.ExpiresAt.After(now) {
+			return errors.ErrVoucherExpired
+		}
+
+		sale := &models.Transaction{
+			Amount:        voucher.Price,
+			TariffID:      -1,
+			AdminID:       &adminID,
+			ShiftID:       &shift.ID,
+			PaymentMethod: method,
+			Type:          models.VoucherSale,
+		}
+		if err := u.walletRepo.CreateTransaction(tx, sale); err != nil {
+			return err
+		}
+
+		voucher.Status = models.VoucherSold
+		voucher.SoldBy = &adminID
+		voucher.SoldAt = &now
+		voucher.SaleID = &sale.ID
+		if err := u.voucherRepo.UpdateVoucher(tx, voucher.ID, map[string]interface{}{
+			"status":  voucher.Status,
+			"sold_by": adminID,
+			"sold_at": now,
+			"sale_id": sale.ID,
+		}); err != nil {
+			return err
+		}
+		if err := u.ledgerService.Move(tx, models.PaymentAccount(method), models.SystemAccount(models.AccountVouchers),
+			voucher.Price, "Продажа подарочной карты "+voucher.Code, &sale.ID); err != nil {
+			return err
+		}
+		_, err = u.receiptService.Issue(tx, sale)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return voucher, nil
+}
+
+// RedeemVoucher погашает проданную карту: сумма зачисляется на кошелек, минуты - пакетом.
+// Неудачные попытки считаются по пользователю и по IP, после MaxAttempts попыток погашение
+// блокируется до конца окна AttemptWindow
+func (u *VoucherUsecase) RedeemVoucher(ctx context.Context, userID int64, ip, code string) (*models.VoucherRedemption, error) {
+	keys := []string{fmt.Sprintf("voucher:user:%d", userID)}
+	if ip != "" {
+		keys = append(keys, "voucher:ip:"+ip)
+	}
+	for _, key := range keys {
+		count, err := u.attemptRepo.Count(ctx, key)
+		if err != nil {
+			return nil, err
+		}
+		if count >= u.policy.MaxAttempts {
+			return nil, errors.ErrTooManyAttempts
+		}
+	}
+
+	if err := u.limitsService.CheckPurchase(ctx, userID, money.Zero(), time.Now()); err != nil {
+		return nil, err
+	}
+
+	redemption, err := u.redeem(ctx, userID, NormalizeVoucherCode(code))
+	switch err {
+	case errors.ErrVoucherInvalid, errors.ErrVoucherRedeemed, errors.ErrVoucherExpired:
+		for _, key := range keys {
+			if _, err := u.attemptRepo.Add(ctx, key, u.policy.AttemptWindow); err != nil {
+				log.Printf("Не удалось учесть попытку погашения %s: %v", key, err)
+			}
+		}
+	}
+	return redemption, err
+}
+
+func (u *VoucherUsecase) redeem(ctx context.Context, userID int64, code string) (*models.VoucherRedemption, error) {
+	redemption := &models.VoucherRedemption{}
+	err := u.txManager.WithTransaction(ctx, func(tx *gorm.DB) error {
+		voucher, err := u.voucherRepo.GetVoucherForUpdate(tx, code)
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+		switch {
+		case voucher.Status == models.VoucherRedeemed:
+			return errors.ErrVoucherRedeemed
+		case voucher.Status == models.VoucherExpired || !voucher.ExpiresAt.After(now):
+			return errors.ErrVoucherExpired
+		case voucher.Status != models.VoucherSold:
+			// Непроданная карта выглядит для клиента как неверный код
+			return errors.ErrVoucherInvalid
+		}
+
+		fields := map[string]interface{}{
+			"status":      models.VoucherRedeemed,
+			"redeemed_by": userID,
+			"redeemed_at": now,
+		}
+		if voucher.Type == models.VoucherValue {
+			// Карты, выпущенные до проверки валюты, могли быть не в рублях
+			if err := voucher.Amount.CheckCurrency(); err != nil {
+				return err
+			}
+			if err := u.walletRepo.Deposit(tx, userID, voucher.Amount); err != nil {
+				return err
+			}
+			transaction := &models.Transaction{
+				UserID:   userID,
+				Amount:   voucher.Amount,
+				TariffID: -1,
+				Type:     models.VoucherRedeem,
+			}
+			if err := u.walletRepo.CreateTransaction(tx, transaction); err != nil {
+				return err
+			}
+			if err := u.ledgerService.Move(tx, models.SystemAccount(models.AccountVouchers), models.CustomerAccount(userID),
+				voucher.Amount, "Погашение подарочной карты", &transaction.ID); err != nil {
+				return err
+			}
+			fields["transaction_id"] = transaction.ID
+			voucher.TransactionID = &transaction.ID
+			redemption.Transaction = transaction
+		} else {
+			userPackage := &models.UserPackage{
+				UserID:       userID,
+				Name:         "Подарочная карта",
+				Type:         models.PackageMinutes,
+				MinutesTotal: voucher.Minutes,
+				MinutesLeft:  voucher.Minutes,
+				Status:       models.PackageActive,
+				ExpiresAt:    now.AddDate(0, 0, u.policy.PackageValidityDays),
+			}
+			if err := u.packageRepo.CreateUserPackage(tx, userPackage); err != nil {
+				return err
+			}
+			// Карта на минуты погашается сразу в выручку, как покупка пакета
+			if voucher.Price.IsPositive() {
+				if err := u.ledgerService.Move(tx, models.SystemAccount(models.AccountVouchers), models.SystemAccount(models.AccountRevenue),
+					voucher.Price, "Погашение подарочной карты на минуты", voucher.SaleID); err != nil {
+					return err
+				}
+			}
+			fields["user_package_id"] = userPackage.ID
+			voucher.UserPackageID = &userPackage.ID
+			redemption.UserPackage = userPackage
+		}
+
+		voucher.Status = models.VoucherRedeemed
+		voucher.RedeemedBy = &userID
+		voucher.RedeemedAt = &now
+		redemption.Voucher = voucher
+		return u.voucherRepo.UpdateVoucher(tx, voucher.ID, fields)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return redemption, nil
+}
+
+// MonitorVouchers раз в час переводит просроченные карты в статус expired
+func (u *VoucherUsecase) MonitorVouchers(ctx context.Context) {
+	ticker := time.NewTicker(1 * time.Hour)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Println("Остановка мониторинга подарочных карт")
+			return
+		case <-ticker.C:
+			expired, err := u.voucherRepo.ExpireVouchers(ctx, time.Now())
+			if err != nil {
+				log.Printf("Не удалось отметить просроченные подарочные карты: %v", err)
+				continue
+			}
+			if expired > 0 {
+				log.Printf("Просрочено подарочных карт: %d", expired)
+			}
+		}
+	}
+}
+
+// NormalizeVoucherCode приводит введенный код к виду XXXX-XXXX-XXXX: регистр, пробелы и дефисы не важны
+func NormalizeVoucherCode(code string) string {
+	code = strings.ToUpper(strings.NewReplacer("-", "", " ", "").Replace(code))
+	if len(code) != voucherCodeLength {
+		return code
+	}
+	return code[:4] + "-" + code[4:8] + "-" + code[8:]
+}
+
+func newVoucherCode() (string, error) {
+	buf := make([]byte, voucherCodeLength)
+	if _, err := rand.Read(buf); err != nil {
+		return "", errors.ErrCreateVoucher
+	}
+	// 256 делится на 32 без остатка, поэтому символы распределены равномерно
+	for i := range buf {
+		buf[i] = voucherAlphabet[int(buf[i])%len(voucherAlphabet)]
+	}
+	return NormalizeVoucherCode(string(buf)), nil
+}
@@ -4,30 +4,90 @@ import (
 	"computer-club/internal/repository"
 	models2 "computer-club/internal/repository/models"
 	"computer-club/pkg/errors"
+	"computer-club/pkg/money"
 	"context"
+	"fmt"
+	"gorm.io/gorm"
+	"log"
+	"strings"
+	"time"
+)
+
+// Размер страницы истории транзакций по умолчанию и максимальный
+const (
+	DefaultTransactionsLimit = 50
+	MaxTransactionsLimit     = 200
+)
+
+// Порядок списания при оплате: сначала бонусы или сначала реальные деньги
+const (
+	SpendBonusFirst = "bonus_first"
+	SpendRealFirst  = "real_first"
 )
 
 type WalletService interface {
-	Deposit(ctx context.Context, userID int64, amount float64) error
-	Withdraw(ctx context.Context, userID int64, amount float64) error
-	GetBalance(ctx context.Context, userID int64) (float64, error)
+	Deposit(ctx context.Context, userID, adminID int64, amount money.Money, method models2.PaymentMethod) (*models2.Transaction, error)
+	Withdraw(ctx context.Context, userID int64, amount money.Money) error
+	GetBalance(ctx context.Context, userID int64) (money.Money, error)
+	GetWallet(ctx context.Context, userID int64) (*models2.Wallet, error)
+	Charge(tx *gorm.DB, transaction *models2.Transaction, description string) error
+	GrantBonus(ctx context.Context, userID int64, amount money.Money, source models2.BonusSource, comment string) (*models2.BonusGrant, error)
+	GrantBonusTx(tx *gorm.DB, userID int64, amount money.Money, source models2.BonusSource, comment string, transactionID *int64) (*models2.BonusGrant, error)
+	GetBonusGrants(ctx context.Context, userID int64) ([]models2.BonusGrant, error)
+	MonitorBonuses(ctx context.Context)
 	GetTransactions(ctx context.Context, userID int64) ([]models2.Transaction, error)
-	CreateTransaction(ctx context.Context, userID int64, amount float64, typ string, tariffID int64) (*models2.Transaction, error)
+	ListTransactions(ctx context.Context, filter models2.TransactionFilter) (*models2.TransactionPage, error)
+	ExportTransactions(ctx context.Context, filter models2.TransactionFilter, fn func(*models2.Transaction) error) error
+	ReverseTransaction(ctx context.Context, transactionID, adminID int64, reason string) (*models2.Transaction, error)
+	CreateTransaction(ctx context.Context, userID int64, amount money.Money, typ string, tariffID int64) (*models2.Transaction, error)
 	CreateWallet(ctx context.Context, userID int64) error
 }
 
 type WalletUsecase struct {
-	walletRepo repository.WalletRepository
-	tariffRepo repository.TariffRepository
-	userRepo   repository.UserRepository
+	walletRepo     repository.WalletRepository
+	tariffRepo     repository.TariffRepository
+	userRepo       repository.UserRepository
+	txManager      repository.TxManager
+	ledgerService  LedgerService
+	bonusRepo      repository.BonusRepository
+	shiftRepo      repository.ShiftRepository
+	sessionRepo    repository.SessionRepository
+	packageRepo    repository.PackageRepository
+	promotionRepo  repository.PromotionRepository
+	loyaltyRepo    repository.LoyaltyRepository
+	receiptService ReceiptService
+	spendPriority  string
+	bonusTTL       time.Duration
 }
 
 func NewWalletUsecase(walletRepo repository.WalletRepository,
 	tariffRepo repository.TariffRepository,
-	userRepo repository.UserRepository) WalletService {
+	userRepo repository.UserRepository,
+	txManager repository.TxManager,
+	ledgerService LedgerService,
+	bonusRepo repository.BonusRepository,
+	shiftRepo repository.ShiftRepository,
+	sessionRepo repository.SessionRepository,
+	packageRepo repository.PackageRepository,
+	promotionRepo repository.PromotionRepository,
+	loyaltyRepo repository.LoyaltyRepository,
+	receiptService ReceiptService,
+	spendPriority string,
+	bonusTTL time.Duration) WalletService {
 	return &WalletUsecase{walletRepo: walletRepo,
-		tariffRepo: tariffRepo,
-		userRepo:   userRepo}
+		tariffRepo:     tariffRepo,
+		userRepo:       userRepo,
+		txManager:      txManager,
+		ledgerService:  ledgerService,
+		bonusRepo:      bonusRepo,
+		shiftRepo:      shiftRepo,
+		sessionRepo:    sessionRepo,
+		packageRepo:    packageRepo,
+		promotionRepo:  promotionRepo,
+		loyaltyRepo:    loyaltyRepo,
+		receiptService: receiptService,
+		spendPriority:  spendPriority,
+		bonusTTL:       bonusTTL}
 }
 
 func (u *WalletUsecase) CreateWallet(ctx context.Context, userID int64) error {
@@ -38,56 +98,557 @@ func (u *WalletUsecase) CreateWallet(ctx context.Context, userID int64) error {
 
 	wallet := &models2.Wallet{
 		UserID:  userID,
-		Balance: 0.0,
+		Balance: money.Zero(),
+		Bonus:   money.Zero(),
 	}
 
 	return u.walletRepo.CreateWallet(ctx, wallet)
 }
 
-func (u *WalletUsecase) Deposit(ctx context.Context, userID int64, amount float64) error {
-	if amount <= 0 {
-		return errors.ErrInvalidAmount
+// Deposit пополняет кошелек и записывает транзакцию "add" в одной транзакции БД,
+// чтобы пополнение не могло пройти без записи о нем. Пополнение привязывается к открытой смене,
+// а наличными без открытой смены пополнить нельзя. На пополнение выписывается чек
+func (u *WalletUsecase) Deposit(ctx context.Context, userID, adminID int64, amount money.Money, method models2.PaymentMethod) (*models2.Transaction, error) {
+	if !amount.IsPositive() {
+		return nil, errors.ErrInvalidAmount
+	}
+	if !method.Valid() {
+		return nil, errors.ErrInvalidPaymentMethod
 	}
 
 	if _, err := u.userRepo.GetUserByID(ctx, userID); err != nil {
-		return errors.ErrUserNotFound
+		return nil, errors.ErrUserNotFound
 	}
-	if _, err := u.walletRepo.GetBalance(ctx, userID); err != nil {
-		return errors.ErrCheckBalance
+	balance, err := u.walletRepo.GetBalance(ctx, userID)
+	if err != nil {
+		return nil, errors.ErrCheckBalance
+	}
+	if balance.Currency != amount.Currency {
+		return nil, errors.ErrCurrencyMismatch
+	}
+
+	transaction := &models2.Transaction{
+		UserID:        userID,
+		Amount:        amount,
+		Type:          models2.Add,
+		TariffID:      -1,
+		AdminID:       &adminID,
+		PaymentMethod: method,
 	}
+	err = u.txManager.WithTransaction(ctx, func(tx *gorm.DB) error {
+		shift, err := u.shiftRepo.LockOpenShift(tx)
+		switch {
+		case err == nil:
+			transaction.ShiftID = &shift.ID
+		case err != errors.ErrNoOpenShift || method == models2.PaymentCash:
+			return err
+		}
 
-	return u.walletRepo.Deposit(ctx, userID, amount)
+		if err := u.walletRepo.Deposit(tx, userID, amount); err != nil {
+			return err
+		}
+		if err := u.walletRepo.CreateTransaction(tx, transaction); err != nil {
+			return err
+		}
+		if err := u.ledgerService.Move(tx, models2.PaymentAccount(method), models2.CustomerAccount(userID),
+			amount, "Пополнение кошелька", &transaction.ID); err != nil {
+			return err
+		}
+		_, err = u.receiptService.Issue(tx, transaction)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return transaction, nil
 }
 
-func (u *WalletUsecase) Withdraw(ctx context.Context, userID int64, amount float64) error {
-	if amount <= 0 {
+func (u *WalletUsecase) Withdraw(ctx context.Context, userID int64, amount money.Money) error {
+	if !amount.IsPositive() {
 		return errors.ErrInvalidAmount
 	}
 	balance, err := u.walletRepo.GetBalance(ctx, userID)
 	if err != nil {
 		return err
 	}
-	if balance < amount {
+	if balance.LessThan(amount) {
 		return errors.ErrInsufficientFunds
 	}
-	return u.walletRepo.Withdraw(nil, userID, amount)
+	return u.txManager.WithTransaction(ctx, func(tx *gorm.DB) error {
+		if err := u.walletRepo.Withdraw(tx, userID, amount); err != nil {
+			return err
+		}
+		return u.ledgerService.Move(tx, models2.CustomerAccount(userID), models2.SystemAccount(models2.AccountRevenue),
+			amount, "Списание с кошелька", nil)
+	})
 }
 
-func (u *WalletUsecase) GetBalance(ctx context.Context, userID int64) (float64, error) {
+func (u *WalletUsecase) GetBalance(ctx context.Context, userID int64) (money.Money, error) {
 	return u.walletRepo.GetBalance(ctx, userID)
 }
 
+func (u *WalletUsecase) GetWallet(ctx context.Context, userID int64) (*models2.Wallet, error) {
+	return u.walletRepo.GetWallet(ctx, userID)
+}
+
+// Charge оплачивает покупку с кошелька внутри транзакции вызывающего: делит сумму между реальным
+// и бонусным балансом по настроенному порядку, списывает их, записывает транзакцию и проводки.
+// Сумма берется из transaction.Amount, поля Balance и BonusAmount заполняются здесь. На покупку выписывается чек
+func (u *WalletUsecase) Charge(tx *gorm.DB, transaction *models2.Transaction, description string) error {
+	amount := transaction.Amount
+	if !amount.IsPositive() {
+		transaction.Balance = models2.BalanceReal
+		return u.walletRepo.CreateTransaction(tx, transaction)
+	}
+
+	wallet, err := u.walletRepo.GetWalletForUpdate(tx, transaction.UserID)
+	if err != nil {
+		return err
+	}
+	if !amount.SameCurrency(wallet.Balance) {
+		return errors.ErrCurrencyMismatch
+	}
+	if wallet.Spendable().LessThan(amount) {
+		return errors.ErrInsufficientFunds
+	}
+
+	var bonus money.Money
+	if u.spendPriority == SpendRealFirst {
+		bonus = amount.Sub(wallet.Balance.Min(amount))
+	} else {
+		bonus = wallet.Bonus.Min(amount)
+	}
+	real := amount.Sub(bonus)
+
+	transaction.BonusAmount = bonus
+	switch {
+	case !bonus.IsPositive():
+		transaction.Balance = models2.BalanceReal
+	case !real.IsPositive():
+		transaction.Balance = models2.BalanceBonus
+	default:
+		transaction.Balance = models2.BalanceMixed
+	}
+
+	if real.IsPositive() {
+		if err := u.walletRepo.Withdraw(tx, transaction.UserID, real); err != nil {
+			return err
+		}
+	}
+	if bonus.IsPositive() {
+		if err := u.walletRepo.WithdrawBonus(tx, transaction.UserID, bonus); err != nil {
+			return err
+		}
+		if err := u.bonusRepo.ConsumeGrants(tx, transaction.UserID, bonus); err != nil {
+			return err
+		}
+	}
+	if err := u.walletRepo.CreateTransaction(tx, transaction); err != nil {
+		return err
+	}
+
+	revenue := models2.SystemAccount(models2.AccountRevenue)
+	if real.IsPositive() {
+		if err := u.ledgerService.Move(tx, models2.CustomerAccount(transaction.UserID), revenue,
+			real, description, &transaction.ID); err != nil {
+			return err
+		}
+	}
+	if bonus.IsPositive() {
+		if err := u.ledgerService.Move(tx, models2.CustomerBonusAccount(transaction.UserID), revenue,
+			bonus, description+" бонусами", &transaction.ID); err != nil {
+			return err
+		}
+	}
+	_, err = u.receiptService.Issue(tx, transaction)
+	return err
+}
+
+// GrantBonus начисляет бонусы со сроком действия bonusTTL в отдельной транзакции
+func (u *WalletUsecase) GrantBonus(ctx context.Context, userID int64, amount money.Money, source models2.BonusSource, comment string) (*models2.BonusGrant, error) {
+	var grant *models2.BonusGrant
+	err := u.txManager.WithTransaction(ctx, func(tx *gorm.DB) error {
+		var err error
+		grant, err = u.GrantBonusTx(tx, userID, amount, source, comment, nil)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return grant, nil
+}
+
+// GrantBonusTx начисляет бонусы внутри транзакции tx. Бонусы за возврат списываются со счета возвратов,
+// остальные - со счета бонусной программы. transactionID связывает начисление с оплатой, за которую оно дано
+func (u *WalletUsecase) GrantBonusTx(tx *gorm.DB, userID int64, amount money.Money, source models2.BonusSource, comment string, transactionID *int64) (*models2.BonusGrant, error) {
+	if !amount.IsPositive() {
+		return nil, errors.ErrInvalidAmount
+	}
+	sourceAccount := models2.SystemAccount(models2.AccountBonus)
+	switch source {
+	case models2.BonusFromPromotion, models2.BonusManual:
+	case models2.BonusFromRefund:
+		sourceAccount = models2.SystemAccount(models2.AccountRefunds)
+	default:
+		return nil, errors.ErrInvalidBonusSource
+	}
+
+	now := time.Now()
+	grant := &models2.BonusGrant{
+		UserID:        userID,
+		Source:        source,
+		Comment:       comment,
+		Amount:        amount,
+		Remaining:     amount,
+		Status:        models2.BonusActive,
+		TransactionID: transactionID,
+		ExpiresAt:     now.Add(u.bonusTTL),
+		CreatedAt:     now,
+	}
+	transaction := &models2.Transaction{
+		UserID:      userID,
+		Amount:      amount,
+		Balance:     models2.BalanceBonus,
+		BonusAmount: amount,
+		TariffID:    -1,
+		Type:        models2.BonusCredit,
+	}
+
+	if err := u.walletRepo.DepositBonus(tx, userID, amount); err != nil {
+		return nil, err
+	}
+	if err := u.bonusRepo.CreateGrant(tx, grant); err != nil {
+		return nil, err
+	}
+	if err := u.walletRepo.CreateTransaction(tx, transaction); err != nil {
+		return nil, err
+	}
+	if err := u.ledgerService.Move(tx, sourceAccount, models2.CustomerBonusAccount(userID),
+		amount, "Начисление бонусов", &transaction.ID); err != nil {
+		return nil, err
+	}
+	return grant, nil
+}
+
+func (u *WalletUsecase) GetBonusGrants(ctx context.Context, userID int64) ([]models2.BonusGrant, error) {
+	return u.bonusRepo.GetActiveGrants(ctx, userID)
+}
+
+// MonitorBonuses раз в минуту списывает остатки истекших бонусных начислений
+func (u *WalletUsecase) MonitorBonuses(ctx context.Context) {
+	ticker := time.NewTicker(1 * time.Minute)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Println("Остановка мониторинга бонусов")
+			return
+		case <-ticker.C:
+			u.expireBonuses(ctx)
+		}
+	}
+}
+
+func (u *WalletUsecase) expireBonuses(ctx context.Context) {
+	grants, err := u.bonusRepo.GetExpiredGrants(ctx, time.Now())
+	if err != nil {
+		log.Printf("Не удалось получить истекшие бонусы: %v", err)
+		return
+	}
+
+	for _, grant := range grants {
+		err := u.txManager.WithTransaction(ctx, func(tx *gorm.DB) error {
+			expired, err := u.bonusRepo.ExpireGrant(tx, grant.ID)
+			if err != nil || expired == nil || !expired.Remaining.IsPositive() {
+				return err
+			}
+			if err := u.walletRepo.WithdrawBonus(tx, expired.UserID, expired.Remaining); err != nil {
+				return err
+			}
+			transaction := &models2.Transaction{
+				UserID:      expired.UserID,
+				Amount:      expired.Remaining,
+				Balance:     models2.BalanceBonus,
+				BonusAmount: expired.Remaining,
+				TariffID:    -1,
+				Type:        models2.BonusExpire,
+			}
+			if err := u.walletRepo.CreateTransaction(tx, transaction); err != nil {
+				return err
+			}
+			return u.ledgerService.Move(tx, models2.CustomerBonusAccount(expired.UserID), models2.SystemAccount(models2.AccountBonus),
+				expired.Remaining, "Сгорание бонусов", &transaction.ID)
+		})
+		if err != nil {
+			log.Printf("Не удалось списать истекшие бонусы %d: %v", grant.ID, err)
+		}
+	}
+}
+
 func (u *WalletUsecase) GetTransactions(ctx context.Context, userID int64) ([]models2.Transaction, error) {
 	return u.walletRepo.GetTransactions(ctx, userID)
 }
 
-func (u *WalletUsecase) CreateTransaction(ctx context.Context, userID int64, amount float64, typ string, tariffID int64) (*models2.Transaction, error) {
-	if tariffID != -1 {
-		tariff, err := u.tariffRepo.GetTariffByID(ctx, tariffID)
+// ReverseTransaction сторнирует ошибочное пополнение кассой или оплату сессии: создает компенсирующую
+// транзакцию, возвращает деньги на кошелек или списывает их и делает обратные проводки. Сторно оплаты
+// сессии отменяет и саму покупку: сессия завершается, минуты пакетов и промокод возвращаются,
+// начисленные баллы списываются.
+// Онлайн-платежи возвращаются через платежного провайдера, переводы - своей отменой,
+// пакеты после покупки уже начислены, поэтому такие транзакции здесь не сторнируются
+func (u *WalletUsecase) ReverseTransaction(ctx context.Context, transactionID, adminID int64, reason string) (*models2.Transaction, error) {
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return nil, errors.ErrReversalReasonRequired
+	}
+
+	var reversal *models2.Transaction
+	err := u.txManager.WithTransaction(ctx, func(tx *gorm.DB) error {
+		original, err := u.walletRepo.GetTransactionForUpdate(tx, transactionID)
 		if err != nil {
+			return err
+		}
+		if original.Reversed() {
+			return errors.ErrTransactionAlreadyReversed
+		}
+		if original.OrganizationID != nil {
+			return errors.ErrTransactionNotReversible
+		}
+		switch {
+		case original.Type == models2.Add && original.PaymentMethod != models2.PaymentOnline:
+		case original.Type == models2.Buy && original.Amount.IsPositive():
+		default:
+			return errors.ErrTransactionNotReversible
+		}
+
+		reversal = &models2.Transaction{
+			UserID:        original.UserID,
+			Amount:        original.Amount,
+			Balance:       original.Balance,
+			BonusAmount:   original.BonusAmount,
+			TariffID:      original.TariffID,
+			AdminID:       &adminID,
+			PaymentMethod: original.PaymentMethod,
+			ReversalOf:    &original.ID,
+			Reason:        reason,
+			Type:          models2.Reversal,
+		}
+		if original.Type == models2.Add {
+			return u.reverseDeposit(tx, original, reversal)
+		}
+		return u.reverseCharge(tx, original, reversal)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return reversal, nil
+}
+
+// reverseDeposit списывает ошибочное пополнение. Сторно наличных, как и пополнение, требует открытой смены
+func (u *WalletUsecase) reverseDeposit(tx *gorm.DB, original, reversal *models2.Transaction) error {
+	shift, err := u.shiftRepo.LockOpenShift(tx)
+	switch {
+	case err == nil:
+		reversal.ShiftID = &shift.ID
+	case err != errors.ErrNoOpenShift || original.PaymentMethod == models2.PaymentCash:
+		return err
+	}
+
+	if err := u.walletRepo.Withdraw(tx, original.UserID, original.Amount); err != nil {
+		return err
+	}
+	if err := u.recordReversal(tx, original, reversal); err != nil {
+		return err
+	}
+	return u.ledgerService.Move(tx, models2.CustomerAccount(original.UserID), models2.PaymentAccount(original.PaymentMethod),
+		original.Amount, "Сторно пополнения кошелька", &reversal.ID)
+}
+
+// reverseCharge возвращает оплату: реальные деньги на баланс, бонусы - новым начислением со свежим сроком
+func (u *WalletUsecase) reverseCharge(tx *gorm.DB, original, reversal *models2.Transaction) error {
+	bonus := original.BonusAmount
+	real := original.Amount.Sub(bonus)
+
+	if real.IsPositive() {
+		if err := u.walletRepo.Deposit(tx, original.UserID, real); err != nil {
+			return err
+		}
+	}
+	if bonus.IsPositive() {
+		if err := u.walletRepo.DepositBonus(tx, original.UserID, bonus); err != nil {
+			return err
+		}
+		now := time.Now()
+		if err := u.bonusRepo.CreateGrant(tx, &models2.BonusGrant{
+			UserID:    original.UserID,
+			Source:    models2.BonusFromRefund,
+			Comment:   fmt.Sprintf("Сторно транзакции %d", original.ID),
+			Amount:    bonus,
+			Remaining: bonus,
+			Status:    models2.BonusActive,
+			ExpiresAt: now.Add(u.bonusTTL),
+			CreatedAt: now,
+		}); err != nil {
+			return err
+		}
+	}
+	if err := u.recordReversal(tx, original, reversal); err != nil {
+		return err
+	}
+	if err := u.cancelPurchase(tx, original, reversal); err != nil {
+		return err
+	}
+
+	revenue := models2.SystemAccount(models2.AccountRevenue)
+	if real.IsPositive() {
+		if err := u.ledgerService.Move(tx, revenue, models2.CustomerAccount(original.UserID),
+			real, "Сторно оплаты", &reversal.ID); err != nil {
+			return err
+		}
+	}
+	if bonus.IsPositive() {
+		if err := u.ledgerService.Move(tx, revenue, models2.CustomerBonusAccount(original.UserID),
+			bonus, "Сторно оплаты бонусами", &reversal.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// cancelPurchase отменяет то, что клиент получил за оплату: завершает еще идущую сессию, возвращает
+// минуты пакетов и использование промокода, отзывает бонусы по промокоду и списывает начисленные баллы.
+// Баллы списываются, даже если клиент их уже потратил: счет уходит в минус и гасится следующими
+// начислениями. У бонусов списывается только неизрасходованный остаток
+func (u *WalletUsecase) cancelPurchase(tx *gorm.DB, original, reversal *models2.Transaction) error {
+	if err := u.sessionRepo.EndSessionByTransaction(tx, original.ID); err != nil {
+		return err
+	}
+	if err := u.packageRepo.RestoreMinutes(tx, original.ID); err != nil {
+		return err
+	}
+	if err := u.promotionRepo.ReleasePromotion(tx, original.ID); err != nil {
+		return err
+	}
+	if err := u.revokePromoBonus(tx, original, reversal); err != nil {
+		return err
+	}
+
+	points, err := u.loyaltyRepo.SumTransactionPoints(tx, original.ID)
+	if err != nil {
+		return err
+	}
+	if points <= 0 {
+		return nil
+	}
+	if err := u.loyaltyRepo.AddPoints(tx, original.UserID, -points); err != nil {
+		return err
+	}
+	return u.loyaltyRepo.CreateEvent(tx, &models2.LoyaltyEvent{
+		UserID:        original.UserID,
+		Type:          models2.LoyaltyReversal,
+		Points:        -points,
+		TransactionID: &original.ID,
+	})
+}
+
+// revokePromoBonus отзывает бонусы, начисленные по промокоду за оплату original, и возвращает
+// их остаток на счет бонусной программы
+func (u *WalletUsecase) revokePromoBonus(tx *gorm.DB, original, reversal *models2.Transaction) error {
+	grants, err := u.bonusRepo.RevokeTransactionGrants(tx, original.ID)
+	if err != nil {
+		return err
+	}
+	for _, grant := range grants {
+		if !grant.Remaining.IsPositive() {
+			continue
+		}
+		if err := u.walletRepo.WithdrawBonus(tx, grant.UserID, grant.Remaining); err != nil {
+			return err
+		}
+		if err := u.ledgerService.Move(tx, models2.CustomerBonusAccount(grant.UserID), models2.SystemAccount(models2.AccountBonus),
+			grant.Remaining, "Отзыв бонусов по промокоду", &reversal.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// recordReversal сохраняет сторнирующую транзакцию, связывает с ней исходную и выписывает чек возврата
+func (u *WalletUsecase) recordReversal(tx *gorm.DB, original, reversal *models2.Transaction) error {
+	if err := u.walletRepo.CreateTransaction(tx, reversal); err != nil {
+		return err
+	}
+	marked, err := u.walletRepo.MarkReversed(tx, original.ID, reversal.ID)
+	if err != nil {
+		return err
+	}
+	if !marked {
+		return errors.ErrTransactionAlreadyReversed
+	}
+	_, err = u.receiptService.Issue(tx, reversal)
+	return err
+}
+
+// ListTransactions возвращает страницу истории транзакций. Запрашивается на одну запись больше,
+// чтобы понять, есть ли следующая страница
+func (u *WalletUsecase) ListTransactions(ctx context.Context, filter models2.TransactionFilter) (*models2.TransactionPage, error) {
+	if err := validateTransactionFilter(filter); err != nil {
+		return nil, err
+	}
+	switch {
+	case filter.Limit <= 0:
+		filter.Limit = DefaultTransactionsLimit
+	case filter.Limit > MaxTransactionsLimit:
+		filter.Limit = MaxTransactionsLimit
+	}
+	limit := filter.Limit
+	filter.Limit++
+
+	transactions, err := u.walletRepo.FindTransactions(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	page := &models2.TransactionPage{Transactions: transactions}
+	if len(transactions) > limit {
+		page.Transactions = transactions[:limit]
+		page.NextCursor = &page.Transactions[limit-1].ID
+	}
+	return page, nil
+}
+
+// ExportTransactions передает в fn все транзакции под фильтром, без постраничной разбивки
+func (u *WalletUsecase) ExportTransactions(ctx context.Context, filter models2.TransactionFilter, fn func(*models2.Transaction) error) error {
+	if err := validateTransactionFilter(filter); err != nil {
+		return err
+	}
+	return u.walletRepo.StreamTransactions(ctx, filter, fn)
+}
+
+func validateTransactionFilter(filter models2.TransactionFilter) error {
+	if filter.From != nil && filter.To != nil && !filter.From.Before(*filter.To) {
+		return errors.ErrInvalidTransactionFilter
+	}
+	if filter.MinAmount != nil && filter.MaxAmount != nil && filter.MaxAmount.LessThan(*filter.MinAmount) {
+		return errors.ErrInvalidTransactionFilter
+	}
+	return nil
+}
+
+func (u *WalletUsecase) CreateTransaction(ctx context.Context, userID int64, amount money.Money, typ string, tariffID int64) (*models2.Transaction, error) {
+	if tariffID != -1 {
+		if _, err := u.tariffRepo.GetTariffByID(ctx, tariffID); err != nil {
 			return nil, err
 		}
-		return u.walletRepo.CreateTransaction(nil, userID, amount, typ, tariff)
 	}
-	return u.walletRepo.CreateTransaction(nil, userID, amount, typ, nil)
+
+	transaction := &models2.Transaction{
+		UserID:   userID,
+		Amount:   amount,
+		Type:     models2.TransactionType(typ),
+		TariffID: tariffID,
+	}
+	if err := u.walletRepo.CreateTransaction(nil, transaction); err != nil {
+		return nil, err
+	}
+	return transaction, nil
 }
@@ -0,0 +1,420 @@
+package usecase
+
+import (
+	"computer-club/internal/repository"
+	"computer-club/internal/repository/models"
+	"computer-club/pkg/errors"
+	"computer-club/pkg/money"
+	"context"
+	"gorm.io/gorm"
+	"testing"
+	"time"
+)
+
+// fakeBonusRepo хранит бонусные начисления
+type fakeBonusRepo struct {
+	repository.BonusRepository
+	grants []*models.BonusGrant
+}
+
+func (r *fakeBonusRepo) CreateGrant(tx *gorm.DB, grant *models.BonusGrant) error {
+	grant.ID = int64(len(r.grants) + 1)
+	r.grants = append(r.grants, grant)
+	return nil
+}
+
+func (r *fakeBonusRepo) ConsumeGrants(tx *gorm.DB, userID int64, amount money.Money) error {
+	left := amount
+	for _, grant := range r.grants {
+		if grant.UserID != userID || grant.Status != models.BonusActive || !left.IsPositive() {
+			continue
+		}
+		take := grant.Remaining.Min(left)
+		grant.Remaining = grant.Remaining.Sub(take)
+		if grant.Remaining.IsZero() {
+			grant.Status = models.BonusSpent
+		}
+		left = left.Sub(take)
+	}
+	if left.IsPositive() {
+		return errors.ErrInsufficientFunds
+	}
+	return nil
+}
+
+func (r *fakeBonusRepo) RevokeTransactionGrants(tx *gorm.DB, transactionID int64) ([]models.BonusGrant, error) {
+	var revoked []models.BonusGrant
+	for _, grant := range r.grants {
+		if grant.TransactionID == nil || *grant.TransactionID != transactionID ||
+			(grant.Status != models.BonusActive && grant.Status != models.BonusSpent) {
+			continue
+		}
+		revoked = append(revoked, *grant)
+		grant.Remaining = money.Zero()
+		grant.Status = models.BonusRevoked
+	}
+	return revoked, nil
+}
+
+// fakeReceiptService запоминает транзакции, по которым выписаны чеки
+type fakeReceiptService struct {
+	ReceiptService
+	issued []int64
+}
+
+func (s *fakeReceiptService) Issue(tx *gorm.DB, transaction *models.Transaction) (*models.Receipt, error) {
+	s.issued = append(s.issued, transaction.ID)
+	return &models.Receipt{TransactionID: transaction.ID}, nil
+}
+
+// fakeShiftRepo отдает открытую смену, если она задана
+type fakeShiftRepo struct {
+	repository.ShiftRepository
+	open *models.Shift
+}
+
+func (r *fakeShiftRepo) LockOpenShift(tx *gorm.DB) (*models.Shift, error) {
+	if r.open == nil {
+		return nil, errors.ErrNoOpenShift
+	}
+	return r.open, nil
+}
+
+// fakePurchaseRepos запоминает, что отменялось при сторно оплаты: сессии, минуты пакетов,
+// использования промокодов и баллы лояльности
+type fakePurchaseRepos struct {
+	repository.SessionRepository
+	repository.PackageRepository
+	repository.PromotionRepository
+	repository.LoyaltyRepository
+	endedSessions    []int64
+	restoredMinutes  []int64
+	releasedPromos   []int64
+	awardedPoints    map[int64]int64
+	points           map[int64]int64
+	loyaltyReversals []models.LoyaltyEvent
+}
+
+func (r *fakePurchaseRepos) EndSessionByTransaction(tx *gorm.DB, transactionID int64) error {
+	r.endedSessions = append(r.endedSessions, transactionID)
+	return nil
+}
+
+func (r *fakePurchaseRepos) RestoreMinutes(tx *gorm.DB, transactionID int64) error {
+	r.restoredMinutes = append(r.restoredMinutes, transactionID)
+	return nil
+}
+
+func (r *fakePurchaseRepos) ReleasePromotion(tx *gorm.DB, transactionID int64) error {
+	r.releasedPromos = append(r.releasedPromos, transactionID)
+	return nil
+}
+
+func (r *fakePurchaseRepos) SumTransactionPoints(tx *gorm.DB, transactionID int64) (int64, error) {
+	return r.awardedPoints[transactionID], nil
+}
+
+func (r *fakePurchaseRepos) AddPoints(tx *gorm.DB, userID int64, points int64) error {
+	r.points[userID] += points
+	return nil
+}
+
+func (r *fakePurchaseRepos) CreateEvent(tx *gorm.DB, event *models.LoyaltyEvent) error {
+	r.loyaltyReversals = append(r.loyaltyReversals, *event)
+	return nil
+}
+
+type walletFixture struct {
+	usecase  *WalletUsecase
+	wallets  *fakeWalletRepo
+	bonuses  *fakeBonusRepo
+	ledger   *fakeLedgerRepo
+	receipts *fakeReceiptService
+	shifts   *fakeShiftRepo
+	purchase *fakePurchaseRepos
+}
+
+// newWalletFixture заводит игрока 1 с 500 ₽ на балансе и 200 ₽ бонусов одним начислением
+func newWalletFixture(spendPriority string) *walletFixture {
+	wallets := newFakeWalletRepo(models.Wallet{UserID: 1, Balance: rub(50000), Bonus: rub(20000)})
+	bonuses := &fakeBonusRepo{}
+	bonuses.CreateGrant(nil, &models.BonusGrant{UserID: 1, Amount: rub(20000), Remaining: rub(20000),
+		Status: models.BonusActive, ExpiresAt: time.Now().Add(time.Hour)})
+	ledger := newFakeLedgerRepo()
+	receipts := &fakeReceiptService{}
+	shifts := &fakeShiftRepo{}
+	purchase := &fakePurchaseRepos{awardedPoints: map[int64]int64{}, points: map[int64]int64{}}
+	txManager := &fakeTxManager{stores: []snapshotter{wallets, ledger}}
+	usecase := NewWalletUsecase(wallets, nil, nil, txManager, NewLedgerUsecase(ledger), bonuses, shifts,
+		purchase, purchase, purchase, purchase, receipts, spendPriority, 24*time.Hour).(*WalletUsecase)
+	return &walletFixture{usecase: usecase, wallets: wallets, bonuses: bonuses, ledger: ledger, receipts: receipts,
+		shifts: shifts, purchase: purchase}
+}
+
+func (f *walletFixture) wallet(t *testing.T, balance, bonus int64) {
+	t.Helper()
+	wallet := f.wallets.wallets[1]
+	if wallet.Balance.Amount != balance || wallet.Bonus.Amount != bonus {
+		t.Fatalf("кошелек = %d/%d бонусов, want %d/%d", wallet.Balance.Amount, wallet.Bonus.Amount, balance, bonus)
+	}
+}
+
+func TestChargeSplitsBetweenRealAndBonus(t *testing.T) {
+	tests := []struct {
+		name        string
+		priority    string
+		amount      int64
+		wantBonus   int64
+		wantBalance models.BalanceKind
+	}{
+		{name: "сначала бонусы", priority: SpendBonusFirst, amount: 30000, wantBonus: 20000, wantBalance: models.BalanceMixed},
+		{name: "только бонусы", priority: SpendBonusFirst, amount: 15000, wantBonus: 15000, wantBalance: models.BalanceBonus},
+		{name: "сначала деньги", priority: SpendRealFirst, amount: 30000, wantBonus: 0, wantBalance: models.BalanceReal},
+		{name: "деньги и остаток бонусами", priority: SpendRealFirst, amount: 60000, wantBonus: 10000, wantBalance: models.BalanceMixed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newWalletFixture(tt.priority)
+			transaction := &models.Transaction{UserID: 1, Amount: rub(tt.amount), Type: models.Buy}
+
+			if err := f.usecase.Charge(nil, transaction, "Оплата сессии"); err != nil {
+				t.Fatalf("Charge() error: %v", err)
+			}
+			real := tt.amount - tt.wantBonus
+			if transaction.BonusAmount.Amount != tt.wantBonus || transaction.Balance != tt.wantBalance {
+				t.Fatalf("транзакция = %d бонусами, %s, want %d, %s",
+					transaction.BonusAmount.Amount, transaction.Balance, tt.wantBonus, tt.wantBalance)
+			}
+			f.wallet(t, 50000-real, 20000-tt.wantBonus)
+			if got := f.bonuses.grants[0].Remaining.Amount; got != 20000-tt.wantBonus {
+				t.Errorf("остаток начисления = %d, want %d", got, 20000-tt.wantBonus)
+			}
+
+			// Выручка получает всю сумму, кошелек и бонусный счет - каждый свою часть
+			if got := f.ledger.balance(models.SystemAccount(models.AccountRevenue)); got != -tt.amount {
+				t.Errorf("выручка = %d, want %d", got, -tt.amount)
+			}
+			if got := f.ledger.balance(models.CustomerAccount(1)); got != real {
+				t.Errorf("списание с кошелька в журнале = %d, want %d", got, real)
+			}
+			if got := f.ledger.balance(models.CustomerBonusAccount(1)); got != tt.wantBonus {
+				t.Errorf("списание бонусов в журнале = %d, want %d", got, tt.wantBonus)
+			}
+			if len(f.receipts.issued) != 1 || f.receipts.issued[0] != transaction.ID {
+				t.Errorf("чеки = %v, want чек по транзакции %d", f.receipts.issued, transaction.ID)
+			}
+		})
+	}
+}
+
+func TestChargeRejectsWithoutSideEffects(t *testing.T) {
+	tests := []struct {
+		name   string
+		amount money.Money
+		want   error
+	}{
+		{name: "недостаточно средств", amount: rub(70001), want: errors.ErrInsufficientFunds},
+		{name: "другая валюта", amount: money.Money{Amount: 100, Currency: "USD"}, want: errors.ErrCurrencyMismatch},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newWalletFixture(SpendBonusFirst)
+			err := f.usecase.Charge(nil, &models.Transaction{UserID: 1, Amount: tt.amount, Type: models.Buy}, "Оплата сессии")
+			if err != tt.want {
+				t.Fatalf("Charge() error = %v, want %v", err, tt.want)
+			}
+			f.wallet(t, 50000, 20000)
+			if len(f.ledger.entries) != 0 || len(f.wallets.transactions) != 0 || len(f.receipts.issued) != 0 {
+				t.Fatal("отклоненная оплата оставила записи")
+			}
+		})
+	}
+}
+
+func TestChargeZeroAmountRecordsTransactionOnly(t *testing.T) {
+	f := newWalletFixture(SpendBonusFirst)
+	transaction := &models.Transaction{UserID: 1, Amount: rub(0), Type: models.Buy}
+
+	if err := f.usecase.Charge(nil, transaction, "Оплата пакетом"); err != nil {
+		t.Fatalf("Charge() error: %v", err)
+	}
+	if transaction.ID == 0 || transaction.Balance != models.BalanceReal {
+		t.Fatalf("транзакция = %+v, want сохраненную с balance real", transaction)
+	}
+	f.wallet(t, 50000, 20000)
+	if len(f.ledger.entries) != 0 {
+		t.Fatalf("нулевая оплата не должна давать проводок: %d", len(f.ledger.entries))
+	}
+}
+
+func TestGrantBonusTxLinksPurchase(t *testing.T) {
+	f := newWalletFixture(SpendBonusFirst)
+	purchaseID := int64(77)
+
+	grant, err := f.usecase.GrantBonusTx(nil, 1, rub(5000), models.BonusFromPromotion, "BONUS50", &purchaseID)
+	if err != nil {
+		t.Fatalf("GrantBonusTx() error: %v", err)
+	}
+	if grant.TransactionID == nil || *grant.TransactionID != purchaseID || grant.Status != models.BonusActive {
+		t.Fatalf("начисление = %+v, want активное и связанное с оплатой %d", grant, purchaseID)
+	}
+	f.wallet(t, 50000, 25000)
+	if got := f.ledger.balance(models.SystemAccount(models.AccountBonus)); got != 5000 {
+		t.Errorf("счет бонусной программы = %d, want 5000", got)
+	}
+	if got := f.ledger.balance(models.CustomerBonusAccount(1)); got != -5000 {
+		t.Errorf("бонусный счет клиента = %d, want -5000", got)
+	}
+
+	if _, err := f.usecase.GrantBonusTx(nil, 1, rub(100), "unknown", "", nil); err != errors.ErrInvalidBonusSource {
+		t.Fatalf("неизвестный источник: error = %v, want %v", err, errors.ErrInvalidBonusSource)
+	}
+}
+
+// buyWithPromoBonus оплачивает сессию за 300 ₽ (200 ₽ бонусами и 100 ₽ деньгами) с промокодом,
+// начислившим 50 ₽ бонусов, и 30 баллами лояльности
+func (f *walletFixture) buyWithPromoBonus(t *testing.T) *models.Transaction {
+	t.Helper()
+	purchase := &models.Transaction{UserID: 1, Amount: rub(30000), TariffID: 1, Type: models.Buy}
+	if err := f.usecase.Charge(nil, purchase, "Оплата сессии"); err != nil {
+		t.Fatalf("Charge() error: %v", err)
+	}
+	if _, err := f.usecase.GrantBonusTx(nil, 1, rub(5000), models.BonusFromPromotion, "BONUS50", &purchase.ID); err != nil {
+		t.Fatalf("GrantBonusTx() error: %v", err)
+	}
+	f.purchase.awardedPoints[purchase.ID] = 30
+	f.purchase.points[1] = 30
+	f.wallet(t, 40000, 5000)
+	return purchase
+}
+
+func TestReverseChargeCancelsPurchase(t *testing.T) {
+	f := newWalletFixture(SpendBonusFirst)
+	purchase := f.buyWithPromoBonus(t)
+
+	reversal, err := f.usecase.ReverseTransaction(context.Background(), purchase.ID, 99, "Компьютер не включился")
+	if err != nil {
+		t.Fatalf("ReverseTransaction() error: %v", err)
+	}
+	if reversal.Type != models.Reversal || *reversal.ReversalOf != purchase.ID || *reversal.AdminID != 99 {
+		t.Fatalf("сторно = %+v, want reversal оплаты %d", reversal, purchase.ID)
+	}
+	if got := f.wallets.transactions[purchase.ID].ReversedBy; got == nil || *got != reversal.ID {
+		t.Fatalf("reversed_by = %v, want %d", got, reversal.ID)
+	}
+
+	// Деньги вернулись, бонусы за оплату - новым начислением, бонусы по промокоду отозваны
+	f.wallet(t, 50000, 20000)
+	promo := f.bonuses.grants[1]
+	if promo.Status != models.BonusRevoked || !promo.Remaining.IsZero() {
+		t.Fatalf("начисление по промокоду = %+v, want revoked без остатка", promo)
+	}
+	refund := f.bonuses.grants[len(f.bonuses.grants)-1]
+	if refund.Source != models.BonusFromRefund || refund.Amount.Amount != 20000 {
+		t.Fatalf("возврат бонусов = %+v, want refund на 20000", refund)
+	}
+
+	// Покупка отменена целиком
+	for name, got := range map[string][]int64{
+		"сессия":   f.purchase.endedSessions,
+		"минуты":   f.purchase.restoredMinutes,
+		"промокод": f.purchase.releasedPromos,
+	} {
+		if len(got) != 1 || got[0] != purchase.ID {
+			t.Errorf("%s: отмена = %v, want по оплате %d", name, got, purchase.ID)
+		}
+	}
+	if f.purchase.points[1] != 0 || len(f.purchase.loyaltyReversals) != 1 || f.purchase.loyaltyReversals[0].Points != -30 {
+		t.Errorf("баллы = %d, события = %+v, want списание 30 баллов", f.purchase.points[1], f.purchase.loyaltyReversals)
+	}
+
+	// Журнал сходится с кошельком: выручка и бонусная программа вернулись к нулю
+	for _, ref := range []models.AccountRef{
+		models.SystemAccount(models.AccountRevenue),
+		models.SystemAccount(models.AccountBonus),
+		models.CustomerAccount(1),
+		models.CustomerBonusAccount(1),
+	} {
+		if got := f.ledger.balance(ref); got != 0 {
+			t.Errorf("сальдо %s = %d, want 0", ref.Code(), got)
+		}
+	}
+	if got := f.receipts.issued[len(f.receipts.issued)-1]; got != reversal.ID {
+		t.Errorf("последний чек по транзакции %d, want чек возврата %d", got, reversal.ID)
+	}
+}
+
+func TestReverseChargeRevokesOnlyUnspentPromoBonus(t *testing.T) {
+	f := newWalletFixture(SpendBonusFirst)
+	purchase := f.buyWithPromoBonus(t)
+	// 30 ₽ из бонусов по промокоду клиент уже потратил
+	if err := f.usecase.Charge(nil, &models.Transaction{UserID: 1, Amount: rub(3000), Type: models.Buy}, "Оплата сессии"); err != nil {
+		t.Fatalf("Charge() error: %v", err)
+	}
+	f.wallet(t, 40000, 2000)
+
+	if _, err := f.usecase.ReverseTransaction(context.Background(), purchase.ID, 99, "Ошибка кассира"); err != nil {
+		t.Fatalf("ReverseTransaction() error: %v", err)
+	}
+	f.wallet(t, 50000, 20000)
+	if got := f.ledger.balance(models.SystemAccount(models.AccountBonus)); got != 3000 {
+		t.Errorf("счет бонусной программы = %d, want 3000 (потраченные бонусы не возвращаются)", got)
+	}
+}
+
+func TestReverseTransactionRejects(t *testing.T) {
+	f := newWalletFixture(SpendBonusFirst)
+	purchase := f.buyWithPromoBonus(t)
+	transferOut := &models.Transaction{UserID: 1, Amount: rub(1000), Type: models.TransferOut}
+	f.wallets.CreateTransaction(nil, transferOut)
+	online := &models.Transaction{UserID: 1, Amount: rub(1000), Type: models.Add, PaymentMethod: models.PaymentOnline}
+	f.wallets.CreateTransaction(nil, online)
+
+	if _, err := f.usecase.ReverseTransaction(context.Background(), purchase.ID, 99, "  "); err != errors.ErrReversalReasonRequired {
+		t.Fatalf("без причины: error = %v, want %v", err, errors.ErrReversalReasonRequired)
+	}
+	for _, id := range []int64{transferOut.ID, online.ID} {
+		if _, err := f.usecase.ReverseTransaction(context.Background(), id, 99, "ошибка"); err != errors.ErrTransactionNotReversible {
+			t.Fatalf("транзакция %d: error = %v, want %v", id, err, errors.ErrTransactionNotReversible)
+		}
+	}
+
+	if _, err := f.usecase.ReverseTransaction(context.Background(), purchase.ID, 99, "ошибка"); err != nil {
+		t.Fatalf("первое сторно: %v", err)
+	}
+	entries := len(f.ledger.entries)
+	if _, err := f.usecase.ReverseTransaction(context.Background(), purchase.ID, 99, "ошибка"); err != errors.ErrTransactionAlreadyReversed {
+		t.Fatalf("повторное сторно: error = %v, want %v", err, errors.ErrTransactionAlreadyReversed)
+	}
+	f.wallet(t, 50000, 20000)
+	if len(f.ledger.entries) != entries {
+		t.Fatal("повторное сторно добавило проводки")
+	}
+}
+
+func TestReverseCashDepositNeedsOpenShift(t *testing.T) {
+	f := newWalletFixture(SpendBonusFirst)
+	deposit := &models.Transaction{UserID: 1, Amount: rub(10000), Type: models.Add, PaymentMethod: models.PaymentCash}
+	f.wallets.CreateTransaction(nil, deposit)
+
+	if _, err := f.usecase.ReverseTransaction(context.Background(), deposit.ID, 99, "ошибка"); err != errors.ErrNoOpenShift {
+		t.Fatalf("без смены: error = %v, want %v", err, errors.ErrNoOpenShift)
+	}
+	f.wallet(t, 50000, 20000)
+
+	f.shifts.open = &models.Shift{ID: 5}
+	reversal, err := f.usecase.ReverseTransaction(context.Background(), deposit.ID, 99, "ошибка")
+	if err != nil {
+		t.Fatalf("ReverseTransaction() error: %v", err)
+	}
+	if reversal.ShiftID == nil || *reversal.ShiftID != 5 {
+		t.Fatalf("сторно не привязано к смене: %+v", reversal)
+	}
+	f.wallet(t, 40000, 20000)
+	if got := f.ledger.balance(models.SystemAccount(models.AccountCashDrawer)); got != -10000 {
+		t.Errorf("касса = %d, want -10000", got)
+	}
+	if got := f.ledger.balance(models.CustomerAccount(1)); got != 10000 {
+		t.Errorf("кошелек в журнале = %d, want 10000", got)
+	}
+}
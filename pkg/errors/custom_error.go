@@ -3,53 +3,208 @@ package errors
 import "errors"
 
 var (
-	ErrUserNotFound         = errors.New("пользователь не найден")
-	ErrForbidden            = errors.New("ошибка доступа к текущему запросу. необходима роль пользователя: админ")
-	ErrWrongIDFromJWT       = errors.New("неправильный user_id в токене")
-	ErrWrongRoleFromJWT     = errors.New("неправильная роль в токене")
-	ErrJSONRequest          = errors.New("ошибка JSON запроса")
-	ErrInvalidRole          = errors.New("некорректная роль")
-	ErrUserAlreadyExists    = errors.New("пользователь уже существует")
-	ErrInvalidCredentials   = errors.New("неверный email или пароль")
-	ErrUsernameTaken        = errors.New("пользователь с таким никнеймом уже существует")
-	ErrHashedPassword       = errors.New("проблема с хешированием пароля")
-	ErrPasswordTooShort     = errors.New("пароль должен содержать минимум 6 символов")
-	ErrCreatedUser          = errors.New("ошибка при создании в базу данных пользователя")
-	ErrCreatedSession       = errors.New("ошибка при создании в базу данных сессии")
-	ErrUpdateSession        = errors.New("ошибка обновления сессии в базу данных")
-	ErrUpdateComputer       = errors.New("ошибка обновления компьютера в базу данных")
-	ErrFindComputer         = errors.New("ошибка при поиске компьютера в базе данных")
-	ErrFindUser             = errors.New("ошибка при поиске пользователя в базе данных")
-	ErrNameEmpty            = errors.New("имя не может быть пустым")
-	ErrPasswordEmpty        = errors.New("пароль не может быть пустым")
-	ErrEmailEmpty           = errors.New("почта не может быть пустая")
-	ErrSessionNotFound      = errors.New("сессия не найдена")
-	ErrSessionActive        = errors.New("у пользователя уже есть активная сессия")
-	ErrPCBusy               = errors.New("компьютер уже занят")
-	ErrInvalidSessionID     = errors.New("некорректный идентификатор сессии")
-	ErrRegistration         = errors.New("ошибка при регистрации пользователя")
-	ErrComputerNotFound     = errors.New("компьютер не найден")
-	ErrTokenGeneration      = errors.New("ошибка генерации токена")
-	ErrUnexpected           = errors.New("неизвестная ошибка")
-	ErrMissingToken         = errors.New("токен не найден в хедере")
-	ErrWrongToken           = errors.New("токен не правильный")
-	ErrFindTariffByID       = errors.New("ошибка при поиске тарифа в базе данных по id")
-	ErrInvalidTariffID      = errors.New("ошибка чтения тариф id")
-	ErrTariffNotFound       = errors.New("тариф не найден")
-	ErrFindTariffs          = errors.New("ошибка при поиске тарифов в базе данных")
-	ErrCommitData           = errors.New("ошибка при сохранении изменений в базе данных")
-	ErrUpdateComputerStatus = errors.New("ошибка при обновлении статуса компьютера")
-	ErrStartTransaction     = errors.New("ошибка при создании транзакции")
-	ErrDeleteRedis          = errors.New("ошибка удаления данных из Редис")
-	ErrInvalidAmount        = errors.New("количество денег должно быть больше нуля")
-	ErrInsufficientFunds    = errors.New("баланс меньше чем сумма которую хотят вывести")
-	ErrToDeposit            = errors.New("ошибка при обновлении суммы в кошельке")
-	ErrCheckBalance         = errors.New("ошибка при проверке баланса, кошелек не найден")
-	ErrCheckTransaction     = errors.New("ошибка при просмотре транзакций в базе данных")
-	ErrWithdraw             = errors.New("ошибка при выводе средств")
-	ErrWalletAlreadyExists  = errors.New("ошибка при создании кошелька: он уже существует")
-	ErrCreateWallet         = errors.New("ошибка при создании кошелька в базу данных")
-	ErrInvalidUserID        = errors.New("ошибка чтения id пользователя")
-	ErrCreateTransaction    = errors.New("ошибка создания модели транзакции")
-	ErrFailedStatus         = errors.New("статус запрашиваемой сессии неверный")
+	ErrUserNotFound               = errors.New("пользователь не найден")
+	ErrForbidden                  = errors.New("ошибка доступа к текущему запросу. необходима роль пользователя: админ")
+	ErrWrongIDFromJWT             = errors.New("неправильный user_id в токене")
+	ErrWrongRoleFromJWT           = errors.New("неправильная роль в токене")
+	ErrJSONRequest                = errors.New("ошибка JSON запроса")
+	ErrInvalidRole                = errors.New("некорректная роль")
+	ErrUserAlreadyExists          = errors.New("пользователь уже существует")
+	ErrInvalidCredentials         = errors.New("неверный email или пароль")
+	ErrUsernameTaken              = errors.New("пользователь с таким никнеймом уже существует")
+	ErrHashedPassword             = errors.New("проблема с хешированием пароля")
+	ErrPasswordTooShort           = errors.New("пароль должен содержать минимум 6 символов")
+	ErrCreatedUser                = errors.New("ошибка при создании в базу данных пользователя")
+	ErrCreatedSession             = errors.New("ошибка при создании в базу данных сессии")
+	ErrUpdateSession              = errors.New("ошибка обновления сессии в базу данных")
+	ErrUpdateComputer             = errors.New("ошибка обновления компьютера в базу данных")
+	ErrFindComputer               = errors.New("ошибка при поиске компьютера в базе данных")
+	ErrFindUser                   = errors.New("ошибка при поиске пользователя в базе данных")
+	ErrNameEmpty                  = errors.New("имя не может быть пустым")
+	ErrPasswordEmpty              = errors.New("пароль не может быть пустым")
+	ErrEmailEmpty                 = errors.New("почта не может быть пустая")
+	ErrSessionNotFound            = errors.New("сессия не найдена")
+	ErrSessionActive              = errors.New("у пользователя уже есть активная сессия")
+	ErrPCBusy                     = errors.New("компьютер уже занят")
+	ErrInvalidSessionID           = errors.New("некорректный идентификатор сессии")
+	ErrRegistration               = errors.New("ошибка при регистрации пользователя")
+	ErrComputerNotFound           = errors.New("компьютер не найден")
+	ErrTokenGeneration            = errors.New("ошибка генерации токена")
+	ErrUnexpected                 = errors.New("неизвестная ошибка")
+	ErrMissingToken               = errors.New("токен не найден в хедере")
+	ErrWrongToken                 = errors.New("токен не правильный")
+	ErrFindTariffByID             = errors.New("ошибка при поиске тарифа в базе данных по id")
+	ErrInvalidTariffID            = errors.New("ошибка чтения тариф id")
+	ErrTariffNotFound             = errors.New("тариф не найден")
+	ErrFindTariffs                = errors.New("ошибка при поиске тарифов в базе данных")
+	ErrCommitData                 = errors.New("ошибка при сохранении изменений в базе данных")
+	ErrUpdateComputerStatus       = errors.New("ошибка при обновлении статуса компьютера")
+	ErrStartTransaction           = errors.New("ошибка при создании транзакции")
+	ErrDeleteRedis                = errors.New("ошибка удаления данных из Редис")
+	ErrInvalidAmount              = errors.New("количество денег должно быть больше нуля")
+	ErrInsufficientFunds          = errors.New("баланс меньше чем сумма которую хотят вывести")
+	ErrToDeposit                  = errors.New("ошибка при обновлении суммы в кошельке")
+	ErrCheckBalance               = errors.New("ошибка при проверке баланса, кошелек не найден")
+	ErrCheckTransaction           = errors.New("ошибка при просмотре транзакций в базе данных")
+	ErrWithdraw                   = errors.New("ошибка при выводе средств")
+	ErrWalletAlreadyExists        = errors.New("ошибка при создании кошелька: он уже существует")
+	ErrCreateWallet               = errors.New("ошибка при создании кошелька в базу данных")
+	ErrInvalidUserID              = errors.New("ошибка чтения id пользователя")
+	ErrCreateTransaction          = errors.New("ошибка создания модели транзакции")
+	ErrFailedStatus               = errors.New("статус запрашиваемой сессии неверный")
+	ErrCreatePromotion            = errors.New("ошибка при создании промокода в базе данных")
+	ErrFindPromotion              = errors.New("ошибка при поиске промокодов в базе данных")
+	ErrPromotionNotFound          = errors.New("промокод не найден")
+	ErrPromotionInactive          = errors.New("промокод отключен")
+	ErrPromotionExpired           = errors.New("срок действия промокода истек или еще не начался")
+	ErrPromotionNotApplicable     = errors.New("промокод не применим к выбранному тарифу, компьютеру или времени")
+	ErrPromotionLimitReached      = errors.New("лимит использований промокода исчерпан")
+	ErrRedeemPromotion            = errors.New("ошибка при применении промокода")
+	ErrInvalidPromotion           = errors.New("некорректные параметры промокода")
+	ErrCreatePackage              = errors.New("ошибка при создании пакета в базе данных")
+	ErrFindPackage                = errors.New("ошибка при поиске пакетов в базе данных")
+	ErrPackageNotFound            = errors.New("пакет не найден")
+	ErrPackageInactive            = errors.New("пакет недоступен для покупки")
+	ErrInvalidPackage             = errors.New("некорректные параметры пакета")
+	ErrInvalidPackageID           = errors.New("ошибка чтения id пакета")
+	ErrCreateUserPackage          = errors.New("ошибка при сохранении купленного пакета")
+	ErrConsumePackage             = errors.New("ошибка при списании минут с пакета")
+	ErrPackageExhausted           = errors.New("в пакете недостаточно минут")
+	ErrExpirePackages             = errors.New("ошибка при обработке истекших пакетов")
+	ErrSaveQuote                  = errors.New("ошибка при сохранении котировки цены")
+	ErrQuoteNotFound              = errors.New("котировка цены не найдена или истекла")
+	ErrQuoteMismatch              = errors.New("параметры сессии не совпадают с котировкой")
+	ErrQuoteChanged               = errors.New("цена изменилась с момента котировки, запросите новую")
+	ErrInvalidMoney               = errors.New("некорректная денежная сумма")
+	ErrCurrencyMismatch           = errors.New("валюта суммы не совпадает с валютой кошелька")
+	ErrMigrateMoney               = errors.New("ошибка при переводе денежных колонок в копейки")
+	ErrLedgerAccount              = errors.New("ошибка при получении счета в журнале проводок")
+	ErrUnbalancedEntry            = errors.New("проводка не сбалансирована: сумма дебета не равна сумме кредита")
+	ErrCreateJournalEntry         = errors.New("ошибка при сохранении проводки в журнал")
+	ErrReconcile                  = errors.New("ошибка при сверке кошельков с журналом проводок")
+	ErrIdempotencyStore           = errors.New("ошибка хранилища ключей идемпотентности")
+	ErrInvalidIdempotencyKey      = errors.New("некорректный ключ идемпотентности")
+	ErrIdempotencyInProgress      = errors.New("запрос с этим ключом идемпотентности уже выполняется")
+	ErrIdempotencyKeyReused       = errors.New("ключ идемпотентности уже использован с другим телом запроса")
+	ErrInvalidPaymentMethod       = errors.New("некорректный способ оплаты: допустимы cash, card, transfer")
+	ErrLockWallet                 = errors.New("ошибка блокировки кошелька")
+	ErrCreateTransfer             = errors.New("ошибка при создании перевода")
+	ErrFindTransfer               = errors.New("ошибка при поиске переводов в базе данных")
+	ErrUpdateTransfer             = errors.New("ошибка обновления перевода")
+	ErrTransferNotFound           = errors.New("перевод не найден")
+	ErrTransferAlreadyReversed    = errors.New("перевод уже отменен")
+	ErrTransferToSelf             = errors.New("нельзя перевести средства самому себе")
+	ErrTransferTooSmall           = errors.New("сумма перевода меньше минимальной")
+	ErrTransferDailyLimit         = errors.New("превышен дневной лимит переводов")
+	ErrInvalidTransferID          = errors.New("некорректный идентификатор перевода")
+	ErrCreateBonusGrant           = errors.New("ошибка при начислении бонусов")
+	ErrFindBonusGrant             = errors.New("ошибка при поиске бонусных начислений в базе данных")
+	ErrUpdateBonusGrant           = errors.New("ошибка обновления бонусного начисления")
+	ErrInvalidBonusSource         = errors.New("некорректный источник бонусов: допустимы promotion, refund, manual")
+	ErrFindLoyalty                = errors.New("ошибка при получении баллов лояльности")
+	ErrUpdateLoyalty              = errors.New("ошибка обновления баллов лояльности")
+	ErrNotEnoughPoints            = errors.New("недостаточно баллов лояльности")
+	ErrInvalidMinutes             = errors.New("количество минут должно быть больше нуля")
+	ErrCreatePayment              = errors.New("ошибка при создании платежа")
+	ErrUpdatePayment              = errors.New("ошибка обновления платежа")
+	ErrPaymentNotFound            = errors.New("платеж не найден")
+	ErrPaymentProvider            = errors.New("ошибка платежного провайдера")
+	ErrInvalidSignature           = errors.New("неверная подпись уведомления")
+	ErrPaymentMismatch            = errors.New("уведомление не соответствует платежу")
+	ErrPaymentNotRefundable       = errors.New("вернуть можно только успешный платеж")
+	ErrInvalidPaymentID           = errors.New("некорректный идентификатор платежа")
+	ErrCreateShift                = errors.New("ошибка при открытии смены")
+	ErrCloseShift                 = errors.New("ошибка при закрытии смены")
+	ErrFindShift                  = errors.New("ошибка при поиске смены в базе данных")
+	ErrShiftNotFound              = errors.New("смена не найдена")
+	ErrShiftAlreadyOpen           = errors.New("смена уже открыта")
+	ErrNoOpenShift                = errors.New("нет открытой смены")
+	ErrShiftReport                = errors.New("ошибка при формировании отчета по смене")
+	ErrInvalidShiftID             = errors.New("некорректный идентификатор смены")
+	ErrCreateReceipt              = errors.New("ошибка при создании чека")
+	ErrFindReceipt                = errors.New("ошибка при поиске чеков в базе данных")
+	ErrUpdateReceipt              = errors.New("ошибка обновления чека")
+	ErrReceiptNotFound            = errors.New("чек не найден")
+	ErrInvalidReceiptID           = errors.New("некорректный идентификатор чека")
+	ErrInvalidReceiptFormat       = errors.New("некорректный формат чека: допустимы json, text, pdf")
+	ErrInvalidTransactionFilter   = errors.New("некорректный фильтр транзакций")
+	ErrExportTransactions         = errors.New("ошибка выгрузки транзакций")
+	ErrUpdateTransaction          = errors.New("ошибка обновления транзакции")
+	ErrTransactionNotFound        = errors.New("транзакция не найдена")
+	ErrInvalidTransactionID       = errors.New("некорректный идентификатор транзакции")
+	ErrTransactionNotReversible   = errors.New("эту транзакцию нельзя сторнировать")
+	ErrTransactionAlreadyReversed = errors.New("транзакция уже сторнирована")
+	ErrReversalReasonRequired     = errors.New("нужно указать причину сторно")
+	ErrFindLimits                 = errors.New("ошибка при поиске ограничений игрока")
+	ErrSaveLimits                 = errors.New("ошибка сохранения ограничений игрока")
+	ErrInvalidLimits              = errors.New("некорректные ограничения: суммы и минуты не могут быть отрицательными, часы задаются парой от 0 до 23")
+	ErrInvalidGuardian            = errors.New("некорректный опекун")
+	ErrTariffNotAllowed           = errors.New("тариф недоступен по ограничениям аккаунта")
+	ErrOutsideAllowedHours        = errors.New("игра в это время недоступна по ограничениям аккаунта")
+	ErrDailyMinutesLimit          = errors.New("превышен дневной лимит игрового времени")
+	ErrDailySpendLimit            = errors.New("превышен дневной лимит расходов")
+	ErrWeeklySpendLimit           = errors.New("превышен недельный лимит расходов")
+	ErrCreateVoucher              = errors.New("ошибка при создании подарочных карт")
+	ErrFindVoucher                = errors.New("ошибка при поиске подарочных карт")
+	ErrUpdateVoucher              = errors.New("ошибка обновления подарочной карты")
+	ErrVoucherBatchNotFound       = errors.New("партия подарочных карт не найдена")
+	ErrInvalidVoucherBatch        = errors.New("некорректная партия: нужен номинал или минуты и количество карт")
+	ErrInvalidVoucherBatchID      = errors.New("некорректный идентификатор партии")
+	ErrVoucherInvalid             = errors.New("неверный код подарочной карты")
+	ErrVoucherNotSellable         = errors.New("карту можно продать только из статуса issued")
+	ErrVoucherRedeemed            = errors.New("подарочная карта уже погашена")
+	ErrVoucherExpired             = errors.New("срок действия подарочной карты истек")
+	ErrAttemptStore               = errors.New("ошибка хранилища счетчиков попыток")
+	ErrTooManyAttempts            = errors.New("слишком много неудачных попыток, попробуйте позже")
+	ErrCreateOrganization         = errors.New("ошибка при создании организации")
+	ErrFindOrganization           = errors.New("ошибка при поиске организации в базе данных")
+	ErrUpdateOrganization         = errors.New("ошибка обновления организации")
+	ErrOrganizationNotFound       = errors.New("организация не найдена")
+	ErrInvalidOrganization        = errors.New("некорректная организация: нужны название и владелец")
+	ErrInvalidOrganizationID      = errors.New("некорректный идентификатор организации")
+	ErrAlreadyMember              = errors.New("пользователь уже состоит в организации")
+	ErrNotOrganizationMember      = errors.New("пользователь не состоит в организации")
+	ErrCannotRemoveOwner          = errors.New("владельца нельзя исключить из организации")
+	ErrMemberCapExceeded          = errors.New("превышен месячный лимит расходов участника организации")
+	ErrCreateInvoice              = errors.New("ошибка при формировании счета организации")
+	ErrInvoiceNotFound            = errors.New("счет не найден")
+	ErrInvalidPeriod              = errors.New("некорректный период: ожидается месяц в формате 2006-01")
+	ErrOrganizationSessionOptions = errors.New("промокоды и котировки не применяются к сессиям за счет организации")
+	ErrInvalidRefreshToken        = errors.New("refresh-токен недействителен или истек")
+	ErrFindRefreshToken           = errors.New("ошибка при поиске refresh-токена в базе данных")
+	ErrRevokeToken                = errors.New("ошибка отзыва токена")
+	ErrRevocationStore            = errors.New("ошибка хранилища отозванных токенов")
+	ErrTokenRevoked               = errors.New("токен отозван")
+	ErrCannotChangeOwnRole        = errors.New("нельзя изменить собственную роль")
+	ErrUpdateUser                 = errors.New("ошибка обновления пользователя")
+	ErrUserBlocked                = errors.New("пользователь заблокирован")
+	ErrInvalidUserFilter          = errors.New("неверные параметры поиска пользователей")
+	ErrCannotModifySelf           = errors.New("нельзя заблокировать или удалить самого себя")
+	ErrBlockReasonRequired        = errors.New("укажите причину блокировки")
+	ErrPhoneTaken                 = errors.New("пользователь с таким телефоном уже существует")
+	ErrUserNotBlocked             = errors.New("пользователь не заблокирован")
+	ErrSendMail                   = errors.New("не удалось отправить письмо")
+	ErrInvalidUserToken           = errors.New("ссылка недействительна или устарела")
+	ErrFindUserToken              = errors.New("ошибка поиска токена")
+	ErrEmailNotVerified           = errors.New("почта не подтверждена")
+	ErrEmailAlreadyVerified       = errors.New("почта уже подтверждена")
+	ErrTwoFactorNotEnabled        = errors.New("двухфакторная аутентификация не включена")
+	ErrTwoFactorEnabled           = errors.New("двухфакторная аутентификация уже включена")
+	ErrTwoFactorNotEnrolled       = errors.New("сначала получите секрет для приложения-аутентификатора")
+	ErrFindTwoFactor              = errors.New("ошибка поиска настроек двухфакторной аутентификации")
+	ErrUpdateTwoFactor            = errors.New("ошибка сохранения настроек двухфакторной аутентификации")
+	ErrInvalidTwoFactorCode       = errors.New("неверный код подтверждения")
+	ErrTwoFactorRequired          = errors.New("для этого действия нужен вход с двухфакторной аутентификацией")
+	ErrInvalidChallenge           = errors.New("сеанс входа истек, войдите заново")
+	ErrLoginThrottled             = errors.New("слишком много неудачных попыток входа, повторите позже")
+	ErrAccountLocked              = errors.New("вход временно заблокирован из-за неудачных попыток")
+	ErrCreateLoginEvent           = errors.New("ошибка записи в журнал входа")
+	ErrFindLoginEvent             = errors.New("ошибка чтения журнала входа")
+	ErrInvalidLoginEventFilter    = errors.New("неверные параметры журнала входа")
+	ErrPaymentsDisabled           = errors.New("онлайн-оплата отключена")
+	ErrRoleTooHigh                = errors.New("недостаточно прав для действий над пользователем с такой же или более высокой ролью")
+	ErrStaffEmailChange           = errors.New("почту сотрудника может менять только владелец")
+	ErrInvalidTariff              = errors.New("некорректные параметры тарифа")
+	ErrCreateTariff               = errors.New("ошибка при создании тарифа в базе данных")
+	ErrUpdateTariff               = errors.New("ошибка при обновлении тарифа в базе данных")
 )
@@ -0,0 +1,221 @@
+package money
+
+import (
+	"bytes"
+	"computer-club/pkg/errors"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// DefaultCurrency - валюта клуба по умолчанию
+const DefaultCurrency = "RUB"
+
+// Money - денежная сумма в минимальных единицах валюты (копейках) с кодом валюты.
+// Все суммы в клубе хранятся в одной валюте DefaultCurrency: суммы в других валютах отклоняются
+// при разборе, а арифметика и сравнения сумм в разных валютах паникуют с ErrCurrencyMismatch,
+// потому что такая сумма может появиться только из-за ошибки в коде.
+type Money struct {
+	Amount   int64  `json:"amount"`
+	Currency string `json:"currency" gorm:"size:3;default:RUB"`
+}
+
+// New создает сумму из копеек в валюте по умолчанию
+func New(minor int64) Money {
+	return Money{Amount: minor, Currency: DefaultCurrency}
+}
+
+// FromMajor создает сумму из целых рублей
+func FromMajor(units int64) Money {
+	return New(units * 100)
+}
+
+// Zero возвращает нулевую сумму в валюте по умолчанию
+func Zero() Money {
+	return New(0)
+}
+
+// Parse разбирает десятичную запись суммы в рублях, например "100", "99.9" или "0.05"
+func Parse(value string) (Money, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return Money{}, errors.ErrInvalidMoney
+	}
+
+	negative := false
+	switch value[0] {
+	case '-':
+		negative = true
+		value = value[1:]
+	case '+':
+		value = value[1:]
+	}
+
+	// Знак допускается только перед всей суммой, поэтому обе части - только цифры
+	whole, fraction, hasPoint := strings.Cut(value, ".")
+	if !isDigits(whole) || len(fraction) > 2 || (hasPoint && !isDigits(fraction)) {
+		return Money{}, errors.ErrInvalidMoney
+	}
+	for len(fraction) < 2 {
+		fraction += "0"
+	}
+
+	units, err := strconv.ParseInt(whole, 10, 64)
+	if err != nil {
+		return Money{}, errors.ErrInvalidMoney
+	}
+	cents, err := strconv.ParseInt(fraction, 10, 64)
+	if err != nil {
+		return Money{}, errors.ErrInvalidMoney
+	}
+	if units > (math.MaxInt64-cents)/100 {
+		return Money{}, errors.ErrInvalidMoney
+	}
+
+	amount := units*100 + cents
+	if negative {
+		amount = -amount
+	}
+	return New(amount), nil
+}
+
+func (m Money) Add(other Money) Money {
+	m.mustMatch(other)
+	return Money{Amount: m.Amount + other.Amount, Currency: m.currency()}
+}
+
+func (m Money) Sub(other Money) Money {
+	m.mustMatch(other)
+	return Money{Amount: m.Amount - other.Amount, Currency: m.currency()}
+}
+
+func (m Money) Neg() Money {
+	return Money{Amount: -m.Amount, Currency: m.currency()}
+}
+
+// MulRatio умножает сумму на дробь num/den с округлением до копейки
+func (m Money) MulRatio(num, den int64) Money {
+	if den == 0 {
+		return Money{Amount: 0, Currency: m.currency()}
+	}
+	return Money{Amount: roundDiv(m.Amount*num, den), Currency: m.currency()}
+}
+
+// MulFloat умножает сумму на коэффициент с округлением до копейки
+func (m Money) MulFloat(factor float64) Money {
+	return Money{Amount: int64(math.Round(float64(m.Amount) * factor)), Currency: m.currency()}
+}
+
+func (m Money) Min(other Money) Money {
+	m.mustMatch(other)
+	if other.Amount < m.Amount {
+		return Money{Amount: other.Amount, Currency: m.currency()}
+	}
+	return m
+}
+
+func (m Money) LessThan(other Money) bool {
+	m.mustMatch(other)
+	return m.Amount < other.Amount
+}
+
+// SameCurrency сообщает, в одной ли валюте суммы. Пустая валюта считается валютой по умолчанию
+func (m Money) SameCurrency(other Money) bool {
+	return m.currency() == other.currency()
+}
+
+// CheckCurrency возвращает ErrCurrencyMismatch, если сумма не в валюте клуба
+func (m Money) CheckCurrency() error {
+	if m.currency() != DefaultCurrency {
+		return errors.ErrCurrencyMismatch
+	}
+	return nil
+}
+
+func (m Money) IsZero() bool {
+	return m.Amount == 0
+}
+
+func (m Money) IsPositive() bool {
+	return m.Amount > 0
+}
+
+func (m Money) IsNegative() bool {
+	return m.Amount < 0
+}
+
+// Major возвращает сумму в рублях строкой с двумя знаками после точки
+func (m Money) Major() string {
+	amount := m.Amount
+	sign := ""
+	if amount < 0 {
+		sign = "-"
+		amount = -amount
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, amount/100, amount%100)
+}
+
+func (m Money) String() string {
+	return m.Major() + " " + m.currency()
+}
+
+// UnmarshalJSON принимает объект {"amount": 10050, "currency": "RUB"} в копейках
+// или число/строку в рублях, например 100.5 или "100.50". Валюта, отличная от DefaultCurrency, отклоняется
+func (m *Money) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if len(data) > 0 && data[0] == '{' {
+		type plain Money
+		var value plain
+		if err := json.Unmarshal(data, &value); err != nil {
+			return errors.ErrInvalidMoney
+		}
+		*m = Money(value)
+		m.Currency = m.currency()
+		return m.CheckCurrency()
+	}
+
+	parsed, err := Parse(strings.Trim(string(data), `"`))
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
+
+func (m Money) mustMatch(other Money) {
+	if !m.SameCurrency(other) {
+		panic(fmt.Errorf("%w: %s и %s", errors.ErrCurrencyMismatch, m.currency(), other.currency()))
+	}
+}
+
+func (m Money) currency() string {
+	if m.Currency == "" {
+		return DefaultCurrency
+	}
+	return m.Currency
+}
+
+func isDigits(value string) bool {
+	if value == "" {
+		return false
+	}
+	for _, r := range value {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// roundDiv делит с округлением половины от нуля
+func roundDiv(a, b int64) int64 {
+	if b < 0 {
+		a, b = -a, -b
+	}
+	if a >= 0 {
+		return (a + b/2) / b
+	}
+	return -((-a + b/2) / b)
+}
@@ -0,0 +1,188 @@
+package money
+
+import (
+	"computer-club/pkg/errors"
+	stderrors "errors"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		input string
+		want  int64
+		err   error
+	}{
+		{input: "100", want: 10000},
+		{input: "99.9", want: 9990},
+		{input: "0.05", want: 5},
+		{input: "1.5", want: 150},
+		{input: " 12.34 ", want: 1234},
+		{input: "+7", want: 700},
+		{input: "-3.25", want: -325},
+		{input: "0", want: 0},
+		{input: "92233720368547758.07", want: 9223372036854775807},
+		{input: "", err: errors.ErrInvalidMoney},
+		{input: "-", err: errors.ErrInvalidMoney},
+		{input: "1.", err: errors.ErrInvalidMoney},
+		{input: ".5", err: errors.ErrInvalidMoney},
+		{input: "1.+5", err: errors.ErrInvalidMoney},
+		{input: "1.-5", err: errors.ErrInvalidMoney},
+		{input: "1. 5", err: errors.ErrInvalidMoney},
+		{input: "+-5", err: errors.ErrInvalidMoney},
+		{input: "-+5", err: errors.ErrInvalidMoney},
+		{input: "1.234", err: errors.ErrInvalidMoney},
+		{input: "1.2.3", err: errors.ErrInvalidMoney},
+		{input: "1e3", err: errors.ErrInvalidMoney},
+		{input: "abc", err: errors.ErrInvalidMoney},
+		{input: "92233720368547758.08", err: errors.ErrInvalidMoney},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := Parse(tt.input)
+			if err != tt.err {
+				t.Fatalf("Parse(%q) error = %v, want %v", tt.input, err, tt.err)
+			}
+			if err != nil {
+				return
+			}
+			if got.Amount != tt.want || got.Currency != DefaultCurrency {
+				t.Fatalf("Parse(%q) = %+v, want %d %s", tt.input, got, tt.want, DefaultCurrency)
+			}
+		})
+	}
+}
+
+func TestMulRatio(t *testing.T) {
+	tests := []struct {
+		name     string
+		amount   int64
+		num, den int64
+		want     int64
+	}{
+		{name: "целое", amount: 10000, num: 1, den: 4, want: 2500},
+		{name: "вниз", amount: 100, num: 1, den: 3, want: 33},
+		{name: "вверх", amount: 200, num: 1, den: 3, want: 67},
+		{name: "половина от нуля", amount: 5, num: 1, den: 2, want: 3},
+		{name: "отрицательная половина от нуля", amount: -5, num: 1, den: 2, want: -3},
+		{name: "отрицательный знаменатель", amount: 5, num: 1, den: -2, want: -3},
+		{name: "процент", amount: 12345, num: 15, den: 100, want: 1852},
+		{name: "нулевой знаменатель", amount: 10000, num: 1, den: 0, want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := New(tt.amount).MulRatio(tt.num, tt.den)
+			if got.Amount != tt.want {
+				t.Fatalf("New(%d).MulRatio(%d, %d) = %d, want %d", tt.amount, tt.num, tt.den, got.Amount, tt.want)
+			}
+		})
+	}
+}
+
+func TestMulFloat(t *testing.T) {
+	tests := []struct {
+		amount int64
+		factor float64
+		want   int64
+	}{
+		{amount: 1000, factor: 0.5, want: 500},
+		{amount: 333, factor: 0.1, want: 33},
+		{amount: 335, factor: 0.1, want: 34},
+		{amount: -335, factor: 0.1, want: -34},
+		{amount: 1999, factor: 1.5, want: 2999},
+	}
+	for _, tt := range tests {
+		got := New(tt.amount).MulFloat(tt.factor)
+		if got.Amount != tt.want {
+			t.Errorf("New(%d).MulFloat(%v) = %d, want %d", tt.amount, tt.factor, got.Amount, tt.want)
+		}
+	}
+}
+
+func TestMajor(t *testing.T) {
+	tests := []struct {
+		amount int64
+		want   string
+	}{
+		{amount: 0, want: "0.00"},
+		{amount: 5, want: "0.05"},
+		{amount: 10050, want: "100.50"},
+		{amount: -325, want: "-3.25"},
+	}
+	for _, tt := range tests {
+		if got := New(tt.amount).Major(); got != tt.want {
+			t.Errorf("New(%d).Major() = %q, want %q", tt.amount, got, tt.want)
+		}
+	}
+}
+
+func TestUnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		input string
+		want  Money
+		err   bool
+	}{
+		{input: `{"amount": 10050, "currency": "RUB"}`, want: New(10050)},
+		{input: `{"amount": 500}`, want: New(500)},
+		{input: `100.5`, want: New(10050)},
+		{input: `"100.50"`, want: New(10050)},
+		{input: `"1.+5"`, err: true},
+		{input: `{"amount": "x"}`, err: true},
+	}
+	for _, tt := range tests {
+		var got Money
+		err := got.UnmarshalJSON([]byte(tt.input))
+		if (err != nil) != tt.err {
+			t.Errorf("UnmarshalJSON(%s) error = %v, want error %v", tt.input, err, tt.err)
+			continue
+		}
+		if !tt.err && got != tt.want {
+			t.Errorf("UnmarshalJSON(%s) = %+v, want %+v", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestUnmarshalJSONRejectsForeignCurrency(t *testing.T) {
+	for _, input := range []string{`{"amount": 10000, "currency": "USD"}`, `{"amount": 10000, "currency": "rub"}`} {
+		var got Money
+		if err := got.UnmarshalJSON([]byte(input)); err != errors.ErrCurrencyMismatch {
+			t.Errorf("UnmarshalJSON(%s) error = %v, want %v", input, err, errors.ErrCurrencyMismatch)
+		}
+	}
+}
+
+func TestCheckCurrency(t *testing.T) {
+	if err := New(100).CheckCurrency(); err != nil {
+		t.Errorf("New(100).CheckCurrency() = %v, want nil", err)
+	}
+	if err := (Money{Amount: 100}).CheckCurrency(); err != nil {
+		t.Errorf("CheckCurrency() без валюты = %v, want nil", err)
+	}
+	if err := (Money{Amount: 100, Currency: "USD"}).CheckCurrency(); err != errors.ErrCurrencyMismatch {
+		t.Errorf("CheckCurrency(USD) = %v, want %v", err, errors.ErrCurrencyMismatch)
+	}
+}
+
+func TestCurrencyMismatchPanics(t *testing.T) {
+	usd := Money{Amount: 100, Currency: "USD"}
+	ops := map[string]func(){
+		"Add":      func() { New(100).Add(usd) },
+		"Sub":      func() { New(100).Sub(usd) },
+		"Min":      func() { New(100).Min(usd) },
+		"LessThan": func() { New(100).LessThan(usd) },
+	}
+	for name, op := range ops {
+		t.Run(name, func(t *testing.T) {
+			defer func() {
+				err, ok := recover().(error)
+				if !ok || !stderrors.Is(err, errors.ErrCurrencyMismatch) {
+					t.Fatalf("%s с разными валютами: recover() = %v, want %v", name, err, errors.ErrCurrencyMismatch)
+				}
+			}()
+			op()
+		})
+	}
+	// Пустая валюта считается валютой по умолчанию
+	if got := New(100).Add(Money{Amount: 50}); got != New(150) {
+		t.Fatalf("Add без валюты = %+v, want %+v", got, New(150))
+	}
+}
@@ -0,0 +1,86 @@
+// Package totp реализует одноразовые пароли по времени (RFC 6238) с параметрами,
+// которые понимают Google Authenticator и аналоги: HMAC-SHA1, 6 цифр, шаг 30 секунд
+package totp
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	Digits = 6
+	Period = 30 * time.Second
+	// secretSize - длина секрета в байтах, рекомендованная RFC 4226
+	secretSize = 20
+)
+
+var encoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// GenerateSecret возвращает случайный секрет в base32, как его вводят в приложение вручную
+func GenerateSecret() (string, error) {
+	buf := make([]byte, secretSize)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return encoding.EncodeToString(buf), nil
+}
+
+// Step - номер временного шага для момента at
+func Step(at time.Time) int64 {
+	return at.Unix() / int64(Period/time.Second)
+}
+
+// Code вычисляет код для шага step (RFC 4226, раздел 5.3)
+func Code(secret string, step int64) (string, error) {
+	key, err := encoding.DecodeString(strings.ToUpper(secret))
+	if err != nil {
+		return "", err
+	}
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(step))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	return fmt.Sprintf("%0*d", Digits, value%1000000), nil
+}
+
+// Validate проверяет код с допуском skew шагов в обе стороны на расхождение часов
+// и возвращает шаг, которому код соответствует. Шаг нужен, чтобы не принять тот же код повторно
+func Validate(secret, code string, at time.Time, skew int64) (int64, bool) {
+	if len(code) != Digits {
+		return 0, false
+	}
+	current := Step(at)
+	for delta := -skew; delta <= skew; delta++ {
+		expected, err := Code(secret, current+delta)
+		if err != nil {
+			return 0, false
+		}
+		if hmac.Equal([]byte(expected), []byte(code)) {
+			return current + delta, true
+		}
+	}
+	return 0, false
+}
+
+// URI возвращает ссылку otpauth://, которую клиент показывает QR-кодом для приложения-аутентификатора
+func URI(issuer, account, secret string) string {
+	values := url.Values{}
+	values.Set("secret", secret)
+	values.Set("issuer", issuer)
+	values.Set("algorithm", "SHA1")
+	values.Set("digits", fmt.Sprint(Digits))
+	values.Set("period", fmt.Sprint(int(Period/time.Second)))
+	label := url.PathEscape(issuer + ":" + account)
+	return "otpauth://totp/" + label + "?" + values.Encode()
+}
@@ -0,0 +1,90 @@
+package totp
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfcSecret - ключ "12345678901234567890" из приложения B RFC 6238 в base32
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+// Тестовые векторы RFC 6238 для SHA1, восьмизначные коды усечены до шести последних цифр
+var rfcVectors = []struct {
+	unix int64
+	code string
+}{
+	{unix: 59, code: "287082"},
+	{unix: 1111111109, code: "081804"},
+	{unix: 1111111111, code: "050471"},
+	{unix: 1234567890, code: "005924"},
+	{unix: 2000000000, code: "279037"},
+	{unix: 20000000000, code: "353130"},
+}
+
+func TestCodeRFC6238(t *testing.T) {
+	for _, tt := range rfcVectors {
+		got, err := Code(rfcSecret, Step(time.Unix(tt.unix, 0)))
+		if err != nil {
+			t.Fatalf("Code(%d) error: %v", tt.unix, err)
+		}
+		if got != tt.code {
+			t.Errorf("Code(%d) = %s, want %s", tt.unix, got, tt.code)
+		}
+	}
+}
+
+func TestCodeLowercaseSecret(t *testing.T) {
+	got, err := Code(strings.ToLower(rfcSecret), Step(time.Unix(59, 0)))
+	if err != nil || got != "287082" {
+		t.Fatalf("Code(lowercase) = %s, %v, want 287082", got, err)
+	}
+}
+
+func TestCodeInvalidSecret(t *testing.T) {
+	if _, err := Code("not base32!", 1); err == nil {
+		t.Fatal("Code с некорректным секретом должен вернуть ошибку")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	at := time.Unix(1111111111, 0)
+	current := Step(at)
+	tests := []struct {
+		name     string
+		code     string
+		at       time.Time
+		skew     int64
+		wantStep int64
+		wantOK   bool
+	}{
+		{name: "текущий шаг", code: "050471", at: at, skew: 0, wantStep: current, wantOK: true},
+		{name: "предыдущий шаг в пределах допуска", code: "050471", at: at.Add(Period), skew: 1, wantStep: current, wantOK: true},
+		{name: "следующий шаг в пределах допуска", code: "050471", at: at.Add(-Period), skew: 1, wantStep: current, wantOK: true},
+		{name: "вне допуска", code: "050471", at: at.Add(2 * Period), skew: 1},
+		{name: "без допуска", code: "050471", at: at.Add(Period), skew: 0},
+		{name: "неверный код", code: "000000", at: at, skew: 1},
+		{name: "короткий код", code: "05047", at: at, skew: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step, ok := Validate(rfcSecret, tt.code, tt.at, tt.skew)
+			if ok != tt.wantOK || step != tt.wantStep {
+				t.Fatalf("Validate() = %d, %v, want %d, %v", step, ok, tt.wantStep, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestGenerateSecret(t *testing.T) {
+	secret, err := GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Code(secret, 0); err != nil {
+		t.Fatalf("сгенерированный секрет не декодируется: %v", err)
+	}
+	if len(secret) != 32 {
+		t.Fatalf("len(secret) = %d, want 32", len(secret))
+	}
+}